@@ -9,6 +9,7 @@ import (
 	mapset "github.com/deckarep/golang-set"
 
 	"github.com/flywave/go-geo"
+	"github.com/flywave/go-tileproxy/imagery"
 	"github.com/flywave/go-tileproxy/layer"
 	"github.com/flywave/go-tileproxy/tile"
 	"github.com/flywave/go-tileproxy/utils"
@@ -128,9 +129,15 @@ func (c *TileCreator) createSingleTile(t *Tile) (*Tile, error) {
 		}()
 
 		if !c.IsCached(t.Coord) {
-			source, err := c.querySources(query)
-			if err != nil {
-				return err
+			source, notModified := c.revalidate(t, query)
+			if notModified {
+				return nil
+			}
+			if source == nil {
+				source, err = c.querySources(query)
+				if err != nil {
+					return err
+				}
 			}
 			source.SetTileOptions(c.manager.GetTileOptions())
 			t.Source = source
@@ -150,22 +157,83 @@ func (c *TileCreator) createSingleTile(t *Tile) (*Tile, error) {
 	return t, err
 }
 
+// revalidate 对已过期的缓存瓦片发送条件请求，上游返回304时只刷新时间戳；
+// 上游返回新数据时直接返回该数据，避免重复请求
+func (c *TileCreator) revalidate(t *Tile, query *layer.MapQuery) (tile.Source, bool) {
+	if len(c.sources) != 1 {
+		return nil, false
+	}
+	rl, ok := c.sources[0].(layer.RevalidateLayer)
+	if !ok {
+		return nil, false
+	}
+	toucher, ok := c.cache.(interface{ TouchTile(*Tile) error })
+	if !ok || !c.cache.IsCached(t) {
+		return nil, false
+	}
+	if err := c.cache.LoadTileMetadata(t); err != nil || (t.ETag == "" && t.LastModified == "") {
+		return nil, false
+	}
+
+	source, notModified, err := rl.GetMapConditional(query, t.GetCacheInfo())
+	if err != nil {
+		return nil, false
+	}
+	if !notModified {
+		return source, false
+	}
+	if t.Source == nil {
+		if err := c.cache.LoadTile(t, false); err != nil {
+			return nil, false
+		}
+	}
+	return nil, toucher.TouchTile(t) == nil
+}
+
+// positionalMerge 波段组合与表达式按配置顺序引用数据源，获取失败的数据源需保留位置
+func (c *TileCreator) positionalMerge() bool {
+	m, ok := c.tileMerger.(*imagery.BandMerger)
+	return ok && m.HasOps()
+}
+
+// needsMerge 单一数据源在配置了波段表达式或调色时也需要经过合并
+func (c *TileCreator) needsMerge(layers []tile.Source) bool {
+	if len(layers) > 1 || c.positionalMerge() {
+		return true
+	}
+	opts, ok := layers[0].GetTileOptions().(*imagery.ImageOptions)
+	return ok && opts.NeedsCompose()
+}
+
 func (c *TileCreator) querySources(query *layer.MapQuery) (tile.Source, error) {
+	positional := c.positionalMerge()
 	layers := []tile.Source{}
+	found := 0
 	for i := range c.sources {
+		var img tile.Source
 		if c.sources[i].GetCoverage() == nil ||
 			(c.sources[i].GetCoverage().Intersects(query.BBox, query.Srs)) {
-			img, err := c.sources[i].GetMap(query)
-			if err == nil && img != nil {
-				layers = append(layers, img)
+			var err error
+			img, err = c.sources[i].GetMap(query)
+			if isTemporaryError(err) {
+				return nil, err
+			}
+			if err != nil {
+				img = nil
 			}
 		}
+		if img != nil {
+			found++
+		}
+		if img != nil || positional {
+			layers = append(layers, img)
+		}
 	}
-	if len(layers) == 0 {
+	if found == 0 {
 		return nil, errors.New("no source create")
 	}
 
-	if len(layers) == 1 {
+	if !c.needsMerge(layers) {
 		return layers[0], nil
 	}
 	ret, err := MergeTiles(layers, c.manager.GetTileOptions(), query, c.tileMerger)
@@ -176,6 +244,12 @@ func (c *TileCreator) querySources(query *layer.MapQuery) (tile.Source, error) {
 	return ret, nil
 }
 
+// isTemporaryError 上游暂时性错误不能与其他数据源合并后写入缓存
+func isTemporaryError(err error) bool {
+	var te interface{ Temporary() bool }
+	return errors.As(err, &te) && te.Temporary()
+}
+
 func (c *TileCreator) createMetaTiles(metaTiles []*geo.MetaTile) ([]*Tile, error) {
 	if c.bulkMetaTiles {
 		created_tiles := []*Tile{}
@@ -3,6 +3,8 @@ package cache
 import (
 	"context"
 	"errors"
+	"image"
+	"image/color"
 	"testing"
 	"time"
 
@@ -536,3 +538,76 @@ func TestTileCreator_CacheLoadError(t *testing.T) {
 		t.Error("Expected tile object even on load error")
 	}
 }
+
+func TestTileCreator_QuerySources_BandSlots(t *testing.T) {
+	expr, err := imagery.ParseBandExpression("s1.r - r", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	merger := imagery.NewBandMerger(imagery.RGBA)
+	merger.SetExpression(expr, nil)
+
+	manager := newCreatorMockManager()
+	creator := NewTileCreator(manager, utils.Dimensions{}, merger, false)
+	failed := newCreatorMockLayer("red")
+	failed.getMapError = errors.New("layer error")
+	creator.sources = []layer.Layer{failed, newCreatorMockLayer("nir"), newCreatorMockLayer("swir")}
+
+	query := &layer.MapQuery{
+		BBox: vec2d.Rect{Min: vec2d.T{0, 0}, Max: vec2d.T{1, 1}},
+		Size: [2]uint32{256, 256},
+		Srs:  geo.NewProj("EPSG:3857"),
+	}
+
+	result, err := creator.querySources(query)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, ok := result.(*imagery.BlankImageSource); !ok {
+		t.Errorf("Expected blank tile when a referenced source fails, got %T", result)
+	}
+}
+
+func TestTileCreator_QuerySources_LayerCompose(t *testing.T) {
+	imageOpts := &imagery.ImageOptions{Format: tile.TileFormat("png"), Transparent: geo.NewBool(true)}
+	tm := NewTileManager(&TileManagerOptions{Options: imageOpts, MetaBuffer: -1, MetaSize: [2]uint32{1, 1}})
+	if tm.merger != nil {
+		t.Fatalf("Expected no band merger without band math, got %T", tm.merger)
+	}
+
+	base := image.NewNRGBA(image.Rect(0, 0, 4, 4))
+	overlay := image.NewNRGBA(image.Rect(0, 0, 4, 4))
+	for y := 0; y < 4; y++ {
+		for x := 0; x < 4; x++ {
+			base.SetNRGBA(x, y, color.NRGBA{B: 255, A: 255})
+			if x < 2 {
+				overlay.SetNRGBA(x, y, color.NRGBA{R: 255, A: 255})
+			}
+		}
+	}
+	bottom, top := newCreatorMockLayer("base"), newCreatorMockLayer("overlay")
+	bottom.mapData = imagery.CreateImageSourceFromImage(base, imageOpts)
+	top.mapData = imagery.CreateImageSourceFromImage(overlay, imageOpts)
+
+	manager := newCreatorMockManager()
+	manager.tileOptions = imageOpts
+	creator := NewTileCreator(manager, utils.Dimensions{}, tm.merger, false)
+	creator.sources = []layer.Layer{bottom, top}
+
+	query := &layer.MapQuery{
+		BBox: vec2d.Rect{Min: vec2d.T{0, 0}, Max: vec2d.T{1, 1}},
+		Size: [2]uint32{4, 4},
+		Srs:  geo.NewProj("EPSG:3857"),
+	}
+	result, err := creator.querySources(query)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	img := result.GetTile().(image.Image)
+	if c := color.NRGBAModel.Convert(img.At(0, 0)).(color.NRGBA); c != (color.NRGBA{R: 255, A: 255}) {
+		t.Errorf("Expected overlay color, got %v", c)
+	}
+	if c := color.NRGBAModel.Convert(img.At(3, 0)).(color.NRGBA); c != (color.NRGBA{B: 255, A: 255}) {
+		t.Errorf("Expected base color, got %v", c)
+	}
+}
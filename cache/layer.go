@@ -10,6 +10,7 @@ import (
 	"github.com/flywave/go-geo"
 	"github.com/flywave/go-tileproxy/imagery"
 	"github.com/flywave/go-tileproxy/layer"
+	"github.com/flywave/go-tileproxy/terrain"
 	"github.com/flywave/go-tileproxy/tile"
 )
 
@@ -84,8 +85,14 @@ func (r *CacheMapLayer) getSource(query *layer.MapQuery) (tile.Source, error) {
 	bbox, srs, tileId := query.BBox, query.Srs, query.TileId
 	currentSrs := r.grid.Srs
 	bbox = srs.TransformRectTo(currentSrs, bbox, 16)
+
+	// 跨坐标系重采样时两个网格的分辨率序列不同(如百度与Web墨卡托)，按分辨率选择级别
+	level := tileId[2]
+	if !srs.Eq(currentSrs) {
+		level = r.grid.ClosestLevel(geo.GetResolution(bbox, query.Size))
+	}
 	if r.queryBuffer != nil {
-		bbox = bufferedBBox(r.grid, bbox, tileId[2], *r.queryBuffer)
+		bbox = bufferedBBox(r.grid, bbox, level, *r.queryBuffer)
 	}
 	src_bbox := bbox
 
@@ -98,7 +105,7 @@ func (r *CacheMapLayer) getSource(query *layer.MapQuery) (tile.Source, error) {
 		currentSrs = r.reprojectSrc
 	}
 
-	_, tile_grid, affected_tile_coords, err := r.grid.GetAffectedLevelTiles(bbox, tileId[2])
+	_, tile_grid, affected_tile_coords, err := r.grid.GetAffectedLevelTiles(bbox, level)
 	if err != nil {
 		return nil, err
 	}
@@ -137,6 +144,19 @@ func (r *CacheMapLayer) getSource(query *layer.MapQuery) (tile.Source, error) {
 		}
 		return r.emptySource, nil
 	}
+	if opts, ok := r.Options.(*terrain.RasterOptions); ok && r.reprojectSrc == nil && srs.Eq(currentSrs) && (!query.TiledOnly || len(tile_collection.tiles) > 1) {
+		tile_sources := []tile.Source{}
+		for _, t := range tile_collection.tiles {
+			tile_sources = append(tile_sources, t.Source)
+		}
+		src_opts, _ := r.tileManager.GetTileOptions().(*terrain.RasterOptions)
+		if src_opts == nil {
+			src_opts = opts
+		}
+		if src := cropRasterTiles(tile_sources, tile_grid, r.grid, r.grid.TilesBBox(coords), level, query, src_opts, opts); src != nil {
+			return src, nil
+		}
+	}
 	if query.TiledOnly {
 		if len(tile_collection.tiles) > 1 || !srs.Eq(currentSrs) {
 			tile_sources := []tile.Source{}
@@ -1,12 +1,16 @@
 package cache
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
 	"os"
+	"path"
 	"strings"
 	"sync"
+	"time"
 
+	"github.com/flywave/go-tileproxy/imagery"
 	"github.com/flywave/go-tileproxy/tile"
 	"github.com/flywave/go-tileproxy/utils"
 )
@@ -40,9 +44,50 @@ func NewLocalCache(cache_dir string, directory_layout string, creater tile.Sourc
 }
 
 func (c *LocalCache) TileLocation(tile *Tile, create_dir bool) (string, error) {
+	if c.isMixed() {
+		return c.mixedTileLocation(tile, create_dir)
+	}
 	return c.tileLocation(tile, c.cacheDir, c.creater.GetExtension(), create_dir)
 }
 
+func (c *LocalCache) isMixed() bool {
+	return imagery.IsMixedFormat(tile.TileFormat(c.creater.GetExtension()))
+}
+
+func replaceExtension(location string, ext string) string {
+	return strings.TrimSuffix(location, path.Ext(location)) + "." + ext
+}
+
+// mixedTileLocation 返回已存在的混合格式瓦片路径，不存在时返回第一种扩展名的路径
+func (c *LocalCache) mixedTileLocation(tile *Tile, create_dir bool) (string, error) {
+	location, err := c.tileLocation(tile, c.cacheDir, imagery.MixedExtensions[0], create_dir)
+	if err != nil {
+		return "", err
+	}
+	for _, ext := range imagery.MixedExtensions {
+		if loc := replaceExtension(location, ext); utils.FileExists(loc) {
+			return loc, nil
+		}
+	}
+	return location, nil
+}
+
+// mixedStoreLocation 按瓦片数据的实际格式确定扩展名，并删除其他扩展名的旧瓦片
+func (c *LocalCache) mixedStoreLocation(location string, data []byte) string {
+	format := imagery.PeekImageFormat(string(data))
+	if !utils.ContainsString(imagery.MixedExtensions, format) {
+		return location
+	}
+	ret := replaceExtension(location, format)
+	for _, ext := range imagery.MixedExtensions {
+		if old := replaceExtension(location, ext); old != ret && utils.FileExists(old) {
+			os.Remove(old)
+			os.Remove(validatorsLocation(old))
+		}
+	}
+	return ret
+}
+
 func (c *LocalCache) LevelLocation(level int) string {
 	return c.levelLocation(level, c.cacheDir)
 }
@@ -141,7 +186,64 @@ func (c *LocalCache) StoreTile(tile *Tile) error {
 
 func (c *LocalCache) store(tile *Tile, location string) error {
 	data := tile.Source.GetBuffer(nil, nil)
-	return os.WriteFile(location, data, 0644)
+	if c.isMixed() {
+		location = c.mixedStoreLocation(location, data)
+	}
+	if err := os.WriteFile(location, data, 0644); err != nil {
+		return err
+	}
+	return c.storeValidators(tile, location)
+}
+
+type tileValidators struct {
+	ETag         string `json:"etag,omitempty"`
+	LastModified string `json:"last_modified,omitempty"`
+}
+
+func validatorsLocation(location string) string {
+	return location + ".meta"
+}
+
+// storeValidators 将上游的ETag/Last-Modified保存在瓦片旁的.meta文件中
+func (c *LocalCache) storeValidators(tile *Tile, location string) error {
+	metaLoc := validatorsLocation(location)
+	if tile.ETag == "" && tile.LastModified == "" {
+		if utils.FileExists(metaLoc) {
+			return os.Remove(metaLoc)
+		}
+		return nil
+	}
+	data, err := json.Marshal(&tileValidators{ETag: tile.ETag, LastModified: tile.LastModified})
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(metaLoc, data, 0644)
+}
+
+func (c *LocalCache) loadValidators(tile *Tile, location string) {
+	data, err := os.ReadFile(validatorsLocation(location))
+	if err != nil {
+		return
+	}
+	v := &tileValidators{}
+	if json.Unmarshal(data, v) == nil {
+		tile.ETag = v.ETag
+		tile.LastModified = v.LastModified
+	}
+}
+
+// TouchTile 上游确认瓦片未变化时只更新时间戳
+func (c *LocalCache) TouchTile(tile *Tile) error {
+	location, err := c.TileLocation(tile, false)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	if err := os.Chtimes(location, now, now); err != nil {
+		return err
+	}
+	tile.Timestamp = now
+	return c.storeValidators(tile, location)
 }
 
 func (c *LocalCache) StoreTiles(tiles *TileCollection) error {
@@ -166,8 +268,7 @@ func (c *LocalCache) StoreTiles(tiles *TileCollection) error {
 				return
 			}
 
-			data := t.Source.GetBuffer(nil, nil)
-			if err := os.WriteFile(tile_loc, data, 0644); err != nil {
+			if err := c.store(t, tile_loc); err != nil {
 				errChan <- err
 				return
 			}
@@ -194,6 +295,10 @@ func (c *LocalCache) RemoveTile(tile *Tile) error {
 	if err != nil {
 		return err
 	}
+	metaLoc := validatorsLocation(location)
+	if utils.FileExists(metaLoc) {
+		os.Remove(metaLoc)
+	}
 	return os.Remove(location)
 }
 
@@ -234,5 +339,6 @@ func (c *LocalCache) LoadTileMetadata(tile *Tile) error {
 	}
 	tile.Timestamp = stats.ModTime()
 	tile.Size = stats.Size()
+	c.loadValidators(tile, location)
 	return nil
 }
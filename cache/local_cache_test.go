@@ -2,12 +2,14 @@ package cache
 
 import (
 	"fmt"
+	"image"
 	"os"
 	"path/filepath"
 	"testing"
 	"time"
 
 	"github.com/flywave/go-geo"
+	"github.com/flywave/go-tileproxy/imagery"
 	"github.com/flywave/go-tileproxy/tile"
 	"github.com/flywave/go-tileproxy/utils"
 )
@@ -799,3 +801,96 @@ func TestLocalCache_Store_ReplaceSymlink(t *testing.T) {
 		t.Errorf("Expected stored data 'test data', got %s", string(data))
 	}
 }
+
+func TestLocalCache_Validators(t *testing.T) {
+	tmpDir := createTestDir(t)
+	defer os.RemoveAll(tmpDir)
+
+	creater := newLocalCacheMockSourceCreater("png")
+	cache := NewLocalCache(tmpDir, "tms", creater)
+
+	tile := createTestTile([3]int{1, 2, 3}, []byte("test data"))
+	tile.ETag = `"abc"`
+	tile.LastModified = "Wed, 21 Oct 2015 07:28:00 GMT"
+	if err := cache.StoreTile(tile); err != nil {
+		t.Fatalf("Failed to store tile: %v", err)
+	}
+
+	loaded := NewTile([3]int{1, 2, 3})
+	if err := cache.LoadTileMetadata(loaded); err != nil {
+		t.Fatalf("Failed to load metadata: %v", err)
+	}
+	if loaded.ETag != tile.ETag || loaded.LastModified != tile.LastModified {
+		t.Errorf("Expected validators to round trip, got %q %q", loaded.ETag, loaded.LastModified)
+	}
+
+	location, _ := cache.TileLocation(tile, false)
+	old := time.Now().Add(-time.Hour)
+	os.Chtimes(location, old, old)
+	if err := cache.TouchTile(loaded); err != nil {
+		t.Fatalf("Failed to touch tile: %v", err)
+	}
+	stats, _ := os.Stat(location)
+	if time.Since(stats.ModTime()) > time.Minute {
+		t.Error("Expected TouchTile to refresh the timestamp")
+	}
+
+	if err := cache.RemoveTile(tile); err != nil {
+		t.Fatalf("Failed to remove tile: %v", err)
+	}
+	if utils.FileExists(validatorsLocation(location)) {
+		t.Error("Expected validators file to be removed with the tile")
+	}
+}
+
+func TestLocalCache_MixedFormat(t *testing.T) {
+	tmpDir := createTestDir(t)
+	defer os.RemoveAll(tmpDir)
+
+	opts := &imagery.ImageOptions{Format: imagery.MIXED_FORMAT, Transparent: geo.NewBool(true)}
+	cache := NewLocalCache(tmpDir, "tms", &imagery.ImageSourceCreater{Opt: opts})
+
+	opaque := image.NewNRGBA(image.Rect(0, 0, 16, 16))
+	for i := range opaque.Pix {
+		opaque.Pix[i] = 200
+	}
+	for i := 3; i < len(opaque.Pix); i += 4 {
+		opaque.Pix[i] = 255
+	}
+
+	tile := NewTile([3]int{1, 2, 3})
+	tile.Source = imagery.CreateImageSourceFromImage(opaque, opts)
+	if err := cache.StoreTile(tile); err != nil {
+		t.Fatalf("Unexpected error storing tile: %v", err)
+	}
+	location, _ := cache.TileLocation(NewTile([3]int{1, 2, 3}), false)
+	if filepath.Ext(location) != ".jpeg" || !utils.FileExists(location) {
+		t.Fatalf("Expected opaque tile stored as jpeg, got %s", location)
+	}
+
+	loaded := NewTile([3]int{1, 2, 3})
+	if err := cache.LoadTile(loaded, false); err != nil {
+		t.Fatalf("Unexpected error loading tile: %v", err)
+	}
+	if imagery.PeekImageFormat(string(loaded.Source.GetBuffer(nil, nil))) != "jpeg" {
+		t.Error("Expected loaded tile to keep jpeg data")
+	}
+
+	// 出现透明像素后改存为 png，并删除旧的 jpeg
+	transparent := image.NewNRGBA(image.Rect(0, 0, 16, 16))
+	tile = NewTile([3]int{1, 2, 3})
+	tile.Source = imagery.CreateImageSourceFromImage(transparent, opts)
+	if err := cache.StoreTile(tile); err != nil {
+		t.Fatalf("Unexpected error storing tile: %v", err)
+	}
+	if utils.FileExists(location) {
+		t.Error("Expected old jpeg tile to be removed")
+	}
+	location, _ = cache.TileLocation(NewTile([3]int{1, 2, 3}), false)
+	if filepath.Ext(location) != ".png" || !utils.FileExists(location) {
+		t.Errorf("Expected transparent tile stored as png, got %s", location)
+	}
+	if !cache.IsCached(NewTile([3]int{1, 2, 3})) {
+		t.Error("Expected mixed tile to be cached")
+	}
+}
@@ -0,0 +1,70 @@
+package cache
+
+import (
+	"errors"
+
+	"github.com/flywave/go-geo"
+	"github.com/flywave/go-tileproxy/imagery"
+	"github.com/flywave/go-tileproxy/terrain"
+	"github.com/flywave/go-tileproxy/tile"
+	"github.com/flywave/go-tileproxy/vector"
+)
+
+// ChildTileCoords 返回下一级覆盖该瓦片的子瓦片及其行列数，按行从上到下排列
+func ChildTileCoords(grid *geo.TileGrid, coord [3]int) ([][3]int, [2]int, error) {
+	if coord[2]+1 >= len(grid.Resolutions) {
+		return nil, [2]int{}, errors.New("no child level")
+	}
+	_, tile_grid, it, err := grid.GetAffectedLevelTiles(grid.TileBBox(coord, false), coord[2]+1)
+	if err != nil {
+		return nil, [2]int{}, err
+	}
+	coords := make([][3]int, 0, tile_grid[0]*tile_grid[1])
+	for {
+		x, y, z, done := it.Next()
+		coords = append(coords, [3]int{x, y, z})
+		if done {
+			break
+		}
+	}
+	return coords, tile_grid, nil
+}
+
+// DownsampleTiles 由下一级的子瓦片生成该瓦片，children 与 ChildTileCoords 的顺序一致，缺失的子瓦片为 nil。
+// 影像拼接后重采样，高程按 method 聚合，矢量合并要素并简化
+func DownsampleTiles(children []tile.Source, tile_grid [2]int, grid *geo.TileGrid, coord [3]int, opts tile.TileOptions, method terrain.DownsampleMethod) (tile.Source, error) {
+	bbox := grid.TileBBox(coord, false)
+	size := [2]uint32{grid.TileSize[0], grid.TileSize[1]}
+	src_bbox, _, _, err := grid.GetAffectedLevelTiles(bbox, coord[2]+1)
+	if err != nil {
+		return nil, err
+	}
+
+	switch opt := opts.(type) {
+	case *imagery.ImageOptions:
+		return imagery.Downsample(children, tile_grid, size, src_bbox, bbox, size, opt), nil
+	case *terrain.RasterOptions:
+		datas := make([]*terrain.TileData, len(children))
+		for i, c := range children {
+			if c == nil {
+				continue
+			}
+			if rs, ok := c.(interface{ GetTileData() *terrain.TileData }); ok {
+				datas[i] = rs.GetTileData()
+			}
+		}
+		data := terrain.Downsample(datas, tile_grid, src_bbox, bbox, grid.Srs, size, method)
+		if data == nil {
+			return nil, errors.New("no child tile data")
+		}
+		return terrain.CreateRasterSourceFromTileData(data, opt, nil), nil
+	case *vector.VectorOptions:
+		wgs84 := geo.NewProj(4326)
+		if !grid.Srs.Eq(wgs84) {
+			bbox = grid.Srs.TransformRectTo(wgs84, bbox, 16)
+		}
+		vt := vector.DownsampleVector(children, bbox, opt)
+		return vector.CreateVectorSourceFromVector(vt, coord, opt, nil), nil
+	}
+	return nil, errors.New("not support source")
+}
@@ -4,6 +4,7 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"math"
 
 	vec2d "github.com/flywave/go3d/float64/vec2"
 
@@ -40,6 +41,44 @@ func ResampleTiles(layers []tile.Source, queryBBox vec2d.Rect, querySrs geo.Proj
 	return nil, errors.New("not support source")
 }
 
+// cropRasterTiles 查询与网格像素对齐且分辨率相同时，直接拼接缓存瓦片并裁剪出查询范围，
+// 向外扩展的查询取得相邻瓦片的原始高程而不经过重采样；不满足条件时返回 nil
+func cropRasterTiles(layers []tile.Source, src_tile_grid [2]int, grid *geo.TileGrid, src_bbox vec2d.Rect, level int, query *layer.MapQuery, src_opts, dest_opts *terrain.RasterOptions) tile.Source {
+	if len(layers) != src_tile_grid[0]*src_tile_grid[1] || query.Size[0] == 0 || query.Size[1] == 0 {
+		return nil
+	}
+	res := grid.Resolution(level)
+	resx := (query.BBox.Max[0] - query.BBox.Min[0]) / float64(query.Size[0])
+	resy := (query.BBox.Max[1] - query.BBox.Min[1]) / float64(query.Size[1])
+	if res <= 0 || math.Abs(resx-res) > res*1e-6 || math.Abs(resy-res) > res*1e-6 {
+		return nil
+	}
+
+	fx := (query.BBox.Min[0] - src_bbox.Min[0]) / res
+	fy := (src_bbox.Max[1] - query.BBox.Max[1]) / res
+	ox, oy := math.Round(fx), math.Round(fy)
+	if math.Abs(fx-ox) > 0.01 || math.Abs(fy-oy) > 0.01 {
+		return nil
+	}
+	tile_size := [2]uint32{grid.TileSize[0], grid.TileSize[1]}
+	if ox < 0 || oy < 0 || int(ox)+int(query.Size[0]) > src_tile_grid[0]*int(tile_size[0]) || int(oy)+int(query.Size[1]) > src_tile_grid[1]*int(tile_size[1]) {
+		return nil
+	}
+
+	m := terrain.NewRasterMerger(src_tile_grid, tile_size)
+	m.BBox = src_bbox
+	m.BBoxSrs = grid.Srs
+	merged := m.Merge(layers, src_opts)
+	cropped, ok := terrain.NewRasterSplitter(merged, dest_opts).GetSplitTile([2]int{int(ox), int(oy)}, query.Size).(interface{ GetTileData() *terrain.TileData })
+	if !ok || cropped.GetTileData() == nil {
+		return nil
+	}
+	data := cropped.GetTileData()
+	data.Box = query.BBox
+	data.Boxsrs = query.Srs
+	return terrain.CreateRasterSourceFromTileData(data, dest_opts, nil)
+}
+
 func MergeTiles(layers []tile.Source, opts tile.TileOptions, query *layer.MapQuery, tileMerger tile.Merger) (tile.Source, error) {
 	switch opt := opts.(type) {
 	case *imagery.ImageOptions:
@@ -47,10 +86,23 @@ func MergeTiles(layers []tile.Source, opts tile.TileOptions, query *layer.MapQue
 		return imagery.MergeImages(layers, opt, size, bbox, Srs, tileMerger), nil
 	case *terrain.RasterOptions:
 		return mergeRasterTile(layers, opts, query), nil
+	case *vector.VectorOptions:
+		return mergeVectorTile(layers, opt, query), nil
 	}
 	return nil, errors.New("not support source")
 }
 
+// mergeVectorTile 合并多个数据源的矢量图层，要素坐标为经纬度，按经纬度范围过滤
+func mergeVectorTile(layers []tile.Source, opts *vector.VectorOptions, query *layer.MapQuery) tile.Source {
+	wgs84 := geo.NewProj(4326)
+	bbox, srs := query.BBox, query.Srs
+	if srs != nil && !srs.Eq(wgs84) {
+		bbox = srs.TransformRectTo(wgs84, bbox, 16)
+	}
+	vt := vector.NewVectorMerger(layers).Merge(opts, query.Size[:], bbox, wgs84, nil)
+	return vector.CreateVectorSourceFromVector(vt, query.TileId, opts, nil)
+}
+
 func mergeRasterTile(layers []tile.Source, opts tile.TileOptions, query *layer.MapQuery) tile.Source {
 	m := terrain.NewRasterMerger([2]int{int(query.MetaSize[0]), int(query.MetaSize[0])}, query.Size)
 	m.BBox = query.BBox
@@ -111,6 +163,34 @@ func ScaleTiles(layers []tile.Source, queryBBox vec2d.Rect, querySrs geo.Proj, s
 	return nil, errors.New("not support source")
 }
 
+// OverzoomTile 由上级瓦片 src_coord 生成超出数据源最大级别的瓦片 coord，影像与高程放大，矢量裁剪要素
+func OverzoomTile(source tile.Source, grid *geo.TileGrid, src_coord, coord [3]int, opts tile.TileOptions) (tile.Source, error) {
+	src_bbox := grid.TileBBox(src_coord, false)
+	bbox := grid.TileBBox(coord, false)
+	size := [2]uint32{grid.TileSize[0], grid.TileSize[1]}
+	switch opt := opts.(type) {
+	case *imagery.ImageOptions:
+		if src := imagery.Upscale(source, src_bbox, bbox, size, opt); src != nil {
+			return src, nil
+		}
+	case *terrain.RasterOptions:
+		if rs, ok := source.(interface{ GetTileData() *terrain.TileData }); ok {
+			if data := rs.GetTileData(); data != nil {
+				return terrain.CreateRasterSourceFromTileData(terrain.Upscale(data, src_bbox, bbox, grid.Srs, size), opt, source.GetCacheable()), nil
+			}
+		}
+	case *vector.VectorOptions:
+		wgs84 := geo.NewProj(4326)
+		if !grid.Srs.Eq(wgs84) {
+			bbox = grid.Srs.TransformRectTo(wgs84, bbox, 16)
+		}
+		if src := vector.Overzoom(source, coord, bbox, opt); src != nil {
+			return src, nil
+		}
+	}
+	return nil, errors.New("not support source")
+}
+
 func MaskImageSourceFromCoverage(source tile.Source, bbox vec2d.Rect, bbox_srs geo.Proj, coverage geo.Coverage, opts tile.TileOptions) (tile.Source, error) {
 	switch opt := opts.(type) {
 	case *imagery.ImageOptions:
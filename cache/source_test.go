@@ -381,6 +381,31 @@ func TestMergeTiles(t *testing.T) {
 		}
 	})
 
+	t.Run("VectorOptions", func(t *testing.T) {
+		// 两个数据源的图层合并，范围外的要素被过滤
+		inside := geom.NewLineStringFeature([][]float64{{1, 1}, {2, 2}})
+		outside := geom.NewLineStringFeature([][]float64{{50, 50}, {51, 51}})
+		roads := &TestMockSource{tileImage: vector.Vector{"roads": {inside, outside}}, tileType: tile.TILE_VECTOR}
+		contours := &TestMockSource{tileImage: vector.Vector{"contour": {geom.NewLineStringFeature([][]float64{{3, 3}, {4, 4}})}}, tileType: tile.TILE_VECTOR}
+
+		vectorOpt := &vector.VectorOptions{Format: tile.TileFormat("mvt")}
+		query := &layer.MapQuery{
+			BBox:   vec2d.Rect{Min: vec2d.T{0, 0}, Max: vec2d.T{10, 10}},
+			Size:   [2]uint32{256, 256},
+			Srs:    geo.NewProj(4326),
+			TileId: [3]int{0, 0, 5},
+		}
+
+		result, err := MergeTiles([]tile.Source{roads, contours}, vectorOpt, query, nil)
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		vt := result.GetTile().(vector.Vector)
+		if len(vt["roads"]) != 1 || len(vt["contour"]) != 1 {
+			t.Fatalf("Expected merged layers with out of range features removed, got %d roads and %d contours", len(vt["roads"]), len(vt["contour"]))
+		}
+	})
+
 	t.Run("UnknownOptions", func(t *testing.T) {
 		// 创建模拟源
 		source := &TestMockSource{buffer: []byte{1, 2, 3, 4}}
@@ -10,15 +10,28 @@ import (
 	"github.com/flywave/go-tileproxy/tile"
 )
 
+// TileStatus 瓦片的缓存状态，用于调试输出
+type TileStatus string
+
+const (
+	TILE_STATUS_HIT   TileStatus = "hit"
+	TILE_STATUS_MISS  TileStatus = "miss"
+	TILE_STATUS_STALE TileStatus = "stale"
+	TILE_STATUS_BLANK TileStatus = "blank"
+)
+
 type Tile struct {
-	Coord     [3]int
-	Source    tile.Source
-	Location  string
-	Stored    bool
-	Cacheable bool
-	Size      int64
-	Timestamp time.Time
-	mu        sync.RWMutex
+	Coord        [3]int
+	Source       tile.Source
+	Location     string
+	Stored       bool
+	Cacheable    bool
+	Size         int64
+	Timestamp    time.Time
+	ETag         string
+	LastModified string
+	Status       TileStatus
+	mu           sync.RWMutex
 }
 
 func NewTile(coord [3]int) *Tile {
@@ -26,7 +39,7 @@ func NewTile(coord [3]int) *Tile {
 }
 
 func (t *Tile) GetCacheInfo() *tile.CacheInfo {
-	return &tile.CacheInfo{Cacheable: t.Cacheable, Timestamp: t.Timestamp, Size: t.Size}
+	return &tile.CacheInfo{Cacheable: t.Cacheable, Timestamp: t.Timestamp, Size: t.Size, ETag: t.ETag, LastModified: t.LastModified}
 }
 
 func (t *Tile) SetCacheInfo(cache *tile.CacheInfo) {
@@ -34,6 +47,8 @@ func (t *Tile) SetCacheInfo(cache *tile.CacheInfo) {
 		t.Cacheable = cache.Cacheable
 		t.Timestamp = cache.Timestamp
 		t.Size = cache.Size
+		t.ETag = cache.ETag
+		t.LastModified = cache.LastModified
 	}
 }
 
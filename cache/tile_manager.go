@@ -55,6 +55,8 @@ type TileManagerOptions struct {
 	ReprojectDstSrs      geo.Proj
 	QueryBuffer          *int
 	SiteURL              string
+	BandExpression       *imagery.BandExpression
+	BandRamp             *imagery.ColorRamp
 }
 
 func NewTileManager(opts *TileManagerOptions) *TileManager {
@@ -91,9 +93,11 @@ func NewTileManager(opts *TileManagerOptions) *TileManager {
 			ret.bulkMetaTiles = true
 		}
 	}
-	switch opt := ret.tileOpts.(type) {
-	case *imagery.ImageOptions:
-		ret.merger = imagery.NewBandMerger(opt.Mode)
+	// 未配置波段运算时合并器为空，多数据源按图层叠加
+	if opt, ok := ret.tileOpts.(*imagery.ImageOptions); ok && opts.BandExpression != nil {
+		merger := imagery.NewBandMerger(opt.Mode)
+		merger.SetExpression(opts.BandExpression, opts.BandRamp)
+		ret.merger = merger
 	}
 	return ret
 }
@@ -211,6 +215,9 @@ func (tm *TileManager) LoadTileCoords(tileCoords [][3]int, dimensions utils.Dime
 		if t.Source == RESCALE_TILE_MISSING {
 			t.Source = nil
 		}
+		if t.Source == nil {
+			t.Status = TILE_STATUS_BLANK
+		}
 	}
 
 	return tiles, nil
@@ -232,6 +239,8 @@ func (tm *TileManager) loadTileCoords(tiles *TileCollection, dimensions utils.Di
 	for _, tile := range tiles.tiles {
 		if !tm.IsCached(tile.Coord, dimensions) {
 			uncached_tiles = append(uncached_tiles, tile)
+		} else {
+			tile.Status = TILE_STATUS_HIT
 		}
 	}
 
@@ -240,7 +249,18 @@ func (tm *TileManager) loadTileCoords(tiles *TileCollection, dimensions utils.Di
 		created_tiles, err := creator.CreateTiles(uncached_tiles)
 
 		if err != nil {
-			return nil, err
+			// 上游暂时不可用或配额用尽时，若已有过期瓦片则继续使用
+			if !isTemporaryError(err) || !allLoaded(uncached_tiles) {
+				return nil, err
+			}
+			created_tiles = uncached_tiles
+			for _, t := range created_tiles {
+				t.Status = TILE_STATUS_STALE
+			}
+		} else {
+			for _, t := range created_tiles {
+				t.Status = TILE_STATUS_MISS
+			}
 		}
 
 		if created_tiles == nil && tm.rescaleTiles != -1 {
@@ -250,12 +270,15 @@ func (tm *TileManager) loadTileCoords(tiles *TileCollection, dimensions utils.Di
 				if err != nil {
 					return nil, err
 				}
+				created_tiles[i].Status = TILE_STATUS_MISS
 			}
 		}
 
 		for _, created_tile := range created_tiles {
 			if tiles.Contains(created_tile.Coord) {
-				tiles.GetItem(created_tile.Coord).Source = created_tile.Source
+				t := tiles.GetItem(created_tile.Coord)
+				t.Source = created_tile.Source
+				t.Status = created_tile.Status
 			}
 		}
 	}
@@ -263,6 +286,15 @@ func (tm *TileManager) loadTileCoords(tiles *TileCollection, dimensions utils.Di
 	return tiles, nil
 }
 
+func allLoaded(tiles []*Tile) bool {
+	for _, t := range tiles {
+		if t.Source == nil {
+			return false
+		}
+	}
+	return true
+}
+
 var (
 	RESCALE_TILE_MISSING = imagery.NewBlankImageSource([2]uint32{256, 256}, &imagery.ImageOptions{}, nil)
 )
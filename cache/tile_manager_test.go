@@ -2,6 +2,7 @@ package cache
 
 import (
 	"bytes"
+	"errors"
 	"image"
 	"image/png"
 	"net/http"
@@ -350,3 +351,63 @@ func TestTileManagerBulkMetaTiles(t *testing.T) {
 		t.FailNow()
 	}
 }
+
+func TestTileManagerQuotaServesStale(t *testing.T) {
+	rgba := image.NewRGBA(image.Rect(0, 0, 256, 256))
+	imagedata := &bytes.Buffer{}
+	png.Encode(imagedata, rgba)
+
+	// 配额用尽时客户端返回429
+	mock := &mockClient{code: http.StatusTooManyRequests}
+	ctx := &mockContext{c: mock}
+
+	opts := geo.DefaultTileGridOptions()
+	opts[geo.TILEGRID_SRS] = "EPSG:4326"
+	opts[geo.TILEGRID_BBOX] = vec2d.Rect{Min: vec2d.T{-180, -90}, Max: vec2d.T{180, 90}}
+	grid := geo.NewTileGrid(opts)
+	imageopts := &imagery.ImageOptions{Format: tile.TileFormat("png")}
+
+	ccreater := &mockImageSourceCreater{imageopts: imageopts}
+
+	c := NewLocalCache("./test_cache", "quadkey", ccreater)
+	defer os.RemoveAll("./test_cache")
+
+	req := request.NewWMSMapRequest(http.Header{"layers": []string{"foo"}}, "/service?map=quota", false, nil, false)
+	source := sources.NewWMSSource(client.NewWMSClient(req, nil, nil, ctx), imageopts, nil, nil, nil, nil, nil, nil, nil)
+
+	manager := NewTileManager(&TileManagerOptions{
+		Sources:      []layer.Layer{source},
+		Grid:         grid,
+		Cache:        c,
+		Locker:       &DummyTileLocker{},
+		Identifier:   "test",
+		Format:       "png",
+		Options:      imageopts,
+		RescaleTiles: -1,
+		MetaBuffer:   -1,
+		MetaSize:     [2]uint32{1, 1},
+	})
+
+	old := time.Now().Add(-time.Hour)
+	create_cached_tile([3]int{0, 0, 1}, imagedata.Bytes(), c, &old)
+	now := time.Now()
+	manager.expireTimestamp = &now
+
+	tiles, err := manager.LoadTileCoords([][3]int{{0, 0, 1}}, nil, false)
+	if err != nil {
+		t.Fatalf("expected stale tile on quota error, got %v", err)
+	}
+	if len(mock.url) == 0 {
+		t.Fatal("expected upstream to be requested")
+	}
+	if tl := tiles.GetItem([3]int{0, 0, 1}); tl.Status != TILE_STATUS_STALE || tl.Source == nil {
+		t.Errorf("expected stale tile to be served, got status %v", tl.Status)
+	}
+
+	// 没有过期瓦片时返回暂时性错误
+	_, err = manager.LoadTileCoords([][3]int{{1, 0, 1}}, nil, false)
+	var herr *client.HTTPError
+	if !errors.As(err, &herr) || !herr.Temporary() {
+		t.Errorf("expected temporary HTTPError, got %v", err)
+	}
+}
@@ -0,0 +1,33 @@
+package cache
+
+import (
+	"github.com/flywave/go-tileproxy/vector"
+)
+
+// VectorFilter 在矢量瓦片存入缓存前按规则处理图层与要素，非矢量瓦片不做处理
+type VectorFilter struct {
+	Filter
+	processor *vector.VectorProcessor
+}
+
+func NewVectorFilter(processor *vector.VectorProcessor) *VectorFilter {
+	return &VectorFilter{processor: processor}
+}
+
+func (f *VectorFilter) Apply(tile *Tile) (*Tile, error) {
+	if tile.Source == nil || f.processor == nil {
+		return tile, nil
+	}
+	opts, ok := tile.Source.GetTileOptions().(*vector.VectorOptions)
+	if !ok {
+		return tile, nil
+	}
+	vt, ok := tile.Source.GetTile().(vector.Vector)
+	if !ok {
+		return tile, nil
+	}
+	if src := vector.CreateVectorSourceFromVector(f.processor.Apply(vt, tile.Coord[2]), tile.Coord, opts, tile.Source.GetCacheable()); src != nil {
+		tile.Source = src
+	}
+	return tile, nil
+}
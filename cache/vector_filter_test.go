@@ -0,0 +1,29 @@
+package cache
+
+import (
+	"testing"
+
+	"github.com/flywave/go-geom"
+
+	"github.com/flywave/go-tileproxy/vector"
+)
+
+func TestVectorFilter(t *testing.T) {
+	opts := &vector.VectorOptions{Format: vector.MVT_MIME, Proto: int(vector.PBF_PTOTO_MAPBOX)}
+	vt := vector.Vector{
+		"roads": {geom.NewPointFeature([]float64{1, 1})},
+		"water": {geom.NewPointFeature([]float64{2, 2})},
+	}
+	tile := NewTile([3]int{1, 1, 3})
+	tile.Source = vector.CreateVectorSourceFromVector(vt, tile.Coord, opts, nil)
+
+	filter := NewVectorFilter(vector.NewVectorProcessor(nil, []string{"water"}, nil))
+	result, err := filter.Apply(tile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := result.Source.GetTile().(vector.Vector)
+	if _, ok := out["water"]; ok || len(out["roads"]) != 1 {
+		t.Errorf("unexpected layers %v", out)
+	}
+}
@@ -0,0 +1,182 @@
+package cache
+
+import (
+	"encoding/json"
+	"fmt"
+	"html"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/beevik/etree"
+	"github.com/flywave/go-geo"
+	"github.com/flywave/go-geom"
+	vec2d "github.com/flywave/go3d/float64/vec2"
+
+	"github.com/flywave/go-tileproxy/layer"
+	"github.com/flywave/go-tileproxy/resource"
+	"github.com/flywave/go-tileproxy/vector"
+)
+
+const DefaultInfoTolerance = 5
+
+// VectorInfoSource 在缓存的矢量瓦片中查询点击位置的要素，Tolerance 为像素容差
+type VectorInfoSource struct {
+	Manager     Manager
+	Tolerance   float64
+	Transformer *resource.XSLTransformer
+}
+
+func NewVectorInfoSource(manager Manager, tolerance float64, transformer *resource.XSLTransformer) *VectorInfoSource {
+	if tolerance <= 0 {
+		tolerance = DefaultInfoTolerance
+	}
+	return &VectorInfoSource{Manager: manager, Tolerance: tolerance, Transformer: transformer}
+}
+
+func (s *VectorInfoSource) GetInfo(query *layer.InfoQuery) resource.FeatureInfoDoc {
+	grid := s.Manager.GetGrid()
+	pos := query.GetCoord()
+	pt := vec2d.T{pos[0], pos[1]}
+	bbox := query.BBox
+	if !query.Srs.Eq(grid.Srs) {
+		pt = query.Srs.TransformTo(grid.Srs, []vec2d.T{pt})[0]
+		bbox = query.Srs.TransformRectTo(grid.Srs, bbox, 16)
+	}
+	if grid.BBox != nil && !grid.BBox.ContainsPoint(&pt) {
+		return nil
+	}
+
+	level := grid.ClosestLevel(geo.GetResolution(bbox, query.Size))
+	x, y, z := grid.Tile(pt[0], pt[1], level)
+	t, err := s.Manager.LoadTileCoord([3]int{x, y, z}, nil, false)
+	if err != nil || t == nil || t.Source == nil {
+		return nil
+	}
+	vt, ok := t.Source.GetTile().(vector.Vector)
+	if !ok {
+		return nil
+	}
+
+	// 要素按经纬度存储，像素容差按查询范围换算为经纬度
+	wgs84 := geo.NewProj(4326)
+	p, qbbox := vec2d.T{pos[0], pos[1]}, query.BBox
+	if !query.Srs.Eq(wgs84) {
+		p = query.Srs.TransformTo(wgs84, []vec2d.T{p})[0]
+		qbbox = query.Srs.TransformRectTo(wgs84, qbbox, 16)
+	}
+	tolerance := s.Tolerance * math.Max(qbbox.Width()/float64(query.Size[0]), qbbox.Height()/float64(query.Size[1]))
+
+	limit := 0
+	if query.FeatureCount != nil {
+		limit = *query.FeatureCount
+	}
+	hits := vector.QueryVector(vt, p[:], tolerance, limit)
+	if len(hits) == 0 {
+		return nil
+	}
+
+	doc := CreateVectorInfoDoc(hits, query.InfoFormat)
+	if s.Transformer != nil && doc.ContentType() == "xml" {
+		doc = s.Transformer.Transform(doc)
+	}
+	return doc
+}
+
+func sortedKeys(props map[string]interface{}) []string {
+	keys := make([]string, 0, len(props))
+	for k := range props {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// xmlName 将属性名转换为合法的 XML 元素名
+func xmlName(name string) string {
+	var b strings.Builder
+	for i, r := range name {
+		valid := r == '_' || (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') ||
+			(i > 0 && (r == '-' || r == '.' || (r >= '0' && r <= '9')))
+		if !valid {
+			if i == 0 && r >= '0' && r <= '9' {
+				b.WriteRune('_')
+				b.WriteRune(r)
+				continue
+			}
+			r = '_'
+		}
+		b.WriteRune(r)
+	}
+	if b.Len() == 0 {
+		return "_"
+	}
+	return b.String()
+}
+
+// CreateVectorInfoDoc 按信息格式生成要素查询结果，xml 为 MapServer 风格的 GML，
+// json 为 GeoJSON FeatureCollection，要素属性中附加所在图层名 _layer
+func CreateVectorInfoDoc(hits vector.Vector, info_format string) resource.FeatureInfoDoc {
+	switch resource.FeatureInfoType(info_format) {
+	case "xml":
+		doc := etree.NewDocument()
+		doc.CreateProcInst("xml", `version="1.0" encoding="UTF-8"`)
+		root := doc.CreateElement("msGMLOutput")
+		root.CreateAttr("xmlns:gml", "http://www.opengis.net/gml")
+		for _, name := range hits.LayerNames() {
+			l := root.CreateElement(xmlName(name + "_layer"))
+			l.CreateElement("gml:name").SetText(name)
+			for _, f := range hits[name] {
+				fe := l.CreateElement(xmlName(name + "_feature"))
+				if f.ID != nil {
+					fe.CreateAttr("fid", fmt.Sprint(f.ID))
+				}
+				for _, k := range sortedKeys(f.Properties) {
+					fe.CreateElement(xmlName(k)).SetText(fmt.Sprint(f.Properties[k]))
+				}
+			}
+		}
+		doc.Indent(2)
+		content, _ := doc.WriteToString()
+		return resource.NewXMLFeatureInfoDoc(content)
+	case "html":
+		var b strings.Builder
+		b.WriteString("<html><body>")
+		for _, name := range hits.LayerNames() {
+			for _, f := range hits[name] {
+				fmt.Fprintf(&b, "<table><caption>%s</caption>", html.EscapeString(name))
+				for _, k := range sortedKeys(f.Properties) {
+					fmt.Fprintf(&b, "<tr><th>%s</th><td>%s</td></tr>", html.EscapeString(k), html.EscapeString(fmt.Sprint(f.Properties[k])))
+				}
+				b.WriteString("</table>")
+			}
+		}
+		b.WriteString("</body></html>")
+		return resource.NewHTMLFeatureInfoDoc(b.String())
+	case "json":
+		fc := geom.NewFeatureCollection()
+		for _, name := range hits.LayerNames() {
+			for _, f := range hits[name] {
+				nf := *f
+				nf.Properties = make(map[string]interface{}, len(f.Properties)+1)
+				for k, v := range f.Properties {
+					nf.Properties[k] = v
+				}
+				nf.Properties["_layer"] = name
+				fc.AddFeature(&nf)
+			}
+		}
+		content, _ := json.Marshal(fc)
+		return resource.NewJSONFeatureInfoDoc(content)
+	}
+	var b strings.Builder
+	for _, name := range hits.LayerNames() {
+		for _, f := range hits[name] {
+			fmt.Fprintf(&b, "layer: %s\n", name)
+			for _, k := range sortedKeys(f.Properties) {
+				fmt.Fprintf(&b, "  %s = %v\n", k, f.Properties[k])
+			}
+		}
+	}
+	return resource.NewTextFeatureInfoDoc(b.String())
+}
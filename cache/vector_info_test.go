@@ -0,0 +1,102 @@
+package cache
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/flywave/go-geo"
+	"github.com/flywave/go-geom"
+	vec2d "github.com/flywave/go3d/float64/vec2"
+
+	"github.com/flywave/go-tileproxy/layer"
+	"github.com/flywave/go-tileproxy/utils"
+	"github.com/flywave/go-tileproxy/vector"
+)
+
+type infoMockManager struct {
+	Manager
+	grid   *geo.TileGrid
+	opts   *vector.VectorOptions
+	vt     vector.Vector
+	loaded [3]int
+}
+
+func (m *infoMockManager) GetGrid() *geo.TileGrid {
+	return m.grid
+}
+
+func (m *infoMockManager) LoadTileCoord(coord [3]int, _ utils.Dimensions, _ bool) (*Tile, error) {
+	m.loaded = coord
+	t := NewTile(coord)
+	t.Source = vector.CreateVectorSourceFromVector(m.vt, coord, m.opts, nil)
+	return t, nil
+}
+
+func TestVectorInfoSource(t *testing.T) {
+	opts := geo.DefaultTileGridOptions()
+	opts[geo.TILEGRID_SRS] = "EPSG:3857"
+	poi := geom.NewPointFeature([]float64{1, 1})
+	poi.Properties["name"] = "a"
+	area := geom.NewPolygonFeature([][][]float64{{{0, 0}, {5, 0}, {5, 5}, {0, 5}, {0, 0}}})
+	area.Properties["name"] = "b&c"
+	far := geom.NewPointFeature([]float64{3, 3})
+	manager := &infoMockManager{
+		grid: geo.NewTileGrid(opts),
+		opts: &vector.VectorOptions{Format: vector.MVT_MIME, Proto: int(vector.PBF_PTOTO_MAPBOX)},
+		vt:   vector.Vector{"poi": {poi, far}, "area": {area}},
+	}
+	source := NewVectorInfoSource(manager, 0, nil)
+
+	// 查询范围 0,0-10,10 的 100x100 像素，点击位置为经纬度 1,1
+	query := &layer.InfoQuery{
+		BBox:       vec2d.Rect{Min: vec2d.T{0, 0}, Max: vec2d.T{10, 10}},
+		Size:       [2]uint32{100, 100},
+		Srs:        geo.NewProj(4326),
+		Pos:        [2]float64{10, 90},
+		InfoFormat: "application/json",
+	}
+	doc := source.GetInfo(query)
+	if doc == nil {
+		t.Fatal("expected feature info")
+	}
+	if manager.loaded[2] == 0 {
+		t.Errorf("expected tile at the query resolution, got %v", manager.loaded)
+	}
+	fc := struct {
+		Features []struct {
+			Properties map[string]interface{} `json:"properties"`
+		} `json:"features"`
+	}{}
+	if err := json.Unmarshal([]byte(doc.ToString()), &fc); err != nil {
+		t.Fatal(err)
+	}
+	if len(fc.Features) != 2 || fc.Features[0].Properties["_layer"] != "area" || fc.Features[1].Properties["name"] != "a" {
+		t.Errorf("unexpected features %s", doc.ToString())
+	}
+
+	query.InfoFormat = "application/vnd.ogc.gml"
+	doc = source.GetInfo(query)
+	if doc.ContentType() != "xml" || !strings.Contains(doc.ToString(), "<poi_layer>") || !strings.Contains(doc.ToString(), "<name>b&amp;c</name>") {
+		t.Errorf("unexpected gml %s", doc.ToString())
+	}
+
+	query.InfoFormat = "text/html"
+	doc = source.GetInfo(query)
+	if doc.ContentType() != "html" || !strings.Contains(doc.ToString(), "<td>b&amp;c</td>") {
+		t.Errorf("unexpected html %s", doc.ToString())
+	}
+
+	count := 1
+	query.InfoFormat, query.FeatureCount = "text/plain", &count
+	doc = source.GetInfo(query)
+	if strings.Count(doc.ToString(), "layer:") != 1 {
+		t.Errorf("expected one feature, got %s", doc.ToString())
+	}
+
+	// 空白位置不返回结果
+	query.Pos = [2]float64{90, 10}
+	if source.GetInfo(query) != nil {
+		t.Error("expected no feature info")
+	}
+}
@@ -25,12 +25,26 @@ func NewArcGISClient(req *request.ArcGISRequest, ctx Context) *ArcGISClient {
 }
 
 func (c *ArcGISClient) Retrieve(query *layer.MapQuery, format *tile.TileFormat) []byte {
+	resp, _ := c.Fetch(query, format)
+	return resp
+}
+
+// Fetch 请求上游服务，非200的响应返回带状态码的 HTTPError
+func (c *ArcGISClient) Fetch(query *layer.MapQuery, format *tile.TileFormat) ([]byte, error) {
 	url := c.queryURL(query, format)
 	status, resp := c.httpClient().Open(url, nil, nil)
 	if status == 200 {
-		return resp
+		return resp, nil
 	}
-	return nil
+	return nil, NewHTTPError(url, status)
+}
+
+// RequestTarget 返回完整的请求地址及认证标识，用于识别请求相同数据的客户端
+func (c *ArcGISClient) RequestTarget() string {
+	if c == nil || c.RequestTemplate == nil {
+		return ""
+	}
+	return requestTarget("GET", c.RequestTemplate.CompleteUrl(), c.authIdentity())
 }
 
 func (c *ArcGISClient) CombinedClient(other MapClient, query *layer.MapQuery) MapClient {
@@ -38,7 +52,8 @@ func (c *ArcGISClient) CombinedClient(other MapClient, query *layer.MapQuery) Ma
 }
 
 func (c *ArcGISClient) queryURL(query *layer.MapQuery, format *tile.TileFormat) string {
-	req := c.RequestTemplate
+	req := *c.RequestTemplate
+	req.Params = req.Params.Copy()
 	params := request.NewArcGISExportRequestParams(req.GetParams())
 	params.SetFormat(*format)
 	params.SetBBox(query.BBox)
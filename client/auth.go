@@ -0,0 +1,264 @@
+package client
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Authenticator 为上游请求附加认证信息，Invalidate在上游返回401后调用
+type Authenticator interface {
+	Apply(u string, hdr http.Header, c HttpClient) (string, http.Header, error)
+	Invalidate()
+}
+
+type HeaderAuth struct {
+	Name  string
+	Value string
+}
+
+func NewBearerAuth(token string) *HeaderAuth {
+	return &HeaderAuth{Name: "Authorization", Value: "Bearer " + token}
+}
+
+func NewAPIKeyAuth(name, key string) *HeaderAuth {
+	return &HeaderAuth{Name: name, Value: key}
+}
+
+func (a *HeaderAuth) Apply(u string, hdr http.Header, c HttpClient) (string, http.Header, error) {
+	hdr = cloneHeader(hdr)
+	hdr.Set(a.Name, a.Value)
+	return u, hdr, nil
+}
+
+func (a *HeaderAuth) Invalidate() {}
+
+type BasicAuth struct {
+	Username string
+	Password string
+}
+
+func NewBasicAuth(username, password string) *BasicAuth {
+	return &BasicAuth{Username: username, Password: password}
+}
+
+func (a *BasicAuth) Apply(u string, hdr http.Header, c HttpClient) (string, http.Header, error) {
+	hdr = cloneHeader(hdr)
+	hdr.Set("Authorization", basicAuthValue(a.Username, a.Password))
+	return u, hdr, nil
+}
+
+func (a *BasicAuth) Invalidate() {}
+
+func basicAuthValue(username, password string) string {
+	return "Basic " + base64.StdEncoding.EncodeToString([]byte(username+":"+password))
+}
+
+type Token struct {
+	Value  string
+	Expiry time.Time
+}
+
+func (t *Token) Valid(skew time.Duration) bool {
+	if t == nil || t.Value == "" {
+		return false
+	}
+	return t.Expiry.IsZero() || time.Now().Add(skew).Before(t.Expiry)
+}
+
+type TokenFetcher interface {
+	FetchToken(c HttpClient) (*Token, error)
+}
+
+// TokenAuth 缓存令牌直到过期，并通过请求头或查询参数发送
+type TokenAuth struct {
+	Fetcher      TokenFetcher
+	HeaderName   string
+	HeaderPrefix string
+	QueryParam   string
+	ExpirySkew   time.Duration
+	token        *Token
+	mu           sync.Mutex
+}
+
+func NewTokenAuth(fetcher TokenFetcher) *TokenAuth {
+	return &TokenAuth{
+		Fetcher:      fetcher,
+		HeaderName:   "Authorization",
+		HeaderPrefix: "Bearer ",
+		ExpirySkew:   30 * time.Second,
+	}
+}
+
+func (a *TokenAuth) Token(c HttpClient) (string, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.token.Valid(a.ExpirySkew) {
+		return a.token.Value, nil
+	}
+	tok, err := a.Fetcher.FetchToken(c)
+	if err != nil {
+		return "", err
+	}
+	a.token = tok
+	return tok.Value, nil
+}
+
+func (a *TokenAuth) Apply(u string, hdr http.Header, c HttpClient) (string, http.Header, error) {
+	tok, err := a.Token(c)
+	if err != nil {
+		return u, hdr, err
+	}
+	if a.QueryParam != "" {
+		pu, err := url.Parse(u)
+		if err != nil {
+			return u, hdr, err
+		}
+		q := pu.Query()
+		q.Set(a.QueryParam, tok)
+		pu.RawQuery = q.Encode()
+		return pu.String(), hdr, nil
+	}
+	hdr = cloneHeader(hdr)
+	hdr.Set(a.HeaderName, a.HeaderPrefix+tok)
+	return u, hdr, nil
+}
+
+func (a *TokenAuth) Invalidate() {
+	a.mu.Lock()
+	a.token = nil
+	a.mu.Unlock()
+}
+
+// OAuth2ClientCredentials 实现OAuth2 client_credentials授权
+type OAuth2ClientCredentials struct {
+	TokenURL     string
+	ClientID     string
+	ClientSecret string
+	Scopes       []string
+	Params       map[string]string
+}
+
+func NewOAuth2Auth(tokenURL, clientID, clientSecret string, scopes []string) *TokenAuth {
+	return NewTokenAuth(&OAuth2ClientCredentials{TokenURL: tokenURL, ClientID: clientID, ClientSecret: clientSecret, Scopes: scopes})
+}
+
+func (o *OAuth2ClientCredentials) FetchToken(c HttpClient) (*Token, error) {
+	form := url.Values{}
+	form.Set("grant_type", "client_credentials")
+	if len(o.Scopes) > 0 {
+		form.Set("scope", strings.Join(o.Scopes, " "))
+	}
+	for k, v := range o.Params {
+		form.Set(k, v)
+	}
+	hdr := http.Header{}
+	hdr.Set("Content-Type", "application/x-www-form-urlencoded")
+	hdr.Set("Accept", "application/json")
+	hdr.Set("Authorization", basicAuthValue(url.QueryEscape(o.ClientID), url.QueryEscape(o.ClientSecret)))
+
+	status, body := c.Open(o.TokenURL, []byte(form.Encode()), hdr)
+	if status != 200 {
+		return nil, fmt.Errorf("oauth2 token request failed with status %d", status)
+	}
+	return parseTokenResponse(body, "access_token", "expires_in", 0)
+}
+
+// TokenEndpoint 从自定义接口获取令牌，TokenPath为JSON响应中令牌的路径，如"data.token"
+type TokenEndpoint struct {
+	URL         string
+	Method      string
+	Headers     http.Header
+	Body        []byte
+	TokenPath   string
+	ExpiresPath string
+	ExpiresIn   time.Duration
+}
+
+func NewTokenEndpointAuth(endpoint *TokenEndpoint) *TokenAuth {
+	return NewTokenAuth(endpoint)
+}
+
+func (e *TokenEndpoint) FetchToken(c HttpClient) (*Token, error) {
+	var data []byte
+	if e.Method == "POST" {
+		data = e.Body
+		if data == nil {
+			data = []byte{}
+		}
+	}
+	status, body := c.Open(e.URL, data, cloneHeader(e.Headers))
+	if status != 200 {
+		return nil, fmt.Errorf("token request failed with status %d", status)
+	}
+	return parseTokenResponse(body, e.TokenPath, e.ExpiresPath, e.ExpiresIn)
+}
+
+func parseTokenResponse(body []byte, tokenPath, expiresPath string, expiresIn time.Duration) (*Token, error) {
+	var doc interface{}
+	if err := json.Unmarshal(body, &doc); err != nil {
+		return nil, err
+	}
+	v, ok := lookupJSONPath(doc, tokenPath)
+	if !ok {
+		return nil, fmt.Errorf("token not found at %q", tokenPath)
+	}
+	value, ok := v.(string)
+	if !ok || value == "" {
+		return nil, errors.New("token is not a string")
+	}
+	tok := &Token{Value: value}
+	if expiresPath != "" {
+		if ev, ok := lookupJSONPath(doc, expiresPath); ok {
+			switch e := ev.(type) {
+			case float64:
+				expiresIn = time.Duration(e) * time.Second
+			case string:
+				if secs, err := strconv.Atoi(e); err == nil {
+					expiresIn = time.Duration(secs) * time.Second
+				}
+			}
+		}
+	}
+	if expiresIn > 0 {
+		tok.Expiry = time.Now().Add(expiresIn)
+	}
+	return tok, nil
+}
+
+func lookupJSONPath(doc interface{}, path string) (interface{}, bool) {
+	cur := doc
+	for _, key := range strings.Split(path, ".") {
+		switch node := cur.(type) {
+		case map[string]interface{}:
+			v, ok := node[key]
+			if !ok {
+				return nil, false
+			}
+			cur = v
+		case []interface{}:
+			i, err := strconv.Atoi(key)
+			if err != nil || i < 0 || i >= len(node) {
+				return nil, false
+			}
+			cur = node[i]
+		default:
+			return nil, false
+		}
+	}
+	return cur, true
+}
+
+func cloneHeader(hdr http.Header) http.Header {
+	if hdr == nil {
+		return http.Header{}
+	}
+	return hdr.Clone()
+}
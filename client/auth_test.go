@@ -0,0 +1,151 @@
+package client
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// tokenMockClient 记录令牌请求次数的mock客户端
+type tokenMockClient struct {
+	calls int
+	body  string
+	code  int
+	hdr   http.Header
+	data  []byte
+}
+
+func (m *tokenMockClient) Open(url string, data []byte, hdr http.Header) (int, []byte) {
+	m.calls++
+	m.hdr = hdr
+	m.data = data
+	return m.code, []byte(m.body)
+}
+
+// TestHeaderAndBasicAuth 测试静态请求头认证
+func TestHeaderAndBasicAuth(t *testing.T) {
+	_, hdr, _ := NewBearerAuth("abc").Apply("http://a/b", nil, nil)
+	if hdr.Get("Authorization") != "Bearer abc" {
+		t.Errorf("Bearer 头错误: %s", hdr.Get("Authorization"))
+	}
+
+	orig := http.Header{}
+	_, hdr, _ = NewAPIKeyAuth("X-Api-Key", "k1").Apply("http://a/b", orig, nil)
+	if hdr.Get("X-Api-Key") != "k1" || orig.Get("X-Api-Key") != "" {
+		t.Error("API Key 应写入副本而非原始请求头")
+	}
+
+	_, hdr, _ = NewBasicAuth("user", "pass").Apply("http://a/b", nil, nil)
+	if hdr.Get("Authorization") != "Basic dXNlcjpwYXNz" {
+		t.Errorf("Basic 头错误: %s", hdr.Get("Authorization"))
+	}
+}
+
+// TestOAuth2TokenCaching 测试令牌缓存与失效后刷新
+func TestOAuth2TokenCaching(t *testing.T) {
+	mock := &tokenMockClient{code: 200, body: `{"access_token":"t1","token_type":"bearer","expires_in":3600}`}
+	auth := NewOAuth2Auth("http://auth/token", "id", "secret", []string{"tiles"})
+
+	for i := 0; i < 3; i++ {
+		_, hdr, err := auth.Apply("http://a/b", nil, mock)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if hdr.Get("Authorization") != "Bearer t1" {
+			t.Fatalf("令牌错误: %s", hdr.Get("Authorization"))
+		}
+	}
+	if mock.calls != 1 {
+		t.Errorf("令牌应被缓存, 实际请求 %d 次", mock.calls)
+	}
+
+	form, _ := url.ParseQuery(string(mock.data))
+	if form.Get("grant_type") != "client_credentials" || form.Get("scope") != "tiles" {
+		t.Errorf("请求参数错误: %s", mock.data)
+	}
+	if mock.hdr.Get("Authorization") != "Basic aWQ6c2VjcmV0" {
+		t.Errorf("客户端凭证头错误: %s", mock.hdr.Get("Authorization"))
+	}
+
+	auth.Invalidate()
+	auth.Apply("http://a/b", nil, mock)
+	if mock.calls != 2 {
+		t.Error("失效后应重新获取令牌")
+	}
+
+	mock.code = 400
+	auth.Invalidate()
+	if _, _, err := auth.Apply("http://a/b", nil, mock); err == nil {
+		t.Error("令牌请求失败应返回错误")
+	}
+}
+
+// TestTokenEndpointPath 测试自定义接口的JSON路径与查询参数
+func TestTokenEndpointPath(t *testing.T) {
+	mock := &tokenMockClient{code: 200, body: `{"data":{"items":[{"key":"xyz","ttl":"1"}]}}`}
+	auth := NewTokenEndpointAuth(&TokenEndpoint{
+		URL:         "http://auth/token",
+		TokenPath:   "data.items.0.key",
+		ExpiresPath: "data.items.0.ttl",
+	})
+	auth.QueryParam = "tk"
+	auth.ExpirySkew = 0
+
+	u, _, err := auth.Apply("http://a/b?x=1", nil, mock)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pu, _ := url.Parse(u)
+	if pu.Query().Get("tk") != "xyz" || pu.Query().Get("x") != "1" {
+		t.Errorf("查询参数错误: %s", u)
+	}
+
+	time.Sleep(1100 * time.Millisecond)
+	auth.Apply("http://a/b", nil, mock)
+	if mock.calls != 2 {
+		t.Errorf("过期后应刷新令牌, 实际请求 %d 次", mock.calls)
+	}
+
+	if _, ok := lookupJSONPath(map[string]interface{}{"a": 1.0}, "a.b"); ok {
+		t.Error("无效路径不应匹配")
+	}
+}
+
+// TestCollectorClientAuthRefreshOn401 测试上游返回401后刷新令牌
+func TestCollectorClientAuthRefreshOn401(t *testing.T) {
+	var issued int32
+	var tiles int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/token":
+			n := atomic.AddInt32(&issued, 1)
+			fmt.Fprintf(w, `{"access_token":"tok%d","expires_in":3600}`, n)
+		default:
+			atomic.AddInt32(&tiles, 1)
+			if r.Header.Get("Authorization") != "Bearer tok2" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			w.Write([]byte("tile"))
+		}
+	}))
+	defer server.Close()
+
+	client := NewCollectorClient(&Config{
+		Threads:        1,
+		RequestTimeout: 5 * time.Second,
+		Auth:           NewOAuth2Auth(server.URL+"/token", "id", "secret", nil),
+	}, nil)
+
+	status, body := client.Open(server.URL+"/1/2/3.png", nil, nil)
+	if status != 200 || string(body) != "tile" {
+		t.Fatalf("401 后应刷新令牌并成功, 实际 %d %q", status, body)
+	}
+	if atomic.LoadInt32(&issued) != 2 || atomic.LoadInt32(&tiles) != 2 {
+		t.Errorf("期望获取令牌2次、请求瓦片2次, 实际 %d %d", issued, tiles)
+	}
+}
@@ -1,12 +1,17 @@
 package client
 
 import (
+	"fmt"
+	"net/url"
+	"strings"
+
 	"github.com/flywave/go-tileproxy/layer"
 	"github.com/flywave/go-tileproxy/tile"
 )
 
 type MapClient interface {
 	Retrieve(query *layer.MapQuery, format *tile.TileFormat) []byte
+	Fetch(query *layer.MapQuery, format *tile.TileFormat) ([]byte, error)
 	CombinedClient(other MapClient, query *layer.MapQuery) MapClient
 }
 
@@ -21,3 +26,31 @@ func (c *BaseClient) GetContext() Context {
 func (c *BaseClient) httpClient() HttpClient {
 	return c.ctx.Client()
 }
+
+// authIdentity 返回客户端的认证标识，使用不同认证的客户端不共享上游结果
+func (c *BaseClient) authIdentity() string {
+	if c.ctx == nil {
+		return ""
+	}
+	if cc, ok := c.ctx.Client().(*CollectorClient); ok && cc != nil && cc.Auth != nil {
+		return fmt.Sprintf("%p", cc.Auth)
+	}
+	return ""
+}
+
+// requestTarget 去除每次请求都会变化的参数并按参数名排序，附加请求方法与认证标识
+func requestTarget(method, rawurl, identity string) string {
+	u, err := url.Parse(rawurl)
+	if err != nil {
+		return method + " " + rawurl + "#" + identity
+	}
+	q := u.Query()
+	for k := range q {
+		switch strings.ToLower(k) {
+		case "bbox", "width", "height", "size", "crs", "srs", "format", "bboxsr", "imagesr":
+			q.Del(k)
+		}
+	}
+	u.RawQuery = q.Encode()
+	return method + " " + u.String() + "#" + identity
+}
@@ -14,6 +14,9 @@ type Config struct {
 	DisableKeepAlives bool
 	Proxys            []string
 	RequestTimeout    time.Duration
+	Retry             *RetryPolicy
+	RateLimit         *RateLimit
+	Auth              Authenticator
 }
 
 type Context interface {
@@ -69,7 +69,6 @@ func (f *Future) GetResult() *crawler.Response {
 		if !f.finished {
 			f.finished = true
 			f.result = result
-			f.error_ = nil
 			if f.resultchan != nil {
 				close(f.resultchan)
 				f.resultchan = nil
@@ -101,6 +100,22 @@ func (f *Future) setResult(result *crawler.Response) {
 	}
 }
 
+func (f *Future) Err() error {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	return f.error_
+}
+
+func (f *Future) setError(result *crawler.Response, err error) {
+	f.mu.Lock()
+	if !f.finished {
+		f.error_ = err
+	}
+	f.mu.Unlock()
+
+	f.setResult(result)
+}
+
 func newFuture() *Future {
 	return &Future{
 		finished:   false,
@@ -1,6 +1,8 @@
 package client
 
 import (
+	"bytes"
+	"context"
 	"crypto/tls"
 	"log"
 	"net"
@@ -9,6 +11,7 @@ import (
 
 	"github.com/flywave/go-tileproxy/crawler"
 	"github.com/flywave/go-tileproxy/crawler/extensions"
+	"github.com/flywave/go-tileproxy/tile"
 )
 
 type HttpClient interface {
@@ -19,11 +22,20 @@ type CollectorClient struct {
 	HttpClient
 	Collector   *crawler.Collector
 	BaseRequest *crawler.Request
+	Retry       *RetryPolicy
+	Throttle    *Throttle
+	Auth        Authenticator
 }
 
 func NewCollectorClient(config *Config, ctx *crawler.Context) *CollectorClient {
 	c := createCollector(config)
-	cli := &CollectorClient{Collector: c, BaseRequest: &crawler.Request{Ctx: ctx}}
+	cli := &CollectorClient{
+		Collector:   c,
+		BaseRequest: &crawler.Request{Ctx: ctx},
+		Retry:       config.Retry,
+		Throttle:    NewThrottle(config.RateLimit, DefaultLimiterRegistry()),
+		Auth:        config.Auth,
+	}
 	return cli
 }
 
@@ -79,6 +91,19 @@ func createCollector(config *Config) *crawler.Collector {
 		}
 	})
 
+	// 非2xx响应与网络错误不会触发OnResponse，需要在这里结束Future，否则调用方只能等到超时
+	sc.OnError(func(resp *crawler.Response, err error) {
+		var userData interface{}
+		if resp.UserData != nil {
+			userData = resp.UserData
+		} else if resp.Request != nil {
+			userData = resp.Request.UserData
+		}
+		if fut, ok := userData.(*Future); ok {
+			fut.setError(resp, err)
+		}
+	})
+
 	return sc
 }
 
@@ -87,30 +112,221 @@ func (c *CollectorClient) GetCollector() *crawler.Collector {
 }
 
 // executeRequest 执行请求并处理结果的公共逻辑
-func (c *CollectorClient) executeRequest(fut *Future) (statusCode int, body []byte) {
+func (c *CollectorClient) executeRequest(fut *Future) (statusCode int, body []byte, respHdr http.Header) {
 	result := fut.GetResult()
-	if result == nil {
-		return 500, nil
+	if result == nil || result.StatusCode == 0 {
+		return 500, nil, nil
 	}
-	return result.StatusCode, result.Body
+	return result.StatusCode, result.Body, responseHeader(result)
 }
 
-func (c *CollectorClient) Open(u string, data []byte, hdr http.Header) (statusCode int, body []byte) {
-	fut := newFuture()
+func responseHeader(resp *crawler.Response) http.Header {
+	if resp.Headers == nil {
+		return nil
+	}
+	return *resp.Headers
+}
+
+func (c *CollectorClient) visit(u string, data []byte, hdr http.Header, timeout time.Duration) (*Future, error) {
+	fut := newFutureWithTimeout(timeout)
 	var err error
 
 	// 根据是否有数据选择不同的请求方法
 	if data == nil {
 		err = c.Collector.Visit(u, fut, hdr)
+	} else if hdr != nil {
+		err = c.Collector.Request("POST", u, bytes.NewReader(data), nil, fut, hdr)
 	} else {
 		err = c.Collector.PostRaw(u, data, fut)
 	}
+	return fut, err
+}
+
+func (c *CollectorClient) Open(u string, data []byte, hdr http.Header) (statusCode int, body []byte) {
+	statusCode, body, _ = c.OpenWithHeaders(u, data, hdr)
+	return statusCode, body
+}
+
+// OpenWithHeaders 与Open相同，额外返回上游响应头
+func (c *CollectorClient) OpenWithHeaders(u string, data []byte, hdr http.Header) (statusCode int, body []byte, respHdr http.Header) {
+	if c.Auth == nil {
+		return c.open(u, data, hdr)
+	}
 
-	// 统一的错误处理
+	raw := &unauthenticatedClient{c: c}
+	au, ahdr, err := c.Auth.Apply(u, hdr, raw)
 	if err != nil {
-		return 500, nil
+		log.Printf("upstream authentication failed: %v", err)
+		return http.StatusUnauthorized, nil, nil
+	}
+	statusCode, body, respHdr = c.open(au, data, ahdr)
+
+	// 令牌可能已被上游提前吊销，刷新后重试一次
+	if statusCode == http.StatusUnauthorized {
+		c.Auth.Invalidate()
+		au, ahdr, err = c.Auth.Apply(u, hdr, raw)
+		if err != nil {
+			return statusCode, body, respHdr
+		}
+		statusCode, body, respHdr = c.open(au, data, ahdr)
 	}
+	return statusCode, body, respHdr
+}
+
+// unauthenticatedClient 用于获取令牌，避免认证逻辑递归
+type unauthenticatedClient struct {
+	c *CollectorClient
+}
+
+func (u *unauthenticatedClient) Open(url string, data []byte, hdr http.Header) (statusCode int, body []byte) {
+	statusCode, body, _ = u.c.open(url, data, hdr)
+	return statusCode, body
+}
+
+func (c *CollectorClient) open(u string, data []byte, hdr http.Header) (statusCode int, body []byte, respHdr http.Header) {
+	if c.Retry == nil || c.Retry.MaxAttempts <= 1 {
+		// 配额用尽时不再请求上游，由调用方回退到缓存数据
+		if err := c.Throttle.Acquire(context.Background(), u); err != nil {
+			return http.StatusTooManyRequests, nil, nil
+		}
 
-	// 统一的结果处理
-	return c.executeRequest(fut)
+		fut, err := c.visit(u, data, hdr, defaultFutureTimeout)
+
+		// 统一的错误处理
+		if err != nil {
+			return 500, nil, nil
+		}
+
+		// 统一的结果处理
+		return c.executeRequest(fut)
+	}
+	return c.openWithRetry(u, data, hdr)
+}
+
+func (c *CollectorClient) acquire(u string, deadline time.Time) error {
+	ctx := context.Background()
+	if !deadline.IsZero() {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithDeadline(ctx, deadline)
+		defer cancel()
+	}
+	return c.Throttle.Acquire(ctx, u)
+}
+
+func (c *CollectorClient) openWithRetry(u string, data []byte, hdr http.Header) (statusCode int, body []byte, respHdr http.Header) {
+	start := time.Now()
+	var deadline time.Time
+	if c.Retry.MaxElapsedTime > 0 {
+		deadline = start.Add(c.Retry.MaxElapsedTime)
+	}
+
+	statusCode = 500
+	for attempt := 1; attempt <= c.Retry.MaxAttempts; attempt++ {
+		timeout := defaultFutureTimeout
+		if !deadline.IsZero() {
+			remaining := time.Until(deadline)
+			if remaining <= 0 {
+				break
+			}
+			if remaining < timeout {
+				timeout = remaining
+			}
+		}
+
+		if err := c.acquire(u, deadline); err != nil {
+			if err == ErrQuotaExceeded {
+				return http.StatusTooManyRequests, nil, nil
+			}
+			break
+		}
+
+		fut, err := c.visit(u, data, hdr, timeout)
+		if err != nil {
+			return 500, nil, nil
+		}
+
+		result := fut.GetResult()
+		var respHeaders *http.Header
+		status := 0
+		if result != nil {
+			status = result.StatusCode
+			respHeaders = result.Headers
+		}
+		if status == 0 {
+			statusCode, body, respHdr = 500, nil, nil
+		} else {
+			statusCode, body, respHdr = status, result.Body, responseHeader(result)
+			if fut.Err() == nil && status >= 200 && status < 300 {
+				return statusCode, body, respHdr
+			}
+		}
+
+		if !c.Retry.IsRetryable(status) || attempt == c.Retry.MaxAttempts {
+			break
+		}
+
+		wait := c.Retry.Backoff(attempt, respHeaders)
+		if !deadline.IsZero() && time.Now().Add(wait).After(deadline) {
+			break
+		}
+		log.Printf("retrying %s in %v (attempt %d/%d, status %d)", u, wait, attempt+1, c.Retry.MaxAttempts, status)
+		time.Sleep(wait)
+	}
+	return statusCode, body, respHdr
+}
+
+type HeaderClient interface {
+	OpenWithHeaders(url string, data []byte, hdr http.Header) (statusCode int, body []byte, respHdr http.Header)
+}
+
+type TileResponse struct {
+	Data         []byte
+	ETag         string
+	LastModified string
+	ContentType  string
+	NotModified  bool
+}
+
+func openConditional(c HttpClient, url string, info *tile.CacheInfo) (*TileResponse, error) {
+	hc, ok := c.(HeaderClient)
+	if !ok {
+		status, body := c.Open(url, nil, nil)
+		if status == 200 {
+			return &TileResponse{Data: body}, nil
+		}
+		return nil, NewHTTPError(url, status)
+	}
+
+	var hdr http.Header
+	if info != nil && (info.ETag != "" || info.LastModified != "") {
+		hdr = http.Header{}
+		if info.ETag != "" {
+			hdr.Set("If-None-Match", info.ETag)
+		}
+		if info.LastModified != "" {
+			hdr.Set("If-Modified-Since", info.LastModified)
+		}
+	}
+
+	status, body, respHdr := hc.OpenWithHeaders(url, nil, hdr)
+	switch status {
+	case http.StatusOK:
+		resp := &TileResponse{Data: body}
+		if respHdr != nil {
+			resp.ETag = respHdr.Get("ETag")
+			resp.LastModified = respHdr.Get("Last-Modified")
+			resp.ContentType = respHdr.Get("Content-Type")
+		}
+		return resp, nil
+	case http.StatusNotModified:
+		resp := &TileResponse{NotModified: true}
+		if info != nil {
+			resp.ETag, resp.LastModified = info.ETag, info.LastModified
+		}
+		if respHdr != nil && respHdr.Get("ETag") != "" {
+			resp.ETag = respHdr.Get("ETag")
+		}
+		return resp, nil
+	}
+	return nil, NewHTTPError(url, status)
 }
@@ -1,10 +1,13 @@
 package client
 
 import (
+	"net/http"
+	"net/http/httptest"
 	"testing"
 	"time"
 
 	"github.com/flywave/go-tileproxy/crawler"
+	"github.com/flywave/go-tileproxy/tile"
 )
 
 // TestNewCollectorClient 测试创建新的CollectorClient
@@ -139,3 +142,35 @@ func TestCollectorClientNilConfig(t *testing.T) {
 		t.Log("nil配置被处理")
 	}
 }
+
+// TestOpenConditional 测试携带ETag的条件请求
+func TestOpenConditional(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", `"v1"`)
+		w.Header().Set("Last-Modified", "Wed, 21 Oct 2015 07:28:00 GMT")
+		w.Write([]byte("tile"))
+	}))
+	defer server.Close()
+
+	client := NewCollectorClient(&Config{Threads: 1, RequestTimeout: 5 * time.Second}, nil)
+
+	resp, err := openConditional(client, server.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(resp.Data) != "tile" || resp.ETag != `"v1"` || resp.LastModified == "" {
+		t.Fatalf("首次请求应返回数据与校验信息, 实际 %+v", resp)
+	}
+
+	resp, err = openConditional(client, server.URL, &tile.CacheInfo{ETag: resp.ETag})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !resp.NotModified || resp.Data != nil || resp.ETag != `"v1"` {
+		t.Errorf("ETag匹配时应返回304, 实际 %+v", resp)
+	}
+}
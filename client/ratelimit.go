@@ -0,0 +1,300 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"sync"
+	"time"
+)
+
+var ErrQuotaExceeded = errors.New("upstream request quota exceeded")
+
+// TokenBucket 令牌桶限流，rate为每秒生成的令牌数，burst为桶容量
+type TokenBucket struct {
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+	mu     sync.Mutex
+}
+
+func NewTokenBucket(rate float64, burst int) *TokenBucket {
+	if burst < 1 {
+		burst = 1
+	}
+	return &TokenBucket{rate: rate, burst: float64(burst), tokens: float64(burst), last: time.Now()}
+}
+
+func (b *TokenBucket) advance(now time.Time) {
+	elapsed := now.Sub(b.last).Seconds()
+	if elapsed > 0 {
+		b.tokens += elapsed * b.rate
+		if b.tokens > b.burst {
+			b.tokens = b.burst
+		}
+	}
+	b.last = now
+}
+
+// reserve 预占一个令牌并返回需要等待的时间
+func (b *TokenBucket) reserve() time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.advance(time.Now())
+	b.tokens--
+	if b.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-b.tokens / b.rate * float64(time.Second))
+}
+
+func (b *TokenBucket) cancel() {
+	b.mu.Lock()
+	b.tokens++
+	if b.tokens > b.burst {
+		b.tokens = b.burst
+	}
+	b.mu.Unlock()
+}
+
+func (b *TokenBucket) Allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.advance(time.Now())
+	if b.tokens >= 1 {
+		b.tokens--
+		return true
+	}
+	return false
+}
+
+func (b *TokenBucket) Wait(ctx context.Context) error {
+	if b == nil || b.rate <= 0 {
+		return nil
+	}
+	wait := b.reserve()
+	if wait == 0 {
+		return nil
+	}
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		b.cancel()
+		return ctx.Err()
+	}
+}
+
+// DailyQuota 每日请求配额，按UTC自然日重置
+type DailyQuota struct {
+	limit int64
+	used  int64
+	day   string
+	mu    sync.Mutex
+	now   func() time.Time
+}
+
+func NewDailyQuota(limit int64) *DailyQuota {
+	return &DailyQuota{limit: limit, now: time.Now}
+}
+
+func (q *DailyQuota) roll() {
+	day := q.now().UTC().Format("2006-01-02")
+	if day != q.day {
+		q.day = day
+		q.used = 0
+	}
+}
+
+func (q *DailyQuota) Take() bool {
+	if q == nil || q.limit <= 0 {
+		return true
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.roll()
+	if q.used >= q.limit {
+		return false
+	}
+	q.used++
+	return true
+}
+
+// release 归还一次未使用的配额，跨日后不再归还
+func (q *DailyQuota) release() {
+	if q == nil || q.limit <= 0 {
+		return
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.now().UTC().Format("2006-01-02") == q.day && q.used > 0 {
+		q.used--
+	}
+}
+
+func (q *DailyQuota) Remaining() int64 {
+	if q == nil || q.limit <= 0 {
+		return -1
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.roll()
+	return q.limit - q.used
+}
+
+type RateLimit struct {
+	Rate       float64
+	Burst      int
+	DailyQuota int64
+}
+
+type Limiter struct {
+	limit  RateLimit
+	bucket *TokenBucket
+	quota  *DailyQuota
+}
+
+func NewLimiter(rl *RateLimit) *Limiter {
+	l := &Limiter{limit: *rl}
+	if rl.Rate > 0 {
+		l.bucket = NewTokenBucket(rl.Rate, rl.Burst)
+	}
+	if rl.DailyQuota > 0 {
+		l.quota = NewDailyQuota(rl.DailyQuota)
+	}
+	return l
+}
+
+func (l *Limiter) Wait(ctx context.Context) error {
+	if l == nil {
+		return nil
+	}
+	return l.bucket.Wait(ctx)
+}
+
+func (l *Limiter) Take() bool {
+	if l == nil {
+		return true
+	}
+	return l.quota.Take()
+}
+
+func (l *Limiter) release() {
+	if l != nil {
+		l.quota.release()
+	}
+}
+
+func (l *Limiter) Remaining() int64 {
+	if l == nil {
+		return -1
+	}
+	return l.quota.Remaining()
+}
+
+// LimiterRegistry 按上游主机共享限流器，所有数据源与预生成任务使用同一份计数
+type LimiterRegistry struct {
+	hosts map[string]*Limiter
+	mu    sync.RWMutex
+}
+
+func NewLimiterRegistry() *LimiterRegistry {
+	return &LimiterRegistry{hosts: make(map[string]*Limiter)}
+}
+
+var defaultLimiterRegistry = NewLimiterRegistry()
+
+func DefaultLimiterRegistry() *LimiterRegistry {
+	return defaultLimiterRegistry
+}
+
+// SetHostLimit 设置主机的限流器，限流参数变化时替换原限流器并保留当日已用配额
+func (r *LimiterRegistry) SetHostLimit(host string, rl *RateLimit) *Limiter {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	old, ok := r.hosts[host]
+	if ok && old.limit == *rl {
+		return old
+	}
+	l := NewLimiter(rl)
+	if ok && old.quota != nil && l.quota != nil {
+		old.quota.mu.Lock()
+		l.quota.day, l.quota.used = old.quota.day, old.quota.used
+		old.quota.mu.Unlock()
+	}
+	r.hosts[host] = l
+	return l
+}
+
+func (r *LimiterRegistry) HostLimiter(host string) *Limiter {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.hosts[host]
+}
+
+func (r *LimiterRegistry) Remove(host string) {
+	r.mu.Lock()
+	delete(r.hosts, host)
+	r.mu.Unlock()
+}
+
+// Throttle 组合数据源级与主机级限流器
+type Throttle struct {
+	Source   *Limiter
+	Registry *LimiterRegistry
+}
+
+func NewThrottle(source *RateLimit, registry *LimiterRegistry) *Throttle {
+	t := &Throttle{Registry: registry}
+	if source != nil {
+		t.Source = NewLimiter(source)
+	}
+	return t
+}
+
+func (t *Throttle) limiters(u string) []*Limiter {
+	ls := []*Limiter{}
+	if t.Source != nil {
+		ls = append(ls, t.Source)
+	}
+	if t.Registry != nil {
+		if pu, err := url.Parse(u); err == nil {
+			if l := t.Registry.HostLimiter(pu.Host); l != nil {
+				ls = append(ls, l)
+			}
+		}
+	}
+	return ls
+}
+
+// Acquire 在发送请求前调用，配额用尽时返回ErrQuotaExceeded且不发送请求
+func (t *Throttle) Acquire(ctx context.Context, u string) error {
+	if t == nil {
+		return nil
+	}
+	ls := t.limiters(u)
+	for _, l := range ls {
+		if l.Remaining() == 0 {
+			return ErrQuotaExceeded
+		}
+	}
+	for _, l := range ls {
+		if err := l.Wait(ctx); err != nil {
+			return err
+		}
+	}
+	for i, l := range ls {
+		if !l.Take() {
+			for _, taken := range ls[:i] {
+				taken.release()
+			}
+			return ErrQuotaExceeded
+		}
+	}
+	return nil
+}
@@ -0,0 +1,148 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// TestTokenBucket 测试令牌桶的突发容量与速率
+func TestTokenBucket(t *testing.T) {
+	b := NewTokenBucket(20, 2)
+
+	if !b.Allow() || !b.Allow() {
+		t.Fatal("突发容量内的请求应立即通过")
+	}
+	if b.Allow() {
+		t.Fatal("令牌耗尽后不应通过")
+	}
+
+	start := time.Now()
+	if err := b.Wait(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed < 30*time.Millisecond {
+		t.Errorf("令牌耗尽后应等待约50ms, 实际 %v", elapsed)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	b.Allow()
+	if err := b.Wait(ctx); err == nil {
+		t.Error("上下文取消后应返回错误")
+	}
+}
+
+// TestDailyQuota 测试每日配额及跨日重置
+func TestDailyQuota(t *testing.T) {
+	now := time.Date(2022, 3, 1, 23, 59, 0, 0, time.UTC)
+	q := NewDailyQuota(2)
+	q.now = func() time.Time { return now }
+
+	if !q.Take() || !q.Take() {
+		t.Fatal("配额内的请求应通过")
+	}
+	if q.Take() {
+		t.Fatal("超出配额的请求不应通过")
+	}
+	if q.Remaining() != 0 {
+		t.Errorf("剩余配额应为0, 实际 %d", q.Remaining())
+	}
+
+	now = now.Add(2 * time.Minute)
+	if !q.Take() || q.Remaining() != 1 {
+		t.Error("跨日后配额应重置")
+	}
+
+	var unlimited *DailyQuota
+	if !unlimited.Take() || unlimited.Remaining() != -1 {
+		t.Error("未配置配额时不应限制")
+	}
+}
+
+// TestThrottleHostQuota 测试按主机共享的配额
+func TestThrottleHostQuota(t *testing.T) {
+	registry := NewLimiterRegistry()
+	registry.SetHostLimit("tiles.example.com", &RateLimit{DailyQuota: 1})
+
+	a := NewThrottle(nil, registry)
+	b := NewThrottle(&RateLimit{Rate: 100, Burst: 10}, registry)
+
+	if err := a.Acquire(context.Background(), "http://tiles.example.com/1/2/3.png"); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.Acquire(context.Background(), "http://tiles.example.com/1/2/4.png"); err != ErrQuotaExceeded {
+		t.Errorf("同一主机的配额应共享, 实际 %v", err)
+	}
+	if err := b.Acquire(context.Background(), "http://other.example.com/1/2/4.png"); err != nil {
+		t.Errorf("其他主机不应受影响: %v", err)
+	}
+}
+
+// TestThrottleQuotaRollback 测试主机配额耗尽时归还已占用的数据源配额
+func TestThrottleQuotaRollback(t *testing.T) {
+	registry := NewLimiterRegistry()
+	host := registry.SetHostLimit("tiles.example.com", &RateLimit{DailyQuota: 1})
+	host.Take()
+
+	th := NewThrottle(&RateLimit{DailyQuota: 5}, registry)
+	if err := th.Acquire(context.Background(), "http://tiles.example.com/1/2/3.png"); err != ErrQuotaExceeded {
+		t.Fatalf("主机配额耗尽时应返回ErrQuotaExceeded, 实际 %v", err)
+	}
+	if th.Source.Remaining() != 5 {
+		t.Errorf("请求未发送时数据源配额应归还, 剩余 %d", th.Source.Remaining())
+	}
+}
+
+// TestSetHostLimitUpdate 测试重新加载配置后主机限流参数生效
+func TestSetHostLimitUpdate(t *testing.T) {
+	registry := NewLimiterRegistry()
+	l := registry.SetHostLimit("tiles.example.com", &RateLimit{DailyQuota: 2})
+	l.Take()
+
+	if same := registry.SetHostLimit("tiles.example.com", &RateLimit{DailyQuota: 2}); same != l {
+		t.Error("参数相同时应复用原限流器")
+	}
+	updated := registry.SetHostLimit("tiles.example.com", &RateLimit{DailyQuota: 3})
+	if updated == l || registry.HostLimiter("tiles.example.com") != updated {
+		t.Fatal("参数变化时应替换限流器")
+	}
+	if updated.Remaining() != 2 {
+		t.Errorf("替换后应保留已用配额, 剩余 %d", updated.Remaining())
+	}
+}
+
+// TestCollectorClientQuotaExceeded 测试配额耗尽时不再请求上游
+func TestCollectorClientQuotaExceeded(t *testing.T) {
+	var hits int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		w.Write([]byte("tile"))
+	}))
+	defer server.Close()
+
+	client := NewCollectorClient(&Config{
+		Threads:        1,
+		RequestTimeout: 5 * time.Second,
+		RateLimit:      &RateLimit{DailyQuota: 1},
+	}, nil)
+
+	if status, _ := client.Open(server.URL, nil, nil); status != 200 {
+		t.Fatalf("第一次请求应成功, 实际 %d", status)
+	}
+	if status, _ := client.Open(server.URL, nil, nil); status != http.StatusTooManyRequests {
+		t.Errorf("配额耗尽应返回429, 实际 %d", status)
+	}
+	if atomic.LoadInt32(&hits) != 1 {
+		t.Errorf("配额耗尽后不应访问上游, 实际请求 %d 次", hits)
+	}
+
+	u, _ := url.Parse(server.URL)
+	if DefaultLimiterRegistry().HostLimiter(u.Host) != nil {
+		t.Error("数据源配额不应注册为主机配额")
+	}
+}
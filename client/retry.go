@@ -0,0 +1,131 @@
+package client
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var DefaultRetryableStatus = []int{
+	http.StatusTooManyRequests,
+	http.StatusInternalServerError,
+	http.StatusBadGateway,
+	http.StatusServiceUnavailable,
+	http.StatusGatewayTimeout,
+}
+
+type RetryPolicy struct {
+	MaxAttempts       int
+	InitialInterval   time.Duration
+	MaxInterval       time.Duration
+	Multiplier        float64
+	Jitter            float64
+	RetryableStatus   []int
+	RespectRetryAfter bool
+	MaxElapsedTime    time.Duration
+}
+
+func DefaultRetryPolicy() *RetryPolicy {
+	return &RetryPolicy{
+		MaxAttempts:       3,
+		InitialInterval:   500 * time.Millisecond,
+		MaxInterval:       10 * time.Second,
+		Multiplier:        2,
+		Jitter:            0.2,
+		RetryableStatus:   DefaultRetryableStatus,
+		RespectRetryAfter: true,
+		MaxElapsedTime:    60 * time.Second,
+	}
+}
+
+// IsRetryable 状态码为0表示网络错误或超时，同样可以重试
+func (p *RetryPolicy) IsRetryable(status int) bool {
+	if status == 0 {
+		return true
+	}
+	codes := p.RetryableStatus
+	if codes == nil {
+		codes = DefaultRetryableStatus
+	}
+	for _, c := range codes {
+		if c == status {
+			return true
+		}
+	}
+	return false
+}
+
+// Backoff 计算第attempt次重试(从1开始)前的等待时间
+func (p *RetryPolicy) Backoff(attempt int, hdr *http.Header) time.Duration {
+	if p.RespectRetryAfter && hdr != nil {
+		if d, ok := parseRetryAfter(hdr.Get("Retry-After"), time.Now()); ok {
+			if p.MaxInterval > 0 && d > p.MaxInterval {
+				return p.MaxInterval
+			}
+			return d
+		}
+	}
+
+	multiplier := p.Multiplier
+	if multiplier < 1 {
+		multiplier = 1
+	}
+	wait := float64(p.InitialInterval) * math.Pow(multiplier, float64(attempt-1))
+	if p.MaxInterval > 0 && wait > float64(p.MaxInterval) {
+		wait = float64(p.MaxInterval)
+	}
+	if p.Jitter > 0 {
+		delta := wait * p.Jitter
+		wait = wait - delta + rand.Float64()*2*delta
+	}
+	if wait < 0 {
+		wait = 0
+	}
+	return time.Duration(wait)
+}
+
+func parseRetryAfter(v string, now time.Time) (time.Duration, bool) {
+	v = strings.TrimSpace(v)
+	if v == "" {
+		return 0, false
+	}
+	if secs, err := strconv.Atoi(v); err == nil {
+		if secs < 0 {
+			return 0, false
+		}
+		return time.Duration(secs) * time.Second, true
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		d := t.Sub(now)
+		if d < 0 {
+			d = 0
+		}
+		return d, true
+	}
+	return 0, false
+}
+
+type HTTPError struct {
+	URL        string
+	StatusCode int
+}
+
+func NewHTTPError(url string, status int) *HTTPError {
+	return &HTTPError{URL: url, StatusCode: status}
+}
+
+func (e *HTTPError) Error() string {
+	if e.StatusCode == 0 {
+		return fmt.Sprintf("upstream request failed: %s", e.URL)
+	}
+	return fmt.Sprintf("upstream returned %d: %s", e.StatusCode, e.URL)
+}
+
+// Temporary 网络错误、429与5xx视为暂时性错误，结果不应写入缓存
+func (e *HTTPError) Temporary() bool {
+	return e.StatusCode == 0 || e.StatusCode == http.StatusTooManyRequests || e.StatusCode >= 500
+}
@@ -0,0 +1,182 @@
+package client
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// TestRetryPolicyIsRetryable 测试可重试状态码判断
+func TestRetryPolicyIsRetryable(t *testing.T) {
+	p := DefaultRetryPolicy()
+
+	for _, code := range []int{0, 429, 500, 502, 503, 504} {
+		if !p.IsRetryable(code) {
+			t.Errorf("状态码 %d 应可重试", code)
+		}
+	}
+	for _, code := range []int{200, 204, 400, 401, 403, 404} {
+		if p.IsRetryable(code) {
+			t.Errorf("状态码 %d 不应重试", code)
+		}
+	}
+
+	p.RetryableStatus = []int{418}
+	if !p.IsRetryable(418) || p.IsRetryable(503) {
+		t.Error("自定义状态码列表应覆盖默认值")
+	}
+}
+
+// TestRetryPolicyBackoff 测试指数退避与上限
+func TestRetryPolicyBackoff(t *testing.T) {
+	p := &RetryPolicy{
+		InitialInterval: 100 * time.Millisecond,
+		MaxInterval:     time.Second,
+		Multiplier:      2,
+	}
+
+	expected := []time.Duration{
+		100 * time.Millisecond,
+		200 * time.Millisecond,
+		400 * time.Millisecond,
+		800 * time.Millisecond,
+		time.Second,
+	}
+	for i, want := range expected {
+		if got := p.Backoff(i+1, nil); got != want {
+			t.Errorf("attempt %d: 期望 %v, 实际 %v", i+1, want, got)
+		}
+	}
+
+	p.Jitter = 0.5
+	for i := 0; i < 100; i++ {
+		got := p.Backoff(1, nil)
+		if got < 50*time.Millisecond || got > 150*time.Millisecond {
+			t.Fatalf("抖动超出范围: %v", got)
+		}
+	}
+}
+
+// TestRetryPolicyRetryAfter 测试Retry-After头
+func TestRetryPolicyRetryAfter(t *testing.T) {
+	p := &RetryPolicy{
+		InitialInterval:   100 * time.Millisecond,
+		MaxInterval:       5 * time.Second,
+		Multiplier:        2,
+		RespectRetryAfter: true,
+	}
+
+	hdr := http.Header{}
+	hdr.Set("Retry-After", "3")
+	if got := p.Backoff(1, &hdr); got != 3*time.Second {
+		t.Errorf("期望 3s, 实际 %v", got)
+	}
+
+	hdr.Set("Retry-After", "120")
+	if got := p.Backoff(1, &hdr); got != 5*time.Second {
+		t.Errorf("Retry-After 应受 MaxInterval 限制, 实际 %v", got)
+	}
+
+	p.RespectRetryAfter = false
+	if got := p.Backoff(1, &hdr); got != 100*time.Millisecond {
+		t.Errorf("关闭后应忽略 Retry-After, 实际 %v", got)
+	}
+
+	now := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	d, ok := parseRetryAfter(now.Add(10*time.Second).Format(http.TimeFormat), now)
+	if !ok || d != 10*time.Second {
+		t.Errorf("HTTP日期格式解析失败: %v %v", d, ok)
+	}
+	if _, ok := parseRetryAfter("soon", now); ok {
+		t.Error("无效值不应解析成功")
+	}
+}
+
+// TestCollectorClientRetry 测试瞬时错误后重试成功
+func TestCollectorClientRetry(t *testing.T) {
+	var hits int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&hits, 1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte("tile"))
+	}))
+	defer server.Close()
+
+	client := NewCollectorClient(&Config{
+		Threads:        1,
+		RequestTimeout: 5 * time.Second,
+		Retry: &RetryPolicy{
+			MaxAttempts:     3,
+			InitialInterval: 10 * time.Millisecond,
+			Multiplier:      2,
+		},
+	}, nil)
+
+	status, body := client.Open(server.URL, nil, nil)
+	if status != 200 || string(body) != "tile" {
+		t.Fatalf("期望重试后成功, 实际 %d %q", status, body)
+	}
+	if atomic.LoadInt32(&hits) != 3 {
+		t.Errorf("期望请求3次, 实际 %d", hits)
+	}
+}
+
+// TestCollectorClientRetryExhausted 测试重试耗尽与不可重试状态码
+func TestCollectorClientRetryExhausted(t *testing.T) {
+	var hits int32
+	status := int32(http.StatusBadGateway)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		w.WriteHeader(int(atomic.LoadInt32(&status)))
+	}))
+	defer server.Close()
+
+	client := NewCollectorClient(&Config{
+		Threads:        1,
+		RequestTimeout: 5 * time.Second,
+		Retry: &RetryPolicy{
+			MaxAttempts:     2,
+			InitialInterval: 10 * time.Millisecond,
+		},
+	}, nil)
+
+	code, _ := client.Open(server.URL, nil, nil)
+	if code != http.StatusBadGateway {
+		t.Errorf("期望返回最后一次状态码 502, 实际 %d", code)
+	}
+	if atomic.LoadInt32(&hits) != 2 {
+		t.Errorf("期望请求2次, 实际 %d", hits)
+	}
+
+	atomic.StoreInt32(&hits, 0)
+	atomic.StoreInt32(&status, http.StatusNotFound)
+	code, _ = client.Open(server.URL, nil, nil)
+	if code != http.StatusNotFound || atomic.LoadInt32(&hits) != 1 {
+		t.Errorf("404 不应重试, 实际 %d 次, 状态码 %d", hits, code)
+	}
+}
+
+// TestTileClientFetchTileError 测试FetchTile返回暂时性错误
+func TestTileClientFetchTileError(t *testing.T) {
+	mock := &tileMockClient{code: 503}
+	c := NewTileClient(nil, NewURLTemplate("/{z}/{x}/{y}.png", "png", nil), nil, &tileMockContext{c: mock})
+
+	data, err := c.FetchTile([3]int{1, 2, 3}, nil)
+	if data != nil || err == nil {
+		t.Fatal("503 应返回错误")
+	}
+	herr, ok := err.(*HTTPError)
+	if !ok || herr.StatusCode != 503 || !herr.Temporary() {
+		t.Errorf("期望暂时性 HTTPError, 实际 %v", err)
+	}
+
+	mock.code = 404
+	_, err = c.FetchTile([3]int{1, 2, 3}, nil)
+	if herr, ok := err.(*HTTPError); !ok || herr.Temporary() {
+		t.Errorf("404 不应视为暂时性错误: %v", err)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"fmt"
 	"html/template"
+	"math"
 	"math/rand"
 	"strconv"
 	"strings"
@@ -25,12 +26,22 @@ func NewTileClient(grid *geo.TileGrid, tpl *TileURLTemplate, accessToken *string
 }
 
 func (c *TileClient) GetTile(tile_coord [3]int, format *tile.TileFormat) []byte {
-	url := c.Template.substitute(tile_coord, format, c.Grid, c.AccessToken)
-	status, resp := c.httpClient().Open(url, nil, nil)
-	if status == 200 {
-		return resp
+	resp, _ := c.FetchTile(tile_coord, format)
+	return resp
+}
+
+func (c *TileClient) FetchTile(tile_coord [3]int, format *tile.TileFormat) ([]byte, error) {
+	resp, err := c.FetchTileConditional(tile_coord, format, nil)
+	if err != nil {
+		return nil, err
 	}
-	return nil
+	return resp.Data, nil
+}
+
+// FetchTileConditional 携带缓存中的ETag/Last-Modified发送条件请求
+func (c *TileClient) FetchTileConditional(tile_coord [3]int, format *tile.TileFormat, info *tile.CacheInfo) (*TileResponse, error) {
+	url := c.Template.substitute(tile_coord, format, c.Grid, c.AccessToken)
+	return openConditional(c.httpClient(), url, info)
 }
 
 func tilecachePath(tile_coord [3]int) string {
@@ -75,6 +86,23 @@ func bbox(tile_coord [3]int, grid *geo.TileGrid) string {
 	return fmt.Sprintf("%.8f,%.8f,%.8f,%.8f", rect.Min[0], rect.Min[1], rect.Max[0], rect.Max[1])
 }
 
+// signedTileIndex 以坐标原点为起点计算瓦片索引，y轴向北，原点西侧与南侧为负数，即百度的编号方式
+func signedTileIndex(tile_coord [3]int, grid *geo.TileGrid) (int, int) {
+	rect := grid.TileBBox(tile_coord, false)
+	res := grid.Resolution(tile_coord[2])
+	x := math.Round(rect.Min[0] / (res * float64(grid.TileSize[0])))
+	y := math.Round(rect.Min[1] / (res * float64(grid.TileSize[1])))
+	return int(x), int(y)
+}
+
+// baiduIndex 部分百度服务用M代替负号
+func baiduIndex(i int) string {
+	if i < 0 {
+		return "M" + strconv.Itoa(-i)
+	}
+	return strconv.Itoa(i)
+}
+
 type TileURLTemplate struct {
 	Template            string
 	Format              string
@@ -83,6 +111,7 @@ type TileURLTemplate struct {
 	WithTMSPath         bool
 	WithArcgisCachePath bool
 	WithBBox            bool
+	WithSignedIndex     bool
 	HasSubdomains       bool
 	HasAccessToken      bool
 	Subdomains          []string
@@ -147,6 +176,14 @@ func NewURLTemplate(template string, format string, subdomains []string) *TileUR
 		rt.WithBBox = false
 	}
 
+	// {bd_x}/{bd_y}为带符号的瓦片索引，{bd_mx}/{bd_my}以M表示负数
+	for _, key := range []string{"bd_x", "bd_y", "bd_mx", "bd_my"} {
+		if strings.Contains(template, "{"+key+"}") {
+			template = strings.Replace(template, "{"+key+"}", "{{ ."+key+" }}", 1)
+			rt.WithSignedIndex = true
+		}
+	}
+
 	rt.Template = template
 
 	return rt
@@ -175,6 +212,11 @@ func (t *TileURLTemplate) substitute(tile_coord [3]int, format *tile.TileFormat,
 	if t.WithBBox {
 		data["bbox"] = bbox(tile_coord, grid)
 	}
+	if t.WithSignedIndex && grid != nil {
+		sx, sy := signedTileIndex(tile_coord, grid)
+		data["bd_x"], data["bd_y"] = strconv.Itoa(sx), strconv.Itoa(sy)
+		data["bd_mx"], data["bd_my"] = baiduIndex(sx), baiduIndex(sy)
+	}
 
 	if t.HasAccessToken && accessToken != nil {
 		data["access_token"] = *accessToken
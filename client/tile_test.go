@@ -5,6 +5,8 @@ import (
 	"strings"
 	"testing"
 
+	vec2d "github.com/flywave/go3d/float64/vec2"
+
 	"github.com/flywave/go-geo"
 )
 
@@ -136,6 +138,33 @@ func TestTileURLTemplateWithBBOX(t *testing.T) {
 	}
 }
 
+func TestTileURLTemplateBaiduIndex(t *testing.T) {
+	opts := geo.DefaultTileGridOptions()
+	opts[geo.TILEGRID_SRS] = "EPSG:BDMC"
+	opts[geo.TILEGRID_BBOX] = &vec2d.Rect{Min: vec2d.T{-(1 << 26), -(1 << 26)}, Max: vec2d.T{1 << 26, 1 << 26}}
+	opts[geo.TILEGRID_ORIGIN] = geo.ORIGIN_LL
+	res := make([]float64, 20)
+	for z := range res {
+		res[z] = float64(int(1<<18) >> z)
+	}
+	opts[geo.TILEGRID_RES] = res
+	grid := geo.NewTileGrid(opts)
+
+	ut := NewURLTemplate("/?x={bd_x}&y={bd_y}&z={z}", "", nil)
+
+	// 天安门附近的BDMC坐标
+	x, y, z := grid.Tile(12958175, 4825923, 18)
+	if url := ut.substitute([3]int{x, y, z}, nil, grid, nil); url != "/?x=50617&y=18851&z=18" {
+		t.Errorf("百度瓦片索引错误: %s", url)
+	}
+
+	ut = NewURLTemplate("/{bd_mx}/{bd_my}/{z}", "", nil)
+	x, y, z = grid.Tile(-300, -300, 18)
+	if url := ut.substitute([3]int{x, y, z}, nil, grid, nil); url != "/M2/M2/18" {
+		t.Errorf("负数索引应以M表示: %s", url)
+	}
+}
+
 func TestTileClient(t *testing.T) {
 	mock := &tileMockClient{code: 200, body: []byte{0, 1, 2, 3}}
 	ctx := &tileMockContext{c: mock}
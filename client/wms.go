@@ -31,6 +31,12 @@ func NewWMSClient(req *request.WMSMapRequest, accessToken *string, accessTokenNa
 }
 
 func (c *WMSClient) Retrieve(query *layer.MapQuery, format *tile.TileFormat) []byte {
+	resp, _ := c.Fetch(query, format)
+	return resp
+}
+
+// Fetch 请求上游服务，非200的响应返回带状态码的 HTTPError
+func (c *WMSClient) Fetch(query *layer.MapQuery, format *tile.TileFormat) ([]byte, error) {
 	var requestMethod string
 	switch c.HttpMethod {
 	case "POST":
@@ -55,9 +61,9 @@ func (c *WMSClient) Retrieve(query *layer.MapQuery, format *tile.TileFormat) []b
 	}
 	status, resp := c.httpClient().Open(url, data, nil)
 	if status == 200 {
-		return resp
+		return resp, nil
 	}
-	return nil
+	return nil, NewHTTPError(url, status)
 }
 
 func (c *WMSClient) queryData(query *layer.MapQuery, format *tile.TileFormat) (url string, data []byte) {
@@ -85,6 +91,7 @@ func (c *WMSClient) queryURL(query *layer.MapQuery, format *tile.TileFormat) str
 
 func (c *WMSClient) queryReq(query *layer.MapQuery, format *tile.TileFormat) *request.WMSMapRequest {
 	req := *c.RequestTemplate
+	req.Params = req.Params.Copy()
 	params := request.NewWMSMapRequestParams(req.GetParams())
 	params.SetBBox(query.BBox)
 	params.SetSize(query.Size)
@@ -97,6 +104,18 @@ func (c *WMSClient) queryReq(query *layer.MapQuery, format *tile.TileFormat) *re
 	return &req
 }
 
+// RequestTarget 返回完整的请求地址、访问令牌及认证标识，用于识别请求相同数据的客户端
+func (c *WMSClient) RequestTarget() string {
+	if c == nil || c.RequestTemplate == nil {
+		return ""
+	}
+	identity := c.authIdentity()
+	if c.AccessToken != nil {
+		identity += "|" + *c.AccessToken
+	}
+	return requestTarget(c.HttpMethod, c.RequestTemplate.CompleteUrl(), identity)
+}
+
 func (c *WMSClient) CombinedClient(other MapClient, query *layer.MapQuery) MapClient {
 	oc := other.(*WMSClient)
 	if c.RequestTemplate.Url != oc.RequestTemplate.Url {
@@ -104,6 +123,7 @@ func (c *WMSClient) CombinedClient(other MapClient, query *layer.MapQuery) MapCl
 	}
 
 	new_req := *c.RequestTemplate
+	new_req.Params = new_req.Params.Copy()
 	params := request.NewWMSMapRequestParams(new_req.GetParams())
 	other_params := request.NewWMSMapRequestParams(oc.RequestTemplate.Params)
 
@@ -0,0 +1,218 @@
+package client
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+
+	vec2d "github.com/flywave/go3d/float64/vec2"
+)
+
+// WMSCapabilities 上游WMS服务GetCapabilities文档中与数据源配置相关的内容，兼容1.1.1与1.3.0
+type WMSCapabilities struct {
+	Version   string
+	MaxWidth  int
+	MaxHeight int
+	Formats   []string
+	Layers    []*WMSLayerCapabilities
+}
+
+// WMSLayerCapabilities 具名图层的能力描述，已合并父图层继承的SRS、范围与比例尺
+type WMSLayerCapabilities struct {
+	Name      string
+	Title     string
+	Queryable bool
+	Srs       []string
+	LLBBox    *vec2d.Rect
+	MinScale  *float64
+	MaxScale  *float64
+	MinRes    *float64
+	MaxRes    *float64
+	LegendURL string
+}
+
+type wmsCapabilitiesDoc struct {
+	Version string `xml:"version,attr"`
+	Service struct {
+		MaxWidth  int `xml:"MaxWidth"`
+		MaxHeight int `xml:"MaxHeight"`
+	} `xml:"Service"`
+	Capability struct {
+		Request struct {
+			GetMap struct {
+				Format []string `xml:"Format"`
+			} `xml:"GetMap"`
+		} `xml:"Request"`
+		Layer []*wmsLayerDoc `xml:"Layer"`
+	} `xml:"Capability"`
+}
+
+type wmsBBoxDoc struct {
+	MinX float64 `xml:"minx,attr"`
+	MinY float64 `xml:"miny,attr"`
+	MaxX float64 `xml:"maxx,attr"`
+	MaxY float64 `xml:"maxy,attr"`
+}
+
+type wmsLayerDoc struct {
+	Queryable string   `xml:"queryable,attr"`
+	Name      string   `xml:"Name"`
+	Title     string   `xml:"Title"`
+	CRS       []string `xml:"CRS"`
+	SRS       []string `xml:"SRS"`
+	EXBBox    *struct {
+		West  float64 `xml:"westBoundLongitude"`
+		East  float64 `xml:"eastBoundLongitude"`
+		South float64 `xml:"southBoundLatitude"`
+		North float64 `xml:"northBoundLatitude"`
+	} `xml:"EX_GeographicBoundingBox"`
+	LatLonBBox *wmsBBoxDoc `xml:"LatLonBoundingBox"`
+	ScaleHint  *struct {
+		Min float64 `xml:"min,attr"`
+		Max float64 `xml:"max,attr"`
+	} `xml:"ScaleHint"`
+	MinScaleDenominator *float64 `xml:"MinScaleDenominator"`
+	MaxScaleDenominator *float64 `xml:"MaxScaleDenominator"`
+	Style               []struct {
+		LegendURL []struct {
+			OnlineResource struct {
+				Href string `xml:"href,attr"`
+			} `xml:"OnlineResource"`
+		} `xml:"LegendURL"`
+	} `xml:"Style"`
+	Layer []*wmsLayerDoc `xml:"Layer"`
+}
+
+// ParseWMSCapabilities 解析GetCapabilities文档，图层按文档顺序展开
+func ParseWMSCapabilities(data []byte) (*WMSCapabilities, error) {
+	doc := &wmsCapabilitiesDoc{}
+	if err := xml.Unmarshal(data, doc); err != nil {
+		return nil, fmt.Errorf("invalid wms capabilities: %w", err)
+	}
+	caps := &WMSCapabilities{
+		Version:   doc.Version,
+		MaxWidth:  doc.Service.MaxWidth,
+		MaxHeight: doc.Service.MaxHeight,
+		Formats:   doc.Capability.Request.GetMap.Format,
+	}
+	for _, l := range doc.Capability.Layer {
+		caps.Layers = flattenWMSLayer(caps.Layers, l, &WMSLayerCapabilities{})
+	}
+	return caps, nil
+}
+
+func flattenWMSLayer(layers []*WMSLayerCapabilities, l *wmsLayerDoc, parent *WMSLayerCapabilities) []*WMSLayerCapabilities {
+	info := &WMSLayerCapabilities{
+		Name:      strings.TrimSpace(l.Name),
+		Title:     l.Title,
+		Queryable: l.Queryable == "1" || l.Queryable == "true",
+		LLBBox:    parent.LLBBox,
+		MinScale:  parent.MinScale,
+		MaxScale:  parent.MaxScale,
+		MinRes:    parent.MinRes,
+		MaxRes:    parent.MaxRes,
+		LegendURL: parent.LegendURL,
+	}
+
+	info.Srs = append(info.Srs, parent.Srs...)
+	for _, srs := range append(l.CRS, l.SRS...) {
+		// 1.1.1 允许在一个元素中以空格分隔多个SRS
+		for _, code := range strings.Fields(srs) {
+			if !containsFold(info.Srs, code) {
+				info.Srs = append(info.Srs, code)
+			}
+		}
+	}
+
+	if l.EXBBox != nil {
+		info.LLBBox = &vec2d.Rect{Min: vec2d.T{l.EXBBox.West, l.EXBBox.South}, Max: vec2d.T{l.EXBBox.East, l.EXBBox.North}}
+	} else if l.LatLonBBox != nil {
+		info.LLBBox = &vec2d.Rect{Min: vec2d.T{l.LatLonBBox.MinX, l.LatLonBBox.MinY}, Max: vec2d.T{l.LatLonBBox.MaxX, l.LatLonBBox.MaxY}}
+	}
+
+	if l.MinScaleDenominator != nil {
+		info.MinScale = l.MinScaleDenominator
+	}
+	if l.MaxScaleDenominator != nil {
+		info.MaxScale = l.MaxScaleDenominator
+	}
+	if l.ScaleHint != nil {
+		// ScaleHint 为像素对角线的地面长度
+		if l.ScaleHint.Min > 0 {
+			res := l.ScaleHint.Min / math.Sqrt2
+			info.MaxRes = &res
+		}
+		if l.ScaleHint.Max > 0 && !math.IsInf(l.ScaleHint.Max, 0) {
+			res := l.ScaleHint.Max / math.Sqrt2
+			info.MinRes = &res
+		}
+	}
+
+	for _, style := range l.Style {
+		if len(style.LegendURL) > 0 && style.LegendURL[0].OnlineResource.Href != "" {
+			info.LegendURL = style.LegendURL[0].OnlineResource.Href
+			break
+		}
+	}
+
+	if info.Name != "" {
+		layers = append(layers, info)
+	}
+	for _, child := range l.Layer {
+		layers = flattenWMSLayer(layers, child, info)
+	}
+	return layers
+}
+
+// Layer 按名称查找图层
+func (c *WMSCapabilities) Layer(name string) *WMSLayerCapabilities {
+	for _, l := range c.Layers {
+		if l.Name == name {
+			return l
+		}
+	}
+	return nil
+}
+
+// SupportsSrs 判断图层是否声明了指定的SRS，比较时忽略大小写
+func (l *WMSLayerCapabilities) SupportsSrs(srs string) bool {
+	return containsFold(l.Srs, srs)
+}
+
+func containsFold(list []string, s string) bool {
+	for _, v := range list {
+		if strings.EqualFold(v, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// WMSCapabilitiesURL 根据数据源地址生成GetCapabilities请求地址
+func WMSCapabilitiesURL(url string, version string) string {
+	sep := "?"
+	if strings.Contains(url, "?") {
+		sep = "&"
+		if strings.HasSuffix(url, "?") || strings.HasSuffix(url, "&") {
+			sep = ""
+		}
+	}
+	if version == "" {
+		version = "1.3.0"
+	}
+	return url + sep + "SERVICE=WMS&REQUEST=GetCapabilities&VERSION=" + version
+}
+
+// FetchWMSCapabilities 请求并解析上游服务的GetCapabilities文档
+func FetchWMSCapabilities(ctx Context, url string, version string) (*WMSCapabilities, error) {
+	status, body := ctx.Client().Open(WMSCapabilitiesURL(url, version), nil, nil)
+	if status != 200 {
+		return nil, fmt.Errorf("get capabilities from %s failed with status %d", url, status)
+	}
+	if len(body) == 0 {
+		return nil, errors.New("empty capabilities document")
+	}
+	return ParseWMSCapabilities(body)
+}
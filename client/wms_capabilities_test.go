@@ -0,0 +1,155 @@
+package client
+
+import (
+	"strings"
+	"testing"
+)
+
+const wms130Capabilities = `<?xml version="1.0" encoding="UTF-8"?>
+<WMS_Capabilities version="1.3.0" xmlns="http://www.opengis.net/wms" xmlns:xlink="http://www.w3.org/1999/xlink">
+  <Service>
+    <Name>WMS</Name>
+    <MaxWidth>2048</MaxWidth>
+    <MaxHeight>1024</MaxHeight>
+  </Service>
+  <Capability>
+    <Request>
+      <GetMap>
+        <Format>image/png</Format>
+        <Format>image/jpeg</Format>
+      </GetMap>
+    </Request>
+    <Layer>
+      <Title>root</Title>
+      <CRS>EPSG:4326</CRS>
+      <CRS>EPSG:3857</CRS>
+      <EX_GeographicBoundingBox>
+        <westBoundLongitude>-180</westBoundLongitude>
+        <eastBoundLongitude>180</eastBoundLongitude>
+        <southBoundLatitude>-90</southBoundLatitude>
+        <northBoundLatitude>90</northBoundLatitude>
+      </EX_GeographicBoundingBox>
+      <Layer queryable="1">
+        <Name>roads</Name>
+        <Title>Roads</Title>
+        <CRS>EPSG:25832</CRS>
+        <EX_GeographicBoundingBox>
+          <westBoundLongitude>5</westBoundLongitude>
+          <eastBoundLongitude>15</eastBoundLongitude>
+          <southBoundLatitude>47</southBoundLatitude>
+          <northBoundLatitude>55</northBoundLatitude>
+        </EX_GeographicBoundingBox>
+        <Style>
+          <Name>default</Name>
+          <LegendURL width="20" height="20">
+            <Format>image/png</Format>
+            <OnlineResource xlink:type="simple" xlink:href="http://example.com/legend?layer=roads"/>
+          </LegendURL>
+        </Style>
+        <MinScaleDenominator>1000</MinScaleDenominator>
+        <MaxScaleDenominator>500000</MaxScaleDenominator>
+      </Layer>
+      <Layer>
+        <Name>water</Name>
+        <Title>Water</Title>
+      </Layer>
+    </Layer>
+  </Capability>
+</WMS_Capabilities>`
+
+const wms111Capabilities = `<?xml version="1.0" encoding="UTF-8"?>
+<WMT_MS_Capabilities version="1.1.1">
+  <Capability>
+    <Request>
+      <GetMap>
+        <Format>image/png</Format>
+      </GetMap>
+    </Request>
+    <Layer>
+      <Title>root</Title>
+      <SRS>EPSG:4326 EPSG:900913</SRS>
+      <Layer>
+        <Name>osm</Name>
+        <LatLonBoundingBox minx="-10" miny="-20" maxx="10" maxy="20"/>
+        <ScaleHint min="1.4142135623730951" max="1414.2135623730951"/>
+      </Layer>
+    </Layer>
+  </Capability>
+</WMT_MS_Capabilities>`
+
+func TestParseWMSCapabilities130(t *testing.T) {
+	caps, err := ParseWMSCapabilities([]byte(wms130Capabilities))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if caps.Version != "1.3.0" || caps.MaxWidth != 2048 || caps.MaxHeight != 1024 {
+		t.Fatalf("unexpected service info: %+v", caps)
+	}
+	if len(caps.Formats) != 2 {
+		t.Fatalf("unexpected formats: %v", caps.Formats)
+	}
+	if len(caps.Layers) != 2 {
+		t.Fatalf("expected 2 named layers, got %d", len(caps.Layers))
+	}
+
+	roads := caps.Layer("roads")
+	if roads == nil {
+		t.Fatal("layer roads not found")
+	}
+	if !roads.Queryable {
+		t.Error("roads should be queryable")
+	}
+	if !roads.SupportsSrs("epsg:3857") || !roads.SupportsSrs("EPSG:25832") {
+		t.Errorf("roads should inherit parent srs: %v", roads.Srs)
+	}
+	if roads.LLBBox == nil || roads.LLBBox.Min[0] != 5 || roads.LLBBox.Max[1] != 55 {
+		t.Errorf("unexpected roads bbox: %v", roads.LLBBox)
+	}
+	if roads.MinScale == nil || *roads.MinScale != 1000 || roads.MaxScale == nil || *roads.MaxScale != 500000 {
+		t.Error("unexpected roads scale denominators")
+	}
+	if roads.LegendURL != "http://example.com/legend?layer=roads" {
+		t.Errorf("unexpected legend url: %s", roads.LegendURL)
+	}
+
+	water := caps.Layer("water")
+	if water.LLBBox == nil || water.LLBBox.Min[0] != -180 {
+		t.Errorf("water should inherit parent bbox: %v", water.LLBBox)
+	}
+	if water.SupportsSrs("EPSG:25832") {
+		t.Error("water should not inherit sibling srs")
+	}
+}
+
+func TestParseWMSCapabilities111(t *testing.T) {
+	caps, err := ParseWMSCapabilities([]byte(wms111Capabilities))
+	if err != nil {
+		t.Fatal(err)
+	}
+	osm := caps.Layer("osm")
+	if osm == nil {
+		t.Fatal("layer osm not found")
+	}
+	if !osm.SupportsSrs("EPSG:900913") || !osm.SupportsSrs("EPSG:4326") {
+		t.Errorf("unexpected srs: %v", osm.Srs)
+	}
+	if osm.LLBBox == nil || osm.LLBBox.Min[1] != -20 {
+		t.Errorf("unexpected bbox: %v", osm.LLBBox)
+	}
+	if osm.MaxRes == nil || *osm.MaxRes < 0.999 || *osm.MaxRes > 1.001 {
+		t.Errorf("unexpected max res: %v", osm.MaxRes)
+	}
+	if osm.MinRes == nil || *osm.MinRes < 999 || *osm.MinRes > 1001 {
+		t.Errorf("unexpected min res: %v", osm.MinRes)
+	}
+}
+
+func TestWMSCapabilitiesURL(t *testing.T) {
+	url := WMSCapabilitiesURL("http://example.com/wms?map=foo", "1.1.1")
+	if url != "http://example.com/wms?map=foo&SERVICE=WMS&REQUEST=GetCapabilities&VERSION=1.1.1" {
+		t.Errorf("unexpected url: %s", url)
+	}
+	if !strings.HasSuffix(WMSCapabilitiesURL("http://example.com/wms", ""), "?SERVICE=WMS&REQUEST=GetCapabilities&VERSION=1.3.0") {
+		t.Error("unexpected default url")
+	}
+}
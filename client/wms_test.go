@@ -217,6 +217,22 @@ func TestWMSClientErrorHandling(t *testing.T) {
 	}
 }
 
+func TestWMSClientRequestTargetAuth(t *testing.T) {
+	newClient := func(auth Authenticator) *WMSClient {
+		param := http.Header{"layers": []string{"test-layer"}, "styles": []string{"default"}}
+		req := request.NewWMSMapRequest(param, "/wms", false, nil, false)
+		return NewWMSClient(req, nil, nil, NewCollectorContext(&Config{Auth: auth}))
+	}
+
+	if newClient(nil).RequestTarget() != newClient(nil).RequestTarget() {
+		t.Errorf("Expected anonymous clients with the same request to share a target")
+	}
+	a, b := newClient(NewBearerAuth("a")), newClient(NewBearerAuth("b"))
+	if a.RequestTarget() == b.RequestTarget() {
+		t.Errorf("Expected clients with different credentials to have different targets")
+	}
+}
+
 func TestWMSClientCombinedClient(t *testing.T) {
 	mock := &wmsMockClient{code: 200, body: []byte{0}}
 	ctx := &wmsMockContext{client: mock}
@@ -16,7 +16,7 @@ const (
 var (
 	amapTMSSource = setting.TileSource{
 		URLTemplate: AMAP_API_URL + "?x={x}&y={y}&z={z}&lang=zh_cn&size=1&scale=1&style=8",
-		Grid:        "global_webmercator",
+		Grid:        "gcj02_webmercator",
 		Subdomains:  []string{"1", "2", "3", "4"},
 		Options:     &setting.ImageOpts{Format: "png"},
 	}
@@ -24,7 +24,7 @@ var (
 	amapTMSCache = setting.CacheSource{
 		Sources:       []string{"amap"},
 		Name:          "amap_cache",
-		Grid:          "global_webmercator",
+		Grid:          "gcj02_webmercator",
 		Format:        "png",
 		RequestFormat: "png",
 		CacheInfo: &setting.CacheInfo{
@@ -34,10 +34,24 @@ var (
 		TileOptions: &setting.ImageOpts{Format: "png"},
 	}
 
+	// 将GCJ-02偏移的高德瓦片纠偏到标准Web墨卡托网格
+	amapMercatorCache = setting.CacheSource{
+		Sources:       []string{"amap_cache"},
+		Name:          "amap_mercator_cache",
+		Grid:          "global_webmercator",
+		Format:        "png",
+		RequestFormat: "png",
+		CacheInfo: &setting.CacheInfo{
+			Directory:       "./cache_data/amap_mercator",
+			DirectoryLayout: "tms",
+		},
+		TileOptions: &setting.ImageOpts{Format: "png"},
+	}
+
 	amapService = setting.TMSService{
 		Layers: []setting.TileLayer{
 			{
-				Source: "amap_cache",
+				Source: "amap_mercator_cache",
 				Name:   "amap_layer",
 			},
 		},
@@ -50,6 +64,7 @@ func getProxyService() *setting.ProxyService {
 
 	pd.Sources["amap"] = &amapTMSSource
 	pd.Caches["amap_cache"] = &amapTMSCache
+	pd.Caches["amap_mercator_cache"] = &amapMercatorCache
 
 	pd.Service = &amapService
 	return pd
@@ -15,8 +15,8 @@ const (
 
 var (
 	baiduTMSSource = setting.TileSource{
-		URLTemplate: BAIDU_API_URL + "?qt=tile&x={x}&y={y}&z={z}&styles=pl&scaler=1&p=1",
-		Grid:        "global_webmercator",
+		URLTemplate: BAIDU_API_URL + "?qt=tile&x={bd_x}&y={bd_y}&z={z}&styles=pl&scaler=1&p=1",
+		Grid:        "baidu",
 		Subdomains:  []string{"0", "1", "2", "3"},
 		Options:     &setting.ImageOpts{Format: "png"},
 	}
@@ -24,7 +24,7 @@ var (
 	baiduTMSCache = setting.CacheSource{
 		Sources:       []string{"baidu"},
 		Name:          "baidu_cache",
-		Grid:          "global_webmercator",
+		Grid:          "baidu",
 		Format:        "png",
 		RequestFormat: "png",
 		CacheInfo: &setting.CacheInfo{
@@ -34,10 +34,24 @@ var (
 		TileOptions: &setting.ImageOpts{Format: "png"},
 	}
 
+	// 将百度瓦片重投影到标准Web墨卡托网格
+	baiduMercatorCache = setting.CacheSource{
+		Sources:       []string{"baidu_cache"},
+		Name:          "baidu_mercator_cache",
+		Grid:          "global_webmercator",
+		Format:        "png",
+		RequestFormat: "png",
+		CacheInfo: &setting.CacheInfo{
+			Directory:       "./cache_data/baidu_mercator",
+			DirectoryLayout: "tms",
+		},
+		TileOptions: &setting.ImageOpts{Format: "png"},
+	}
+
 	baiduService = setting.TMSService{
 		Layers: []setting.TileLayer{
 			{
-				Source: "baidu_cache",
+				Source: "baidu_mercator_cache",
 				Name:   "baidu_layer",
 			},
 		},
@@ -50,6 +64,7 @@ func getProxyService() *setting.ProxyService {
 
 	pd.Sources["baidu"] = &baiduTMSSource
 	pd.Caches["baidu_cache"] = &baiduTMSCache
+	pd.Caches["baidu_mercator_cache"] = &baiduMercatorCache
 
 	pd.Service = &baiduService
 	return pd
@@ -0,0 +1,43 @@
+package imagery
+
+import (
+	"image"
+	"image/color"
+
+	"github.com/flywave/gg"
+
+	"github.com/flywave/go-tileproxy/tile"
+)
+
+// DrawDebugInfo 在瓦片上绘制边框、四等分网格线及调试信息，返回新的图像，不修改原图
+func DrawDebugInfo(src tile.Source, message string) tile.Source {
+	img, ok := src.GetTile().(image.Image)
+	if !ok || img == nil {
+		return src
+	}
+	opts, _ := src.GetTileOptions().(*ImageOptions)
+
+	dc := gg.NewContextForImage(img)
+	w, h := float64(dc.Width()), float64(dc.Height())
+
+	dc.SetColor(color.NRGBA{R: 255, A: 96})
+	dc.SetLineWidth(1)
+	for i := 1; i < 4; i++ {
+		x := w * float64(i) / 4
+		y := h * float64(i) / 4
+		dc.DrawLine(x, 0, x, h)
+		dc.DrawLine(0, y, w, y)
+	}
+	dc.Stroke()
+
+	dc.SetColor(color.NRGBA{R: 255, A: 255})
+	dc.DrawRectangle(0.5, 0.5, w-1, h-1)
+	dc.Stroke()
+
+	size := []uint32{uint32(dc.Width()), uint32(dc.Height())}
+	m := newMessageImage(message, opts)
+	m.box_color = color.NRGBA{R: 255, G: 255, B: 255, A: 180}
+	m.drawMsg(dc, size)
+
+	return &ImageSource{image: dc.Image(), size: size, Options: opts}
+}
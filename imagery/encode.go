@@ -0,0 +1,192 @@
+package imagery
+
+import (
+	"fmt"
+	"image"
+	"image/draw"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"strings"
+
+	webp "github.com/flywave/webp"
+)
+
+// 当前编码器不支持的参数，配置后返回错误并被忽略
+var unsupportedEncodingOptions = map[string]string{
+	"jpeg_progressive":   "the JPEG encoder only writes baseline JPEG",
+	"webp_method":        "the WebP encoder does not expose the compression method",
+	"webp_alpha_quality": "the WebP encoder does not expose the alpha quality",
+}
+
+// EncodeOptions 影像编码参数，由 ImageOptions.EncodingOptions 解析
+type EncodeOptions struct {
+	JpegQuality    int
+	WebpLossless   bool
+	WebpQuality    float32
+	WebpExact      bool
+	PngCompression png.CompressionLevel
+	Paletted       bool
+	Colors         int
+	Dither         bool
+}
+
+func DefaultEncodeOptions() *EncodeOptions {
+	return &EncodeOptions{
+		JpegQuality:    jpeg.DefaultQuality,
+		WebpLossless:   true,
+		WebpQuality:    webp.DefaulQuality,
+		PngCompression: png.DefaultCompression,
+		Colors:         256,
+	}
+}
+
+func encodingNumber(key string, v interface{}) (float64, error) {
+	switch n := v.(type) {
+	case int:
+		return float64(n), nil
+	case int64:
+		return float64(n), nil
+	case float32:
+		return float64(n), nil
+	case float64:
+		return n, nil
+	}
+	return 0, fmt.Errorf("encoding option %s must be a number", key)
+}
+
+func encodingBool(key string, v interface{}) (bool, error) {
+	if b, ok := v.(bool); ok {
+		return b, nil
+	}
+	return false, fmt.Errorf("encoding option %s must be a boolean", key)
+}
+
+func encodingRange(key string, v interface{}, lo, hi float64) (float64, error) {
+	n, err := encodingNumber(key, v)
+	if err != nil {
+		return 0, err
+	}
+	if n < lo || n > hi {
+		return 0, fmt.Errorf("encoding option %s must be between %v and %v", key, lo, hi)
+	}
+	return n, nil
+}
+
+// pngCompressionLevel 支持 0-9 的压缩级别或 none、fast、default、best
+func pngCompressionLevel(v interface{}) (png.CompressionLevel, error) {
+	if s, ok := v.(string); ok {
+		switch s {
+		case "none":
+			return png.NoCompression, nil
+		case "fast":
+			return png.BestSpeed, nil
+		case "default":
+			return png.DefaultCompression, nil
+		case "best":
+			return png.BestCompression, nil
+		}
+		return 0, fmt.Errorf("unknown png_compression: %s", s)
+	}
+	n, err := encodingRange("png_compression", v, 0, 9)
+	if err != nil {
+		return 0, err
+	}
+	switch {
+	case n == 0:
+		return png.NoCompression, nil
+	case n <= 3:
+		return png.BestSpeed, nil
+	case n <= 6:
+		return png.DefaultCompression, nil
+	}
+	return png.BestCompression, nil
+}
+
+// ParseEncodeOptions 解析编码参数，参数错误或不支持时返回错误，其余参数仍然生效。
+// 设置 webp_quality 且未设置 webp_lossless 时使用有损压缩
+func ParseEncodeOptions(opts map[string]interface{}) (*EncodeOptions, error) {
+	ret := DefaultEncodeOptions()
+	var errs []string
+	_, lossless := opts["webp_lossless"]
+	for key, v := range opts {
+		var err error
+		var n float64
+		switch key {
+		case "jpeg_quality":
+			if n, err = encodingRange(key, v, 1, 100); err == nil {
+				ret.JpegQuality = int(n)
+			}
+		case "webp_lossless":
+			ret.WebpLossless, err = encodingBool(key, v)
+		case "webp_quality":
+			if n, err = encodingRange(key, v, 0, 100); err == nil {
+				ret.WebpQuality = float32(n)
+				if !lossless {
+					ret.WebpLossless = false
+				}
+			}
+		case "webp_exact":
+			ret.WebpExact, err = encodingBool(key, v)
+		case "png_compression":
+			ret.PngCompression, err = pngCompressionLevel(v)
+		case "paletted":
+			ret.Paletted, err = encodingBool(key, v)
+		case "colors":
+			if n, err = encodingRange(key, v, 2, 256); err == nil {
+				ret.Colors = int(n)
+			}
+		case "dither":
+			ret.Dither, err = encodingBool(key, v)
+		default:
+			if reason, ok := unsupportedEncodingOptions[key]; ok {
+				err = fmt.Errorf("encoding option %s is not supported by the encoder: %s", key, reason)
+			} else {
+				err = fmt.Errorf("unknown encoding option: %s", key)
+			}
+		}
+		if err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+	if len(errs) > 0 {
+		return ret, fmt.Errorf("%s", strings.Join(errs, "; "))
+	}
+	return ret, nil
+}
+
+// GetEncodeOptions 返回编码参数，无效的参数使用默认值
+func (o *ImageOptions) GetEncodeOptions() *EncodeOptions {
+	if o == nil || len(o.EncodingOptions) == 0 {
+		return DefaultEncodeOptions()
+	}
+	ret, _ := ParseEncodeOptions(o.EncodingOptions)
+	return ret
+}
+
+// EncodeImageWithOptions 按扩展名与编码参数编码影像，opts 为空时使用默认参数
+func EncodeImageWithOptions(inputName string, writer io.Writer, img image.Image, opts *EncodeOptions) error {
+	if opts == nil {
+		opts = DefaultEncodeOptions()
+	}
+	switch {
+	case strings.HasSuffix(inputName, "jpg") || strings.HasSuffix(inputName, "jpeg"):
+		return jpeg.Encode(writer, img, &jpeg.Options{Quality: opts.JpegQuality})
+	case strings.HasSuffix(inputName, "png"):
+		enc := &png.Encoder{CompressionLevel: opts.PngCompression}
+		if opts.Paletted {
+			return enc.Encode(writer, QuantizeImage(img, opts.Colors, opts.Dither))
+		}
+		return enc.Encode(writer, img)
+	case strings.HasSuffix(inputName, "gif"):
+		gopts := &gif.Options{NumColors: opts.Colors, Quantizer: &MedianCutQuantizer{Colors: opts.Colors}, Drawer: draw.Src}
+		if opts.Dither {
+			gopts.Drawer = draw.FloydSteinberg
+		}
+		return gif.Encode(writer, img, gopts)
+	case strings.HasSuffix(inputName, "webp"):
+		return webp.Encode(writer, img, &webp.Options{Lossless: opts.WebpLossless, Quality: opts.WebpQuality, Exact: opts.WebpExact})
+	}
+	return fmt.Errorf("unsupported image format: %s", inputName)
+}
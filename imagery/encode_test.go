@@ -0,0 +1,147 @@
+package imagery
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"math/rand"
+	"strings"
+	"testing"
+
+	"github.com/flywave/go-tileproxy/tile"
+)
+
+func gradientImage(w, h int) *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			img.SetNRGBA(x, y, color.NRGBA{R: uint8(x * 255 / w), G: uint8(y * 255 / h), B: uint8((x + y) % 256), A: 255})
+		}
+	}
+	return img
+}
+
+func TestParseEncodeOptions(t *testing.T) {
+	opts, err := ParseEncodeOptions(map[string]interface{}{
+		"jpeg_quality":    90,
+		"webp_quality":    75.0,
+		"png_compression": "best",
+		"paletted":        true,
+		"colors":          64.0,
+		"dither":          true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if opts.JpegQuality != 90 || opts.WebpQuality != 75 || opts.WebpLossless {
+		t.Errorf("unexpected options %+v", opts)
+	}
+	if opts.PngCompression != png.BestCompression || !opts.Paletted || opts.Colors != 64 || !opts.Dither {
+		t.Errorf("unexpected options %+v", opts)
+	}
+
+	opts, err = ParseEncodeOptions(map[string]interface{}{"webp_quality": 75, "webp_lossless": true})
+	if err != nil || !opts.WebpLossless {
+		t.Errorf("explicit lossless should win: %+v %v", opts, err)
+	}
+
+	opts, err = ParseEncodeOptions(map[string]interface{}{"jpeg_quality": 150, "jpeg_progressive": true, "foo": 1})
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	if !strings.Contains(err.Error(), "jpeg_progressive is not supported by the encoder") {
+		t.Errorf("options the encoders cannot honour should be reported as unsupported: %v", err)
+	}
+	if !strings.Contains(err.Error(), "unknown encoding option: foo") {
+		t.Errorf("unknown options should be rejected: %v", err)
+	}
+	if opts.JpegQuality != DefaultEncodeOptions().JpegQuality {
+		t.Errorf("invalid quality should keep default, got %d", opts.JpegQuality)
+	}
+}
+
+func TestEncodeJpegQuality(t *testing.T) {
+	img := gradientImage(64, 64)
+	low, high := &bytes.Buffer{}, &bytes.Buffer{}
+	if err := EncodeImageWithOptions("jpeg", low, img, &EncodeOptions{JpegQuality: 10}); err != nil {
+		t.Fatal(err)
+	}
+	if err := EncodeImageWithOptions("jpeg", high, img, &EncodeOptions{JpegQuality: 95}); err != nil {
+		t.Fatal(err)
+	}
+	if low.Len() >= high.Len() {
+		t.Errorf("quality 10 (%d bytes) should be smaller than quality 95 (%d bytes)", low.Len(), high.Len())
+	}
+}
+
+func TestEncodeWebpLossy(t *testing.T) {
+	img := gradientImage(64, 64)
+	rnd := rand.New(rand.NewSource(1))
+	for i := range img.Pix {
+		if i%4 != 3 {
+			img.Pix[i] = uint8(int(img.Pix[i]) + rnd.Intn(32))
+		}
+	}
+	lossless, lossy := &bytes.Buffer{}, &bytes.Buffer{}
+	if err := EncodeImageWithOptions("webp", lossless, img, nil); err != nil {
+		t.Fatal(err)
+	}
+	opts := DefaultEncodeOptions()
+	opts.WebpLossless = false
+	opts.WebpQuality = 50
+	if err := EncodeImageWithOptions("webp", lossy, img, opts); err != nil {
+		t.Fatal(err)
+	}
+	if lossy.Len() >= lossless.Len() {
+		t.Errorf("lossy (%d bytes) should be smaller than lossless (%d bytes)", lossy.Len(), lossless.Len())
+	}
+	if PeekImageFormat(lossy.String()) != "webp" {
+		t.Errorf("expected webp output")
+	}
+}
+
+func TestQuantizeImage(t *testing.T) {
+	img := gradientImage(32, 32)
+	img.SetNRGBA(0, 0, color.NRGBA{R: 10, A: 0})
+	img.SetNRGBA(1, 0, color.NRGBA{G: 10, A: 0})
+
+	for _, dither := range []bool{false, true} {
+		p := QuantizeImage(img, 16, dither)
+		if len(p.Palette) > 16 {
+			t.Fatalf("palette has %d colors", len(p.Palette))
+		}
+		if _, _, _, a := p.At(0, 0).RGBA(); a != 0 {
+			t.Errorf("dither=%v: transparent pixel should stay transparent", dither)
+		}
+	}
+
+	// 颜色数不超过上限时保持原色
+	small := filledImage(color.NRGBA{R: 200, G: 100, B: 50, A: 128}, 4, 4)
+	small.SetNRGBA(3, 3, color.NRGBA{B: 255, A: 255})
+	p := QuantizeImage(small, 256, false)
+	if len(p.Palette) != 2 {
+		t.Fatalf("palette has %d colors", len(p.Palette))
+	}
+	if c := color.NRGBAModel.Convert(p.At(0, 0)).(color.NRGBA); c != small.NRGBAAt(0, 0) {
+		t.Errorf("color changed %v", c)
+	}
+}
+
+func TestImageSourcePaletted(t *testing.T) {
+	opts := &ImageOptions{Format: tile.TileFormat("image/png"), EncodingOptions: map[string]interface{}{"paletted": true, "colors": 32}}
+	src := CreateImageSourceFromImage(gradientImage(64, 64), opts)
+	buf := src.GetBuffer(nil, nil)
+
+	img, err := png.Decode(bytes.NewReader(buf))
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, ok := img.(*image.Paletted)
+	if !ok {
+		t.Fatalf("expected paletted png, got %T", img)
+	}
+	if len(p.Palette) > 32 {
+		t.Errorf("palette has %d colors", len(p.Palette))
+	}
+}
@@ -0,0 +1,457 @@
+package imagery
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// BandExpression 波段运算表达式，支持跨数据源的四则运算、比较、逻辑运算、三元条件及常用函数。
+//
+// 变量 b0..bN 或 r、g、b、a 表示第一个数据源的波段，s1.b0、s2.r 表示其他数据源的波段，
+// 波段取值范围为0-255。比较与逻辑运算结果为1或0，nan表示无数据。
+type BandExpression struct {
+	Source  string
+	Aliases map[string]string
+	root    exprNode
+	bands   [][2]int
+	maxSrc  int
+}
+
+type exprEnv struct {
+	values []float64
+}
+
+type exprNode func(env *exprEnv) float64
+
+// ParseBandExpression 解析表达式，aliases 为变量别名，例如 {"nir": "s1.b0"}
+func ParseBandExpression(src string, aliases map[string]string) (*BandExpression, error) {
+	e := &BandExpression{Source: src, Aliases: aliases, maxSrc: -1}
+	p := &exprParser{expr: e}
+	if err := p.tokenize(src); err != nil {
+		return nil, err
+	}
+	root, err := p.parseExpr(0)
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.tokens) {
+		return nil, fmt.Errorf("unexpected token '%s' at %d", p.tokens[p.pos].text, p.tokens[p.pos].pos)
+	}
+	e.root = root
+	return e, nil
+}
+
+// SourceCount 表达式引用的数据源数量
+func (e *BandExpression) SourceCount() int {
+	return e.maxSrc + 1
+}
+
+// Bands 表达式引用的数据源与波段
+func (e *BandExpression) Bands() [][2]int {
+	return e.bands
+}
+
+// Eval 计算表达式，values 与 Bands 的顺序一一对应
+func (e *BandExpression) Eval(values []float64) float64 {
+	return e.root(&exprEnv{values: values})
+}
+
+func (e *BandExpression) bandSlot(src, band int) int {
+	for i, b := range e.bands {
+		if b[0] == src && b[1] == band {
+			return i
+		}
+	}
+	e.bands = append(e.bands, [2]int{src, band})
+	if src > e.maxSrc {
+		e.maxSrc = src
+	}
+	return len(e.bands) - 1
+}
+
+const (
+	tokNumber = iota
+	tokIdent
+	tokOp
+)
+
+type exprToken struct {
+	kind int
+	text string
+	num  float64
+	pos  int
+}
+
+type exprParser struct {
+	expr   *BandExpression
+	tokens []exprToken
+	pos    int
+}
+
+var exprOperators = []string{"&&", "||", "==", "!=", "<=", ">=", "<", ">", "+", "-", "*", "/", "%", "^", "!", "(", ")", ",", "?", ":"}
+
+func (p *exprParser) tokenize(src string) error {
+	for i := 0; i < len(src); {
+		c := rune(src[i])
+		switch {
+		case unicode.IsSpace(c):
+			i++
+		case unicode.IsDigit(c) || (c == '.' && i+1 < len(src) && unicode.IsDigit(rune(src[i+1]))):
+			j := i
+			for j < len(src) && (unicode.IsDigit(rune(src[j])) || src[j] == '.' || src[j] == 'e' || src[j] == 'E' ||
+				((src[j] == '+' || src[j] == '-') && j > i && (src[j-1] == 'e' || src[j-1] == 'E'))) {
+				j++
+			}
+			v, err := strconv.ParseFloat(src[i:j], 64)
+			if err != nil {
+				return fmt.Errorf("invalid number '%s' at %d", src[i:j], i)
+			}
+			p.tokens = append(p.tokens, exprToken{kind: tokNumber, text: src[i:j], num: v, pos: i})
+			i = j
+		case unicode.IsLetter(c) || c == '_':
+			j := i
+			for j < len(src) && (unicode.IsLetter(rune(src[j])) || unicode.IsDigit(rune(src[j])) || src[j] == '_' || src[j] == '.') {
+				j++
+			}
+			p.tokens = append(p.tokens, exprToken{kind: tokIdent, text: src[i:j], pos: i})
+			i = j
+		default:
+			matched := false
+			for _, op := range exprOperators {
+				if strings.HasPrefix(src[i:], op) {
+					p.tokens = append(p.tokens, exprToken{kind: tokOp, text: op, pos: i})
+					i += len(op)
+					matched = true
+					break
+				}
+			}
+			if !matched {
+				return fmt.Errorf("unexpected character '%c' at %d", c, i)
+			}
+		}
+	}
+	return nil
+}
+
+func (p *exprParser) peek() *exprToken {
+	if p.pos < len(p.tokens) {
+		return &p.tokens[p.pos]
+	}
+	return nil
+}
+
+func (p *exprParser) expect(op string) error {
+	t := p.peek()
+	if t == nil || t.kind != tokOp || t.text != op {
+		if t == nil {
+			return fmt.Errorf("expected '%s' at end of expression", op)
+		}
+		return fmt.Errorf("expected '%s' at %d, got '%s'", op, t.pos, t.text)
+	}
+	p.pos++
+	return nil
+}
+
+// 二元运算符优先级，数值越大结合越紧密
+var exprPrecedence = map[string]int{
+	"?": 1, "||": 2, "&&": 3,
+	"==": 4, "!=": 4, "<": 5, "<=": 5, ">": 5, ">=": 5,
+	"+": 6, "-": 6, "*": 7, "/": 7, "%": 7, "^": 9,
+}
+
+func (p *exprParser) parseExpr(minPrec int) (exprNode, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		t := p.peek()
+		if t == nil || t.kind != tokOp {
+			return left, nil
+		}
+		prec, ok := exprPrecedence[t.text]
+		if !ok || prec < minPrec {
+			return left, nil
+		}
+		p.pos++
+
+		if t.text == "?" {
+			then, err := p.parseExpr(0)
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expect(":"); err != nil {
+				return nil, err
+			}
+			els, err := p.parseExpr(prec)
+			if err != nil {
+				return nil, err
+			}
+			left = ternaryNode(left, then, els)
+			continue
+		}
+
+		// 幂运算右结合
+		next := prec + 1
+		if t.text == "^" {
+			next = prec
+		}
+		right, err := p.parseExpr(next)
+		if err != nil {
+			return nil, err
+		}
+		left = binaryNode(t.text, left, right)
+	}
+}
+
+func (p *exprParser) parseUnary() (exprNode, error) {
+	t := p.peek()
+	if t != nil && t.kind == tokOp && (t.text == "-" || t.text == "+" || t.text == "!") {
+		p.pos++
+		// 一元运算优先级低于幂运算，-x^2 == -(x^2)
+		operand, err := p.parseExpr(exprPrecedence["^"])
+		if err != nil {
+			return nil, err
+		}
+		switch t.text {
+		case "-":
+			return func(env *exprEnv) float64 { return -operand(env) }, nil
+		case "!":
+			return func(env *exprEnv) float64 { return boolValue(operand(env) == 0) }, nil
+		}
+		return operand, nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *exprParser) parsePrimary() (exprNode, error) {
+	t := p.peek()
+	if t == nil {
+		return nil, fmt.Errorf("unexpected end of expression")
+	}
+	p.pos++
+	switch t.kind {
+	case tokNumber:
+		v := t.num
+		return func(*exprEnv) float64 { return v }, nil
+	case tokIdent:
+		if n := p.peek(); n != nil && n.kind == tokOp && n.text == "(" {
+			p.pos++
+			return p.parseCall(t)
+		}
+		return p.resolveIdent(t)
+	case tokOp:
+		if t.text == "(" {
+			node, err := p.parseExpr(0)
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expect(")"); err != nil {
+				return nil, err
+			}
+			return node, nil
+		}
+	}
+	return nil, fmt.Errorf("unexpected token '%s' at %d", t.text, t.pos)
+}
+
+func (p *exprParser) parseCall(name *exprToken) (exprNode, error) {
+	var args []exprNode
+	if t := p.peek(); t != nil && t.kind == tokOp && t.text == ")" {
+		p.pos++
+	} else {
+		for {
+			arg, err := p.parseExpr(0)
+			if err != nil {
+				return nil, err
+			}
+			args = append(args, arg)
+			t := p.peek()
+			if t != nil && t.kind == tokOp && t.text == "," {
+				p.pos++
+				continue
+			}
+			if err := p.expect(")"); err != nil {
+				return nil, err
+			}
+			break
+		}
+	}
+	return callNode(name, args)
+}
+
+func (p *exprParser) resolveIdent(t *exprToken) (exprNode, error) {
+	name := strings.ToLower(t.text)
+	switch name {
+	case "nan":
+		return func(*exprEnv) float64 { return math.NaN() }, nil
+	case "pi":
+		return func(*exprEnv) float64 { return math.Pi }, nil
+	}
+	if alias, ok := p.expr.Aliases[t.text]; ok {
+		name = strings.ToLower(alias)
+	}
+
+	src, band, err := parseBandRef(name)
+	if err != nil {
+		return nil, fmt.Errorf("%v at %d", err, t.pos)
+	}
+	slot := p.expr.bandSlot(src, band)
+	return func(env *exprEnv) float64 { return env.values[slot] }, nil
+}
+
+// parseBandRef 解析波段引用，如 b1、r、s1.b0、s2.a
+func parseBandRef(name string) (int, int, error) {
+	src := 0
+	band := name
+	if strings.HasPrefix(name, "s") && strings.Contains(name, ".") {
+		parts := strings.SplitN(name, ".", 2)
+		v, err := strconv.Atoi(parts[0][1:])
+		if err != nil || v < 0 {
+			return 0, 0, fmt.Errorf("unknown source in '%s'", name)
+		}
+		src, band = v, parts[1]
+	}
+	switch band {
+	case "r":
+		return src, 0, nil
+	case "g":
+		return src, 1, nil
+	case "b":
+		return src, 2, nil
+	case "a":
+		return src, 3, nil
+	}
+	if strings.HasPrefix(band, "b") {
+		v, err := strconv.Atoi(band[1:])
+		if err == nil && v >= 0 && v < 4 {
+			return src, v, nil
+		}
+	}
+	return 0, 0, fmt.Errorf("unknown variable '%s'", name)
+}
+
+func boolValue(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+func ternaryNode(cond, then, els exprNode) exprNode {
+	return func(env *exprEnv) float64 {
+		if c := cond(env); c != 0 && !math.IsNaN(c) {
+			return then(env)
+		}
+		return els(env)
+	}
+}
+
+func binaryNode(op string, l, r exprNode) exprNode {
+	switch op {
+	case "+":
+		return func(env *exprEnv) float64 { return l(env) + r(env) }
+	case "-":
+		return func(env *exprEnv) float64 { return l(env) - r(env) }
+	case "*":
+		return func(env *exprEnv) float64 { return l(env) * r(env) }
+	case "/":
+		return func(env *exprEnv) float64 {
+			d := r(env)
+			if d == 0 {
+				return math.NaN()
+			}
+			return l(env) / d
+		}
+	case "%":
+		return func(env *exprEnv) float64 { return math.Mod(l(env), r(env)) }
+	case "^":
+		return func(env *exprEnv) float64 { return math.Pow(l(env), r(env)) }
+	case "==":
+		return func(env *exprEnv) float64 { return boolValue(l(env) == r(env)) }
+	case "!=":
+		return func(env *exprEnv) float64 { return boolValue(l(env) != r(env)) }
+	case "<":
+		return func(env *exprEnv) float64 { return boolValue(l(env) < r(env)) }
+	case "<=":
+		return func(env *exprEnv) float64 { return boolValue(l(env) <= r(env)) }
+	case ">":
+		return func(env *exprEnv) float64 { return boolValue(l(env) > r(env)) }
+	case ">=":
+		return func(env *exprEnv) float64 { return boolValue(l(env) >= r(env)) }
+	case "&&":
+		return func(env *exprEnv) float64 { return boolValue(l(env) != 0 && r(env) != 0) }
+	case "||":
+		return func(env *exprEnv) float64 { return boolValue(l(env) != 0 || r(env) != 0) }
+	}
+	return nil
+}
+
+var exprUnaryFuncs = map[string]func(float64) float64{
+	"abs":   math.Abs,
+	"sqrt":  math.Sqrt,
+	"log":   math.Log,
+	"log10": math.Log10,
+	"exp":   math.Exp,
+	"floor": math.Floor,
+	"ceil":  math.Ceil,
+	"round": math.Round,
+	"isnan": func(v float64) float64 { return boolValue(math.IsNaN(v)) },
+}
+
+func callNode(name *exprToken, args []exprNode) (exprNode, error) {
+	fn := strings.ToLower(name.text)
+	argc := func(n int) error {
+		if len(args) != n {
+			return fmt.Errorf("function %s expects %d arguments, got %d", fn, n, len(args))
+		}
+		return nil
+	}
+
+	if f, ok := exprUnaryFuncs[fn]; ok {
+		if err := argc(1); err != nil {
+			return nil, err
+		}
+		a := args[0]
+		return func(env *exprEnv) float64 { return f(a(env)) }, nil
+	}
+
+	switch fn {
+	case "min", "max":
+		if len(args) == 0 {
+			return nil, fmt.Errorf("function %s expects at least 1 argument", fn)
+		}
+		pick := math.Min
+		if fn == "max" {
+			pick = math.Max
+		}
+		return func(env *exprEnv) float64 {
+			v := args[0](env)
+			for _, a := range args[1:] {
+				v = pick(v, a(env))
+			}
+			return v
+		}, nil
+	case "clamp":
+		if err := argc(3); err != nil {
+			return nil, err
+		}
+		return func(env *exprEnv) float64 {
+			return math.Max(args[1](env), math.Min(args[2](env), args[0](env)))
+		}, nil
+	case "pow":
+		if err := argc(2); err != nil {
+			return nil, err
+		}
+		return func(env *exprEnv) float64 { return math.Pow(args[0](env), args[1](env)) }, nil
+	case "if":
+		if err := argc(3); err != nil {
+			return nil, err
+		}
+		return ternaryNode(args[0], args[1], args[2]), nil
+	}
+	return nil, fmt.Errorf("unknown function '%s' at %d", name.text, name.pos)
+}
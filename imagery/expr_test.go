@@ -0,0 +1,163 @@
+package imagery
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"testing"
+
+	vec2d "github.com/flywave/go3d/float64/vec2"
+
+	"github.com/flywave/go-geo"
+	"github.com/flywave/go-tileproxy/tile"
+)
+
+func TestBandExpressionEval(t *testing.T) {
+	cases := []struct {
+		expr   string
+		values []float64
+		want   float64
+	}{
+		{"1 + 2 * 3", nil, 7},
+		{"(1 + 2) * 3", nil, 9},
+		{"-2 ^ 2", nil, -4},
+		{"2 ^ 3 ^ 2", nil, 512},
+		{"b0 / 2", []float64{10}, 5},
+		{"clamp(b0, 0, 100)", []float64{300}, 100},
+		{"b0 > 10 && b0 < 20 ? 1 : 0", []float64{15}, 1},
+		{"if(b0 == 0, 5, 6)", []float64{1}, 6},
+		{"max(1, 5, 3) + min(4, 2)", nil, 7},
+		{"!(b0 >= 1)", []float64{0}, 1},
+	}
+	for _, c := range cases {
+		e, err := ParseBandExpression(c.expr, nil)
+		if err != nil {
+			t.Fatalf("%s: %v", c.expr, err)
+		}
+		if got := e.Eval(c.values); math.Abs(got-c.want) > 1e-9 {
+			t.Errorf("%s = %v, want %v", c.expr, got, c.want)
+		}
+	}
+}
+
+func TestBandExpressionVariables(t *testing.T) {
+	e, err := ParseBandExpression("(nir - r) / (nir + r)", map[string]string{"nir": "s1.b0"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e.SourceCount() != 2 {
+		t.Fatalf("source count %d", e.SourceCount())
+	}
+	bands := e.Bands()
+	if len(bands) != 2 || bands[0] != [2]int{1, 0} || bands[1] != [2]int{0, 0} {
+		t.Fatalf("bands %v", bands)
+	}
+	if got := e.Eval([]float64{200, 100}); math.Abs(got-1.0/3) > 1e-9 {
+		t.Errorf("ndvi %v", got)
+	}
+	if got := e.Eval([]float64{0, 0}); !math.IsNaN(got) {
+		t.Errorf("expected NaN for zero division, got %v", got)
+	}
+
+	for _, bad := range []string{"b9", "foo(1)", "1 +", "(1", "b0 ? 1", "min()", "1 $ 2"} {
+		if _, err := ParseBandExpression(bad, nil); err == nil {
+			t.Errorf("expected error for %q", bad)
+		}
+	}
+}
+
+func TestColorRamp(t *testing.T) {
+	ramp := NewColorRamp([]ColorStop{
+		{Value: 1, Color: color.NRGBA{R: 255, A: 255}},
+		{Value: -1, Color: color.NRGBA{B: 255, A: 255}},
+	}, false)
+	if c := ramp.Color(0); c != (color.NRGBA{R: 128, B: 128, A: 255}) {
+		t.Errorf("interpolated %v", c)
+	}
+	if c := ramp.Color(5); c != (color.NRGBA{R: 255, A: 255}) {
+		t.Errorf("above range %v", c)
+	}
+	if c := ramp.Color(math.NaN()); c.A != 0 {
+		t.Errorf("nan %v", c)
+	}
+
+	ramp.Discrete = true
+	if c := ramp.Color(0.5); c != (color.NRGBA{B: 255, A: 255}) {
+		t.Errorf("discrete %v", c)
+	}
+
+	if NewColorRampPreset("hypsometric", false) == nil || NewColorRampPreset("unknown", false) != nil {
+		t.Errorf("preset lookup")
+	}
+}
+
+func TestBandMergerExpression(t *testing.T) {
+	img_opts := *PNG_FORMAT
+	img_opts.Transparent = geo.NewBool(true)
+
+	red := image.NewNRGBA(image.Rect(0, 0, 4, 4))
+	nir := image.NewNRGBA(image.Rect(0, 0, 4, 4))
+	for i := 0; i < 16; i++ {
+		red.Pix[i*4], red.Pix[i*4+3] = 50, 255
+		nir.Pix[i*4], nir.Pix[i*4+3] = 150, 255
+	}
+	nir.Pix[0] = 0
+	red.Pix[0] = 0
+
+	expr, err := ParseBandExpression("(s1.r - r) / (s1.r + r)", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	merger := NewBandMerger(RGBA)
+	merger.SetExpression(expr, NewColorRamp([]ColorStop{
+		{Value: 0, Color: color.NRGBA{A: 255}},
+		{Value: 1, Color: color.NRGBA{G: 200, A: 255}},
+	}, false))
+
+	layers := []tile.Source{CreateImageSourceFromImage(red, &img_opts), CreateImageSourceFromImage(nir, &img_opts)}
+	result := MergeImages(layers, &img_opts, [2]uint32{4, 4}, vec2d.Rect{}, nil, merger)
+	img := result.GetTile().(image.Image)
+
+	if c := color.NRGBAModel.Convert(img.At(1, 1)).(color.NRGBA); c != (color.NRGBA{G: 100, A: 255}) {
+		t.Errorf("ndvi color %v", c)
+	}
+	if _, _, _, a := img.At(0, 0).RGBA(); a != 0 {
+		t.Errorf("nodata pixel should be transparent")
+	}
+
+	if len(merger.Layers) != 0 {
+		t.Errorf("shared merger should not keep layers")
+	}
+}
+
+func TestBandMergerMissingSource(t *testing.T) {
+	img_opts := *PNG_FORMAT
+	img_opts.Transparent = geo.NewBool(true)
+
+	nir := image.NewNRGBA(image.Rect(0, 0, 4, 4))
+	for i := range nir.Pix {
+		nir.Pix[i] = 255
+	}
+
+	expr, err := ParseBandExpression("s1.r - r", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	merger := NewBandMerger(RGBA)
+	merger.SetExpression(expr, nil)
+
+	// 第一个数据源获取失败时保留空位，不能把第二个数据源当作第一个
+	layers := []tile.Source{nil, CreateImageSourceFromImage(nir, &img_opts)}
+	result := MergeImages(layers, &img_opts, [2]uint32{4, 4}, vec2d.Rect{}, nil, merger)
+	if _, ok := result.(*BlankImageSource); !ok {
+		t.Errorf("missing source should produce a blank tile")
+	}
+
+	merger = NewBandMerger(RGBA)
+	merger.AddOps(0, 1, 0, 1.0)
+	layers = []tile.Source{nil, CreateImageSourceFromImage(nir, &img_opts)}
+	result = MergeImages(layers, &img_opts, [2]uint32{4, 4}, vec2d.Rect{}, nil, merger)
+	if c := color.NRGBAModel.Convert(result.GetTile().(image.Image).At(1, 1)).(color.NRGBA); c.R != 255 {
+		t.Errorf("unreferenced missing source should be ignored")
+	}
+}
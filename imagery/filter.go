@@ -0,0 +1,218 @@
+package imagery
+
+import (
+	"image"
+	"image/color"
+	"math"
+)
+
+type FilterType string
+
+const (
+	FILTER_BRIGHTNESS FilterType = "brightness"
+	FILTER_CONTRAST   FilterType = "contrast"
+	FILTER_GAMMA      FilterType = "gamma"
+	FILTER_SATURATION FilterType = "saturation"
+	FILTER_HUE        FilterType = "hue"
+	FILTER_GRAYSCALE  FilterType = "grayscale"
+	FILTER_INVERT     FilterType = "invert"
+)
+
+// ImageFilter 影像调色参数。brightness 为-1到1的增量，contrast、gamma、saturation 为系数，
+// hue 为旋转角度，grayscale 与 invert 为0到1的程度
+type ImageFilter struct {
+	Type  FilterType
+	Value float64
+}
+
+// DefaultFilterValue 未指定参数时使用的默认值
+func DefaultFilterValue(tp FilterType) float64 {
+	switch tp {
+	case FILTER_CONTRAST, FILTER_GAMMA, FILTER_SATURATION, FILTER_GRAYSCALE, FILTER_INVERT:
+		return 1
+	}
+	return 0
+}
+
+// IsValidFilterType 判断调色类型是否支持
+func IsValidFilterType(tp FilterType) bool {
+	switch tp {
+	case FILTER_BRIGHTNESS, FILTER_CONTRAST, FILTER_GAMMA, FILTER_SATURATION, FILTER_HUE, FILTER_GRAYSCALE, FILTER_INVERT:
+		return true
+	}
+	return false
+}
+
+type BlendMode string
+
+const (
+	BLEND_NORMAL   BlendMode = "normal"
+	BLEND_MULTIPLY BlendMode = "multiply"
+	BLEND_SCREEN   BlendMode = "screen"
+	BLEND_OVERLAY  BlendMode = "overlay"
+	BLEND_DARKEN   BlendMode = "darken"
+	BLEND_LIGHTEN  BlendMode = "lighten"
+)
+
+// IsValidBlendMode 判断混合模式是否支持，空值等同于 normal
+func IsValidBlendMode(mode BlendMode) bool {
+	switch mode {
+	case "", BLEND_NORMAL, BLEND_MULTIPLY, BLEND_SCREEN, BLEND_OVERLAY, BLEND_DARKEN, BLEND_LIGHTEN:
+		return true
+	}
+	return false
+}
+
+func luminance(c [3]float64) float64 {
+	return 0.2126*c[0] + 0.7152*c[1] + 0.0722*c[2]
+}
+
+func clamp01(v float64) float64 {
+	return math.Max(0, math.Min(1, v))
+}
+
+// hueMatrix 按亮度保持的色相旋转矩阵
+func hueMatrix(deg float64) [3][3]float64 {
+	rad := deg * math.Pi / 180
+	c, s := math.Cos(rad), math.Sin(rad)
+	return [3][3]float64{
+		{0.213 + c*0.787 - s*0.213, 0.715 - c*0.715 - s*0.715, 0.072 - c*0.072 + s*0.928},
+		{0.213 - c*0.213 + s*0.143, 0.715 + c*0.285 + s*0.140, 0.072 - c*0.072 - s*0.283},
+		{0.213 - c*0.213 - s*0.787, 0.715 - c*0.715 + s*0.715, 0.072 + c*0.928 + s*0.072},
+	}
+}
+
+func applyFilter(c [3]float64, f ImageFilter, hue *[3][3]float64) [3]float64 {
+	switch f.Type {
+	case FILTER_BRIGHTNESS:
+		for i := range c {
+			c[i] += f.Value
+		}
+	case FILTER_CONTRAST:
+		for i := range c {
+			c[i] = (c[i]-0.5)*f.Value + 0.5
+		}
+	case FILTER_GAMMA:
+		if f.Value > 0 {
+			for i := range c {
+				c[i] = math.Pow(clamp01(c[i]), 1/f.Value)
+			}
+		}
+	case FILTER_SATURATION:
+		l := luminance(c)
+		for i := range c {
+			c[i] = l + (c[i]-l)*f.Value
+		}
+	case FILTER_GRAYSCALE:
+		l := luminance(c)
+		for i := range c {
+			c[i] = c[i]*(1-f.Value) + l*f.Value
+		}
+	case FILTER_INVERT:
+		for i := range c {
+			c[i] = c[i]*(1-f.Value) + (1-c[i])*f.Value
+		}
+	case FILTER_HUE:
+		m := hue
+		c = [3]float64{
+			m[0][0]*c[0] + m[0][1]*c[1] + m[0][2]*c[2],
+			m[1][0]*c[0] + m[1][1]*c[1] + m[1][2]*c[2],
+			m[2][0]*c[0] + m[2][1]*c[1] + m[2][2]*c[2],
+		}
+	}
+	for i := range c {
+		c[i] = clamp01(c[i])
+	}
+	return c
+}
+
+// ApplyFilters 按顺序对影像调色，透明度保持不变
+func ApplyFilters(img image.Image, filters []ImageFilter) *image.NRGBA {
+	src := toNRGBA(img)
+	out := image.NewNRGBA(src.Rect)
+	if len(filters) == 0 {
+		copy(out.Pix, src.Pix)
+		return out
+	}
+
+	hues := make([]*[3][3]float64, len(filters))
+	for i, f := range filters {
+		if f.Type == FILTER_HUE {
+			m := hueMatrix(f.Value)
+			hues[i] = &m
+		}
+	}
+
+	for i := 0; i+3 < len(src.Pix); i += 4 {
+		a := src.Pix[i+3]
+		if a == 0 {
+			continue
+		}
+		c := [3]float64{float64(src.Pix[i]) / 255, float64(src.Pix[i+1]) / 255, float64(src.Pix[i+2]) / 255}
+		for fi, f := range filters {
+			c = applyFilter(c, f, hues[fi])
+		}
+		out.Pix[i] = uint8(math.Round(c[0] * 255))
+		out.Pix[i+1] = uint8(math.Round(c[1] * 255))
+		out.Pix[i+2] = uint8(math.Round(c[2] * 255))
+		out.Pix[i+3] = a
+	}
+	return out
+}
+
+func blendChannel(mode BlendMode, cb, cs float64) float64 {
+	switch mode {
+	case BLEND_MULTIPLY:
+		return cb * cs
+	case BLEND_SCREEN:
+		return cb + cs - cb*cs
+	case BLEND_OVERLAY:
+		if cb <= 0.5 {
+			return 2 * cb * cs
+		}
+		return 1 - 2*(1-cb)*(1-cs)
+	case BLEND_DARKEN:
+		return math.Min(cb, cs)
+	case BLEND_LIGHTEN:
+		return math.Max(cb, cs)
+	}
+	return cs
+}
+
+// BlendImage 按混合模式将 src 叠加到 dst 上，mask 不为空时按遮罩透明度叠加
+func BlendImage(dst image.Image, src image.Image, mode BlendMode, mask *image.Alpha) *image.NRGBA {
+	out := ApplyFilters(dst, nil)
+	s := toNRGBA(src)
+	rect := out.Rect.Intersect(s.Rect)
+	for y := rect.Min.Y; y < rect.Max.Y; y++ {
+		for x := rect.Min.X; x < rect.Max.X; x++ {
+			sc := s.NRGBAAt(x, y)
+			as := float64(sc.A) / 255
+			if mask != nil {
+				as *= float64(mask.AlphaAt(x, y).A) / 255
+			}
+			if as == 0 {
+				continue
+			}
+			bc := out.NRGBAAt(x, y)
+			ab := float64(bc.A) / 255
+
+			cs := [3]float64{float64(sc.R) / 255, float64(sc.G) / 255, float64(sc.B) / 255}
+			cb := [3]float64{float64(bc.R) / 255, float64(bc.G) / 255, float64(bc.B) / 255}
+			ao := as + ab*(1-as)
+			var co [3]float64
+			for i := range co {
+				// 源颜色先与背景混合，再按 source-over 合成
+				mixed := (1-ab)*cs[i] + ab*blendChannel(mode, cb[i], cs[i])
+				co[i] = (as*mixed + (1-as)*ab*cb[i]) / ao
+			}
+			out.SetNRGBA(x, y, color.NRGBA{
+				R: uint8(math.Round(clamp01(co[0]) * 255)),
+				G: uint8(math.Round(clamp01(co[1]) * 255)),
+				B: uint8(math.Round(clamp01(co[2]) * 255)),
+				A: uint8(math.Round(ao * 255)),
+			})
+		}
+	}
+	return out
+}
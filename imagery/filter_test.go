@@ -0,0 +1,108 @@
+package imagery
+
+import (
+	"image"
+	"image/color"
+	"testing"
+
+	vec2d "github.com/flywave/go3d/float64/vec2"
+
+	"github.com/flywave/go-geo"
+	"github.com/flywave/go-tileproxy/tile"
+)
+
+func filledImage(c color.NRGBA, w, h int) *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			img.SetNRGBA(x, y, c)
+		}
+	}
+	return img
+}
+
+func TestApplyFilters(t *testing.T) {
+	src := filledImage(color.NRGBA{R: 200, G: 100, B: 50, A: 128}, 2, 2)
+
+	cases := []struct {
+		filters []ImageFilter
+		want    color.NRGBA
+	}{
+		{nil, color.NRGBA{R: 200, G: 100, B: 50, A: 128}},
+		{[]ImageFilter{{Type: FILTER_INVERT, Value: 1}}, color.NRGBA{R: 55, G: 155, B: 205, A: 128}},
+		{[]ImageFilter{{Type: FILTER_HUE, Value: 0}}, color.NRGBA{R: 200, G: 100, B: 50, A: 128}},
+		{[]ImageFilter{{Type: FILTER_BRIGHTNESS, Value: 1}}, color.NRGBA{R: 255, G: 255, B: 255, A: 128}},
+		{[]ImageFilter{{Type: FILTER_SATURATION, Value: 1}, {Type: FILTER_CONTRAST, Value: 1}}, color.NRGBA{R: 200, G: 100, B: 50, A: 128}},
+	}
+	for i, c := range cases {
+		got := ApplyFilters(src, c.filters).NRGBAAt(1, 1)
+		if got != c.want {
+			t.Errorf("case %d: got %v, want %v", i, got, c.want)
+		}
+	}
+
+	gray := ApplyFilters(src, []ImageFilter{{Type: FILTER_GRAYSCALE, Value: 1}}).NRGBAAt(0, 0)
+	if gray.R != gray.G || gray.G != gray.B {
+		t.Errorf("grayscale not neutral: %v", gray)
+	}
+
+	if src.NRGBAAt(0, 0) != (color.NRGBA{R: 200, G: 100, B: 50, A: 128}) {
+		t.Errorf("source image modified")
+	}
+}
+
+func TestBlendImage(t *testing.T) {
+	dst := filledImage(color.NRGBA{R: 128, G: 255, B: 0, A: 255}, 2, 2)
+	src := filledImage(color.NRGBA{R: 128, G: 128, B: 255, A: 255}, 2, 2)
+
+	if c := BlendImage(dst, src, BLEND_MULTIPLY, nil).NRGBAAt(0, 0); c != (color.NRGBA{R: 64, G: 128, B: 0, A: 255}) {
+		t.Errorf("multiply %v", c)
+	}
+	if c := BlendImage(dst, src, BLEND_SCREEN, nil).NRGBAAt(0, 0); c != (color.NRGBA{R: 192, G: 255, B: 255, A: 255}) {
+		t.Errorf("screen %v", c)
+	}
+	if c := BlendImage(dst, src, BLEND_DARKEN, nil).NRGBAAt(0, 0); c != (color.NRGBA{R: 128, G: 128, B: 0, A: 255}) {
+		t.Errorf("darken %v", c)
+	}
+
+	// 透明背景上直接显示源颜色
+	empty := image.NewNRGBA(image.Rect(0, 0, 2, 2))
+	if c := BlendImage(empty, src, BLEND_MULTIPLY, nil).NRGBAAt(0, 0); c != src.NRGBAAt(0, 0) {
+		t.Errorf("transparent backdrop %v", c)
+	}
+
+	mask := image.NewAlpha(image.Rect(0, 0, 2, 2))
+	mask.SetAlpha(1, 1, color.Alpha{A: 255})
+	masked := BlendImage(dst, src, BLEND_MULTIPLY, mask)
+	if c := masked.NRGBAAt(0, 0); c != dst.NRGBAAt(0, 0) {
+		t.Errorf("masked pixel changed %v", c)
+	}
+	if c := masked.NRGBAAt(1, 1); c != (color.NRGBA{R: 64, G: 128, B: 0, A: 255}) {
+		t.Errorf("unmasked pixel %v", c)
+	}
+}
+
+func TestLayerMergerBlend(t *testing.T) {
+	img_opts := *PNG_FORMAT
+	img_opts.Transparent = geo.NewBool(true)
+
+	base_opts := img_opts
+	top_opts := img_opts
+	top_opts.Blend = BLEND_MULTIPLY
+	top_opts.Filters = []ImageFilter{{Type: FILTER_INVERT, Value: 1}}
+
+	base := CreateImageSourceFromImage(filledImage(color.NRGBA{R: 255, G: 128, B: 255, A: 255}, 4, 4), &base_opts)
+	top := CreateImageSourceFromImage(filledImage(color.NRGBA{R: 0, G: 0, B: 255, A: 255}, 4, 4), &top_opts)
+
+	result := MergeImages([]tile.Source{base, top}, &img_opts, [2]uint32{4, 4}, vec2d.Rect{}, nil, nil)
+	img := result.GetTile().(image.Image)
+	if c := color.NRGBAModel.Convert(img.At(2, 2)).(color.NRGBA); c != (color.NRGBA{R: 255, G: 128, B: 0, A: 255}) {
+		t.Errorf("blended color %v", c)
+	}
+
+	filtered := MergeImages([]tile.Source{top}, &img_opts, [2]uint32{4, 4}, vec2d.Rect{}, nil, nil)
+	img = filtered.GetTile().(image.Image)
+	if c := color.NRGBAModel.Convert(img.At(0, 0)).(color.NRGBA); c != (color.NRGBA{R: 255, G: 255, B: 0, A: 255}) {
+		t.Errorf("single layer filter %v", c)
+	}
+}
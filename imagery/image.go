@@ -37,11 +37,12 @@ func stringIn(str string, slice []string) bool {
 	return false
 }
 
+// isJpeg 按 SOI 标记判断，Go 编码的 JPEG 不含 JFIF 段
 func isJpeg(h string) bool {
-	if len(h) < 10 {
+	if len(h) < 3 {
 		return false
 	}
-	return h[6:10] == "JFIF"
+	return h[:3] == "\xff\xd8\xff"
 }
 
 func isPng(h string) bool {
@@ -70,7 +71,7 @@ func isWEBP(h string) bool {
 	if len(h) < 15 {
 		return false
 	}
-	return h[:15] == "RIFF????WEBPVP8"
+	return h[:4] == "RIFF" && h[8:15] == "WEBPVP8"
 }
 
 func PeekImageFormat(buf string) string {
@@ -207,8 +208,12 @@ func (s *ImageSource) makeImageBuf() error {
 
 func imageToBuf(image image.Image, image_opts *ImageOptions, _ *geo.GeoReference) []byte {
 	fname := image_opts.Format.Extension()
+	if IsMixedFormat(image_opts.Format) {
+		format := MixedFormat(image)
+		fname = format.Extension()
+	}
 	buf := &bytes.Buffer{}
-	EncodeImage(fname, buf, image)
+	EncodeImageWithOptions(fname, buf, image, image_opts.GetEncodeOptions())
 	return buf.Bytes()
 }
 
@@ -267,15 +272,7 @@ func (s *ImageSource) GetImage() image.Image {
 }
 
 func EncodeImage(inputName string, writer io.Writer, rgba image.Image) {
-	if strings.HasSuffix(inputName, "jpg") || strings.HasSuffix(inputName, "jpeg") {
-		jpeg.Encode(writer, rgba, nil)
-	} else if strings.HasSuffix(inputName, "png") {
-		png.Encode(writer, rgba)
-	} else if strings.HasSuffix(inputName, "gif") {
-		gif.Encode(writer, rgba, nil)
-	} else if strings.HasSuffix(inputName, "webp") {
-		webp.Encode(writer, rgba, &webp.Options{Lossless: true})
-	}
+	EncodeImageWithOptions(inputName, writer, rgba, nil)
 }
 
 func DecodeImage(inputName string, reader io.Reader) image.Image {
@@ -468,7 +465,12 @@ func (c *ImageSourceCreater) CreateEmpty(size [2]uint32, opts tile.TileOptions)
 }
 
 func (c *ImageSourceCreater) Create(data []byte, tile [3]int) tile.Source {
-	return CreateImageSourceFromBufer(data, c.Opt)
+	ret := CreateImageSourceFromBufer(data, c.Opt)
+	if IsMixedFormat(c.Opt.Format) && PeekImageFormat(string(data)) != "" {
+		// 混合格式保留原始数据，避免按另一种格式重新编码
+		ret.buf = data
+	}
+	return ret
 }
 
 func (c *ImageSourceCreater) GetExtension() string {
@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"image"
 	"image/color"
+	"image/draw"
+	"math"
 
 	vec2d "github.com/flywave/go3d/float64/vec2"
 
@@ -38,7 +40,7 @@ func (l *LayerMerger) Merge(opts tile.TileOptions, size []uint32, bbox vec2d.Rec
 			layer_coverage = l.Coverages[0]
 		}
 		layer_opts := layer_img.GetTileOptions().(*ImageOptions)
-		if ((layer_opts != nil && layer_opts.Transparent != nil && !*layer_opts.Transparent) ||
+		if !layer_opts.NeedsCompose() && ((layer_opts != nil && layer_opts.Transparent != nil && !*layer_opts.Transparent) ||
 			(layer_opts.Transparent != nil && *image_opts.Transparent)) &&
 			(size != nil && size[0] == layer_img.GetSize()[0] && size[1] == layer_img.GetSize()[1]) &&
 			(layer_coverage != nil && !layer_coverage.IsClip()) && coverage != nil {
@@ -75,6 +77,10 @@ func (l *LayerMerger) Merge(opts tile.TileOptions, size []uint32, bbox vec2d.Rec
 			opacity = layer_image_opts.Opacity
 		}
 
+		if layer_image_opts != nil && len(layer_image_opts.Filters) > 0 {
+			img = ApplyFilters(img, layer_image_opts.Filters)
+		}
+
 		if layer_coverage != nil && layer_coverage.IsClip() {
 			img, mask = maskImage(img, bbox, bbox_srs, layer_coverage)
 		}
@@ -83,7 +89,9 @@ func (l *LayerMerger) Merge(opts tile.TileOptions, size []uint32, bbox vec2d.Rec
 			img = opacityAdjust(img, *opacity)
 		}
 
-		if opacity != nil && *opacity < 1.0 {
+		if layer_image_opts != nil && layer_image_opts.Blend != "" && layer_image_opts.Blend != BLEND_NORMAL {
+			result = BlendImage(result, img, layer_image_opts.Blend, mask)
+		} else if opacity != nil && *opacity < 1.0 {
 			result = ImagingBlend(result, img, *layer_image_opts.Opacity)
 		} else {
 			dc := gg.NewContextForImage(result)
@@ -143,6 +151,8 @@ type BandMerger struct {
 	Mode         ImageMode
 	MaxBand      map[int]int
 	MaxSrcImages int
+	Expression   *BandExpression
+	Ramp         *ColorRamp
 }
 
 func NewBandMerger(mode ImageMode) *BandMerger {
@@ -167,6 +177,88 @@ func (l *BandMerger) AddOps(dst_band, src_img, src_band int, factor float64) {
 	l.MaxSrcImages = geo.MaxInt(src_img+1, l.MaxSrcImages)
 }
 
+// SetExpression 设置波段运算表达式，结果经色带映射为RGBA，ramp 为空时使用0-255灰度色带
+func (l *BandMerger) SetExpression(expr *BandExpression, ramp *ColorRamp) {
+	if ramp == nil {
+		ramp = DefaultColorRamp()
+	}
+	l.Expression = expr
+	l.Ramp = ramp
+	l.MaxSrcImages = geo.MaxInt(expr.SourceCount(), l.MaxSrcImages)
+}
+
+// HasOps 判断是否配置了波段组合或表达式
+func (l *BandMerger) HasOps() bool {
+	return len(l.Ops) > 0 || l.Expression != nil
+}
+
+func toNRGBA(img image.Image) *image.NRGBA {
+	if nrgba, ok := img.(*image.NRGBA); ok && nrgba.Rect.Min == (image.Point{}) {
+		return nrgba
+	}
+	b := img.Bounds()
+	out := image.NewNRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	draw.Draw(out, out.Rect, img, b.Min, draw.Src)
+	return out
+}
+
+// references 判断波段组合或表达式是否引用第 i 个数据源
+func (l *BandMerger) references(i int) bool {
+	if l.Expression != nil {
+		for _, b := range l.Expression.Bands() {
+			if b[0] == i {
+				return true
+			}
+		}
+		return false
+	}
+	_, ok := l.MaxBand[i]
+	return ok
+}
+
+// sourceImages 按数据源顺序返回图像，Layers 中获取失败的数据源为 nil，被引用的数据源缺失时返回 false
+func (l *BandMerger) sourceImages() ([]image.Image, bool) {
+	imgs := make([]image.Image, len(l.Layers))
+	for i, layer := range l.Layers {
+		if layer != nil {
+			imgs[i], _ = layer.GetTile().(image.Image)
+		}
+		if imgs[i] == nil && l.references(i) {
+			return nil, false
+		}
+	}
+	return imgs, true
+}
+
+// mergeExpression 逐像素计算表达式，结果为 NaN 的像素透明
+func (l *BandMerger) mergeExpression(image_opts *ImageOptions, size []uint32, srcs []image.Image) tile.Source {
+	imgs := make([]*image.NRGBA, len(srcs))
+	for i, img := range srcs {
+		if img != nil {
+			imgs[i] = toNRGBA(img)
+		}
+	}
+
+	rect := image.Rect(0, 0, int(size[0]), int(size[1]))
+	out := image.NewNRGBA(rect)
+	bands := l.Expression.Bands()
+	values := make([]float64, len(bands))
+	for y := 0; y < rect.Dy(); y++ {
+		for x := 0; x < rect.Dx(); x++ {
+			for i, b := range bands {
+				img := imgs[b[0]]
+				if x >= img.Rect.Dx() || y >= img.Rect.Dy() {
+					values[i] = math.NaN()
+					continue
+				}
+				values[i] = float64(img.Pix[img.PixOffset(x, y)+b[1]])
+			}
+			out.SetNRGBA(x, y, l.Ramp.Color(l.Expression.Eval(values)))
+		}
+	}
+	return &ImageSource{image: out, size: size, Options: image_opts, cacheable: l.Cacheable}
+}
+
 func splitImage(img image.Image, mode ImageMode) (cha [][]uint32, rect image.Rectangle) {
 	rect = img.Bounds()
 	numcha := 1
@@ -245,7 +337,8 @@ func mergeImage(mode ImageMode, rect image.Rectangle, bands [][]uint32) image.Im
 func (l *BandMerger) Merge(opts tile.TileOptions, size []uint32, bbox vec2d.Rect, bbox_srs geo.Proj, coverage geo.Coverage) tile.Source {
 	image_opts := opts.(*ImageOptions)
 
-	if len(l.Layers) < l.MaxSrcImages {
+	imgs, ok := l.sourceImages()
+	if len(l.Layers) < l.MaxSrcImages || !ok {
 		return NewBlankImageSource([2]uint32{size[0], size[1]}, image_opts, l.Cacheable)
 	}
 
@@ -254,12 +347,14 @@ func (l *BandMerger) Merge(opts tile.TileOptions, size []uint32, bbox vec2d.Rect
 		size = ss[:]
 	}
 
+	if l.Expression != nil {
+		return l.mergeExpression(image_opts, size, imgs)
+	}
+
 	src_img_bands := make([][][]uint32, 0)
 	var src_image_rect image.Rectangle
 	var bands [][]uint32
-	for i, layer := range l.Layers {
-		img := layer.GetTile().(image.Image)
-
+	for i, img := range imgs {
 		if _, ok := l.MaxBand[i]; !ok {
 			src_img_bands = append(src_img_bands, nil)
 			continue
@@ -342,8 +437,10 @@ func MergeImages(layers []tile.Source, image_opts *ImageOptions, size [2]uint32,
 	}
 
 	if m, ok := merger.(*BandMerger); ok {
-		m.Layers = layers
-		return m.Merge(image_opts, size[:], bbox, bbox_srs, nil)
+		// 合并器在多个请求间共享，复制后再设置图层
+		bm := *m
+		bm.Layers = layers
+		return bm.Merge(image_opts, size[:], bbox, bbox_srs, nil)
 	} else if ml, ok := merger.(*LayerMerger); ok {
 		ml.Layers = layers
 		return ml.Merge(image_opts, size[:], bbox, bbox_srs, nil)
@@ -5,8 +5,10 @@ import (
 	"fmt"
 	"image"
 	"image/color"
+	"os"
 	"path"
 	"strings"
+	"sync"
 
 	_ "embed"
 
@@ -95,6 +97,41 @@ func newMessageImage(message string, image_opts *ImageOptions) *MessageImage {
 	return ret
 }
 
+var parsedFonts sync.Map
+
+// LoadFontFace 按字体名与字号创建字体，DejaVu Sans 与 DejaVu Sans Mono 使用内置字体，
+// 其他字体从字体目录读取，加载失败时使用内置的 DejaVu Sans
+func LoadFontFace(name string, size float64) font.Face {
+	if name == "" {
+		name = "DejaVu Sans"
+	}
+	var f *truetype.Font
+	if cached, ok := parsedFonts.Load(name); ok {
+		f = cached.(*truetype.Font)
+	} else {
+		var data []byte
+		switch {
+		case name == "DejaVu Sans Mono":
+			data = DejaVuSansMono
+		case name == "DejaVu Sans" || font_paths == "":
+			data = DejaVuSans
+		default:
+			var err error
+			if data, err = os.ReadFile(fontFile(name)); err != nil {
+				data = DejaVuSans
+			}
+		}
+		var err error
+		if f, err = truetype.Parse(data); err != nil {
+			if f, err = truetype.Parse(DejaVuSans); err != nil {
+				return loadDefaultFontFace()
+			}
+		}
+		parsedFonts.Store(name, f)
+	}
+	return truetype.NewFace(f, &truetype.Options{Size: size})
+}
+
 func (m *MessageImage) GetFont() font.Face {
 	if m.font_face == nil {
 		if m.font_name == "default" || m.font_name == "DejaVu Sans Mono" || m.font_name == "DejaVu Sans" || font_paths == "" {
@@ -0,0 +1,58 @@
+package imagery
+
+import (
+	"image"
+
+	"github.com/flywave/go-tileproxy/tile"
+)
+
+// MIXED_FORMAT 混合格式，不透明瓦片使用 JPEG，含透明像素的瓦片使用 PNG
+const MIXED_FORMAT = tile.TileFormat("mixed")
+
+// MixedExtensions 混合格式瓦片可能使用的扩展名
+var MixedExtensions = []string{"png", "jpeg"}
+
+func IsMixedFormat(format tile.TileFormat) bool {
+	return format.Extension() == string(MIXED_FORMAT)
+}
+
+// IsOpaque 判断影像是否不含透明像素
+func IsOpaque(img image.Image) bool {
+	switch m := img.(type) {
+	case *image.NRGBA:
+		return alphaOpaque(m.Pix, m.Stride, m.Rect)
+	case *image.RGBA:
+		return alphaOpaque(m.Pix, m.Stride, m.Rect)
+	case interface{ Opaque() bool }:
+		return m.Opaque()
+	}
+	b := img.Bounds()
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			if _, _, _, a := img.At(x, y).RGBA(); a != 0xffff {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func alphaOpaque(pix []uint8, stride int, rect image.Rectangle) bool {
+	w := rect.Dx() * 4
+	for y, off := 0, 0; y < rect.Dy(); y, off = y+1, off+stride {
+		for i := off + 3; i < off+w; i += 4 {
+			if pix[i] != 0xff {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// MixedFormat 返回混合格式下影像实际使用的格式
+func MixedFormat(img image.Image) tile.TileFormat {
+	if IsOpaque(img) {
+		return tile.TileFormat("image/jpeg")
+	}
+	return tile.TileFormat("image/png")
+}
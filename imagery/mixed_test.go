@@ -0,0 +1,71 @@
+package imagery
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"testing"
+
+	"github.com/flywave/go-geo"
+)
+
+func TestIsOpaque(t *testing.T) {
+	img := filledImage(color.NRGBA{R: 10, A: 255}, 4, 4)
+	if !IsOpaque(img) {
+		t.Error("expected opaque")
+	}
+	if !IsOpaque(img.SubImage(image.Rect(1, 1, 3, 3))) {
+		t.Error("expected opaque sub image")
+	}
+	img.SetNRGBA(3, 3, color.NRGBA{R: 10, A: 254})
+	if IsOpaque(img) {
+		t.Error("expected transparent")
+	}
+	if !IsOpaque(img.SubImage(image.Rect(0, 0, 3, 3))) {
+		t.Error("expected opaque sub image")
+	}
+	if !IsOpaque(image.NewGray(image.Rect(0, 0, 2, 2))) {
+		t.Error("gray image is opaque")
+	}
+}
+
+func TestMixedFormatBuffer(t *testing.T) {
+	opts := &ImageOptions{Format: MIXED_FORMAT, Transparent: geo.NewBool(true)}
+
+	opaque := CreateImageSourceFromImage(filledImage(color.NRGBA{R: 100, G: 50, B: 25, A: 255}, 8, 8), opts)
+	buf := opaque.GetBuffer(nil, nil)
+	if f := PeekImageFormat(string(buf)); f != "jpeg" {
+		t.Errorf("opaque tile encoded as %q", f)
+	}
+
+	transparent := CreateImageSourceFromImage(filledImage(color.NRGBA{R: 100, A: 128}, 8, 8), opts)
+	if f := PeekImageFormat(string(transparent.GetBuffer(nil, nil))); f != "png" {
+		t.Errorf("transparent tile encoded as %q", f)
+	}
+	if opts.Format != MIXED_FORMAT {
+		t.Errorf("options format changed to %s", opts.Format)
+	}
+
+	creater := &ImageSourceCreater{Opt: opts}
+	loaded := creater.Create(buf, [3]int{0, 0, 0})
+	if !bytes.Equal(loaded.GetBuffer(nil, nil), buf) {
+		t.Error("mixed tile should keep the stored buffer")
+	}
+	if loaded.GetTile().(image.Image).Bounds().Dx() != 8 {
+		t.Error("mixed tile should decode")
+	}
+	if creater.GetExtension() != "mixed" {
+		t.Errorf("extension %s", creater.GetExtension())
+	}
+}
+
+func TestPeekImageFormatJpeg(t *testing.T) {
+	buf := &bytes.Buffer{}
+	if err := jpeg.Encode(buf, filledImage(color.NRGBA{A: 255}, 2, 2), nil); err != nil {
+		t.Fatal(err)
+	}
+	if f := PeekImageFormat(buf.String()); f != "jpeg" {
+		t.Errorf("got %q", f)
+	}
+}
@@ -36,12 +36,19 @@ type ImageOptions struct {
 	Mode            ImageMode
 	BgColor         color.Color
 	EncodingOptions map[string]interface{}
+	Filters         []ImageFilter
+	Blend           BlendMode
 }
 
 func (o *ImageOptions) GetFormat() tile.TileFormat {
 	return o.Format
 }
 
+// NeedsCompose 判断数据源影像是否需要调色，单一数据源时也需经过合并
+func (o *ImageOptions) NeedsCompose() bool {
+	return o != nil && len(o.Filters) > 0
+}
+
 func CreateImage(size [2]uint32, image_opts *ImageOptions) image.Image {
 	var mode ImageMode
 	var bgcolor color.Color
@@ -0,0 +1,67 @@
+package imagery
+
+import (
+	"image"
+	"math/bits"
+)
+
+// AverageHash 计算图像的8x8均值感知哈希，用于识别经过重新编码的相同瓦片
+func AverageHash(img image.Image) uint64 {
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	if w == 0 || h == 0 {
+		return 0
+	}
+
+	var cells [64]float64
+	for cy := 0; cy < 8; cy++ {
+		y0, y1 := b.Min.Y+cy*h/8, b.Min.Y+(cy+1)*h/8
+		if y1 <= y0 {
+			y1 = y0 + 1
+		}
+		for cx := 0; cx < 8; cx++ {
+			x0, x1 := b.Min.X+cx*w/8, b.Min.X+(cx+1)*w/8
+			if x1 <= x0 {
+				x1 = x0 + 1
+			}
+			var sum float64
+			var n int
+			for y := y0; y < y1 && y < b.Max.Y; y++ {
+				for x := x0; x < x1 && x < b.Max.X; x++ {
+					r, g, bl, a := img.At(x, y).RGBA()
+					// 透明像素按白色处理，避免全透明与全白图像的差异被放大
+					lum := (0.299*float64(r) + 0.587*float64(g) + 0.114*float64(bl)) * float64(a) / 0xffff
+					lum += float64(0xffff - a)
+					sum += lum
+					n++
+				}
+			}
+			if n > 0 {
+				cells[cy*8+cx] = sum / float64(n)
+			}
+		}
+	}
+
+	var mean float64
+	for _, v := range cells {
+		mean += v
+	}
+	mean /= 64
+
+	var hash uint64
+	for i, v := range cells {
+		if v > mean {
+			hash |= 1 << uint(i)
+		}
+	}
+	return hash
+}
+
+// DegenerateHash 判断哈希是否全为0或全为1，纯色图像的哈希都相同，不能用于感知比较
+func DegenerateHash(h uint64) bool {
+	return h == 0 || h == ^uint64(0)
+}
+
+func HashDistance(a, b uint64) int {
+	return bits.OnesCount64(a ^ b)
+}
@@ -0,0 +1,192 @@
+package imagery
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"sort"
+)
+
+// MedianCutQuantizer 中位切分颜色量化，透明度参与量化，完全透明的像素合并为一种颜色
+type MedianCutQuantizer struct {
+	Colors int
+}
+
+type colorCount struct {
+	c     [4]uint8
+	count int
+}
+
+type colorBox struct {
+	colors []colorCount
+	total  int
+}
+
+func (b *colorBox) span() (int, int) {
+	lo := [4]uint8{255, 255, 255, 255}
+	var hi [4]uint8
+	for _, cc := range b.colors {
+		for i := 0; i < 4; i++ {
+			lo[i] = min(lo[i], cc.c[i])
+			hi[i] = max(hi[i], cc.c[i])
+		}
+	}
+	channel, width := 0, -1
+	for i := 0; i < 4; i++ {
+		if w := int(hi[i]) - int(lo[i]); w > width {
+			channel, width = i, w
+		}
+	}
+	return channel, width
+}
+
+func (b *colorBox) average() color.NRGBA {
+	var sum [4]int
+	for _, cc := range b.colors {
+		for i := 0; i < 4; i++ {
+			sum[i] += int(cc.c[i]) * cc.count
+		}
+	}
+	h := b.total / 2
+	return color.NRGBA{
+		R: uint8((sum[0] + h) / b.total),
+		G: uint8((sum[1] + h) / b.total),
+		B: uint8((sum[2] + h) / b.total),
+		A: uint8((sum[3] + h) / b.total),
+	}
+}
+
+// split 沿跨度最大的通道按像素数中位切分
+func (b *colorBox) split() (*colorBox, *colorBox) {
+	channel, _ := b.span()
+	sort.Slice(b.colors, func(i, j int) bool { return b.colors[i].c[channel] < b.colors[j].c[channel] })
+	acc, cut := 0, 1
+	for i, cc := range b.colors[:len(b.colors)-1] {
+		acc += cc.count
+		cut = i + 1
+		if acc*2 >= b.total {
+			break
+		}
+	}
+	left := &colorBox{colors: b.colors[:cut], total: acc}
+	right := &colorBox{colors: b.colors[cut:], total: b.total - acc}
+	return left, right
+}
+
+func colorHistogram(m image.Image) []colorCount {
+	src := toNRGBA(m)
+	hist := make(map[[4]uint8]int)
+	for i := 0; i+3 < len(src.Pix); i += 4 {
+		c := [4]uint8{src.Pix[i], src.Pix[i+1], src.Pix[i+2], src.Pix[i+3]}
+		if c[3] == 0 {
+			c = [4]uint8{}
+		}
+		hist[c]++
+	}
+	ret := make([]colorCount, 0, len(hist))
+	for c, n := range hist {
+		ret = append(ret, colorCount{c: c, count: n})
+	}
+	// 固定顺序，保证相同影像得到相同调色板
+	sort.Slice(ret, func(i, j int) bool {
+		a, b := ret[i].c, ret[j].c
+		for k := 0; k < 4; k++ {
+			if a[k] != b[k] {
+				return a[k] < b[k]
+			}
+		}
+		return false
+	})
+	return ret
+}
+
+// Quantize 实现 draw.Quantizer，在 p 的基础上追加颜色直到 cap(p) 或 Colors 个
+func (q *MedianCutQuantizer) Quantize(p color.Palette, m image.Image) color.Palette {
+	n := q.Colors
+	if n <= 0 || n > 256 {
+		n = 256
+	}
+	if c := cap(p); c > 0 && c < n {
+		n = c
+	}
+	n -= len(p)
+	if n <= 0 {
+		return p
+	}
+
+	hist := colorHistogram(m)
+	if len(hist) == 0 {
+		return p
+	}
+	if len(hist) <= n {
+		for _, cc := range hist {
+			p = append(p, color.NRGBA{R: cc.c[0], G: cc.c[1], B: cc.c[2], A: cc.c[3]})
+		}
+		return p
+	}
+
+	// 完全透明单独保留一种颜色，避免与不透明颜色平均
+	if hist[0].c == ([4]uint8{}) && n > 1 {
+		p = append(p, color.NRGBA{})
+		hist = hist[1:]
+		n--
+	}
+	total := 0
+	for _, cc := range hist {
+		total += cc.count
+	}
+	boxes := []*colorBox{{colors: hist, total: total}}
+	for len(boxes) < n {
+		best, bestScore := -1, 0
+		for i, b := range boxes {
+			if len(b.colors) < 2 {
+				continue
+			}
+			_, w := b.span()
+			if score := w * b.total; best < 0 || score > bestScore {
+				best, bestScore = i, score
+			}
+		}
+		if best < 0 {
+			break
+		}
+		l, r := boxes[best].split()
+		boxes[best] = l
+		boxes = append(boxes, r)
+	}
+	for _, b := range boxes {
+		p = append(p, b.average())
+	}
+	return p
+}
+
+// QuantizeImage 将影像量化为不超过 colors 种颜色的调色板影像，dither 为真时使用 Floyd-Steinberg 抖动
+func QuantizeImage(m image.Image, colors int, dither bool) *image.Paletted {
+	q := &MedianCutQuantizer{Colors: colors}
+	b := m.Bounds()
+	palette := q.Quantize(make(color.Palette, 0, 256), m)
+	if len(palette) == 0 {
+		palette = color.Palette{color.NRGBA{}}
+	}
+	dst := image.NewPaletted(image.Rect(0, 0, b.Dx(), b.Dy()), palette)
+	if dither {
+		draw.FloydSteinberg.Draw(dst, dst.Rect, m, b.Min)
+		return dst
+	}
+
+	src := toNRGBA(m)
+	lookup := make(map[[4]uint8]uint8)
+	for i, j := 0, 0; i+3 < len(src.Pix); i, j = i+4, j+1 {
+		c := [4]uint8{src.Pix[i], src.Pix[i+1], src.Pix[i+2], src.Pix[i+3]}
+		if c[3] == 0 {
+			c = [4]uint8{}
+		}
+		idx, ok := lookup[c]
+		if !ok {
+			idx = uint8(palette.Index(color.NRGBA{R: c[0], G: c[1], B: c[2], A: c[3]}))
+			lookup[c] = idx
+		}
+		dst.Pix[j] = idx
+	}
+	return dst
+}
@@ -0,0 +1,107 @@
+package imagery
+
+import (
+	"image/color"
+	"math"
+	"sort"
+)
+
+type ColorStop struct {
+	Value float64
+	Color color.NRGBA
+}
+
+// ColorRamp 将数值映射为颜色，Discrete 为 true 时按分级取色，否则在相邻色标间线性插值
+type ColorRamp struct {
+	Stops    []ColorStop
+	Discrete bool
+}
+
+func NewColorRamp(stops []ColorStop, discrete bool) *ColorRamp {
+	sorted := append([]ColorStop{}, stops...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Value < sorted[j].Value })
+	return &ColorRamp{Stops: sorted, Discrete: discrete}
+}
+
+// DefaultColorRamp 0-255 的灰度色带
+func DefaultColorRamp() *ColorRamp {
+	return NewColorRamp([]ColorStop{
+		{Value: 0, Color: color.NRGBA{A: 255}},
+		{Value: 255, Color: color.NRGBA{R: 255, G: 255, B: 255, A: 255}},
+	}, false)
+}
+
+// Color 返回数值对应的颜色，NaN 返回透明，超出色标范围时取端点颜色
+func (r *ColorRamp) Color(v float64) color.NRGBA {
+	if math.IsNaN(v) || len(r.Stops) == 0 {
+		return color.NRGBA{}
+	}
+	stops := r.Stops
+	if v <= stops[0].Value {
+		return stops[0].Color
+	}
+	last := stops[len(stops)-1]
+	if v >= last.Value {
+		return last.Color
+	}
+
+	i := sort.Search(len(stops), func(i int) bool { return stops[i].Value > v })
+	lo, hi := stops[i-1], stops[i]
+	if r.Discrete {
+		return lo.Color
+	}
+	t := (v - lo.Value) / (hi.Value - lo.Value)
+	lerp := func(a, b uint8) uint8 {
+		return uint8(math.Round(float64(a) + (float64(b)-float64(a))*t))
+	}
+	return color.NRGBA{
+		R: lerp(lo.Color.R, hi.Color.R),
+		G: lerp(lo.Color.G, hi.Color.G),
+		B: lerp(lo.Color.B, hi.Color.B),
+		A: lerp(lo.Color.A, hi.Color.A),
+	}
+}
+
+// ColorRampPresets 内置的高程分层设色色带，单位为米
+var ColorRampPresets = map[string][]ColorStop{
+	"hypsometric": {
+		{Value: 0, Color: color.NRGBA{R: 112, G: 147, B: 141, A: 255}},
+		{Value: 200, Color: color.NRGBA{R: 120, G: 172, B: 149, A: 255}},
+		{Value: 500, Color: color.NRGBA{R: 168, G: 198, B: 138, A: 255}},
+		{Value: 1000, Color: color.NRGBA{R: 232, G: 225, B: 161, A: 255}},
+		{Value: 2000, Color: color.NRGBA{R: 202, G: 158, B: 117, A: 255}},
+		{Value: 3000, Color: color.NRGBA{R: 166, G: 122, B: 97, A: 255}},
+		{Value: 4000, Color: color.NRGBA{R: 220, G: 220, B: 220, A: 255}},
+		{Value: 5000, Color: color.NRGBA{R: 255, G: 255, B: 255, A: 255}},
+	},
+	"bathymetry": {
+		{Value: -8000, Color: color.NRGBA{R: 8, G: 29, B: 88, A: 255}},
+		{Value: -4000, Color: color.NRGBA{R: 37, G: 52, B: 148, A: 255}},
+		{Value: -2000, Color: color.NRGBA{R: 34, G: 94, B: 168, A: 255}},
+		{Value: -500, Color: color.NRGBA{R: 65, G: 182, B: 196, A: 255}},
+		{Value: 0, Color: color.NRGBA{R: 199, G: 233, B: 180, A: 255}},
+	},
+	"etopo": {
+		{Value: -8000, Color: color.NRGBA{R: 8, G: 29, B: 88, A: 255}},
+		{Value: -2000, Color: color.NRGBA{R: 34, G: 94, B: 168, A: 255}},
+		{Value: -1, Color: color.NRGBA{R: 150, G: 210, B: 230, A: 255}},
+		{Value: 0, Color: color.NRGBA{R: 112, G: 147, B: 141, A: 255}},
+		{Value: 500, Color: color.NRGBA{R: 168, G: 198, B: 138, A: 255}},
+		{Value: 1500, Color: color.NRGBA{R: 232, G: 225, B: 161, A: 255}},
+		{Value: 3000, Color: color.NRGBA{R: 166, G: 122, B: 97, A: 255}},
+		{Value: 5000, Color: color.NRGBA{R: 255, G: 255, B: 255, A: 255}},
+	},
+	"grayscale": {
+		{Value: 0, Color: color.NRGBA{A: 255}},
+		{Value: 5000, Color: color.NRGBA{R: 255, G: 255, B: 255, A: 255}},
+	},
+}
+
+// NewColorRampPreset 按名称创建内置色带，名称不存在时返回空
+func NewColorRampPreset(name string, discrete bool) *ColorRamp {
+	stops, ok := ColorRampPresets[name]
+	if !ok {
+		return nil
+	}
+	return NewColorRamp(stops, discrete)
+}
@@ -25,8 +25,9 @@ func NewTileMerger(tile_grid [2]int, tile_size [2]uint32) *TileMerger {
 func (t *TileMerger) Merge(ordered_tiles []tile.Source, image_opts *ImageOptions) tile.Source {
 	if t.Grid[0] == 1 && t.Grid[1] == 1 {
 		if len(ordered_tiles) >= 1 && ordered_tiles[0] != nil {
-			tile := ordered_tiles[0]
-			return tile
+			if img, ok := ordered_tiles[0].GetTile().(image.Image); ok && img != nil {
+				return ordered_tiles[0]
+			}
 		}
 	}
 
@@ -46,7 +47,10 @@ func (t *TileMerger) Merge(ordered_tiles []tile.Source, image_opts *ImageOptions
 			cacheable = source.GetCacheable()
 		}
 
-		tile := source.GetTile().(image.Image)
+		tile, ok := source.GetTile().(image.Image)
+		if !ok || tile == nil {
+			continue
+		}
 		pos := t.tileOffset(i)
 		tile = imaging.Resize(tile, int(t.Size[0]), int(t.Size[1]), imaging.Lanczos)
 		dcresult.DrawImage(tile, pos[0], pos[1])
@@ -166,3 +170,59 @@ func Resample(tiles []tile.Source, tile_grid [2]int, tile_size [2]uint32, src_bb
 
 	return sp.GetTile(off, out_size)
 }
+
+// Downsample 将下一级的子瓦片拼接后缩小为 src_bbox 中 dst_bbox 范围的一个瓦片，缺失的子瓦片按背景色填充。
+// 未配置重采样方式时使用 Box 滤波，即按像素块取平均
+func Downsample(tiles []tile.Source, tile_grid [2]int, tile_size [2]uint32, src_bbox, dst_bbox vec2d.Rect, out_size [2]uint32, image_opts *ImageOptions) tile.Source {
+	merged := NewTileMerger(tile_grid, tile_size).Merge(tiles, image_opts)
+	filter, ok := image_filter[image_opts.Resampling]
+	if !ok {
+		filter = imaging.Box
+	}
+	result := cropResize(merged.GetTile().(image.Image), src_bbox, dst_bbox, out_size, filter)
+	return &ImageSource{image: result, size: out_size[:], Options: image_opts, cacheable: merged.GetCacheable()}
+}
+
+// Upscale 截取上级瓦片中 dst_bbox 的部分并放大为 out_size，未配置重采样方式时使用双线性插值
+func Upscale(src tile.Source, src_bbox, dst_bbox vec2d.Rect, out_size [2]uint32, image_opts *ImageOptions) tile.Source {
+	img, ok := src.GetTile().(image.Image)
+	if !ok {
+		return nil
+	}
+	filter, ok := image_filter[image_opts.Resampling]
+	if !ok {
+		filter = imaging.Linear
+	}
+	result := cropResize(img, src_bbox, dst_bbox, out_size, filter)
+	return &ImageSource{image: result, size: out_size[:], Options: image_opts, cacheable: src.GetCacheable()}
+}
+
+// cropResize 按地理范围截取影像后缩放到 out_size。范围不足一个像素时先按整像素截取并缩放，再截取亚像素偏移
+func cropResize(img image.Image, src_bbox, dst_bbox vec2d.Rect, out_size [2]uint32, filter imaging.ResampleFilter) image.Image {
+	size := img.Bounds().Size()
+	to_px := geo.MakeLinTransf(src_bbox, vec2d.Rect{Min: vec2d.T{0, 0}, Max: vec2d.T{float64(size.X), float64(size.Y)}})
+	minxy := to_px([]float64{dst_bbox.Min[0], dst_bbox.Max[1]})
+	maxxy := to_px([]float64{dst_bbox.Max[0], dst_bbox.Min[1]})
+
+	// 消除浮点误差，避免对齐的范围多截取一个像素
+	for i := 0; i < 2; i++ {
+		minxy[i] = math.Round(minxy[i]*1e6) / 1e6
+		maxxy[i] = math.Round(maxxy[i]*1e6) / 1e6
+	}
+	x0, y0 := math.Floor(minxy[0]), math.Floor(minxy[1])
+	x1, y1 := math.Max(math.Ceil(maxxy[0]), x0+1), math.Max(math.Ceil(maxxy[1]), y0+1)
+	crop := image.Rect(int(x0), int(y0), int(x1), int(y1))
+	if crop != img.Bounds() {
+		img = imaging.Crop(img, crop)
+	}
+
+	sx := float64(out_size[0]) / (maxxy[0] - minxy[0])
+	sy := float64(out_size[1]) / (maxxy[1] - minxy[1])
+	w, h := int(math.Round((x1-x0)*sx)), int(math.Round((y1-y0)*sy))
+	if w == int(out_size[0]) && h == int(out_size[1]) {
+		return imaging.Resize(img, w, h, filter)
+	}
+	img = imaging.Resize(img, w, h, filter)
+	ox, oy := int(math.Round((minxy[0]-x0)*sx)), int(math.Round((minxy[1]-y0)*sy))
+	return imaging.Crop(img, image.Rect(ox, oy, ox+int(out_size[0]), oy+int(out_size[1])))
+}
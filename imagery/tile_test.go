@@ -52,6 +52,20 @@ func TestMissingTileMerge(t *testing.T) {
 	}
 }
 
+func TestUndecodableTileMerge(t *testing.T) {
+	tiles := []tile.Source{
+		&ImageSource{fname: createTmpImageFile([2]uint32{100, 100}), Options: PNG_FORMAT},
+		&ImageSource{Options: PNG_FORMAT},
+	}
+	m := NewTileMerger([2]int{2, 1}, [2]uint32{100, 100})
+	result := m.Merge(tiles, PNG_FORMAT)
+	img := result.GetTile().(image.Image)
+
+	if img.Bounds().Dx() != 200 || img.Bounds().Dy() != 100 {
+		t.FailNow()
+	}
+}
+
 func TestTileSplitter(t *testing.T) {
 	img := CreateImageSource([2]uint32{356, 266}, PNG_FORMAT)
 	splitter := NewTileSplitter(img, PNG_FORMAT)
@@ -29,6 +29,10 @@ type MapboxTileStatsLayer interface {
 	GetTileStats(id string) *resource.TileStats
 }
 
+type RevalidateLayer interface {
+	GetMapConditional(query *MapQuery, info *tile.CacheInfo) (tile.Source, bool, error)
+}
+
 type Layer interface {
 	GetMap(query *MapQuery) (tile.Source, error)
 	GetResolutionRange() *geo.ResolutionRange
@@ -1,6 +1,7 @@
 package metrics
 
 import (
+	"strconv"
 	"sync"
 	"time"
 )
@@ -10,6 +11,9 @@ type MetricsCollector interface {
 	RecordTileMiss(service, layer, level int)
 	RecordTileError(service, layer, level int, err error)
 	RecordTaskProgress(taskId string, completed, total int)
+	RecordValidationFailure(source, rule string)
+	RecordVectorReduction(level, before, after int, met bool)
+	RecordVectorValidation(level int, fixes map[string]int)
 	GetStats() map[string]interface{}
 	Reset()
 }
@@ -24,6 +28,9 @@ type ServiceMetrics struct {
 	mu            sync.RWMutex
 	tileStats     map[string]*TileStats
 	taskProgress  map[string]*TaskProgressMetrics
+	validation    map[string]map[string]int64
+	reductions    map[int]*VectorReductionMetrics
+	vectorFixes   map[int]map[string]int64
 	requestCount  int64
 	errorCount    int64
 	startTime     time.Time
@@ -37,10 +44,21 @@ type TaskProgressMetrics struct {
 	UpdatedAt time.Time
 }
 
+// VectorReductionMetrics 某一级别因超出大小限制被缩减的矢量瓦片统计
+type VectorReductionMetrics struct {
+	Tiles       int64
+	BytesBefore int64
+	BytesAfter  int64
+	OverBudget  int64
+}
+
 func NewMetricsCollector() MetricsCollector {
 	return &ServiceMetrics{
 		tileStats:     make(map[string]*TileStats),
 		taskProgress:  make(map[string]*TaskProgressMetrics),
+		validation:    make(map[string]map[string]int64),
+		reductions:    make(map[int]*VectorReductionMetrics),
+		vectorFixes:   make(map[int]map[string]int64),
 		startTime:     time.Now(),
 		lastResetTime: time.Now(),
 	}
@@ -110,6 +128,53 @@ func (m *ServiceMetrics) RecordTaskProgress(taskId string, completed, total int)
 	progress.UpdatedAt = time.Now()
 }
 
+// RecordValidationFailure 记录上游响应被某条校验规则拒绝的次数
+func (m *ServiceMetrics) RecordValidationFailure(source, rule string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	rules, ok := m.validation[source]
+	if !ok {
+		rules = make(map[string]int64)
+		m.validation[source] = rules
+	}
+	rules[rule]++
+}
+
+// RecordVectorReduction 记录一个矢量瓦片缩减前后的字节数，met 为缩减后是否满足大小限制
+func (m *ServiceMetrics) RecordVectorReduction(level, before, after int, met bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	r, ok := m.reductions[level]
+	if !ok {
+		r = &VectorReductionMetrics{}
+		m.reductions[level] = r
+	}
+	r.Tiles++
+	r.BytesBefore += int64(before)
+	r.BytesAfter += int64(after)
+	if !met {
+		r.OverBudget++
+	}
+}
+
+// RecordVectorValidation 记录某一级别矢量瓦片校验时各类修复的数量
+func (m *ServiceMetrics) RecordVectorValidation(level int, fixes map[string]int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	counts, ok := m.vectorFixes[level]
+	if !ok {
+		counts = make(map[string]int64)
+		m.vectorFixes[level] = counts
+	}
+	counts["tiles"]++
+	for kind, n := range fixes {
+		counts[kind] += int64(n)
+	}
+}
+
 func (m *ServiceMetrics) GetStats() map[string]interface{} {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
@@ -122,6 +187,9 @@ func (m *ServiceMetrics) GetStats() map[string]interface{} {
 		"uptime_seconds":  time.Since(m.startTime).Seconds(),
 		"tile_stats":      m.getTileStatsMap(),
 		"task_progress":   m.getTaskProgressMap(),
+		"validation":      m.getValidationMap(),
+		"vector_reduced":  m.getVectorReductionMap(),
+		"vector_fixed":    m.getVectorValidationMap(),
 	}
 
 	return stats
@@ -154,12 +222,52 @@ func (m *ServiceMetrics) getTaskProgressMap() map[string]interface{} {
 	return taskProgressMap
 }
 
+func (m *ServiceMetrics) getValidationMap() map[string]interface{} {
+	validationMap := make(map[string]interface{})
+	for source, rules := range m.validation {
+		counts := make(map[string]int64, len(rules))
+		for rule, n := range rules {
+			counts[rule] = n
+		}
+		validationMap[source] = counts
+	}
+	return validationMap
+}
+
+func (m *ServiceMetrics) getVectorReductionMap() map[string]interface{} {
+	reductionMap := make(map[string]interface{})
+	for level, r := range m.reductions {
+		reductionMap[strconv.Itoa(level)] = map[string]interface{}{
+			"tiles":        r.Tiles,
+			"bytes_before": r.BytesBefore,
+			"bytes_after":  r.BytesAfter,
+			"over_budget":  r.OverBudget,
+		}
+	}
+	return reductionMap
+}
+
+func (m *ServiceMetrics) getVectorValidationMap() map[string]interface{} {
+	validationMap := make(map[string]interface{})
+	for level, fixes := range m.vectorFixes {
+		counts := make(map[string]int64, len(fixes))
+		for kind, n := range fixes {
+			counts[kind] = n
+		}
+		validationMap[strconv.Itoa(level)] = counts
+	}
+	return validationMap
+}
+
 func (m *ServiceMetrics) Reset() {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
 	m.tileStats = make(map[string]*TileStats)
 	m.taskProgress = make(map[string]*TaskProgressMetrics)
+	m.validation = make(map[string]map[string]int64)
+	m.reductions = make(map[int]*VectorReductionMetrics)
+	m.vectorFixes = make(map[int]map[string]int64)
 	m.requestCount = 0
 	m.errorCount = 0
 	m.lastResetTime = time.Now()
@@ -187,6 +295,18 @@ func RecordTaskProgress(taskId string, completed, total int) {
 	DefaultCollector.RecordTaskProgress(taskId, completed, total)
 }
 
+func RecordValidationFailure(source, rule string) {
+	DefaultCollector.RecordValidationFailure(source, rule)
+}
+
+func RecordVectorReduction(level, before, after int, met bool) {
+	DefaultCollector.RecordVectorReduction(level, before, after, met)
+}
+
+func RecordVectorValidation(level int, fixes map[string]int) {
+	DefaultCollector.RecordVectorValidation(level, fixes)
+}
+
 func GetStats() map[string]interface{} {
 	return DefaultCollector.GetStats()
 }
@@ -112,6 +112,11 @@ func (p RequestParams) copy() RequestParams {
 	return map_copy
 }
 
+// Copy 返回参数的深拷贝，基于同一请求模板的并发请求各自修改拷贝
+func (p RequestParams) Copy() RequestParams {
+	return p.copy()
+}
+
 func (p RequestParams) WithDefaults(defaults RequestParams) RequestParams {
 	new := p.copy()
 	for key, value := range defaults {
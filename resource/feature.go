@@ -227,6 +227,11 @@ var (
 	}
 )
 
+// FeatureInfoType 返回信息格式对应的文档类型：xml、html、json 或 text
+func FeatureInfoType(info_format string) string {
+	return featureInfoType(info_format)
+}
+
 func featureInfoType(info_format string) string {
 	formats := strings.Split(info_format, ";")
 	info_format = strings.Trim(formats[0], "")
@@ -2,8 +2,10 @@ package tileproxy
 
 import (
 	"fmt"
+	"log"
 	"net/http"
 	"sync"
+	"time"
 
 	"github.com/flywave/go-geo"
 	"github.com/flywave/go-tileproxy/cache"
@@ -34,6 +36,7 @@ type Service struct {
 	LegendSources map[string]layer.LegendLayer
 	Caches        map[string]cache.Manager
 	mu            sync.RWMutex
+	discoveryStop chan struct{}
 }
 
 func NewService(dataset *setting.ProxyService, globals *setting.GlobalsSetting, fac setting.CacheFactory) *Service {
@@ -50,6 +53,8 @@ func NewService(dataset *setting.ProxyService, globals *setting.GlobalsSetting,
 }
 
 func (s *Service) load(dataset *setting.ProxyService, globals *setting.GlobalsSetting, fac setting.CacheFactory) {
+	s.stopDiscovery()
+	s.discoveryStop = make(chan struct{})
 	s.loadGrids(dataset)
 	s.loadSources(dataset, globals, fac)
 	s.loadCaches(dataset, globals, fac)
@@ -60,12 +65,20 @@ func (s *Service) loadGrids(dataset *setting.ProxyService) {
 	for k, g := range dataset.Grids {
 		s.Grids[k] = setting.ConvertGridOpts(&g)
 	}
+	for k, g := range setting.DefaultGridPresets {
+		if _, ok := s.Grids[k]; !ok {
+			s.Grids[k] = setting.ConvertGridOpts(&g)
+		}
+	}
 }
 
 func (s *Service) loadSources(dataset *setting.ProxyService, globals *setting.GlobalsSetting, fac setting.CacheFactory) {
 	for k, src := range dataset.Sources {
 		switch source := src.(type) {
 		case *setting.WMSSource:
+			if source.DiscoveryEnabled() {
+				s.discoverWMSSource(k, source, globals)
+			}
 			if source.Opts.FeatureInfo != nil && *source.Opts.FeatureInfo {
 				s.InfoSources[k] = setting.LoadWMSInfoSource(source, globals)
 			} else if source.Opts.LegendGraphic != nil && *source.Opts.LegendGraphic {
@@ -75,6 +88,8 @@ func (s *Service) loadSources(dataset *setting.ProxyService, globals *setting.Gl
 			}
 		case *setting.TileSource:
 			s.Sources[k] = setting.LoadTileSource(source, globals, s)
+		case *setting.DebugSource:
+			s.Sources[k] = setting.LoadDebugSource(source, s)
 		case *setting.MapboxTileSource:
 			s.Sources[k] = setting.LoadMapboxTileSource(source, globals, s, fac)
 		case *setting.CesiumTileSource:
@@ -89,18 +104,54 @@ func (s *Service) loadSources(dataset *setting.ProxyService, globals *setting.Gl
 	}
 }
 
+// loadDerivedSources 加载基于高程或矢量缓存生成的数据源，需在缓存创建之后、缓存绑定数据源之前执行
+func (s *Service) loadDerivedSources(dataset *setting.ProxyService) {
+	for k, src := range dataset.Sources {
+		var (
+			l   layer.Layer
+			err error
+		)
+		switch source := src.(type) {
+		case *setting.RasterStyleSource:
+			l, err = setting.LoadRasterStyleSource(source, s)
+		case *setting.RasterShadeSource:
+			l, err = setting.LoadRasterShadeSource(source, s)
+		case *setting.ContourSource:
+			l, err = setting.LoadContourSource(source, s)
+		case *setting.VectorStyleSource:
+			l, err = setting.LoadVectorStyleSource(source, s)
+		default:
+			continue
+		}
+		if err != nil {
+			log.Printf("Source '%s' failed to load: %v", k, err)
+			continue
+		}
+		s.Sources[k] = l
+	}
+}
+
 func (s *Service) loadCaches(dataset *setting.ProxyService, globals *setting.GlobalsSetting, fac setting.CacheFactory) {
 	for k, c := range dataset.Caches {
 		switch cache := c.(type) {
 		case *setting.CacheSource:
-			s.Caches[k] = setting.PreLoadCacheManager(cache, globals, s, fac)
+			manager, err := setting.PreLoadCacheManager(cache, globals, s, fac)
+			if err != nil {
+				fmt.Printf("Cache '%s' failed to load: %v\n", k, err)
+				continue
+			}
+			s.Caches[k] = manager
 		}
 	}
 
+	s.loadDerivedSources(dataset)
+
 	for k, c := range dataset.Caches {
 		switch cache := c.(type) {
 		case *setting.CacheSource:
-			setting.LoadCacheManager(cache, globals, s, fac, s.Caches[k])
+			if manager, ok := s.Caches[k]; ok {
+				setting.LoadCacheManager(cache, globals, s, fac, manager)
+			}
 		}
 	}
 }
@@ -112,7 +163,12 @@ func (s *Service) loadService(dataset *setting.ProxyService, globals *setting.Gl
 	case *setting.WMSService:
 		s.Service = setting.LoadWMSService(srv, s, globals)
 	case *setting.MapboxService:
-		s.Service = setting.LoadMapboxService(srv, globals, s, fac)
+		mapbox, err := setting.LoadMapboxService(srv, globals, s, fac)
+		if err != nil {
+			fmt.Printf("Service '%s' failed to load: %v\n", s.Id, err)
+			return
+		}
+		s.Service = mapbox
 	case *setting.CesiumService:
 		s.Service = setting.LoadCesiumService(srv, globals, s, fac)
 	case *setting.WMTSService:
@@ -125,6 +181,53 @@ func (s *Service) loadService(dataset *setting.ProxyService, globals *setting.Gl
 }
 
 func (s *Service) Clean() {
+	s.stopDiscovery()
+}
+
+// discoverWMSSource 加载前根据上游能力文档补全数据源配置。
+// 配置了检查间隔时定期重新获取能力文档，仅记录上游与配置的差异，已加载的数据源不会随之更新，需重新加载服务才能生效
+func (s *Service) discoverWMSSource(name string, source *setting.WMSSource, globals *setting.GlobalsSetting) {
+	warnings, err := setting.DiscoverWMSSource(name, source, globals)
+	if err != nil {
+		log.Printf("WMS source '%s' capabilities discovery failed: %v", name, err)
+	}
+	logSourceWarnings(s.Id, warnings)
+
+	if source.Opts.DiscoveryCheckInterval == nil || *source.Opts.DiscoveryCheckInterval <= 0 {
+		return
+	}
+	interval := time.Duration(*source.Opts.DiscoveryCheckInterval) * time.Second
+	stop := s.discoveryStop
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				caps, err := setting.FetchWMSCapabilities(source, globals)
+				if err != nil {
+					log.Printf("WMS source '%s' capabilities check failed: %v", name, err)
+					continue
+				}
+				logSourceWarnings(s.Id, setting.ValidateWMSCapabilities(name, source, caps))
+			}
+		}
+	}()
+}
+
+func (s *Service) stopDiscovery() {
+	if s.discoveryStop != nil {
+		close(s.discoveryStop)
+		s.discoveryStop = nil
+	}
+}
+
+func logSourceWarnings(id string, warnings []string) {
+	for _, w := range warnings {
+		log.Printf("service '%s': %s", id, w)
+	}
 }
 
 func (s *Service) GetId() string {
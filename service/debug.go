@@ -0,0 +1,46 @@
+package service
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/flywave/go-geo"
+	"github.com/flywave/go-tileproxy/cache"
+	"github.com/flywave/go-tileproxy/imagery"
+	"github.com/flywave/go-tileproxy/tile"
+	"github.com/flywave/go-tileproxy/vector"
+)
+
+// debugTile 在瓦片上叠加网格、缓存状态及生成耗时等调试信息，影像绘制文字，矢量增加debug图层
+func debugTile(t *cache.Tile, grid *geo.TileGrid, elapsed time.Duration) tile.Source {
+	coord := t.Coord
+	bbox := grid.TileBBox(coord, false)
+	res := grid.Resolution(coord[2])
+	status := t.Status
+	if status == "" {
+		status = cache.TILE_STATUS_MISS
+	}
+
+	switch t.Source.GetType() {
+	case tile.TILE_IMAGERY:
+		msg := fmt.Sprintf("z/x/y: %d/%d/%d\ngrid: %s\nbbox: %.6f,%.6f,%.6f,%.6f\nres: %.8f\ncache: %s\ntime: %v",
+			coord[2], coord[0], coord[1], grid.Name,
+			bbox.Min[0], bbox.Min[1], bbox.Max[0], bbox.Max[1],
+			res, status, elapsed.Round(time.Millisecond))
+		return imagery.DrawDebugInfo(t.Source, msg)
+	case tile.TILE_VECTOR:
+		lonlat := grid.Srs.TransformRectTo(geo.NewProj(4326), bbox, 16)
+		props := map[string]interface{}{
+			"z":       coord[2],
+			"x":       coord[0],
+			"y":       coord[1],
+			"grid":    grid.Name,
+			"bbox":    fmt.Sprintf("%.6f,%.6f,%.6f,%.6f", bbox.Min[0], bbox.Min[1], bbox.Max[0], bbox.Max[1]),
+			"res":     res,
+			"cache":   string(status),
+			"time_ms": elapsed.Milliseconds(),
+		}
+		return vector.AddDebugLayer(t.Source, coord, lonlat, props)
+	}
+	return t.Source
+}
@@ -17,6 +17,7 @@ import (
 	"github.com/flywave/go-tileproxy/request"
 	"github.com/flywave/go-tileproxy/resource"
 	"github.com/flywave/go-tileproxy/tile"
+	"github.com/flywave/go-tileproxy/vector"
 )
 
 type MapboxMetadata struct {
@@ -168,6 +169,9 @@ type MapboxTileProvider struct {
 	tilejsonSource  layer.MapboxSourceJSONLayer
 	tileStatsSource layer.MapboxTileStatsLayer
 	vectorLayers    []*resource.VectorLayer
+	debug           bool
+	vectorProcessor *vector.VectorProcessor
+	sourceMaxZoom   *int
 }
 
 func GetMapboxTileType(tp string) MapboxTileType {
@@ -203,6 +207,9 @@ type MapboxTileOptions struct {
 	TileStatsSource layer.MapboxTileStatsLayer
 	VectorLayers    []*resource.VectorLayer
 	ZoomRange       *[2]int
+	Debug           bool
+	VectorProcessor *vector.VectorProcessor
+	SourceMaxZoom   *int
 }
 
 func NewMapboxTileProvider(opts *MapboxTileOptions) *MapboxTileProvider {
@@ -215,12 +222,21 @@ func NewMapboxTileProvider(opts *MapboxTileOptions) *MapboxTileProvider {
 		tilejsonSource:  opts.TilejsonSource,
 		tileStatsSource: opts.TileStatsSource,
 		vectorLayers:    opts.VectorLayers,
+		debug:           opts.Debug,
+		vectorProcessor: opts.VectorProcessor,
 	}
 	if opts.ZoomRange != nil {
 		ret.zoomRange = *opts.ZoomRange
 	} else {
 		ret.zoomRange = [2]int{0, 20}
 	}
+	// 未配置数据源最大级别时使用矢量参数的最大级别
+	if opts.SourceMaxZoom != nil {
+		ret.sourceMaxZoom = opts.SourceMaxZoom
+	} else if vopts, ok := opts.TileManager.GetTileOptions().(*vector.VectorOptions); ok && vopts.MaxZoom > 0 {
+		z := int(vopts.MaxZoom)
+		ret.sourceMaxZoom = &z
+	}
 	return ret
 }
 
@@ -321,14 +337,62 @@ func (tl *MapboxTileProvider) Render(req request.TiledRequest, use_profiles bool
 		}
 	}
 
-	t, _ := tl.tileManager.LoadTileCoord([3]int{tile_coord[0], tile_coord[1], tile_coord[2]}, nil, true)
-	if t.Source == nil {
+	start := time.Now()
+	coord := [3]int{tile_coord[0], tile_coord[1], tile_coord[2]}
+	var t *cache.Tile
+	if tl.sourceMaxZoom != nil && coord[2] > *tl.sourceMaxZoom {
+		t = tl.loadOverzoomTile(coord)
+	} else {
+		t, _ = tl.tileManager.LoadTileCoord(coord, nil, true)
+	}
+	if t == nil || t.Source == nil {
 		return nil, tl.emptyResponse()
 	}
+	if tl.vectorProcessor != nil {
+		tl.applyVectorProcessor(t)
+	}
+	if tl.debug {
+		t.Source = debugTile(t, tl.GetGrid(), time.Since(start))
+		t.Cacheable = false
+	}
 	format := tile_request.Format
 	return nil, newTileResponse(t, format, nil, tl.tileManager.GetTileOptions())
 }
 
+// loadOverzoomTile 超出数据源最大级别时由最大级别的上级瓦片生成瓦片
+func (tl *MapboxTileProvider) loadOverzoomTile(coord [3]int) *cache.Tile {
+	grid := tl.GetGrid()
+	bbox := grid.TileBBox(coord, false)
+	x, y, z := grid.Tile((bbox.Min[0]+bbox.Max[0])/2, (bbox.Min[1]+bbox.Max[1])/2, *tl.sourceMaxZoom)
+	src, err := tl.tileManager.LoadTileCoord([3]int{x, y, z}, nil, true)
+	if err != nil || src == nil || src.Source == nil {
+		return nil
+	}
+	source, err := cache.OverzoomTile(src.Source, grid, src.Coord, coord, tl.tileManager.GetTileOptions())
+	if err != nil {
+		return nil
+	}
+	t := cache.NewTile(coord)
+	t.SetCacheInfo(src.GetCacheInfo())
+	t.Source = source
+	return t
+}
+
+// applyVectorProcessor 按服务的处理规则重新打包矢量瓦片，缓存中的数据不受影响
+func (tl *MapboxTileProvider) applyVectorProcessor(t *cache.Tile) {
+	opts, ok := t.Source.GetTileOptions().(*vector.VectorOptions)
+	if !ok {
+		return
+	}
+	vt, ok := t.Source.GetTile().(vector.Vector)
+	if !ok {
+		return
+	}
+	if src := vector.CreateVectorSourceFromVector(tl.vectorProcessor.Apply(vt, t.Coord[2]), t.Coord, opts, t.Source.GetCacheable()); src != nil {
+		t.Source = src
+	}
+}
+
 func (c *MapboxTileProvider) convertTileJson(tilejson *resource.TileJSON, req *request.MapboxSourceJSONRequest, md *MapboxLayerMetadata) []byte {
 	url := req.LayerName + "/{z}/{x}/{y}." + c.GetFormat()
 	url = strings.ReplaceAll(url, "//", "/")
@@ -526,6 +526,7 @@ type TileProvider struct {
 	extent       *geo.MapExtent
 	emptyTile    []byte
 	errorHandler ExceptionHandler
+	debug        bool
 }
 
 type TileProviderOptions struct {
@@ -536,6 +537,7 @@ type TileProviderOptions struct {
 	InfoSources  []layer.InfoLayer
 	Dimensions   utils.Dimensions
 	ErrorHandler ExceptionHandler
+	Debug        bool
 }
 
 func NewTileProvider(opts *TileProviderOptions) *TileProvider {
@@ -547,6 +549,7 @@ func NewTileProvider(opts *TileProviderOptions) *TileProvider {
 		grid:         NewTileServiceGrid(opts.TileManager.GetGrid()),
 		extent:       geo.MapExtentFromGrid(opts.TileManager.GetGrid()),
 		errorHandler: opts.ErrorHandler,
+		debug:        opts.Debug,
 	}
 	return ret
 }
@@ -607,6 +610,9 @@ func (t *TileProvider) emptyResponse() TileResponse {
 		tile := cache.GetEmptyTile([2]uint32{si[0], si[1]}, t.tileManager.GetTileOptions())
 		t.emptyTile = tile.GetBuffer(nil, nil)
 	}
+	if t.isMixed() {
+		format = "image/" + imagery.PeekImageFormat(string(t.emptyTile))
+	}
 	return newImageResponse(t.emptyTile, format, time.Now())
 }
 
@@ -628,7 +634,7 @@ func (tl *TileProvider) checkedDimensions(_ request.TiledRequest) utils.Dimensio
 
 func (tl *TileProvider) Render(req request.TiledRequest, useProfiles bool, coverage geo.Coverage, decorateTile func(image tile.Source) tile.Source) (*RequestError, TileResponse) {
 	format := req.GetFormat()
-	if format == nil || format.Extension() != tl.GetFormat() {
+	if format == nil || !tl.supportsFormat(format.Extension()) {
 		return NewRequestError(fmt.Sprintf("invalid format (%s). this tile set only supports (%s)", tl.GetFormat(), tl.GetFormat()), "InvalidParameterValue", tl.errorHandler, req, false, nil), nil
 	}
 	_, tile_coord := tl.getInternalTileCoord(req, useProfiles)
@@ -647,11 +653,17 @@ func (tl *TileProvider) Render(req request.TiledRequest, useProfiles bool, cover
 
 	dimensions := tl.checkedDimensions(req)
 
+	start := time.Now()
 	t, _ := tl.tileManager.LoadTileCoord([3]int{tile_coord[0], tile_coord[1], tile_coord[2]}, dimensions, true)
 	if t.Source == nil {
 		return nil, tl.emptyResponse()
 	}
 
+	if tl.debug {
+		t.Source = debugTile(t, tl.grid.grid, time.Since(start))
+		t.Cacheable = false
+	}
+
 	if decorateTile != nil {
 		t.Source = decorateTile(t.Source)
 	}
@@ -665,10 +677,36 @@ func (tl *TileProvider) Render(req request.TiledRequest, useProfiles bool, cover
 		}
 		nt := cache.NewTile(t.Coord)
 		nt.Source = s
-		return nil, newTileResponse(nt, &format, nil, tl.tileManager.GetTileOptions())
+		return nil, tl.newTileResponse(nt, &format)
+	}
+
+	if tl.isMixed() {
+		format = nil
+	}
+	return nil, tl.newTileResponse(t, format)
+}
+
+func (tl *TileProvider) isMixed() bool {
+	return imagery.IsMixedFormat(tile.TileFormat(tl.GetFormat()))
+}
+
+// supportsFormat 混合格式的图层同时接受 png 与 jpeg 请求
+func (tl *TileProvider) supportsFormat(ext string) bool {
+	if ext == tl.GetFormat() {
+		return true
 	}
+	return tl.isMixed() && (ext == "jpg" || utils.ContainsString(imagery.MixedExtensions, ext))
+}
 
-	return nil, newTileResponse(t, format, nil, tl.tileManager.GetTileOptions())
+// newTileResponse 混合格式的瓦片按实际编码格式返回 Content-Type
+func (tl *TileProvider) newTileResponse(t *cache.Tile, format *tile.TileFormat) *tileResponse {
+	resp := newTileResponse(t, format, nil, tl.tileManager.GetTileOptions())
+	if tl.isMixed() {
+		if f := resp.peekFormat(); f != "" {
+			resp.format = "image/" + f
+		}
+	}
+	return resp
 }
 
 type TMSExceptionHandler struct {
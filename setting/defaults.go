@@ -41,11 +41,42 @@ var (
 		{Srs: "EPSG:4326", Origin: "sw", Name: "GLOBAL_GEODETIC"},
 		{Srs: "EPSG:900913", Origin: "sw", Name: "GLOBAL_MERCATOR"},
 		{Srs: "EPSG:3857", Origin: "nw", Name: "GLOBAL_WEBMERCATOR"},
-		{Srs: "EPSG:GCJ02MC", Origin: "nw", Name: "GCJ02_GLOBAL_WEBMERCATOR"},
-		{Srs: "EPSG:BDMC", Origin: "nw", Name: "BD_GLOBAL_WEBMERCATOR"},
+		GCJ02Grid,
+		BaiduGrid,
 	}
 )
 
+// 高德、腾讯等GCJ-02瓦片与Web墨卡托的编号方式相同，只是坐标有偏移
+var (
+	GCJ02Grid = GridOpts{Srs: "EPSG:GCJ02MC", Origin: "ul", Name: "GCJ02_GLOBAL_WEBMERCATOR"}
+)
+
+// 百度瓦片以BDMC坐标原点为起点编号，第18级分辨率为1米，索引可以为负数。
+// 范围取±2^26米，使各级的网格原点都落在百度瓦片的边界上
+var (
+	BaiduExtent      = float64(1 << 26)
+	BaiduResolutions = baiduResolutions(20)
+	BaiduGrid        = GridOpts{
+		Name:        "BAIDU",
+		Srs:         "EPSG:BDMC",
+		BBox:        &[4]float64{-BaiduExtent, -BaiduExtent, BaiduExtent, BaiduExtent},
+		Origin:      "sw",
+		Resolutions: BaiduResolutions,
+	}
+	DefaultGridPresets = map[string]GridOpts{
+		"baidu":             BaiduGrid,
+		"gcj02_webmercator": GCJ02Grid,
+	}
+)
+
+func baiduResolutions(levels int) []float64 {
+	res := make([]float64, levels)
+	for z := range res {
+		res[z] = float64(int64(1)<<18) / float64(int64(1)<<z)
+	}
+	return res
+}
+
 var (
 	DefaultExpiresHours             = 72
 	DefaultRequestTimeout           = 60
@@ -54,3 +85,16 @@ var (
 	DefaultRandomDelay              = 0
 	DefaultThreads                  = 4
 )
+
+var (
+	DefaultRetryMaxAttempts     = 3
+	DefaultRetryInitialInterval = 500
+	DefaultRetryMaxInterval     = 10000
+	DefaultRetryMultiplier      = 2.0
+	DefaultRetryJitter          = 0.2
+	DefaultRetryDeadline        = 60
+	DefaultRateLimitBurst       = 1
+	DefaultAuthTokenPath        = "access_token"
+	DefaultPerceptualThreshold  = 4
+	DefaultDiscoveryTimeout     = 10
+)
@@ -0,0 +1,162 @@
+package setting
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/flywave/go-tileproxy/client"
+)
+
+// DiscoveryEnabled 判断WMS数据源是否开启能力文档自动发现
+func (s *WMSSource) DiscoveryEnabled() bool {
+	return s.Opts.Discovery != nil && *s.Opts.Discovery
+}
+
+// FetchWMSCapabilities 请求WMS数据源上游的GetCapabilities文档
+func FetchWMSCapabilities(s *WMSSource, globals *GlobalsSetting) (*client.WMSCapabilities, error) {
+	var http *HttpSetting
+	if s.Http != nil {
+		http = s.Http
+	} else {
+		http = &globals.Http.HttpSetting
+	}
+	return client.FetchWMSCapabilities(newCollectorContext(http), s.Url, s.Opts.Version)
+}
+
+// fetchWMSCapabilitiesTimeout 在限定时间内获取能力文档，超时后放弃等待，避免上游无响应时阻塞服务加载
+func fetchWMSCapabilitiesTimeout(s *WMSSource, globals *GlobalsSetting) (*client.WMSCapabilities, error) {
+	timeout := DefaultDiscoveryTimeout
+	if s.Opts.DiscoveryTimeout != nil && *s.Opts.DiscoveryTimeout > 0 {
+		timeout = *s.Opts.DiscoveryTimeout
+	}
+	type result struct {
+		caps *client.WMSCapabilities
+		err  error
+	}
+	done := make(chan result, 1)
+	go func() {
+		caps, err := FetchWMSCapabilities(s, globals)
+		done <- result{caps, err}
+	}()
+	select {
+	case r := <-done:
+		return r.caps, r.err
+	case <-time.After(time.Duration(timeout) * time.Second):
+		return nil, fmt.Errorf("get capabilities from %s timed out after %ds", s.Url, timeout)
+	}
+}
+
+// DiscoverWMSSource 获取上游能力文档，检查配置与文档的差异后补全未配置的参数，返回差异说明。
+// 获取超时或失败时保持原有配置
+func DiscoverWMSSource(name string, s *WMSSource, globals *GlobalsSetting) ([]string, error) {
+	caps, err := fetchWMSCapabilitiesTimeout(s, globals)
+	if err != nil {
+		return nil, err
+	}
+	warnings := ValidateWMSCapabilities(name, s, caps)
+	ApplyWMSCapabilities(s, caps)
+	return warnings, nil
+}
+
+// ApplyWMSCapabilities 使用能力文档补全SRS、格式、比例尺、覆盖范围、图例地址及最大请求尺寸，已配置的值保持不变
+func ApplyWMSCapabilities(s *WMSSource, caps *client.WMSCapabilities) {
+	layers := make([]*client.WMSLayerCapabilities, 0, len(s.Layers))
+	for _, name := range s.Layers {
+		if l := caps.Layer(name); l != nil {
+			layers = append(layers, l)
+		}
+	}
+
+	if len(s.SupportedFormats) == 0 && len(caps.Formats) > 0 {
+		s.SupportedFormats = append([]string{}, caps.Formats...)
+	}
+	if s.MaxWidth == nil && caps.MaxWidth > 0 {
+		w := uint32(caps.MaxWidth)
+		s.MaxWidth = &w
+	}
+	if s.MaxHeight == nil && caps.MaxHeight > 0 {
+		h := uint32(caps.MaxHeight)
+		s.MaxHeight = &h
+	}
+
+	if len(layers) == 0 {
+		return
+	}
+
+	if len(s.SupportedSrs) == 0 {
+		s.SupportedSrs = commonLayerSrs(layers)
+	}
+
+	if s.ScaleHints.MinScale == nil && s.ScaleHints.MaxScale == nil && s.ScaleHints.MinRes == nil && s.ScaleHints.MaxRes == nil {
+		s.ScaleHints = layerScaleHints(layers)
+	}
+
+	if s.Coverage == nil {
+		var bbox *[4]float64
+		for _, l := range layers {
+			if l.LLBBox == nil {
+				bbox = nil
+				break
+			}
+			if bbox == nil {
+				bbox = &[4]float64{l.LLBBox.Min[0], l.LLBBox.Min[1], l.LLBBox.Max[0], l.LLBBox.Max[1]}
+				continue
+			}
+			bbox[0], bbox[1] = min(bbox[0], l.LLBBox.Min[0]), min(bbox[1], l.LLBBox.Min[1])
+			bbox[2], bbox[3] = max(bbox[2], l.LLBBox.Max[0]), max(bbox[3], l.LLBBox.Max[1])
+		}
+		if bbox != nil {
+			s.Coverage = &Coverage{BBox: bbox, BBoxSrs: "EPSG:4326"}
+		}
+	}
+
+	if s.Opts.LegendURL == "" && layers[0].LegendURL != "" {
+		s.Opts.LegendURL = layers[0].LegendURL
+	}
+}
+
+// commonLayerSrs 返回所有图层都支持的EPSG坐标系
+func commonLayerSrs(layers []*client.WMSLayerCapabilities) []string {
+	var srs []string
+	for _, code := range layers[0].Srs {
+		if !strings.HasPrefix(strings.ToUpper(code), "EPSG:") {
+			continue
+		}
+		supported := true
+		for _, l := range layers[1:] {
+			if !l.SupportsSrs(code) {
+				supported = false
+				break
+			}
+		}
+		if supported {
+			srs = append(srs, strings.ToUpper(code))
+		}
+	}
+	return srs
+}
+
+// layerScaleHints 合并图层的比例尺范围，任一图层不限制时对应方向不限制
+func layerScaleHints(layers []*client.WMSLayerCapabilities) ScaleHints {
+	var hints ScaleHints
+	merge := func(get func(*client.WMSLayerCapabilities) *float64, less bool) *float64 {
+		var ret *float64
+		for _, l := range layers {
+			v := get(l)
+			if v == nil {
+				return nil
+			}
+			if ret == nil || (less && *v < *ret) || (!less && *v > *ret) {
+				val := *v
+				ret = &val
+			}
+		}
+		return ret
+	}
+	hints.MinScale = merge(func(l *client.WMSLayerCapabilities) *float64 { return l.MinScale }, true)
+	hints.MaxScale = merge(func(l *client.WMSLayerCapabilities) *float64 { return l.MaxScale }, false)
+	hints.MinRes = merge(func(l *client.WMSLayerCapabilities) *float64 { return l.MinRes }, false)
+	hints.MaxRes = merge(func(l *client.WMSLayerCapabilities) *float64 { return l.MaxRes }, true)
+	return hints
+}
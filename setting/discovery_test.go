@@ -0,0 +1,33 @@
+package setting
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestDiscoverWMSSourceTimeout(t *testing.T) {
+	release := make(chan struct{})
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer ts.Close()
+	defer close(release)
+
+	timeout := 1
+	s := &WMSSource{Url: ts.URL, Layers: []string{"roads"}}
+	s.Opts.DiscoveryTimeout = &timeout
+	globals := &GlobalsSetting{}
+
+	start := time.Now()
+	if _, err := DiscoverWMSSource("roads", s, globals); err == nil {
+		t.Error("上游无响应时应返回超时错误")
+	}
+	if d := time.Since(start); d > 5*time.Second {
+		t.Errorf("能力文档获取未按超时返回: %v", d)
+	}
+	if len(s.SupportedSrs) != 0 || s.Coverage != nil {
+		t.Error("获取失败时不应修改配置")
+	}
+}
@@ -1,8 +1,11 @@
 package setting
 
 import (
+	"fmt"
 	"image/color"
 	"math"
+	"net/http"
+	"os"
 	"strconv"
 	"strings"
 	"time"
@@ -61,9 +64,36 @@ func NewImageOptions(opt *ImageOpts) *imagery.ImageOptions {
 		image_opt.BgColor = color.RGBA{R: opt.BgColor[0], G: opt.BgColor[1], B: opt.BgColor[2], A: opt.BgColor[3]}
 	}
 	image_opt.EncodingOptions = opt.EncodingOptions
+	for _, f := range opt.Filters {
+		filter := imagery.ImageFilter{Type: imagery.FilterType(f.Type), Value: imagery.DefaultFilterValue(imagery.FilterType(f.Type))}
+		if f.Value != nil {
+			filter.Value = *f.Value
+		}
+		image_opt.Filters = append(image_opt.Filters, filter)
+	}
+	image_opt.Blend = imagery.BlendMode(opt.Blend)
 	return image_opt
 }
 
+// applyGlobalEncodingOptions 使用全局格式配置与 paletted 补全缓存未设置的编码参数
+func applyGlobalEncodingOptions(opts *imagery.ImageOptions, img *ImageSetting) {
+	enc := make(map[string]interface{})
+	if img.Paletted != nil && strings.HasSuffix(opts.Format.Extension(), "png") {
+		enc["paletted"] = *img.Paletted
+	}
+	if f, ok := img.Formats[string(opts.Format)]; ok {
+		for k, v := range f.EncodingOptions {
+			enc[k] = v
+		}
+	}
+	for k, v := range opts.EncodingOptions {
+		enc[k] = v
+	}
+	if len(enc) > 0 {
+		opts.EncodingOptions = enc
+	}
+}
+
 func NewRasterOptions(opt *RasterOpts) *terrain.RasterOptions {
 	raster_opt := &terrain.RasterOptions{}
 	raster_opt.Format = tile.TileFormat(opt.Format)
@@ -108,9 +138,41 @@ func NewVectorOptions(opt *VectorOpts) *vector.VectorOptions {
 	if opt.Proto != nil {
 		vector_opt.Proto = *opt.Proto
 	}
+	if opt.Budget != nil {
+		vector_opt.Budget = NewVectorBudget(opt.Budget)
+	}
+	if opt.InfoTolerance != nil {
+		vector_opt.InfoTolerance = *opt.InfoTolerance
+	}
+	if opt.Validate != nil {
+		vector_opt.Validate = *opt.Validate
+	}
+	if opt.StrictValidate != nil {
+		vector_opt.StrictValidate = *opt.StrictValidate
+	}
+	if opt.FailOnRepair != nil {
+		vector_opt.FailOnRepair = *opt.FailOnRepair
+	}
 	return vector_opt
 }
 
+func NewVectorBudget(b *VectorBudget) *vector.VectorBudget {
+	ret := &vector.VectorBudget{
+		SizeBudget:       vector.SizeBudget{MaxBytes: b.MaxBytes, MaxFeatures: b.MaxFeatures},
+		PriorityProperty: b.PriorityProperty,
+		MaxIterations:    b.MaxIterations,
+	}
+	if len(b.Levels) > 0 {
+		ret.Levels = make(map[int]vector.SizeBudget, len(b.Levels))
+		for z, l := range b.Levels {
+			if l != nil {
+				ret.Levels[z] = vector.SizeBudget{MaxBytes: l.MaxBytes, MaxFeatures: l.MaxFeatures}
+			}
+		}
+	}
+	return ret
+}
+
 func ConvertGridOpts(opt *GridOpts) *geo.TileGrid {
 	conf := make(geo.TileGridOptions)
 	conf[geo.TILEGRID_NAME] = opt.Name
@@ -175,15 +237,54 @@ func newWaterMarkFilter(w *WaterMark) cache.Filter {
 	return cache.NewWatermark(w.Text, w.Opacity, w.Spacing, w.FontSize, &c)
 }
 
-func LoadFilter(f interface{}) cache.Filter {
+// NewVectorProcessor 转换矢量瓦片处理规则，过滤表达式无效时返回错误
+func NewVectorProcessor(f *VectorFilter) (*vector.VectorProcessor, error) {
+	rules := make(map[string]*vector.LayerRule, len(f.Rules))
+	for name, r := range f.Rules {
+		if r == nil {
+			continue
+		}
+		rule := &vector.LayerRule{
+			Rename:            r.Rename,
+			MinZoom:           r.MinZoom,
+			MaxZoom:           r.MaxZoom,
+			Properties:        r.Properties,
+			ExcludeProperties: r.ExcludeProperties,
+			RenameProperties:  r.RenameProperties,
+		}
+		if len(r.Filter) > 0 {
+			filter, err := vector.ParseFeatureFilter(r.Filter)
+			if err != nil {
+				return nil, fmt.Errorf("layer %s: %v", name, err)
+			}
+			rule.Filter = filter
+		}
+		rules[name] = rule
+	}
+	return vector.NewVectorProcessor(f.Layers, f.DropLayers, rules), nil
+}
+
+func newVectorFilter(f *VectorFilter) (cache.Filter, error) {
+	processor, err := NewVectorProcessor(f)
+	if err != nil {
+		return nil, err
+	}
+	return cache.NewVectorFilter(processor), nil
+}
+
+// LoadFilter 转换缓存过滤器，矢量过滤规则无效时返回错误
+func LoadFilter(f interface{}) (cache.Filter, error) {
 	switch filter := f.(type) {
 	case *WaterMark:
-		return newWaterMarkFilter(filter)
+		return newWaterMarkFilter(filter), nil
+	case *VectorFilter:
+		return newVectorFilter(filter)
 	}
-	return nil
+	return nil, nil
 }
 
-func PreLoadCacheManager(c *CacheSource, globals *GlobalsSetting, instance ProxyInstance, fac CacheFactory) cache.Manager {
+// PreLoadCacheManager 创建缓存管理器，过滤器无效时返回错误
+func PreLoadCacheManager(c *CacheSource, globals *GlobalsSetting, instance ProxyInstance, fac CacheFactory) (cache.Manager, error) {
 	grid := instance.GetGrid(c.Grid)
 	request_format := c.RequestFormat
 	var request_format_ext string
@@ -232,16 +333,16 @@ func PreLoadCacheManager(c *CacheSource, globals *GlobalsSetting, instance Proxy
 
 	upscale_tiles := c.UpscaleTiles
 	if upscale_tiles != nil && *upscale_tiles < 0 {
-		return nil
+		return nil, fmt.Errorf("cache '%s' has negative upscale_tiles", c.Name)
 	}
 
 	downscale_tiles := c.DownscaleTiles
 	if downscale_tiles != nil && *downscale_tiles < 0 {
-		return nil
+		return nil, fmt.Errorf("cache '%s' has negative downscale_tiles", c.Name)
 	}
 
 	if upscale_tiles != nil && downscale_tiles != nil {
-		return nil
+		return nil, fmt.Errorf("cache '%s' cannot set both upscale_tiles and downscale_tiles", c.Name)
 	}
 
 	rescale_tiles := 0
@@ -266,7 +367,13 @@ func PreLoadCacheManager(c *CacheSource, globals *GlobalsSetting, instance Proxy
 
 	if c.Filters != nil {
 		for i := range c.Filters {
-			pre_store_filter = append(pre_store_filter, LoadFilter(c.Filters[i]))
+			f, err := LoadFilter(c.Filters[i])
+			if err != nil {
+				return nil, fmt.Errorf("cache '%s' has invalid filter: %v", c.Name, err)
+			}
+			if f != nil {
+				pre_store_filter = append(pre_store_filter, f)
+			}
 		}
 	}
 
@@ -278,7 +385,12 @@ func PreLoadCacheManager(c *CacheSource, globals *GlobalsSetting, instance Proxy
 	var opts tile.TileOptions
 	switch o := c.TileOptions.(type) {
 	case *ImageOpts:
-		opts = NewImageOptions(o)
+		image_opts := NewImageOptions(o)
+		if image_opts.Format == "" && imagery.IsMixedFormat(tile.TileFormat(format)) {
+			image_opts.Format = imagery.MIXED_FORMAT
+		}
+		applyGlobalEncodingOptions(image_opts, &globals.Image)
+		opts = image_opts
 	case *RasterOpts:
 		opts = NewRasterOptions(o)
 	case *VectorOpts:
@@ -318,6 +430,11 @@ func PreLoadCacheManager(c *CacheSource, globals *GlobalsSetting, instance Proxy
 		request_format_ext = string(opts.GetFormat())
 	}
 
+	// 混合格式的缓存向数据源请求可保留透明度的 png
+	if imagery.IsMixedFormat(tile.TileFormat(request_format_ext)) {
+		request_format_ext = "png"
+	}
+
 	topts := &cache.TileManagerOptions{
 		Sources:              nil,
 		Grid:                 tilegrid,
@@ -340,7 +457,11 @@ func PreLoadCacheManager(c *CacheSource, globals *GlobalsSetting, instance Proxy
 		SiteURL:              globals.Http.HttpSetting.SiteURL,
 	}
 
-	return cache.NewTileManager(topts)
+	if c.BandMath != nil {
+		topts.BandExpression, topts.BandRamp = NewBandMath(c.BandMath)
+	}
+
+	return cache.NewTileManager(topts), nil
 }
 
 func LoadCacheManager(c *CacheSource, globals *GlobalsSetting, instance ProxyInstance, fac CacheFactory, manager cache.Manager) {
@@ -366,7 +487,7 @@ func NewResolutionRange(conf *ScaleHints) *geo.ResolutionRange {
 	if conf.MinRes != nil && conf.MaxRes != nil && *conf.MinRes != 0 && *conf.MaxRes != 0 {
 		return &geo.ResolutionRange{Min: conf.MinRes, Max: conf.MaxRes}
 	}
-	if conf.MinRes != nil && conf.MaxRes != nil && *conf.MinRes != 0 && *conf.MaxRes != 0 {
+	if conf.MinScale != nil && conf.MaxScale != nil && *conf.MinScale != 0 && *conf.MaxScale != 0 {
 		return geo.NewResolutionRangeScale(conf.MinScale, conf.MaxScale)
 	}
 	return nil
@@ -380,14 +501,15 @@ func ConvertLocalStore(opt *StoreInfo) *resource.LocalStore {
 	return resource.NewLocalStore(opt.Directory)
 }
 
-func ConvertMapboxTileLayer(l *MapboxTileLayer, globals *GlobalsSetting, instance ProxyInstance) *service.MapboxTileProvider {
+// ConvertMapboxTileLayer 创建 Mapbox 图层，缓存不存在时返回空，矢量过滤规则无效时返回错误
+func ConvertMapboxTileLayer(l *MapboxTileLayer, globals *GlobalsSetting, instance ProxyInstance) (*service.MapboxTileProvider, error) {
 	tp := service.MapboxVector
 	if l.TileType != "" {
 		tp = service.GetMapboxTileType(l.TileType)
 	}
 	tileManager := instance.GetCache(l.Source)
 	if tileManager == nil {
-		return nil
+		return nil, nil
 	}
 
 	metadata := &service.MapboxLayerMetadata{
@@ -407,9 +529,19 @@ func ConvertMapboxTileLayer(l *MapboxTileLayer, globals *GlobalsSetting, instanc
 		TileStatsSource: nil,
 		VectorLayers:    l.VectorLayers,
 		ZoomRange:       l.ZoomRange,
+		Debug:           l.Debug,
+		SourceMaxZoom:   l.SourceMaxZoom,
 	}
 
-	return service.NewMapboxTileProvider(topts)
+	if l.VectorFilter != nil {
+		processor, err := NewVectorProcessor(l.VectorFilter)
+		if err != nil {
+			return nil, fmt.Errorf("mapbox layer '%s' has invalid vector filter: %v", l.Name, err)
+		}
+		topts.VectorProcessor = processor
+	}
+
+	return service.NewMapboxTileProvider(topts), nil
 }
 
 func ConvertCesiumTileLayer(l *CesiumTileLayer, globals *GlobalsSetting, instance ProxyInstance) *service.CesiumTileProvider {
@@ -433,6 +565,19 @@ func ConvertCesiumTileLayer(l *CesiumTileLayer, globals *GlobalsSetting, instanc
 	return service.NewCesiumTileProvider(topts)
 }
 
+// getInfoSource 返回名为 name 的信息源，没有同名信息源时使用同名矢量缓存的瓦片查询要素
+func getInfoSource(name string, instance ProxyInstance) layer.InfoLayer {
+	if info := instance.GetInfoSource(name); info != nil {
+		return info
+	}
+	if manager := instance.GetCache(name); manager != nil {
+		if opts, ok := manager.GetTileOptions().(*vector.VectorOptions); ok {
+			return cache.NewVectorInfoSource(manager, opts.InfoTolerance, nil)
+		}
+	}
+	return nil
+}
+
 func ConvertTileLayer(l *TileLayer, instance ProxyInstance) *service.TileProvider {
 	dimensions := utils.NewDimensionsFromValues(l.Dimensions)
 
@@ -443,7 +588,9 @@ func ConvertTileLayer(l *TileLayer, instance ProxyInstance) *service.TileProvide
 
 	infoSources := []layer.InfoLayer{}
 	for _, info := range l.InfoSources {
-		infoSources = append(infoSources, instance.GetInfoSource(info))
+		if src := getInfoSource(info, instance); src != nil {
+			infoSources = append(infoSources, src)
+		}
 	}
 
 	metadata := &service.TileProviderMetadata{Name: l.Name, Title: l.Title}
@@ -456,6 +603,7 @@ func ConvertTileLayer(l *TileLayer, instance ProxyInstance) *service.TileProvide
 		InfoSources:  infoSources,
 		Dimensions:   dimensions,
 		ErrorHandler: &service.TMSExceptionHandler{},
+		Debug:        l.Debug,
 	}
 
 	return service.NewTileProvider(tpopts)
@@ -553,7 +701,9 @@ func ConvertWMSLayer(l *WMSLayer, instance ProxyInstance) service.WMSLayer {
 	}
 
 	for _, name := range l.FeatureinfoSources {
-		infos[name] = instance.GetInfoSource(name)
+		if src := getInfoSource(name, instance); src != nil {
+			infos[name] = src
+		}
 	}
 
 	for _, name := range l.LegendSources {
@@ -734,10 +884,26 @@ func LoadWMSMapSource(s *WMSSource, instance ProxyInstance, globals *GlobalsSett
 		c.AdaptTo111 = true
 	}
 
-	return sources.NewWMSSource(c, image_opts, coverage,
+	src := sources.NewWMSSource(c, image_opts, coverage,
 		res_range, transparent_color,
 		transparent_color_tolerance, supported_srs, s.SupportedFormats,
 		s.ForwardReqParams)
+	if s.Coalesce != nil && !*s.Coalesce {
+		src.Coalescer = nil
+	}
+	src.MaxSize = newMaxSize(s.MaxWidth, s.MaxHeight)
+	return src
+}
+
+func newMaxSize(maxWidth, maxHeight *uint32) [2]uint32 {
+	var size [2]uint32
+	if maxWidth != nil {
+		size[0] = *maxWidth
+	}
+	if maxHeight != nil {
+		size[1] = *maxHeight
+	}
+	return size
 }
 
 func newCollectorContext(httpOpts *HttpSetting) *client.CollectorContext {
@@ -768,9 +934,150 @@ func newCollectorContext(httpOpts *HttpSetting) *client.CollectorContext {
 	} else {
 		conf.Threads = DefaultThreads
 	}
+	if httpOpts.Retry != nil {
+		conf.Retry = NewRetryPolicy(httpOpts.Retry)
+	}
+	if httpOpts.RateLimit != nil {
+		conf.RateLimit = NewRateLimit(httpOpts.RateLimit)
+	}
+	for host, rl := range httpOpts.HostLimits {
+		client.DefaultLimiterRegistry().SetHostLimit(host, NewRateLimit(&rl))
+	}
+	if httpOpts.Auth != nil {
+		conf.Auth = NewAuthenticator(httpOpts.Auth)
+	}
 	return client.NewCollectorContext(&conf)
 }
 
+func NewAuthenticator(s *AuthSetting) client.Authenticator {
+	switch s.Type {
+	case AUTH_TYPE_BEARER:
+		return client.NewBearerAuth(s.Token)
+	case AUTH_TYPE_HEADER:
+		name := s.HeaderName
+		if name == "" {
+			name = "X-API-Key"
+		}
+		return client.NewAPIKeyAuth(name, s.Token)
+	case AUTH_TYPE_BASIC:
+		return client.NewBasicAuth(s.Username, s.Password)
+	case AUTH_TYPE_OAUTH2:
+		auth := client.NewOAuth2Auth(s.TokenURL, s.ClientID, s.ClientSecret, s.Scopes)
+		applyTokenDelivery(auth, s)
+		return auth
+	case AUTH_TYPE_TOKEN:
+		endpoint := &client.TokenEndpoint{
+			URL:         s.TokenURL,
+			Method:      strings.ToUpper(s.Method),
+			TokenPath:   s.TokenPath,
+			ExpiresPath: s.ExpiresPath,
+		}
+		if endpoint.TokenPath == "" {
+			endpoint.TokenPath = DefaultAuthTokenPath
+		}
+		if len(s.Headers) > 0 {
+			endpoint.Headers = make(http.Header)
+			for k, v := range s.Headers {
+				endpoint.Headers.Set(k, v)
+			}
+		}
+		if s.Body != "" {
+			endpoint.Body = []byte(s.Body)
+		}
+		if s.ExpiresIn != nil {
+			endpoint.ExpiresIn = time.Duration(*s.ExpiresIn) * time.Second
+		}
+		auth := client.NewTokenEndpointAuth(endpoint)
+		applyTokenDelivery(auth, s)
+		return auth
+	}
+	return nil
+}
+
+func applyTokenDelivery(auth *client.TokenAuth, s *AuthSetting) {
+	if s.HeaderName != "" {
+		auth.HeaderName = s.HeaderName
+	}
+	if s.HeaderPrefix != nil {
+		auth.HeaderPrefix = *s.HeaderPrefix
+	}
+	auth.QueryParam = s.QueryParam
+}
+
+func NewResponseValidator(name string, s *ValidationSetting) *sources.ResponseValidator {
+	v := sources.NewResponseValidator(name)
+	v.ContentTypes = s.ContentTypes
+	v.Formats = s.Formats
+	v.Decode = s.Decode
+	v.PHashMaxDist = DefaultPerceptualThreshold
+	if s.PerceptualThreshold != nil {
+		v.PHashMaxDist = *s.PerceptualThreshold
+	}
+	for _, h := range s.BadHashes {
+		v.AddBadHash(h)
+	}
+	for _, h := range s.BadPerceptualHashes {
+		if ph, err := strconv.ParseUint(h, 16, 64); err == nil {
+			v.BadPHashes = append(v.BadPHashes, ph)
+		}
+	}
+	for _, f := range s.BadTiles {
+		if data, err := os.ReadFile(f); err == nil {
+			v.AddBadTile(data)
+		}
+	}
+	return v
+}
+
+func NewRateLimit(s *RateLimitSetting) *client.RateLimit {
+	rl := &client.RateLimit{Rate: s.Rate, Burst: DefaultRateLimitBurst}
+	if s.Burst != nil {
+		rl.Burst = *s.Burst
+	}
+	if s.DailyQuota != nil {
+		rl.DailyQuota = *s.DailyQuota
+	}
+	return rl
+}
+
+func NewRetryPolicy(s *RetrySetting) *client.RetryPolicy {
+	policy := &client.RetryPolicy{
+		MaxAttempts:       DefaultRetryMaxAttempts,
+		InitialInterval:   time.Duration(DefaultRetryInitialInterval) * time.Millisecond,
+		MaxInterval:       time.Duration(DefaultRetryMaxInterval) * time.Millisecond,
+		Multiplier:        DefaultRetryMultiplier,
+		Jitter:            DefaultRetryJitter,
+		RetryableStatus:   client.DefaultRetryableStatus,
+		RespectRetryAfter: true,
+		MaxElapsedTime:    time.Duration(DefaultRetryDeadline) * time.Second,
+	}
+	if s.MaxAttempts != nil {
+		policy.MaxAttempts = *s.MaxAttempts
+	}
+	if s.InitialInterval != nil {
+		policy.InitialInterval = time.Duration(*s.InitialInterval) * time.Millisecond
+	}
+	if s.MaxInterval != nil {
+		policy.MaxInterval = time.Duration(*s.MaxInterval) * time.Millisecond
+	}
+	if s.Multiplier != nil {
+		policy.Multiplier = *s.Multiplier
+	}
+	if s.Jitter != nil {
+		policy.Jitter = *s.Jitter
+	}
+	if len(s.RetryableStatus) > 0 {
+		policy.RetryableStatus = s.RetryableStatus
+	}
+	if s.RespectRetryAfter != nil {
+		policy.RespectRetryAfter = *s.RespectRetryAfter
+	}
+	if s.Deadline != nil {
+		policy.MaxElapsedTime = time.Duration(*s.Deadline) * time.Second
+	}
+	return policy
+}
+
 func LoadTileSource(s *TileSource, globals *GlobalsSetting, instance ProxyInstance) *sources.TileSource {
 	var opts tile.TileOptions
 	switch o := s.Options.(type) {
@@ -801,7 +1108,25 @@ func LoadTileSource(s *TileSource, globals *GlobalsSetting, instance ProxyInstan
 
 	c := client.NewTileClient(grid.(*geo.TileGrid), tpl, s.AccessToken, newCollectorContext(http))
 
-	return sources.NewTileSource(grid.(*geo.TileGrid), c, coverage, opts, res_range, creater)
+	src := sources.NewTileSource(grid.(*geo.TileGrid), c, coverage, opts, res_range, creater)
+	if s.Validation != nil {
+		src.Validator = NewResponseValidator(s.Name, s.Validation)
+	}
+	return src
+}
+
+func LoadDebugSource(s *DebugSource, instance ProxyInstance) *sources.DebugSource {
+	src := sources.NewDebugSource()
+	if s.Coverage != nil {
+		src.Coverage = LoadCoverage(s.Coverage)
+	}
+	src.ResRange = NewResolutionRange(&s.ScaleHints)
+	if s.Grid != "" {
+		if grid, ok := instance.GetGrid(s.Grid).(*geo.TileGrid); ok && grid != nil {
+			src.GridName = grid.Name
+		}
+	}
+	return src
 }
 
 func LoadMapboxTileSource(s *MapboxTileSource, globals *GlobalsSetting, instance ProxyInstance, fac CacheFactory) *sources.MapboxTileSource {
@@ -965,7 +1290,12 @@ func LoadArcGISSource(s *ArcGISSource, instance ProxyInstance, globals *GlobalsS
 	req := request.NewArcGISRequest(params, url)
 	c := client.NewArcGISClient(req, newCollectorContext(http))
 
-	return sources.NewArcGISSource(c, image_opts, coverage, res_range, supported_srs, s.SupportedFormats)
+	src := sources.NewArcGISSource(c, image_opts, coverage, res_range, supported_srs, s.SupportedFormats)
+	if s.Coalesce != nil && !*s.Coalesce {
+		src.Coalescer = nil
+	}
+	src.MaxSize = newMaxSize(s.MaxWidth, s.MaxHeight)
+	return src
 }
 
 func LoadArcGISInfoSource(s *ArcGISSource, globals *GlobalsSetting) *sources.ArcGISInfoSource {
@@ -1020,12 +1350,18 @@ func LoadArcGISInfoSource(s *ArcGISSource, globals *GlobalsSetting) *sources.Arc
 	return sources.NewArcGISInfoSource(c)
 }
 
-func LoadMapboxService(s *MapboxService, globals *GlobalsSetting, instance ProxyInstance, fac CacheFactory) *service.MapboxService {
+func LoadMapboxService(s *MapboxService, globals *GlobalsSetting, instance ProxyInstance, fac CacheFactory) (*service.MapboxService, error) {
 	layers := make(map[string]service.Provider)
 	metadata := &service.MapboxMetadata{}
 
 	for _, tl := range s.Layers {
-		layers[tl.Name] = ConvertMapboxTileLayer(&tl, globals, instance)
+		provider, err := ConvertMapboxTileLayer(&tl, globals, instance)
+		if err != nil {
+			return nil, err
+		}
+		if provider != nil {
+			layers[tl.Name] = provider
+		}
 	}
 
 	var maxTileAge *time.Duration
@@ -1037,7 +1373,7 @@ func LoadMapboxService(s *MapboxService, globals *GlobalsSetting, instance Proxy
 
 	sopts := &service.MapboxServiceOptions{Tilesets: layers, Metadata: metadata, MaxTileAge: maxTileAge}
 
-	return service.NewMapboxService(sopts)
+	return service.NewMapboxService(sopts), nil
 }
 
 func LoadCesiumService(s *CesiumService, globals *GlobalsSetting, instance ProxyInstance, fac CacheFactory) *service.CesiumService {
@@ -1344,3 +1680,230 @@ func ConvertContactInformation(contactInformation *WMSContactInformation) *wms13
 
 	return ret
 }
+
+// NewBandMath 转换波段运算配置，表达式无效时返回空，错误由配置校验给出
+func NewBandMath(b *BandMath) (*imagery.BandExpression, *imagery.ColorRamp) {
+	expr, err := imagery.ParseBandExpression(b.Expression, b.Bands)
+	if err != nil {
+		return nil, nil
+	}
+	if len(b.Ramp) == 0 {
+		return expr, nil
+	}
+	return expr, NewColorRamp(b.Ramp, b.Discrete)
+}
+
+// NewColorRamp 转换色带配置，色标设置了 opacity 时覆盖颜色的透明度
+func NewColorRamp(ramp []ColorStop, discrete bool) *imagery.ColorRamp {
+	stops := make([]imagery.ColorStop, len(ramp))
+	for i, s := range ramp {
+		c := color.NRGBA{R: s.Color[0], G: s.Color[1], B: s.Color[2], A: s.Color[3]}
+		if s.Opacity != nil {
+			c.A = uint8(math.Round(math.Max(0, math.Min(1, *s.Opacity)) * 255))
+		}
+		stops[i] = imagery.ColorStop{Value: s.Value, Color: c}
+	}
+	return imagery.NewColorRamp(stops, discrete)
+}
+
+// LoadRasterStyleSource 依赖高程缓存，需在缓存创建后加载，缓存不存在时返回错误
+func LoadRasterStyleSource(s *RasterStyleSource, instance ProxyInstance) (*sources.RasterStyleSource, error) {
+	dem := instance.GetCacheSource(s.Source, nil)
+	if dem == nil {
+		return nil, fmt.Errorf("elevation cache %s not found", s.Source)
+	}
+
+	var ramp *imagery.ColorRamp
+	if len(s.Ramp) > 0 {
+		ramp = NewColorRamp(s.Ramp, s.Discrete)
+	} else if s.Preset != "" {
+		ramp = imagery.NewColorRampPreset(s.Preset, s.Discrete)
+	}
+	style := terrain.NewRasterStyle(ramp)
+	if s.Opacity != nil {
+		style.Opacity = *s.Opacity
+	}
+	if s.NodataColor != nil {
+		style.NodataColor = color.NRGBA{R: s.NodataColor[0], G: s.NodataColor[1], B: s.NodataColor[2], A: s.NodataColor[3]}
+	}
+
+	var opts *imagery.ImageOptions
+	if s.ImageOpts != nil {
+		opts = NewImageOptions(s.ImageOpts)
+	}
+	src := sources.NewRasterStyleSource(dem, style, opts)
+	if s.Coverage != nil {
+		src.Coverage = LoadCoverage(s.Coverage)
+	}
+	if rr := NewResolutionRange(&s.ScaleHints); rr != nil {
+		src.ResRange = rr
+	}
+	return src, nil
+}
+
+// LoadRasterShadeSource 依赖高程缓存，需在缓存创建后加载，缓存不存在时返回错误
+func LoadRasterShadeSource(s *RasterShadeSource, instance ProxyInstance) (*sources.RasterShadeSource, error) {
+	dem := instance.GetCacheSource(s.Source, nil)
+	if dem == nil {
+		return nil, fmt.Errorf("elevation cache %s not found", s.Source)
+	}
+
+	mode := terrain.SHADE_HILLSHADE
+	if s.Mode != "" {
+		mode = terrain.ShadeMode(s.Mode)
+	}
+	shader := terrain.NewShader(mode)
+	if s.Azimuth != nil {
+		shader.Azimuth = *s.Azimuth
+	}
+	if s.Altitude != nil {
+		shader.Altitude = *s.Altitude
+	}
+	if s.ZFactor != nil {
+		shader.ZFactor = *s.ZFactor
+	}
+	shader.Multidirectional = s.Multidirectional
+	if len(s.Ramp) > 0 {
+		shader.Ramp = NewColorRamp(s.Ramp, s.Discrete)
+	}
+	if s.Opacity != nil {
+		shader.Opacity = *s.Opacity
+	}
+
+	var opts *imagery.ImageOptions
+	if s.ImageOpts != nil {
+		opts = NewImageOptions(s.ImageOpts)
+	}
+	src := sources.NewRasterShadeSource(dem, shader, opts)
+	if s.Coverage != nil {
+		src.Coverage = LoadCoverage(s.Coverage)
+	}
+	if rr := NewResolutionRange(&s.ScaleHints); rr != nil {
+		src.ResRange = rr
+	}
+	return src, nil
+}
+
+func newStyleNumber(n *StyleNumber) *vector.StyleNumber {
+	if n == nil {
+		return nil
+	}
+	return &vector.StyleNumber{Value: n.Value, Base: n.Base, Stops: n.Stops}
+}
+
+func newStyleColor(c *StyleColor) *vector.StyleColor {
+	if c == nil {
+		return nil
+	}
+	ret := &vector.StyleColor{Value: color.NRGBA{R: c.Color[0], G: c.Color[1], B: c.Color[2], A: c.Color[3]}, Base: c.Base}
+	for _, s := range c.Stops {
+		ret.Stops = append(ret.Stops, vector.StyleColorStop{Zoom: s.Zoom, Color: color.NRGBA{R: s.Color[0], G: s.Color[1], B: s.Color[2], A: s.Color[3]}})
+	}
+	return ret
+}
+
+// NewVectorStyle 转换矢量样式配置，图层类型或过滤表达式无效时返回错误
+func NewVectorStyle(s *VectorStyleSource) (*vector.VectorStyle, error) {
+	layers := make([]*vector.StyleLayer, 0, len(s.Layers))
+	for _, l := range s.Layers {
+		if l == nil {
+			continue
+		}
+		if !vector.IsValidStyleLayerType(vector.StyleLayerType(l.Type)) {
+			return nil, fmt.Errorf("style layer %s has unknown type: %s", l.ID, l.Type)
+		}
+		sl := &vector.StyleLayer{
+			ID:          l.ID,
+			Type:        vector.StyleLayerType(l.Type),
+			SourceLayer: l.SourceLayer,
+			MinZoom:     l.MinZoom,
+			MaxZoom:     l.MaxZoom,
+			Paint: vector.StylePaint{
+				FillColor:         newStyleColor(l.Paint.FillColor),
+				FillOpacity:       newStyleNumber(l.Paint.FillOpacity),
+				FillOutlineColor:  newStyleColor(l.Paint.FillOutlineColor),
+				LineColor:         newStyleColor(l.Paint.LineColor),
+				LineWidth:         newStyleNumber(l.Paint.LineWidth),
+				LineOpacity:       newStyleNumber(l.Paint.LineOpacity),
+				CircleColor:       newStyleColor(l.Paint.CircleColor),
+				CircleRadius:      newStyleNumber(l.Paint.CircleRadius),
+				CircleOpacity:     newStyleNumber(l.Paint.CircleOpacity),
+				CircleStrokeColor: newStyleColor(l.Paint.CircleStrokeColor),
+				CircleStrokeWidth: newStyleNumber(l.Paint.CircleStrokeWidth),
+				TextField:         l.Paint.TextField,
+				TextFont:          l.Paint.TextFont,
+				TextSize:          newStyleNumber(l.Paint.TextSize),
+				TextColor:         newStyleColor(l.Paint.TextColor),
+				TextHaloColor:     newStyleColor(l.Paint.TextHaloColor),
+				TextHaloWidth:     newStyleNumber(l.Paint.TextHaloWidth),
+			},
+		}
+		if len(l.Filter) > 0 {
+			f, err := vector.ParseFeatureFilter(l.Filter)
+			if err != nil {
+				return nil, fmt.Errorf("style layer %s: %v", l.ID, err)
+			}
+			sl.Filter = f
+		}
+		layers = append(layers, sl)
+	}
+	style := vector.NewVectorStyle(layers)
+	style.Background = newStyleColor(s.Background)
+	return style, nil
+}
+
+// LoadVectorStyleSource 依赖矢量缓存，需在缓存创建后加载，缓存不存在或样式无效时返回错误
+func LoadVectorStyleSource(s *VectorStyleSource, instance ProxyInstance) (*sources.VectorStyleSource, error) {
+	vt := instance.GetCacheSource(s.Source, nil)
+	if vt == nil {
+		return nil, fmt.Errorf("vector cache %s not found", s.Source)
+	}
+	style, err := NewVectorStyle(s)
+	if err != nil {
+		return nil, err
+	}
+
+	var opts *imagery.ImageOptions
+	if s.ImageOpts != nil {
+		opts = NewImageOptions(s.ImageOpts)
+	}
+	src := sources.NewVectorStyleSource(vt, style, opts)
+	if s.Coverage != nil {
+		src.Coverage = LoadCoverage(s.Coverage)
+	}
+	if rr := NewResolutionRange(&s.ScaleHints); rr != nil {
+		src.ResRange = rr
+	}
+	return src, nil
+}
+
+// LoadContourSource 依赖高程缓存，需在缓存创建后加载，缓存不存在时返回错误
+func LoadContourSource(s *ContourSource, instance ProxyInstance) (*sources.ContourSource, error) {
+	dem := instance.GetCacheSource(s.Source, nil)
+	if dem == nil {
+		return nil, fmt.Errorf("elevation cache %s not found", s.Source)
+	}
+
+	var opts *vector.VectorOptions
+	if s.Options != nil {
+		opts = NewVectorOptions(s.Options)
+	}
+	src := sources.NewContourSource(dem, s.Intervals, opts)
+	if s.LayerName != "" {
+		src.LayerName = s.LayerName
+	}
+	if s.MajorEvery != nil {
+		src.MajorEvery = *s.MajorEvery
+	}
+	if s.Buffer != nil {
+		src.Buffer = *s.Buffer
+	}
+	src.Smooth = s.Smooth
+	if s.Coverage != nil {
+		src.Coverage = LoadCoverage(s.Coverage)
+	}
+	if rr := NewResolutionRange(&s.ScaleHints); rr != nil {
+		src.ResRange = rr
+	}
+	return src, nil
+}
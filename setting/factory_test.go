@@ -0,0 +1,28 @@
+package setting
+
+import (
+	"math"
+	"testing"
+)
+
+func TestNewResolutionRangeScale(t *testing.T) {
+	minScale, maxScale := 1000.0, 500000.0
+	r := NewResolutionRange(&ScaleHints{MinScale: &minScale, MaxScale: &maxScale})
+	if r == nil {
+		t.Fatal("只配置比例尺时应生成分辨率范围")
+	}
+	gotMin, gotMax := r.ScaleDenominator()
+	if math.Abs(gotMin-minScale) > 1e-6 || math.Abs(gotMax-maxScale) > 1e-6 {
+		t.Errorf("比例尺范围错误: %v %v", gotMin, gotMax)
+	}
+
+	minRes, maxRes := 0.5, 100.0
+	r = NewResolutionRange(&ScaleHints{MinRes: &minRes, MaxRes: &maxRes, MinScale: &minScale, MaxScale: &maxScale})
+	if r == nil || *r.Min != minRes || *r.Max != maxRes {
+		t.Error("同时配置时应优先使用分辨率")
+	}
+
+	if NewResolutionRange(&ScaleHints{}) != nil {
+		t.Error("未配置时不应生成分辨率范围")
+	}
+}
@@ -17,6 +17,11 @@ const (
 	MAPBOXTILE_SOURCE SourceType = "mapbox"
 	ARCGIS_SOURCE     SourceType = "arcgis"
 	CESIUMTILE_SOURCE SourceType = "cesium"
+	DEBUG_SOURCE      SourceType = "debug"
+	RASTER_STYLE      SourceType = "raster_style"
+	RASTER_SHADE      SourceType = "raster_shade"
+	CONTOUR_SOURCE    SourceType = "contour"
+	VECTOR_STYLE      SourceType = "vector_style"
 )
 
 type ServiceType string
@@ -41,6 +46,7 @@ type FilterType string
 
 const (
 	FILTER_TYPE_WATERMARK FilterType = "watermark"
+	FILTER_TYPE_VECTOR    FilterType = "vector"
 )
 
 const (
@@ -68,13 +74,75 @@ type ImageSetting struct {
 }
 
 type HttpSetting struct {
-	SiteURL           string         `json:"site_url,omitempty"`
-	UserAgent         *string        `json:"user_agent,omitempty"`
-	RandomDelay       *int           `json:"random_delay,omitempty"`
-	DisableKeepAlives *bool          `json:"disable_keep_alives,omitempty"`
-	Proxys            []string       `json:"proxys,omitempty"`
-	RequestTimeout    *time.Duration `json:"request_timeout,omitempty"`
-	Threads           *int           `json:"thread_size,omitempty"`
+	SiteURL           string                      `json:"site_url,omitempty"`
+	UserAgent         *string                     `json:"user_agent,omitempty"`
+	RandomDelay       *int                        `json:"random_delay,omitempty"`
+	DisableKeepAlives *bool                       `json:"disable_keep_alives,omitempty"`
+	Proxys            []string                    `json:"proxys,omitempty"`
+	RequestTimeout    *time.Duration              `json:"request_timeout,omitempty"`
+	Threads           *int                        `json:"thread_size,omitempty"`
+	Retry             *RetrySetting               `json:"retry,omitempty"`
+	RateLimit         *RateLimitSetting           `json:"rate_limit,omitempty"`
+	HostLimits        map[string]RateLimitSetting `json:"host_limits,omitempty"`
+	Auth              *AuthSetting                `json:"auth,omitempty"`
+}
+
+type AuthType string
+
+const (
+	AUTH_TYPE_BEARER AuthType = "bearer"
+	AUTH_TYPE_HEADER AuthType = "header"
+	AUTH_TYPE_BASIC  AuthType = "basic"
+	AUTH_TYPE_OAUTH2 AuthType = "oauth2"
+	AUTH_TYPE_TOKEN  AuthType = "token"
+)
+
+type AuthSetting struct {
+	Type         AuthType          `json:"type"`
+	Token        string            `json:"token,omitempty"`
+	HeaderName   string            `json:"header_name,omitempty"`
+	HeaderPrefix *string           `json:"header_prefix,omitempty"`
+	QueryParam   string            `json:"query_param,omitempty"`
+	Username     string            `json:"username,omitempty"`
+	Password     string            `json:"password,omitempty"`
+	TokenURL     string            `json:"token_url,omitempty"`
+	ClientID     string            `json:"client_id,omitempty"`
+	ClientSecret string            `json:"client_secret,omitempty"`
+	Scopes       []string          `json:"scopes,omitempty"`
+	Method       string            `json:"method,omitempty"`
+	Headers      map[string]string `json:"headers,omitempty"`
+	Body         string            `json:"body,omitempty"`
+	TokenPath    string            `json:"token_path,omitempty"`
+	ExpiresPath  string            `json:"expires_path,omitempty"`
+	ExpiresIn    *int              `json:"expires_in,omitempty"`
+}
+
+// ValidationSetting 上游瓦片校验规则，bad_tiles为已知错误瓦片的文件路径
+type ValidationSetting struct {
+	ContentTypes        []string `json:"content_types,omitempty"`
+	Formats             []string `json:"formats,omitempty"`
+	Decode              bool     `json:"decode,omitempty"`
+	BadHashes           []string `json:"bad_hashes,omitempty"`
+	BadPerceptualHashes []string `json:"bad_perceptual_hashes,omitempty"`
+	BadTiles            []string `json:"bad_tiles,omitempty"`
+	PerceptualThreshold *int     `json:"perceptual_threshold,omitempty"`
+}
+
+type RateLimitSetting struct {
+	Rate       float64 `json:"rate,omitempty"`
+	Burst      *int    `json:"burst,omitempty"`
+	DailyQuota *int64  `json:"daily_quota,omitempty"`
+}
+
+type RetrySetting struct {
+	MaxAttempts       *int     `json:"max_attempts,omitempty"`
+	InitialInterval   *int     `json:"initial_interval,omitempty"`
+	MaxInterval       *int     `json:"max_interval,omitempty"`
+	Multiplier        *float64 `json:"multiplier,omitempty"`
+	Jitter            *float64 `json:"jitter,omitempty"`
+	RetryableStatus   []int    `json:"retryable_status,omitempty"`
+	RespectRetryAfter *bool    `json:"respect_retry_after,omitempty"`
+	Deadline          *int     `json:"deadline,omitempty"`
 }
 
 type GlobalsSetting struct {
@@ -136,17 +204,44 @@ type ImageOpts struct {
 	Format           string                 `json:"format,omitempty"`
 	EncodingOptions  map[string]interface{} `json:"encoding_options,omitempty"`
 	BgColor          *[4]uint8              `json:"bgcolor,omitempty"`
+	Filters          []ImageFilter          `json:"filters,omitempty"`
+	Blend            string                 `json:"blend,omitempty"`
+}
+
+// ImageFilter 影像调色，Value 为空时使用该类型的默认值
+type ImageFilter struct {
+	Type  string   `json:"type"`
+	Value *float64 `json:"value,omitempty"`
 }
 
 type VectorOpts struct {
-	Type        string   `json:"type"`
-	Format      string   `json:"format,omitempty"`
-	Tolerance   *float64 `json:"tolerance,omitempty"`
-	Extent      uint16   `json:"extent,omitempty"`
-	Buffer      *uint16  `json:"buffer,omitempty"`
-	LineMetrics *bool    `json:"line_metrics,omitempty"`
-	MaxZoom     *uint8   `json:"max_zoom,omitempty"`
-	Proto       *int     `json:"proto,omitempty"`
+	Type           string        `json:"type"`
+	Format         string        `json:"format,omitempty"`
+	Tolerance      *float64      `json:"tolerance,omitempty"`
+	Extent         uint16        `json:"extent,omitempty"`
+	Buffer         *uint16       `json:"buffer,omitempty"`
+	LineMetrics    *bool         `json:"line_metrics,omitempty"`
+	MaxZoom        *uint8        `json:"max_zoom,omitempty"`
+	Proto          *int          `json:"proto,omitempty"`
+	Budget         *VectorBudget `json:"budget,omitempty"`
+	InfoTolerance  *float64      `json:"info_tolerance,omitempty"`
+	Validate       *bool         `json:"validate,omitempty"`
+	StrictValidate *bool         `json:"strict_validate,omitempty"`
+	FailOnRepair   *bool         `json:"fail_on_repair,omitempty"`
+}
+
+// VectorBudget 矢量瓦片大小限制，Levels 的键为级别，PriorityProperty 为丢弃要素时参考的数值属性
+type VectorBudget struct {
+	MaxBytes         int                      `json:"max_bytes,omitempty"`
+	MaxFeatures      int                      `json:"max_features,omitempty"`
+	Levels           map[int]*VectorSizeLimit `json:"levels,omitempty"`
+	PriorityProperty string                   `json:"priority_property,omitempty"`
+	MaxIterations    int                      `json:"max_iterations,omitempty"`
+}
+
+type VectorSizeLimit struct {
+	MaxBytes    int `json:"max_bytes,omitempty"`
+	MaxFeatures int `json:"max_features,omitempty"`
 }
 
 type GridOpts struct {
@@ -218,6 +313,21 @@ type CacheSource struct {
 	ReprojectSrs         *Reproject    `json:"reproject,omitempty"`
 	QueryBuffer          *int          `json:"query_buffer,omitempty"`
 	TileOptions          interface{}   `json:"tile_options,omitempty"`
+	BandMath             *BandMath     `json:"band_math,omitempty"`
+}
+
+type ColorStop struct {
+	Value   float64  `json:"value"`
+	Color   [4]uint8 `json:"color"`
+	Opacity *float64 `json:"opacity,omitempty"`
+}
+
+// BandMath 波段运算配置，变量 b0、r 表示第一个数据源的波段，s1.b0 表示第二个数据源的波段
+type BandMath struct {
+	Expression string            `json:"expression"`
+	Bands      map[string]string `json:"bands,omitempty"`
+	Ramp       []ColorStop       `json:"ramp,omitempty"`
+	Discrete   bool              `json:"discrete,omitempty"`
 }
 
 func (c *CacheSource) FromJson(data []byte) error {
@@ -240,9 +350,38 @@ func (c *CacheSource) FromJson(data []byte) error {
 		}
 		c.TileOptions = opt
 	}
+
+	for i := range c.Filters {
+		f, err := FilterUnmarshal(c.Filters[i])
+		if err != nil {
+			return err
+		}
+		c.Filters[i] = f
+	}
 	return nil
 }
 
+func FilterUnmarshal(obj interface{}) (interface{}, error) {
+	bt, _ := json.Marshal(obj)
+	mp := make(map[string]interface{})
+	json.Unmarshal(bt, &mp)
+	ty, ok := mp["type"].(string)
+	if !ok {
+		return nil, errors.New("filter lost type")
+	}
+	switch FilterType(ty) {
+	case FILTER_TYPE_WATERMARK:
+		f := &WaterMark{}
+		json.Unmarshal(bt, f)
+		return f, nil
+	case FILTER_TYPE_VECTOR:
+		f := &VectorFilter{}
+		json.Unmarshal(bt, f)
+		return f, nil
+	}
+	return nil, errors.New("not support filter")
+}
+
 func TileOptionUnmarshal(obj interface{}) (interface{}, error) {
 	bt, _ := json.Marshal(obj)
 	mp := make(map[string]interface{})
@@ -278,6 +417,10 @@ type WMSSourceOpts struct {
 	FeatureinfoFormat    string `json:"featureinfo_format,omitempty"`
 	FeatureinfoXslt      string `json:"featureinfo_xslt,omitempty"`
 	FeatureinfoOutFormat string `json:"featureinfo_out_format,omitempty"`
+	Discovery            *bool  `json:"discovery,omitempty"`
+	// DiscoveryCheckInterval 定期检查上游能力文档的间隔（秒），仅输出与配置的差异警告，不会修改已加载的数据源
+	DiscoveryCheckInterval *int `json:"discovery_check_interval,omitempty"`
+	DiscoveryTimeout       *int `json:"discovery_timeout,omitempty"`
 }
 
 type Source interface {
@@ -316,17 +459,21 @@ type WMSSource struct {
 	Store            *StoreInfo        `json:"store"`
 	AccessToken      *string           `json:"access_token,omitempty"`
 	AccessTokenName  *string           `json:"access_token_name,omitempty"`
+	Coalesce         *bool             `json:"coalesce,omitempty"`
+	MaxWidth         *uint32           `json:"max_width,omitempty"`
+	MaxHeight        *uint32           `json:"max_height,omitempty"`
 }
 
 type TileSource struct {
 	SourceCommons
-	Type          SourceType  `json:"type,omitempty"`
-	URLTemplate   string      `json:"url_template,omitempty"`
-	AccessToken   *string     `json:"access_token,omitempty"`
-	Grid          string      `json:"grid,omitempty"`
-	RequestFormat string      `json:"request_format,omitempty"`
-	Subdomains    []string    `json:"subdomains,omitempty"`
-	Options       interface{} `json:"options,omitempty"`
+	Type          SourceType         `json:"type,omitempty"`
+	URLTemplate   string             `json:"url_template,omitempty"`
+	AccessToken   *string            `json:"access_token,omitempty"`
+	Grid          string             `json:"grid,omitempty"`
+	RequestFormat string             `json:"request_format,omitempty"`
+	Subdomains    []string           `json:"subdomains,omitempty"`
+	Validation    *ValidationSetting `json:"validation,omitempty"`
+	Options       interface{}        `json:"options,omitempty"`
 }
 
 func (c *TileSource) FromJson(data []byte) error {
@@ -350,6 +497,116 @@ func (c *TileSource) FromJson(data []byte) error {
 	return nil
 }
 
+// DebugSource 不请求上游，直接生成标注瓦片号、范围与分辨率的影像
+type DebugSource struct {
+	SourceCommons
+	Type SourceType `json:"type,omitempty"`
+	Grid string     `json:"grid,omitempty"`
+}
+
+// RasterStyleSource 将高程缓存按色带渲染为影像，Preset 为内置色带名称，Ramp 优先
+type RasterStyleSource struct {
+	SourceCommons
+	Type        SourceType  `json:"type,omitempty"`
+	Source      string      `json:"source"`
+	Preset      string      `json:"preset,omitempty"`
+	Ramp        []ColorStop `json:"ramp,omitempty"`
+	Discrete    bool        `json:"discrete,omitempty"`
+	Opacity     *float64    `json:"opacity,omitempty"`
+	NodataColor *[4]uint8   `json:"nodata_color,omitempty"`
+	ImageOpts   *ImageOpts  `json:"image,omitempty"`
+}
+
+// RasterShadeSource 由高程缓存生成晕渲、坡度或坡向影像，Mode 为 hillshade、slope 或 aspect
+type RasterShadeSource struct {
+	SourceCommons
+	Type             SourceType  `json:"type,omitempty"`
+	Source           string      `json:"source"`
+	Mode             string      `json:"mode,omitempty"`
+	Azimuth          *float64    `json:"azimuth,omitempty"`
+	Altitude         *float64    `json:"altitude,omitempty"`
+	ZFactor          *float64    `json:"z_factor,omitempty"`
+	Multidirectional bool        `json:"multidirectional,omitempty"`
+	Ramp             []ColorStop `json:"ramp,omitempty"`
+	Discrete         bool        `json:"discrete,omitempty"`
+	Opacity          *float64    `json:"opacity,omitempty"`
+	ImageOpts        *ImageOpts  `json:"image,omitempty"`
+}
+
+// VectorStyleSource 将矢量缓存按样式渲染为影像，图层按顺序绘制
+type VectorStyleSource struct {
+	SourceCommons
+	Type       SourceType          `json:"type,omitempty"`
+	Source     string              `json:"source"`
+	Background *StyleColor         `json:"background,omitempty"`
+	Layers     []*VectorStyleLayer `json:"layers"`
+	ImageOpts  *ImageOpts          `json:"image,omitempty"`
+}
+
+// VectorStyleLayer 样式图层，Type 为 fill、line、circle 或 symbol，Filter 为 Mapbox 旧版过滤表达式
+type VectorStyleLayer struct {
+	ID          string           `json:"id"`
+	Type        string           `json:"type"`
+	SourceLayer string           `json:"source_layer"`
+	MinZoom     *float64         `json:"minzoom,omitempty"`
+	MaxZoom     *float64         `json:"maxzoom,omitempty"`
+	Filter      []interface{}    `json:"filter,omitempty"`
+	Paint       VectorStylePaint `json:"paint"`
+}
+
+// VectorStylePaint 绘制属性，TextField 中的 {属性名} 替换为要素属性，TextFont 为字体目录中的字体名
+type VectorStylePaint struct {
+	FillColor         *StyleColor  `json:"fill_color,omitempty"`
+	FillOpacity       *StyleNumber `json:"fill_opacity,omitempty"`
+	FillOutlineColor  *StyleColor  `json:"fill_outline_color,omitempty"`
+	LineColor         *StyleColor  `json:"line_color,omitempty"`
+	LineWidth         *StyleNumber `json:"line_width,omitempty"`
+	LineOpacity       *StyleNumber `json:"line_opacity,omitempty"`
+	CircleColor       *StyleColor  `json:"circle_color,omitempty"`
+	CircleRadius      *StyleNumber `json:"circle_radius,omitempty"`
+	CircleOpacity     *StyleNumber `json:"circle_opacity,omitempty"`
+	CircleStrokeColor *StyleColor  `json:"circle_stroke_color,omitempty"`
+	CircleStrokeWidth *StyleNumber `json:"circle_stroke_width,omitempty"`
+	TextField         string       `json:"text_field,omitempty"`
+	TextFont          string       `json:"text_font,omitempty"`
+	TextSize          *StyleNumber `json:"text_size,omitempty"`
+	TextColor         *StyleColor  `json:"text_color,omitempty"`
+	TextHaloColor     *StyleColor  `json:"text_halo_color,omitempty"`
+	TextHaloWidth     *StyleNumber `json:"text_halo_width,omitempty"`
+}
+
+// StyleNumber 随级别变化的数值，Stops 为 [级别, 数值] 列表，为空时使用 Value，Base 为指数插值的底数
+type StyleNumber struct {
+	Value float64      `json:"value,omitempty"`
+	Base  float64      `json:"base,omitempty"`
+	Stops [][2]float64 `json:"stops,omitempty"`
+}
+
+// StyleColor 随级别变化的颜色，Stops 为空时使用 Color
+type StyleColor struct {
+	Color [4]uint8         `json:"color"`
+	Base  float64          `json:"base,omitempty"`
+	Stops []StyleColorStop `json:"stops,omitempty"`
+}
+
+type StyleColorStop struct {
+	Zoom  float64  `json:"zoom"`
+	Color [4]uint8 `json:"color"`
+}
+
+// ContourSource 由高程缓存生成等高线矢量瓦片，Intervals 为级别到等距的映射
+type ContourSource struct {
+	SourceCommons
+	Type       SourceType      `json:"type,omitempty"`
+	Source     string          `json:"source"`
+	LayerName  string          `json:"layer_name,omitempty"`
+	Intervals  map[int]float64 `json:"intervals"`
+	MajorEvery *int            `json:"major_every,omitempty"`
+	Smooth     int             `json:"smooth,omitempty"`
+	Buffer     *int            `json:"buffer,omitempty"`
+	Options    *VectorOpts     `json:"options,omitempty"`
+}
+
 type MapboxTileSource struct {
 	SourceCommons
 	Type            SourceType  `json:"type,omitempty"`
@@ -446,6 +703,9 @@ type ArcGISSource struct {
 	Opts               ArcGISSourceOpts `json:"opts"`
 	SupportedFormats   []string         `json:"supported_formats,omitempty"`
 	SupportedSrs       []string         `json:"supported_srs,omitempty"`
+	Coalesce           *bool            `json:"coalesce,omitempty"`
+	MaxWidth           *uint32          `json:"max_width,omitempty"`
+	MaxHeight          *uint32          `json:"max_height,omitempty"`
 }
 
 type WaterMark struct {
@@ -457,18 +717,40 @@ type WaterMark struct {
 	Spacing  *string   `json:"spacing,omitempty"`
 }
 
+// VectorFilter 矢量瓦片处理规则，Layers 为空时保留全部图层，Rules 的键为原图层名
+type VectorFilter struct {
+	Type       string                      `json:"type"`
+	Layers     []string                    `json:"layers,omitempty"`
+	DropLayers []string                    `json:"drop_layers,omitempty"`
+	Rules      map[string]*VectorLayerRule `json:"rules,omitempty"`
+}
+
+// VectorLayerRule 图层规则，Filter 为 Mapbox 旧版过滤表达式
+type VectorLayerRule struct {
+	Rename            string            `json:"rename,omitempty"`
+	MinZoom           *int              `json:"minzoom,omitempty"`
+	MaxZoom           *int              `json:"maxzoom,omitempty"`
+	Properties        []string          `json:"properties,omitempty"`
+	ExcludeProperties []string          `json:"exclude_properties,omitempty"`
+	RenameProperties  map[string]string `json:"rename_properties,omitempty"`
+	Filter            []interface{}     `json:"filter,omitempty"`
+}
+
 type MapboxTileLayer struct {
-	Source       string                  `json:"source"`
-	Name         string                  `json:"name,omitempty"`
-	Title        string                  `json:"title"`
-	VectorLayers []*resource.VectorLayer `json:"vector_layers,omitempty"`
-	TileType     string                  `json:"tile_type,omitempty"`
-	ZoomRange    *[2]int                 `json:"zoom_range,omitempty"`
-	TileJSON     string                  `json:"tilejson,omitempty"`
-	Attribution  *string                 `json:"attribution,omitempty"`
-	Description  *string                 `json:"description,omitempty"`
-	Legend       *string                 `json:"legend,omitempty"`
-	FillZoom     *uint32                 `json:"fill_zoom,omitempty"`
+	Source        string                  `json:"source"`
+	Name          string                  `json:"name,omitempty"`
+	Title         string                  `json:"title"`
+	VectorLayers  []*resource.VectorLayer `json:"vector_layers,omitempty"`
+	TileType      string                  `json:"tile_type,omitempty"`
+	ZoomRange     *[2]int                 `json:"zoom_range,omitempty"`
+	TileJSON      string                  `json:"tilejson,omitempty"`
+	Attribution   *string                 `json:"attribution,omitempty"`
+	Description   *string                 `json:"description,omitempty"`
+	Legend        *string                 `json:"legend,omitempty"`
+	FillZoom      *uint32                 `json:"fill_zoom,omitempty"`
+	Debug         bool                    `json:"debug,omitempty"`
+	VectorFilter  *VectorFilter           `json:"vector_filter,omitempty"`
+	SourceMaxZoom *int                    `json:"source_max_zoom,omitempty"`
 }
 
 type MapboxService struct {
@@ -500,6 +782,7 @@ type TileLayer struct {
 	Source      string                   `json:"source"`
 	InfoSources []string                 `json:"info_sources"`
 	Dimensions  map[string][]interface{} `json:"dimensions,omitempty"`
+	Debug       bool                     `json:"debug,omitempty"`
 }
 
 type TMSService struct {
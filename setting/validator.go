@@ -5,6 +5,12 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+
+	"github.com/flywave/go-tileproxy/client"
+	"github.com/flywave/go-tileproxy/imagery"
+	"github.com/flywave/go-tileproxy/terrain"
+	"github.com/flywave/go-tileproxy/tile"
+	"github.com/flywave/go-tileproxy/utils"
 )
 
 type ConfigValidator interface {
@@ -39,6 +45,18 @@ func (ps *ProxyService) Validate() error {
 		return err
 	}
 
+	if err := ps.validateServiceCaches(); err != nil {
+		return err
+	}
+
+	if err := ps.validateVectorFilters(); err != nil {
+		return err
+	}
+
+	if err := ps.validateVectorStyles(); err != nil {
+		return err
+	}
+
 	if err := ps.validateCoverages(); err != nil {
 		return err
 	}
@@ -70,6 +88,22 @@ func (ps *ProxyService) GetWarnings() []string {
 		}
 	}
 
+	for name, c := range ps.Caches {
+		if cs, ok := c.(*CacheSource); ok {
+			if opts, ok := cs.TileOptions.(*ImageOpts); ok {
+				warnings = append(warnings, validateImageOpts(name, opts)...)
+			}
+			if opts, ok := cs.TileOptions.(*VectorOpts); ok && opts.Budget != nil {
+				warnings = append(warnings, validateVectorBudget(name, opts.Budget)...)
+			}
+			for _, f := range cs.Filters {
+				if vf, ok := f.(*VectorFilter); ok {
+					warnings = append(warnings, validateVectorFilter(name, vf)...)
+				}
+			}
+		}
+	}
+
 	return warnings
 }
 
@@ -133,12 +167,15 @@ func (ps *ProxyService) validateSourceGrid(name string, src interface{}) error {
 		gridName = s.Grid
 	case *CacheSource:
 		gridName = s.Grid
+	case *DebugSource:
+		gridName = s.Grid
 	default:
 		return nil
 	}
 
 	if gridName != "" {
-		if _, ok := ps.Grids[gridName]; !ok {
+		_, ok := ps.Grids[gridName]
+		if _, preset := DefaultGridPresets[gridName]; !ok && !preset {
 			return fmt.Errorf("source '%s' references undefined grid: %s", name, gridName)
 		}
 	}
@@ -158,6 +195,54 @@ func (ps *ProxyService) validateSourceConfig(name string, src interface{}) []str
 			warnings = append(warnings, fmt.Sprintf("WMS source '%s' has no layers defined", name))
 		}
 
+	case *RasterStyleSource:
+		if s.Source == "" {
+			return []string{fmt.Sprintf("Raster style source '%s' has empty source", name)}
+		}
+		if _, ok := ps.Caches[s.Source]; !ok {
+			return []string{fmt.Sprintf("Raster style source '%s' references undefined cache: %s", name, s.Source)}
+		}
+		if len(s.Ramp) == 0 && s.Preset != "" && imagery.NewColorRampPreset(s.Preset, false) == nil {
+			warnings = append(warnings, fmt.Sprintf("Raster style source '%s' has unknown preset: %s", name, s.Preset))
+		}
+
+	case *RasterShadeSource:
+		if s.Source == "" {
+			return []string{fmt.Sprintf("Raster shade source '%s' has empty source", name)}
+		}
+		if _, ok := ps.Caches[s.Source]; !ok {
+			return []string{fmt.Sprintf("Raster shade source '%s' references undefined cache: %s", name, s.Source)}
+		}
+		switch terrain.ShadeMode(s.Mode) {
+		case "", terrain.SHADE_HILLSHADE, terrain.SHADE_SLOPE, terrain.SHADE_ASPECT:
+		default:
+			warnings = append(warnings, fmt.Sprintf("Raster shade source '%s' has unknown mode: %s", name, s.Mode))
+		}
+
+	case *VectorStyleSource:
+		if s.Source == "" {
+			return []string{fmt.Sprintf("Vector style source '%s' has empty source", name)}
+		}
+		if _, ok := ps.Caches[s.Source]; !ok {
+			return []string{fmt.Sprintf("Vector style source '%s' references undefined cache: %s", name, s.Source)}
+		}
+
+	case *ContourSource:
+		if s.Source == "" {
+			return []string{fmt.Sprintf("Contour source '%s' has empty source", name)}
+		}
+		if _, ok := ps.Caches[s.Source]; !ok {
+			return []string{fmt.Sprintf("Contour source '%s' references undefined cache: %s", name, s.Source)}
+		}
+		if len(s.Intervals) == 0 {
+			warnings = append(warnings, fmt.Sprintf("Contour source '%s' has no intervals defined", name))
+		}
+		for zoom, interval := range s.Intervals {
+			if interval <= 0 {
+				warnings = append(warnings, fmt.Sprintf("Contour source '%s' has invalid interval %v at zoom %d", name, interval, zoom))
+			}
+		}
+
 	case *TileSource:
 		if s.URLTemplate == "" {
 			return []string{fmt.Sprintf("Tile source '%s' has empty url_template", name)}
@@ -204,6 +289,116 @@ func (ps *ProxyService) validateSourceConfig(name string, src interface{}) []str
 		}
 	}
 
+	warnings = append(warnings, validateImageOpts(name, sourceImageOpts(src))...)
+	return warnings
+}
+
+// sourceImageOpts 返回数据源配置的影像参数
+func sourceImageOpts(src interface{}) *ImageOpts {
+	switch s := src.(type) {
+	case *WMSSource:
+		return &s.Image.ImageOpts
+	case *ArcGISSource:
+		return &s.Image.ImageOpts
+	case *RasterStyleSource:
+		return s.ImageOpts
+	case *RasterShadeSource:
+		return s.ImageOpts
+	case *VectorStyleSource:
+		return s.ImageOpts
+	case *TileSource:
+		opts, _ := s.Options.(*ImageOpts)
+		return opts
+	}
+	return nil
+}
+
+// validateImageOpts 检查调色类型、混合模式与编码参数
+func validateImageOpts(name string, opts *ImageOpts) []string {
+	if opts == nil {
+		return nil
+	}
+	var warnings []string
+	for _, f := range opts.Filters {
+		if !imagery.IsValidFilterType(imagery.FilterType(f.Type)) {
+			warnings = append(warnings, fmt.Sprintf("Source '%s' has unknown image filter: %s", name, f.Type))
+		}
+	}
+	if !imagery.IsValidBlendMode(imagery.BlendMode(opts.Blend)) {
+		warnings = append(warnings, fmt.Sprintf("Source '%s' has unknown blend mode: %s", name, opts.Blend))
+	}
+	if _, err := imagery.ParseEncodeOptions(opts.EncodingOptions); err != nil {
+		warnings = append(warnings, fmt.Sprintf("'%s' has invalid encoding options: %v", name, err))
+	}
+	return warnings
+}
+
+// validateVectorFilter 检查级别范围，过滤表达式由 validateVectorFilters 检查
+func validateVectorFilter(name string, f *VectorFilter) []string {
+	var warnings []string
+	for layer, r := range f.Rules {
+		if r != nil && r.MinZoom != nil && r.MaxZoom != nil && *r.MinZoom > *r.MaxZoom {
+			warnings = append(warnings, fmt.Sprintf("'%s' vector layer '%s' has minzoom greater than maxzoom", name, layer))
+		}
+	}
+	return warnings
+}
+
+// validateVectorBudget 检查大小限制不为负数
+func validateVectorBudget(name string, b *VectorBudget) []string {
+	var warnings []string
+	if b.MaxBytes < 0 || b.MaxFeatures < 0 || b.MaxIterations < 0 {
+		warnings = append(warnings, fmt.Sprintf("'%s' has negative vector budget", name))
+	}
+	for z, l := range b.Levels {
+		if l != nil && (l.MaxBytes < 0 || l.MaxFeatures < 0) {
+			warnings = append(warnings, fmt.Sprintf("'%s' has negative vector budget for level %d", name, z))
+		}
+	}
+	return warnings
+}
+
+// ValidateWMSCapabilities 比较WMS数据源配置与上游能力文档，返回图层、坐标系、格式及请求尺寸的差异
+func ValidateWMSCapabilities(name string, s *WMSSource, caps *client.WMSCapabilities) []string {
+	var warnings []string
+
+	layers := make([]*client.WMSLayerCapabilities, 0, len(s.Layers))
+	for _, l := range s.Layers {
+		info := caps.Layer(l)
+		if info == nil {
+			warnings = append(warnings, fmt.Sprintf("WMS source '%s' layer '%s' is not advertised by upstream capabilities", name, l))
+			continue
+		}
+		layers = append(layers, info)
+	}
+
+	for _, srs := range s.SupportedSrs {
+		for _, info := range layers {
+			if !info.SupportsSrs(srs) {
+				warnings = append(warnings, fmt.Sprintf("WMS source '%s' srs '%s' is not supported by upstream layer '%s'", name, srs, info.Name))
+			}
+		}
+	}
+
+	if len(caps.Formats) > 0 {
+		formats := append([]string{}, s.SupportedFormats...)
+		if s.Format != "" {
+			formats = append(formats, s.Format)
+		}
+		for _, f := range formats {
+			if !utils.ContainsString(caps.Formats, f) {
+				warnings = append(warnings, fmt.Sprintf("WMS source '%s' format '%s' is not offered by upstream GetMap", name, f))
+			}
+		}
+	}
+
+	if s.MaxWidth != nil && caps.MaxWidth > 0 && int(*s.MaxWidth) > caps.MaxWidth {
+		warnings = append(warnings, fmt.Sprintf("WMS source '%s' max_width %d exceeds upstream MaxWidth %d", name, *s.MaxWidth, caps.MaxWidth))
+	}
+	if s.MaxHeight != nil && caps.MaxHeight > 0 && int(*s.MaxHeight) > caps.MaxHeight {
+		warnings = append(warnings, fmt.Sprintf("WMS source '%s' max_height %d exceeds upstream MaxHeight %d", name, *s.MaxHeight, caps.MaxHeight))
+	}
+
 	return warnings
 }
 
@@ -233,6 +428,11 @@ func (ps *ProxyService) validateServiceConfig() []string {
 		if len(srv.Layers) == 0 {
 			warnings = append(warnings, "Mapbox service has no layers defined")
 		}
+		for _, l := range srv.Layers {
+			if l.VectorFilter != nil {
+				warnings = append(warnings, validateVectorFilter(l.Name, l.VectorFilter)...)
+			}
+		}
 
 	case *CesiumService:
 		if len(srv.Layers) == 0 {
@@ -282,12 +482,88 @@ func (ps *ProxyService) validateCaches() error {
 					return fmt.Errorf("cache '%s': %v", name, err)
 				}
 			}
+
+			if c.BandMath != nil {
+				expr, err := imagery.ParseBandExpression(c.BandMath.Expression, c.BandMath.Bands)
+				if err != nil {
+					return fmt.Errorf("cache '%s' has invalid band_math expression: %v", name, err)
+				}
+				if expr.SourceCount() > len(c.Sources) {
+					return fmt.Errorf("cache '%s' band_math expression references %d sources, only %d configured", name, expr.SourceCount(), len(c.Sources))
+				}
+			}
 		}
 	}
 
 	return nil
 }
 
+// validateServiceCaches Mapbox 与 Cesium 服务按请求的扩展名返回固定格式的瓦片，不能发布混合格式的缓存
+func (ps *ProxyService) validateServiceCaches() error {
+	var kind string
+	var sources []string
+	switch srv := ps.Service.(type) {
+	case *MapboxService:
+		kind = "Mapbox"
+		for _, l := range srv.Layers {
+			sources = append(sources, l.Source)
+		}
+	case *CesiumService:
+		kind = "Cesium"
+		for _, l := range srv.Layers {
+			sources = append(sources, l.Source)
+		}
+	default:
+		return nil
+	}
+	for _, name := range sources {
+		if c, ok := ps.Caches[name].(*CacheSource); ok && imagery.IsMixedFormat(tile.TileFormat(c.Format)) {
+			return fmt.Errorf("%s service cannot serve cache '%s' with mixed format", kind, name)
+		}
+	}
+	return nil
+}
+
+// validateVectorFilters 检查缓存与 Mapbox 图层的矢量过滤表达式，无效的过滤规则不能加载
+func (ps *ProxyService) validateVectorFilters() error {
+	for name, c := range ps.Caches {
+		cs, ok := c.(*CacheSource)
+		if !ok {
+			continue
+		}
+		for _, f := range cs.Filters {
+			if vf, ok := f.(*VectorFilter); ok {
+				if _, err := NewVectorProcessor(vf); err != nil {
+					return fmt.Errorf("cache '%s' has invalid vector filter: %v", name, err)
+				}
+			}
+		}
+	}
+	if srv, ok := ps.Service.(*MapboxService); ok {
+		for _, l := range srv.Layers {
+			if l.VectorFilter == nil {
+				continue
+			}
+			if _, err := NewVectorProcessor(l.VectorFilter); err != nil {
+				return fmt.Errorf("mapbox layer '%s' has invalid vector filter: %v", l.Name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// validateVectorStyles 检查矢量样式数据源的样式，图层类型或过滤表达式无效时无法加载
+func (ps *ProxyService) validateVectorStyles() error {
+	for name, src := range ps.Sources {
+		if s, ok := src.(*VectorStyleSource); ok {
+			if _, err := NewVectorStyle(s); err != nil {
+				return fmt.Errorf("vector style source '%s' has invalid style: %v", name, err)
+			}
+		}
+	}
+	return nil
+}
+
 func (ps *ProxyService) validateCachePath(path string) error {
 	if !filepath.IsAbs(path) {
 		return fmt.Errorf("cache path must be absolute: %s", path)
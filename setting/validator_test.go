@@ -0,0 +1,119 @@
+package setting
+
+import (
+	"testing"
+
+	"github.com/flywave/go-geo"
+	"github.com/flywave/go-tileproxy/cache"
+	"github.com/flywave/go-tileproxy/layer"
+	"github.com/flywave/go-tileproxy/tile"
+)
+
+func TestValidateServiceCachesMixed(t *testing.T) {
+	ps := &ProxyService{
+		Id:     "test",
+		Caches: map[string]interface{}{"mixed": &CacheSource{Format: "mixed"}, "png": &CacheSource{Format: "png"}},
+	}
+
+	ps.Service = &MapboxService{Layers: []MapboxTileLayer{{Source: "png"}}}
+	if err := ps.validateServiceCaches(); err != nil {
+		t.Errorf("PNG 缓存应可以发布: %v", err)
+	}
+	ps.Service = &MapboxService{Layers: []MapboxTileLayer{{Source: "mixed"}}}
+	if err := ps.validateServiceCaches(); err == nil {
+		t.Error("Mapbox 服务不能发布混合格式的缓存")
+	}
+	ps.Service = &CesiumService{Layers: []CesiumTileLayer{{Source: "mixed"}}}
+	if err := ps.validateServiceCaches(); err == nil {
+		t.Error("Cesium 服务不能发布混合格式的缓存")
+	}
+	ps.Service = &TMSService{}
+	if err := ps.validateServiceCaches(); err != nil {
+		t.Errorf("TMS 服务支持混合格式: %v", err)
+	}
+}
+
+func TestValidateVectorFilters(t *testing.T) {
+	bad := &VectorFilter{Type: string(FILTER_TYPE_VECTOR), Rules: map[string]*VectorLayerRule{
+		"roads": {Filter: []interface{}{"unknown-op", "class"}},
+	}}
+	ps := &ProxyService{
+		Id:      "test",
+		Caches:  map[string]interface{}{"osm": &CacheSource{Filters: []interface{}{bad}}},
+		Service: &MapboxService{},
+	}
+	if err := ps.validateVectorFilters(); err == nil {
+		t.Error("缓存的矢量过滤规则无效时应返回错误")
+	}
+	if _, err := LoadFilter(bad); err == nil {
+		t.Error("加载无效的矢量过滤规则应返回错误")
+	}
+
+	ps.Caches = map[string]interface{}{"osm": &CacheSource{}}
+	ps.Service = &MapboxService{Layers: []MapboxTileLayer{{Name: "osm", Source: "osm", VectorFilter: bad}}}
+	if err := ps.validateVectorFilters(); err == nil {
+		t.Error("Mapbox 图层的矢量过滤规则无效时应返回错误")
+	}
+}
+
+func TestFilterUnmarshalType(t *testing.T) {
+	if _, err := FilterUnmarshal(map[string]interface{}{"type": 1}); err == nil {
+		t.Error("过滤器类型不是字符串时应返回错误")
+	}
+	f, err := FilterUnmarshal(map[string]interface{}{"type": string(FILTER_TYPE_VECTOR)})
+	if _, ok := f.(*VectorFilter); !ok || err != nil {
+		t.Errorf("矢量过滤器解析失败: %v", err)
+	}
+}
+
+func TestValidateVectorStyles(t *testing.T) {
+	ps := &ProxyService{
+		Id:     "test",
+		Caches: map[string]interface{}{"osm": &CacheSource{}},
+		Sources: map[string]interface{}{"style": &VectorStyleSource{Source: "osm", Layers: []*VectorStyleLayer{
+			{ID: "roads", Type: "line", Filter: []interface{}{"unknown-op", "class"}},
+		}}},
+	}
+	if err := ps.validateVectorStyles(); err == nil {
+		t.Error("矢量样式的过滤表达式无效时应返回错误")
+	}
+
+	ps.Sources["style"] = &VectorStyleSource{Source: "osm", Layers: []*VectorStyleLayer{{ID: "roads", Type: "unknown"}}}
+	if err := ps.validateVectorStyles(); err == nil {
+		t.Error("矢量样式的图层类型无效时应返回错误")
+	}
+	if _, err := LoadVectorStyleSource(ps.Sources["style"].(*VectorStyleSource), &emptyInstance{}); err == nil {
+		t.Error("加载无效的矢量样式应返回错误")
+	}
+
+	ps.Sources["style"] = &VectorStyleSource{Source: "osm", Layers: []*VectorStyleLayer{{ID: "roads", Type: "line"}}}
+	if err := ps.validateVectorStyles(); err != nil {
+		t.Errorf("有效的矢量样式不应返回错误: %v", err)
+	}
+}
+
+func TestLoadDerivedSourcesMissingCache(t *testing.T) {
+	inst := &emptyInstance{}
+	if _, err := LoadRasterStyleSource(&RasterStyleSource{Source: "dem"}, inst); err == nil {
+		t.Error("高程缓存不存在时加载色带数据源应返回错误")
+	}
+	if _, err := LoadRasterShadeSource(&RasterShadeSource{Source: "dem"}, inst); err == nil {
+		t.Error("高程缓存不存在时加载晕渲数据源应返回错误")
+	}
+	if _, err := LoadContourSource(&ContourSource{Source: "dem"}, inst); err == nil {
+		t.Error("高程缓存不存在时加载等高线数据源应返回错误")
+	}
+	if _, err := LoadVectorStyleSource(&VectorStyleSource{Source: "osm"}, inst); err == nil {
+		t.Error("矢量缓存不存在时加载矢量样式数据源应返回错误")
+	}
+}
+
+// emptyInstance 不包含任何网格、数据源与缓存的服务实例
+type emptyInstance struct{}
+
+func (emptyInstance) GetGrid(string) geo.Grid                             { return nil }
+func (emptyInstance) GetSource(string) layer.Layer                        { return nil }
+func (emptyInstance) GetCache(string) cache.Manager                       { return nil }
+func (emptyInstance) GetCacheSource(string, tile.TileOptions) layer.Layer { return nil }
+func (emptyInstance) GetInfoSource(string) layer.InfoLayer                { return nil }
+func (emptyInstance) GetLegendSource(string) layer.LegendLayer            { return nil }
@@ -33,6 +33,8 @@ func NewArcGISSource(
 			SupportedSRS:              supported_srs,
 			SupportedFormats:          supported_formats,
 			ExtReqParams:              nil,
+			Coalescer:                 DefaultRequestCoalescer,
+			target:                    requestTarget(client),
 		},
 	}
 }
@@ -0,0 +1,118 @@
+package sources
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+
+	vec2d "github.com/flywave/go3d/float64/vec2"
+
+	"github.com/flywave/go-tileproxy/layer"
+	"github.com/flywave/go-tileproxy/tile"
+)
+
+// RequestCoalescer 合并并发的上游地图请求，相同或被正在进行的请求完全包含的请求
+// 等待该请求完成后共享其结果并裁剪
+type RequestCoalescer struct {
+	mu       sync.Mutex
+	inflight map[string][]*coalescedCall
+}
+
+type coalescedCall struct {
+	bbox vec2d.Rect
+	size [2]uint32
+	done chan struct{}
+	data []byte
+	err  error
+}
+
+func NewRequestCoalescer() *RequestCoalescer {
+	return &RequestCoalescer{inflight: make(map[string][]*coalescedCall)}
+}
+
+// DefaultRequestCoalescer 为WMS/ArcGIS数据源默认共享的请求合并器
+var DefaultRequestCoalescer = NewRequestCoalescer()
+
+// Do 执行或加入一次上游请求，返回请求结果、查询范围在结果图像中的像素偏移、结果图像大小及请求错误
+func (c *RequestCoalescer) Do(key string, bbox vec2d.Rect, size [2]uint32, fetch func() ([]byte, error)) ([]byte, [2]int, [2]uint32, error) {
+	c.mu.Lock()
+	for _, call := range c.inflight[key] {
+		if offset, ok := containedOffset(call.bbox, call.size, bbox, size); ok {
+			c.mu.Unlock()
+			<-call.done
+			return call.data, offset, call.size, call.err
+		}
+	}
+	call := &coalescedCall{bbox: bbox, size: size, done: make(chan struct{})}
+	c.inflight[key] = append(c.inflight[key], call)
+	c.mu.Unlock()
+
+	defer func() {
+		c.mu.Lock()
+		calls := c.inflight[key]
+		for i := range calls {
+			if calls[i] == call {
+				calls = append(calls[:i], calls[i+1:]...)
+				break
+			}
+		}
+		if len(calls) == 0 {
+			delete(c.inflight, key)
+		} else {
+			c.inflight[key] = calls
+		}
+		c.mu.Unlock()
+		close(call.done)
+	}()
+
+	call.data, call.err = fetch()
+	return call.data, [2]int{0, 0}, size, call.err
+}
+
+// containedOffset 判断查询是否可以从正在进行的请求结果中裁剪得到，要求分辨率一致且像素对齐
+func containedOffset(callBBox vec2d.Rect, callSize [2]uint32, bbox vec2d.Rect, size [2]uint32) ([2]int, bool) {
+	if callSize[0] == 0 || callSize[1] == 0 || size[0] == 0 || size[1] == 0 {
+		return [2]int{}, false
+	}
+	cresx := (callBBox.Max[0] - callBBox.Min[0]) / float64(callSize[0])
+	cresy := (callBBox.Max[1] - callBBox.Min[1]) / float64(callSize[1])
+	resx := (bbox.Max[0] - bbox.Min[0]) / float64(size[0])
+	resy := (bbox.Max[1] - bbox.Min[1]) / float64(size[1])
+	if math.Abs(cresx-resx) > cresx*1e-9 || math.Abs(cresy-resy) > cresy*1e-9 {
+		return [2]int{}, false
+	}
+
+	fx := (bbox.Min[0] - callBBox.Min[0]) / cresx
+	fy := (callBBox.Max[1] - bbox.Max[1]) / cresy
+	ox, oy := math.Round(fx), math.Round(fy)
+	if math.Abs(fx-ox) > 0.01 || math.Abs(fy-oy) > 0.01 {
+		return [2]int{}, false
+	}
+	if ox < 0 || oy < 0 || int(ox)+int(size[0]) > int(callSize[0]) || int(oy)+int(size[1]) > int(callSize[1]) {
+		return [2]int{}, false
+	}
+	return [2]int{int(ox), int(oy)}, true
+}
+
+// coalesceKey 生成合并请求的键，target 包含完整的请求参数与认证标识，bbox与size在Do中单独比较
+func coalesceKey(target string, query *layer.MapQuery, format tile.TileFormat, dimensions map[string]string) string {
+	var b strings.Builder
+	b.WriteString(target)
+	if query.Srs != nil {
+		b.WriteString("|" + query.Srs.GetSrsCode())
+	}
+	b.WriteString("|" + string(format))
+	fmt.Fprintf(&b, "|%v", query.Transparent)
+
+	keys := make([]string, 0, len(dimensions))
+	for k := range dimensions {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		b.WriteString("|" + k + "=" + dimensions[k])
+	}
+	return b.String()
+}
@@ -0,0 +1,200 @@
+package sources
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	vec2d "github.com/flywave/go3d/float64/vec2"
+
+	"github.com/flywave/go-geo"
+	"github.com/flywave/go-tileproxy/client"
+	"github.com/flywave/go-tileproxy/imagery"
+	"github.com/flywave/go-tileproxy/layer"
+	"github.com/flywave/go-tileproxy/request"
+	"github.com/flywave/go-tileproxy/tile"
+)
+
+type slowClient struct {
+	client.HttpClient
+	calls  int32
+	delay  time.Duration
+	body   []byte
+	failAt int32
+}
+
+func (c *slowClient) Open(url string, data []byte, hdr http.Header) (int, []byte) {
+	n := atomic.AddInt32(&c.calls, 1)
+	time.Sleep(c.delay)
+	if n == c.failAt {
+		return 500, []byte("internal error")
+	}
+	return 200, c.body
+}
+
+func TestRequestCoalescerContained(t *testing.T) {
+	c := NewRequestCoalescer()
+	big := vec2d.Rect{Min: vec2d.T{0, 0}, Max: vec2d.T{512, 512}}
+	small := vec2d.Rect{Min: vec2d.T{256, 0}, Max: vec2d.T{512, 256}}
+
+	var calls int32
+	started := make(chan struct{})
+	release := make(chan struct{})
+	go c.Do("k", big, [2]uint32{512, 512}, func() ([]byte, error) {
+		atomic.AddInt32(&calls, 1)
+		close(started)
+		<-release
+		return []byte("data"), nil
+	})
+	<-started
+
+	done := make(chan struct{})
+	var data []byte
+	var offset [2]int
+	var size [2]uint32
+	go func() {
+		data, offset, size, _ = c.Do("k", small, [2]uint32{256, 256}, func() ([]byte, error) {
+			atomic.AddInt32(&calls, 1)
+			return nil, nil
+		})
+		close(done)
+	}()
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+	<-done
+
+	if calls != 1 {
+		t.Fatalf("被包含的请求应合并, 上游调用次数: %d", calls)
+	}
+	if string(data) != "data" || offset != [2]int{256, 256} || size != [2]uint32{512, 512} {
+		t.Fatalf("合并结果错误: %q %v %v", data, offset, size)
+	}
+
+	// 分辨率不同的请求不能合并
+	_, _, size, _ = c.Do("k", small, [2]uint32{512, 512}, func() ([]byte, error) { return nil, nil })
+	if size != [2]uint32{512, 512} || len(c.inflight) != 0 {
+		t.Fatal("请求完成后应清理进行中的请求")
+	}
+}
+
+func TestContainedOffset(t *testing.T) {
+	call := vec2d.Rect{Min: vec2d.T{0, 0}, Max: vec2d.T{100, 100}}
+	if _, ok := containedOffset(call, [2]uint32{100, 100}, vec2d.Rect{Min: vec2d.T{10, 10}, Max: vec2d.T{20, 20}}, [2]uint32{20, 20}); ok {
+		t.Error("分辨率不同不应合并")
+	}
+	if _, ok := containedOffset(call, [2]uint32{100, 100}, vec2d.Rect{Min: vec2d.T{10.5, 10}, Max: vec2d.T{20.5, 20}}, [2]uint32{10, 10}); ok {
+		t.Error("像素未对齐不应合并")
+	}
+	if _, ok := containedOffset(call, [2]uint32{100, 100}, vec2d.Rect{Min: vec2d.T{95, 10}, Max: vec2d.T{105, 20}}, [2]uint32{10, 10}); ok {
+		t.Error("超出范围不应合并")
+	}
+	offset, ok := containedOffset(call, [2]uint32{100, 100}, vec2d.Rect{Min: vec2d.T{10, 70}, Max: vec2d.T{20, 80}}, [2]uint32{10, 10})
+	if !ok || offset != [2]int{10, 20} {
+		t.Errorf("偏移错误: %v %v", offset, ok)
+	}
+}
+
+func TestWMSSourceCoalescing(t *testing.T) {
+	rgba := image.NewRGBA(image.Rect(0, 0, 256, 256))
+	for y := 0; y < 256; y++ {
+		for x := 0; x < 256; x++ {
+			rgba.SetRGBA(x, y, color.RGBA{R: uint8(x), G: uint8(y), B: 128, A: 255})
+		}
+	}
+	imagedata := &bytes.Buffer{}
+	png.Encode(imagedata, rgba)
+
+	mock := &slowClient{delay: 50 * time.Millisecond, body: imagedata.Bytes()}
+	ctx := &mockContext{c: nil}
+	param := http.Header{"layers": []string{"coalesce-layer"}, "format": []string{"image/png"}}
+	req := request.NewWMSMapRequest(param, "/service?map=coalesce", false, nil, false)
+	wmsClient := client.NewWMSClient(req, nil, nil, &slowContext{mockContext: ctx, c: mock})
+
+	source := NewWMSSource(wmsClient, &imagery.ImageOptions{Format: tile.TileFormat("png")}, nil, nil, nil, nil, nil, nil, nil)
+	source.Coalescer = NewRequestCoalescer()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			query := &layer.MapQuery{
+				BBox:   vec2d.Rect{Min: vec2d.T{-180, -90}, Max: vec2d.T{0, 90}},
+				Size:   [2]uint32{256, 256},
+				Srs:    geo.NewProj(4326),
+				Format: tile.TileFormat("png"),
+			}
+			if _, err := source.GetMap(query); err != nil {
+				t.Errorf("GetMap 失败: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if calls := atomic.LoadInt32(&mock.calls); calls != 1 {
+		t.Fatalf("相同的并发请求应只请求一次上游, 实际: %d", calls)
+	}
+}
+
+type slowContext struct {
+	*mockContext
+	c *slowClient
+}
+
+func (c *slowContext) Client() client.HttpClient {
+	return c.c
+}
+
+func TestWMSSourceCoalescedServiceException(t *testing.T) {
+	body := []byte(`<?xml version="1.0"?><ServiceExceptionReport><ServiceException>error</ServiceException></ServiceExceptionReport>`)
+	mock := &slowClient{delay: 50 * time.Millisecond, body: body}
+	param := http.Header{"layers": []string{"exception-layer"}, "format": []string{"image/png"}}
+	req := request.NewWMSMapRequest(param, "/service?map=exception", false, nil, false)
+	wmsClient := client.NewWMSClient(req, nil, nil, &slowContext{mockContext: &mockContext{}, c: mock})
+
+	source := NewWMSSource(wmsClient, &imagery.ImageOptions{Format: tile.TileFormat("png")}, nil, nil, nil, nil, nil, nil, nil)
+	source.Coalescer = NewRequestCoalescer()
+
+	queries := []*layer.MapQuery{
+		{BBox: vec2d.Rect{Min: vec2d.T{-180, -90}, Max: vec2d.T{0, 90}}, Size: [2]uint32{256, 256}},
+		{BBox: vec2d.Rect{Min: vec2d.T{-90, 0}, Max: vec2d.T{0, 90}}, Size: [2]uint32{128, 128}},
+	}
+	var wg sync.WaitGroup
+	for i, q := range queries {
+		q.Srs = geo.NewProj(4326)
+		q.Format = tile.TileFormat("png")
+		wg.Add(1)
+		go func(i int, q *layer.MapQuery) {
+			defer wg.Done()
+			time.Sleep(time.Duration(i) * 10 * time.Millisecond)
+			if _, err := source.GetMap(q); err == nil {
+				t.Error("上游返回 ServiceException 时应返回错误")
+			}
+		}(i, q)
+	}
+	wg.Wait()
+
+	if calls := atomic.LoadInt32(&mock.calls); calls != 1 {
+		t.Errorf("被包含的请求应合并, 上游调用次数: %d", calls)
+	}
+}
+
+func TestWMSClientRequestTarget(t *testing.T) {
+	newClient := func(styles string) *client.WMSClient {
+		param := http.Header{"layers": []string{"roads"}, "styles": []string{styles}, "format": []string{"image/png"}}
+		req := request.NewWMSMapRequest(param, "/service?map=target", false, nil, false)
+		return client.NewWMSClient(req, nil, nil, &mockContext{})
+	}
+	if requestTarget(newClient("default")) != requestTarget(newClient("default")) {
+		t.Error("请求参数相同的客户端应可以合并请求")
+	}
+	if requestTarget(newClient("default")) == requestTarget(newClient("night")) {
+		t.Error("样式不同的客户端不能合并请求")
+	}
+}
@@ -0,0 +1,119 @@
+package sources
+
+import (
+	"sort"
+
+	vec2d "github.com/flywave/go3d/float64/vec2"
+
+	"github.com/flywave/go-geo"
+	"github.com/flywave/go-geom"
+	"github.com/flywave/go-tileproxy/layer"
+	"github.com/flywave/go-tileproxy/terrain"
+	"github.com/flywave/go-tileproxy/tile"
+	"github.com/flywave/go-tileproxy/vector"
+)
+
+const DefaultContourLayerName = "contour"
+
+// ContourSource 从高程数据源生成等高线矢量瓦片。
+// Intervals 按级别配置等距，取不大于当前级别的最大级别对应的等距；
+// 高程为 MajorEvery 倍等距的等高线标记为计曲线
+type ContourSource struct {
+	layer.MapLayer
+	Source     layer.Layer
+	LayerName  string
+	Intervals  map[int]float64
+	MajorEvery int
+	Smooth     int
+	Buffer     int
+}
+
+func NewContourSource(src layer.Layer, intervals map[int]float64, opts *vector.VectorOptions) *ContourSource {
+	if opts == nil {
+		opts = &vector.VectorOptions{Format: tile.TileFormat("mvt"), Extent: 4096}
+	}
+	ret := &ContourSource{
+		MapLayer: layer.MapLayer{
+			SupportMetaTiles: false,
+			Extent:           geo.MapExtentFromDefault(),
+			Options:          opts,
+		},
+		Source:     src,
+		LayerName:  DefaultContourLayerName,
+		Intervals:  intervals,
+		MajorEvery: 5,
+		Buffer:     4,
+	}
+	if ext := src.GetExtent(); ext != nil {
+		ret.Extent = ext
+	}
+	ret.ResRange = src.GetResolutionRange()
+	return ret
+}
+
+// Interval 返回指定级别的等距，未配置时为0
+func (s *ContourSource) Interval(zoom int) float64 {
+	levels := make([]int, 0, len(s.Intervals))
+	for z := range s.Intervals {
+		levels = append(levels, z)
+	}
+	sort.Ints(levels)
+	interval := 0.0
+	for _, z := range levels {
+		if z > zoom {
+			break
+		}
+		interval = s.Intervals[z]
+	}
+	return interval
+}
+
+func (s *ContourSource) GetMap(query *layer.MapQuery) (tile.Source, error) {
+	if err := s.CheckResRange(query); err != nil {
+		return nil, err
+	}
+	opts := s.Options.(*vector.VectorOptions)
+	vt := make(vector.Vector)
+
+	interval := s.Interval(query.TileId[2])
+	if interval <= 0 {
+		return vector.CreateVectorSourceFromVector(vt, query.TileId, opts, nil), nil
+	}
+
+	data, _, err := getBufferedRasterData(s.Source, query, s.Buffer)
+	if err != nil {
+		return nil, err
+	}
+	if data.Boxsrs == nil {
+		data.Box, data.Boxsrs = query.BBox, query.Srs
+	}
+
+	wgs84 := geo.NewProj(4326)
+	feats := []*geom.Feature{}
+	for _, line := range terrain.Contours(data, interval) {
+		pts := terrain.SmoothContour(line.Points, s.Smooth)
+		if len(pts) < 2 {
+			continue
+		}
+		coords := make([]vec2d.T, len(pts))
+		for i, p := range pts {
+			coords[i] = vec2d.T{p[0], p[1]}
+		}
+		if !data.Boxsrs.Eq(wgs84) {
+			coords = data.Boxsrs.TransformTo(wgs84, coords)
+		}
+		ls := make([][]float64, len(coords))
+		for i, c := range coords {
+			ls[i] = []float64{c[0], c[1]}
+		}
+
+		f := geom.NewLineStringFeature(ls)
+		f.Properties["ele"] = line.Level
+		f.Properties["major"] = s.MajorEvery > 0 && line.Index%s.MajorEvery == 0
+		feats = append(feats, f)
+	}
+	if len(feats) > 0 {
+		vt[s.LayerName] = feats
+	}
+	return vector.CreateVectorSourceFromVector(vt, query.TileId, opts, nil), nil
+}
@@ -0,0 +1,148 @@
+package sources
+
+import (
+	"math"
+	"testing"
+
+	vec2d "github.com/flywave/go3d/float64/vec2"
+
+	"github.com/flywave/go-geo"
+	"github.com/flywave/go-geom"
+	"github.com/flywave/go-tileproxy/layer"
+	"github.com/flywave/go-tileproxy/vector"
+)
+
+func TestContourSourceInterval(t *testing.T) {
+	source := NewContourSource(newDemLayer(nil), map[int]float64{0: 500, 10: 100, 14: 20}, nil)
+	for zoom, want := range map[int]float64{0: 500, 9: 500, 10: 100, 13: 100, 16: 20} {
+		if got := source.Interval(zoom); got != want {
+			t.Errorf("级别 %d 等距错误: %v, 期望 %v", zoom, got, want)
+		}
+	}
+}
+
+func TestContourSource(t *testing.T) {
+	dem := newDemLayer(func(x, y float64) float64 { return x * 100 })
+	source := NewContourSource(dem, map[int]float64{0: 100, 12: 10}, nil)
+	source.Smooth = 1
+
+	query := &layer.MapQuery{
+		BBox:   vec2d.Rect{Min: vec2d.T{0, 0}, Max: vec2d.T{1, 1}},
+		Size:   [2]uint32{32, 32},
+		Srs:    geo.NewProj(4326),
+		TileId: [3]int{0, 0, 12},
+	}
+	src, err := source.GetMap(query)
+	if err != nil {
+		t.Fatalf("GetMap 失败: %v", err)
+	}
+	if last := dem.queries[len(dem.queries)-1]; last.Size != [2]uint32{40, 40} {
+		t.Fatalf("高程请求应外扩缓冲像素: %v", last.Size)
+	}
+
+	vt := src.GetTile().(vector.Vector)
+	feats := vt[DefaultContourLayerName]
+	// 缓冲后高程范围约为 -12.5 到 112.5
+	if len(feats) != 13 {
+		t.Fatalf("等高线数量错误: %d", len(feats))
+	}
+	majors := 0
+	for _, f := range feats {
+		ele := f.Properties["ele"].(float64)
+		if math.Mod(ele, 10) != 0 {
+			t.Errorf("高程不是等距的整数倍: %v", ele)
+		}
+		if f.Properties["major"].(bool) {
+			majors++
+			if math.Mod(ele, 50) != 0 {
+				t.Errorf("计曲线高程错误: %v", ele)
+			}
+		}
+		for _, p := range f.GeometryData.LineString {
+			if math.Abs(p[0]*100-ele) > 1e-6 {
+				t.Fatalf("等高线 %v 坐标错误: %v", ele, p)
+			}
+		}
+	}
+	if majors != 3 {
+		t.Errorf("计曲线数量错误: %d", majors)
+	}
+
+	query.TileId = [3]int{0, 0, 2}
+	src, _ = source.GetMap(query)
+	if n := len(src.GetTile().(vector.Vector)[DefaultContourLayerName]); n != 2 {
+		t.Errorf("低级别使用大等距, 等高线数量错误: %d", n)
+	}
+}
+
+// crossing 返回等高线与经线 lon 的交点纬度
+func crossing(f *geom.Feature, lon float64) (float64, bool) {
+	ls := f.GeometryData.LineString
+	for i := 1; i < len(ls); i++ {
+		a, b := ls[i-1], ls[i]
+		if (a[0]-lon)*(b[0]-lon) > 0 || a[0] == b[0] {
+			continue
+		}
+		t := (lon - a[0]) / (b[0] - a[0])
+		return a[1] + t*(b[1]-a[1]), true
+	}
+	return 0, false
+}
+
+func TestContourSourceCacheSeamless(t *testing.T) {
+	dem := newDemLayer(func(x, y float64) float64 { return y/20 + math.Sin(x/1500)*40 })
+	source := NewContourSource(newDemCache(t, dem), map[int]float64{0: 25}, nil)
+
+	grid := geo.NewTileGrid(geo.DefaultTileGridOptions())
+	contours := func(coord [3]int) []*geom.Feature {
+		src, err := source.GetMap(&layer.MapQuery{BBox: grid.TileBBox(coord, false), Size: [2]uint32{16, 16}, Srs: geo.NewProj(3857), TileId: coord})
+		if err != nil {
+			t.Fatalf("GetMap 失败: %v", err)
+		}
+		return src.GetTile().(vector.Vector)[DefaultContourLayerName]
+	}
+	// 两个瓦片在经度0处相接，外扩的等高线应在接边处重合
+	left, right := contours([3]int{2047, 2047, 12}), contours([3]int{2048, 2047, 12})
+
+	matched := 0
+	for _, lf := range left {
+		llat, ok := crossing(lf, 0)
+		if !ok {
+			continue
+		}
+		found := false
+		for _, rf := range right {
+			if rf.Properties["ele"] != lf.Properties["ele"] {
+				continue
+			}
+			if rlat, ok := crossing(rf, 0); ok && math.Abs(rlat-llat) < 1e-9 {
+				found = true
+				break
+			}
+		}
+		if !found {
+			t.Errorf("高程 %v 的等高线在瓦片接边处不连续: %v", lf.Properties["ele"], llat)
+		}
+		matched++
+	}
+	if matched == 0 {
+		t.Fatal("没有跨越瓦片接边的等高线")
+	}
+}
+
+func TestContourSourceUnbuffered(t *testing.T) {
+	dem := &unbufferedLayer{newDemLayer(func(x, y float64) float64 { return x * 100 })}
+	source := NewContourSource(dem, map[int]float64{0: 100}, nil)
+	query := &layer.MapQuery{
+		BBox:   vec2d.Rect{Min: vec2d.T{0, 0}, Max: vec2d.T{1, 1}},
+		Size:   [2]uint32{32, 32},
+		Srs:    geo.NewProj(4326),
+		TileId: [3]int{0, 0, 12},
+	}
+	if _, err := source.GetMap(query); err == nil {
+		t.Fatal("返回大小与外扩请求不一致时应返回错误")
+	}
+	if len(dem.queries) != 1 {
+		t.Errorf("返回大小不一致时不应重新请求, 请求次数: %d", len(dem.queries))
+	}
+}
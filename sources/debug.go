@@ -12,39 +12,57 @@ import (
 )
 
 type DebugSource struct {
-	SupportMetaTiles bool
-	ResRange         *geo.ResolutionRange
-	Coverage         geo.Coverage
-	Extent           *geo.MapExtent
+	layer.MapLayer
+	GridName string
 }
 
 func NewDebugSource() *DebugSource {
-	return &DebugSource{Extent: geo.MapExtentFromDefault(), SupportMetaTiles: true}
-}
-
-func (s *DebugSource) GetCoverage() geo.Coverage {
-	return s.Coverage
+	return &DebugSource{
+		MapLayer: layer.MapLayer{
+			Extent:           geo.MapExtentFromDefault(),
+			SupportMetaTiles: true,
+			Options:          &imagery.ImageOptions{Transparent: geo.NewBool(true)},
+		},
+	}
 }
 
-func (s *DebugSource) GetMap(query *layer.MapQuery) tile.Source {
+func (s *DebugSource) GetMap(query *layer.MapQuery) (tile.Source, error) {
 	bbox := query.BBox
 	w := bbox.Max[0] - bbox.Min[0]
 	h := bbox.Max[1] - bbox.Min[1]
 	res_x := w / float64(query.Size[0])
 	res_y := h / float64(query.Size[1])
-	debug_info := fmt.Sprintf("bbox: {Min: {%.8f, %.8f}, Max: {%.8f, %.8f}} \nres: %.8f(%.8f)", bbox.Min[0], bbox.Min[1], bbox.Max[0], bbox.Max[1], res_x, res_y)
-	return imagery.GenMessageImage(debug_info, query.Size, &imagery.ImageOptions{Transparent: geo.NewBool(true)})
+	debug_info := ""
+	// 只有来自瓦片缓存的请求才会设置MetaSize，WMS请求没有瓦片号
+	if query.TileId != [3]int{} || query.MetaSize != [2]uint32{} {
+		debug_info = fmt.Sprintf("z/x/y: %d/%d/%d\n", query.TileId[2], query.TileId[0], query.TileId[1])
+	}
+	if s.GridName != "" {
+		debug_info += fmt.Sprintf("grid: %s\n", s.GridName)
+	}
+	if query.Srs != nil {
+		debug_info += fmt.Sprintf("srs: %s\n", query.Srs.GetSrsCode())
+	}
+	debug_info += fmt.Sprintf("bbox: {Min: {%.8f, %.8f}, Max: {%.8f, %.8f}} \nres: %.8f(%.8f)", bbox.Min[0], bbox.Min[1], bbox.Max[0], bbox.Max[1], res_x, res_y)
+	src := imagery.GenMessageImage(debug_info, query.Size, &imagery.ImageOptions{Transparent: geo.NewBool(true)})
+	if src == nil {
+		return nil, fmt.Errorf("failed to render debug tile")
+	}
+	return src, nil
 }
 
 type DummySource struct {
-	SupportMetaTiles bool
-	ResRange         *geo.ResolutionRange
-	Coverage         geo.Coverage
-	Extent           *geo.MapExtent
+	layer.MapLayer
 }
 
 func NewDummySource(cov geo.Coverage) *DummySource {
-	ds := &DummySource{Coverage: cov, SupportMetaTiles: true}
+	ds := &DummySource{
+		MapLayer: layer.MapLayer{
+			Coverage:         cov,
+			SupportMetaTiles: true,
+			Options:          &imagery.ImageOptions{Transparent: geo.NewBool(true)},
+		},
+	}
 	ds.Extent = &geo.MapExtent{
 		BBox: vec2d.Rect{Min: vec2d.T{-180, -90}, Max: vec2d.T{180, 90}},
 		Srs:  geo.NewProj(4326),
@@ -55,10 +73,6 @@ func NewDummySource(cov geo.Coverage) *DummySource {
 	return ds
 }
 
-func (s *DummySource) GetCoverage() geo.Coverage {
-	return s.Coverage
-}
-
-func (s *DummySource) GetMap(query *layer.MapQuery) tile.Source {
-	return imagery.NewBlankImageSource(query.Size, &imagery.ImageOptions{Transparent: geo.NewBool(true)}, nil)
+func (s *DummySource) GetMap(query *layer.MapQuery) (tile.Source, error) {
+	return imagery.NewBlankImageSource(query.Size, &imagery.ImageOptions{Transparent: geo.NewBool(true)}, nil), nil
 }
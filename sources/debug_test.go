@@ -0,0 +1,55 @@
+package sources
+
+import (
+	"testing"
+
+	vec2d "github.com/flywave/go3d/float64/vec2"
+
+	"github.com/flywave/go-geo"
+	"github.com/flywave/go-tileproxy/layer"
+	"github.com/flywave/go-tileproxy/tile"
+)
+
+func TestDebugSource(t *testing.T) {
+	source := NewDebugSource()
+	source.GridName = "global_webmercator"
+
+	query := &layer.MapQuery{
+		BBox:   vec2d.Rect{Min: vec2d.T{-180, -90}, Max: vec2d.T{0, 90}},
+		Size:   [2]uint32{256, 256},
+		Srs:    geo.NewProj(4326),
+		TileId: [3]int{0, 0, 1},
+	}
+
+	src, err := source.GetMap(query)
+	if err != nil {
+		t.Fatalf("GetMap 失败: %v", err)
+	}
+	if src == nil {
+		t.Fatal("debug瓦片不应为空")
+	}
+	if src.GetType() != tile.TILE_IMAGERY {
+		t.Fatalf("debug瓦片应为影像瓦片, 实际为 %v", src.GetType())
+	}
+	if size := src.GetSize(); size != [2]uint32{256, 256} {
+		t.Fatalf("debug瓦片大小错误: %v", size)
+	}
+}
+
+func TestDummySource(t *testing.T) {
+	source := NewDummySource(nil)
+
+	query := &layer.MapQuery{
+		BBox: vec2d.Rect{Min: vec2d.T{-180, -90}, Max: vec2d.T{180, 90}},
+		Size: [2]uint32{64, 32},
+		Srs:  geo.NewProj(4326),
+	}
+
+	src, err := source.GetMap(query)
+	if err != nil {
+		t.Fatalf("GetMap 失败: %v", err)
+	}
+	if size := src.GetSize(); size != [2]uint32{64, 32} {
+		t.Fatalf("空白瓦片大小错误: %v", size)
+	}
+}
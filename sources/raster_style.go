@@ -0,0 +1,143 @@
+package sources
+
+import (
+	"errors"
+	"fmt"
+
+	vec2d "github.com/flywave/go3d/float64/vec2"
+
+	"github.com/flywave/go-geo"
+	"github.com/flywave/go-tileproxy/imagery"
+	"github.com/flywave/go-tileproxy/layer"
+	"github.com/flywave/go-tileproxy/terrain"
+	"github.com/flywave/go-tileproxy/tile"
+)
+
+type rasterDataSource interface {
+	GetTileData() *terrain.TileData
+}
+
+// RasterStyleSource 从高程数据源读取高程并按色带渲染为影像，可作为影像缓存的数据源
+type RasterStyleSource struct {
+	layer.MapLayer
+	Source layer.Layer
+	Style  *terrain.RasterStyle
+}
+
+func NewRasterStyleSource(src layer.Layer, style *terrain.RasterStyle, opts *imagery.ImageOptions) *RasterStyleSource {
+	if opts == nil {
+		opts = &imagery.ImageOptions{Format: "image/png", Transparent: geo.NewBool(true)}
+	}
+	ret := &RasterStyleSource{
+		MapLayer: layer.MapLayer{
+			SupportMetaTiles: true,
+			Extent:           geo.MapExtentFromDefault(),
+			Options:          opts,
+		},
+		Source: src,
+		Style:  style,
+	}
+	if ext := src.GetExtent(); ext != nil {
+		ret.Extent = ext
+	}
+	ret.ResRange = src.GetResolutionRange()
+	return ret
+}
+
+func getRasterData(src layer.Layer, query *layer.MapQuery) (*terrain.TileData, error) {
+	q := *query
+	resp, err := src.GetMap(&q)
+	if err != nil {
+		return nil, err
+	}
+	raster, ok := resp.(rasterDataSource)
+	if !ok {
+		return nil, errors.New("source does not provide raster data")
+	}
+	data := raster.GetTileData()
+	if data == nil {
+		return nil, errors.New("raster data is empty")
+	}
+	return data, nil
+}
+
+func (s *RasterStyleSource) GetMap(query *layer.MapQuery) (tile.Source, error) {
+	if err := s.CheckResRange(query); err != nil {
+		return nil, err
+	}
+	data, err := getRasterData(s.Source, query)
+	if err != nil {
+		return nil, err
+	}
+	return s.Style.Render(data, query.Size, s.Options.(*imagery.ImageOptions)), nil
+}
+
+// RasterShadeSource 从高程数据源生成晕渲、坡度或坡向影像，
+// 请求时向外扩展一个像素，使瓦片边缘使用相邻瓦片的高程计算，避免接缝
+type RasterShadeSource struct {
+	layer.MapLayer
+	Source layer.Layer
+	Shader *terrain.Shader
+}
+
+func NewRasterShadeSource(src layer.Layer, shader *terrain.Shader, opts *imagery.ImageOptions) *RasterShadeSource {
+	if opts == nil {
+		opts = &imagery.ImageOptions{Format: "image/png", Transparent: geo.NewBool(true)}
+	}
+	ret := &RasterShadeSource{
+		MapLayer: layer.MapLayer{
+			SupportMetaTiles: true,
+			Extent:           geo.MapExtentFromDefault(),
+			Options:          opts,
+		},
+		Source: src,
+		Shader: shader,
+	}
+	if ext := src.GetExtent(); ext != nil {
+		ret.Extent = ext
+	}
+	ret.ResRange = src.GetResolutionRange()
+	return ret
+}
+
+// bufferedQuery 按像素向外扩展请求范围
+func bufferedQuery(query *layer.MapQuery, buffer int) *layer.MapQuery {
+	resx := (query.BBox.Max[0] - query.BBox.Min[0]) / float64(query.Size[0])
+	resy := (query.BBox.Max[1] - query.BBox.Min[1]) / float64(query.Size[1])
+	q := *query
+	q.BBox = vec2d.Rect{
+		Min: vec2d.T{query.BBox.Min[0] - resx*float64(buffer), query.BBox.Min[1] - resy*float64(buffer)},
+		Max: vec2d.T{query.BBox.Max[0] + resx*float64(buffer), query.BBox.Max[1] + resy*float64(buffer)},
+	}
+	q.Size = [2]uint32{query.Size[0] + uint32(2*buffer), query.Size[1] + uint32(2*buffer)}
+	return &q
+}
+
+// getBufferedRasterData 请求向外扩展 buffer 个像素的高程，使瓦片边缘使用相邻瓦片的高程计算。
+// 超出网格范围等无法扩展的情况退回原范围，返回实际扩展的像素数；
+// 数据源返回的大小与请求不一致时返回错误，不以边缘像素伪造相邻瓦片的高程
+func getBufferedRasterData(src layer.Layer, query *layer.MapQuery, buffer int) (*terrain.TileData, int, error) {
+	q := bufferedQuery(query, buffer)
+	data, err := getRasterData(src, q)
+	if err != nil {
+		q, buffer = query, 0
+		if data, err = getRasterData(src, q); err != nil {
+			return nil, 0, err
+		}
+	}
+	if data.Size != q.Size {
+		return nil, 0, fmt.Errorf("elevation source returned %dx%d for a %dx%d query", data.Size[0], data.Size[1], q.Size[0], q.Size[1])
+	}
+	return data, buffer, nil
+}
+
+func (s *RasterShadeSource) GetMap(query *layer.MapQuery) (tile.Source, error) {
+	if err := s.CheckResRange(query); err != nil {
+		return nil, err
+	}
+	data, buffer, err := getBufferedRasterData(s.Source, query, 1)
+	if err != nil {
+		return nil, err
+	}
+	return s.Shader.Render(data, buffer, query.Size, s.Options.(*imagery.ImageOptions)), nil
+}
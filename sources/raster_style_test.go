@@ -0,0 +1,214 @@
+package sources
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"testing"
+
+	vec2d "github.com/flywave/go3d/float64/vec2"
+
+	"github.com/flywave/go-geo"
+	"github.com/flywave/go-tileproxy/cache"
+	"github.com/flywave/go-tileproxy/imagery"
+	"github.com/flywave/go-tileproxy/layer"
+	"github.com/flywave/go-tileproxy/terrain"
+	"github.com/flywave/go-tileproxy/tile"
+)
+
+// demLayer 按函数生成高程数据的测试图层
+type demLayer struct {
+	layer.MapLayer
+	height  func(x, y float64) float64
+	nodata  float64
+	queries []*layer.MapQuery
+}
+
+func newDemLayer(height func(x, y float64) float64) *demLayer {
+	return &demLayer{
+		MapLayer: layer.MapLayer{Options: &terrain.RasterOptions{Format: "image/tiff", Mode: terrain.BORDER_NONE}},
+		height:   height,
+		nodata:   -9999,
+	}
+}
+
+func (l *demLayer) GetMap(query *layer.MapQuery) (tile.Source, error) {
+	l.queries = append(l.queries, query)
+	td := terrain.NewTileData(query.Size, terrain.BORDER_NONE)
+	td.NoData = l.nodata
+	td.Box = query.BBox
+	td.Boxsrs = query.Srs
+	resx := (query.BBox.Max[0] - query.BBox.Min[0]) / float64(query.Size[0])
+	resy := (query.BBox.Max[1] - query.BBox.Min[1]) / float64(query.Size[1])
+	for y := 0; y < int(query.Size[1]); y++ {
+		for x := 0; x < int(query.Size[0]); x++ {
+			gx := query.BBox.Min[0] + (float64(x)+0.5)*resx
+			gy := query.BBox.Max[1] - (float64(y)+0.5)*resy
+			td.Set(x, y, l.height(gx, gy))
+		}
+	}
+	return terrain.CreateRasterSourceFromTileData(td, l.Options.(*terrain.RasterOptions), nil), nil
+}
+
+func TestRasterStyleSource(t *testing.T) {
+	dem := newDemLayer(func(x, y float64) float64 {
+		if x < 0 {
+			return -9999
+		}
+		return x * 10
+	})
+	ramp := imagery.NewColorRamp([]imagery.ColorStop{
+		{Value: 0, Color: color.NRGBA{R: 255, A: 255}},
+		{Value: 100, Color: color.NRGBA{B: 255, A: 255}},
+	}, true)
+	style := terrain.NewRasterStyle(ramp)
+	style.Opacity = 0.5
+	source := NewRasterStyleSource(dem, style, nil)
+
+	query := &layer.MapQuery{
+		BBox: vec2d.Rect{Min: vec2d.T{-10, 0}, Max: vec2d.T{30, 10}},
+		Size: [2]uint32{4, 1},
+		Srs:  geo.NewProj(4326),
+	}
+	src, err := source.GetMap(query)
+	if err != nil {
+		t.Fatalf("GetMap 失败: %v", err)
+	}
+	if src.GetType() != tile.TILE_IMAGERY {
+		t.Fatalf("渲染结果应为影像瓦片, 实际为 %v", src.GetType())
+	}
+	img := src.GetTile().(image.Image)
+
+	want := []color.NRGBA{{}, {R: 255, A: 128}, {B: 255, A: 128}, {B: 255, A: 128}}
+	for x, w := range want {
+		if c := img.(*image.NRGBA).NRGBAAt(x, 0); c != w {
+			t.Errorf("像素 %d 颜色错误: %v, 期望 %v", x, c, w)
+		}
+	}
+}
+
+func TestRasterShadeSourceSeamless(t *testing.T) {
+	dem := newDemLayer(func(x, y float64) float64 { return x*x/10 + y*3 })
+	source := NewRasterShadeSource(dem, terrain.NewShader(terrain.SHADE_HILLSHADE), nil)
+	srs := geo.NewProj(3857)
+
+	whole, err := source.GetMap(&layer.MapQuery{BBox: vec2d.Rect{Min: vec2d.T{0, 0}, Max: vec2d.T{16, 8}}, Size: [2]uint32{16, 8}, Srs: srs})
+	if err != nil {
+		t.Fatalf("GetMap 失败: %v", err)
+	}
+	right, err := source.GetMap(&layer.MapQuery{BBox: vec2d.Rect{Min: vec2d.T{8, 0}, Max: vec2d.T{16, 8}}, Size: [2]uint32{8, 8}, Srs: srs})
+	if err != nil {
+		t.Fatalf("GetMap 失败: %v", err)
+	}
+
+	last := dem.queries[len(dem.queries)-1]
+	if last.Size != [2]uint32{10, 10} || last.BBox.Min[0] != 7 || last.BBox.Max[1] != 9 {
+		t.Fatalf("高程请求应外扩一个像素: %v %v", last.BBox, last.Size)
+	}
+
+	wimg := whole.GetTile().(*image.NRGBA)
+	rimg := right.GetTile().(*image.NRGBA)
+	for y := 0; y < 8; y++ {
+		if a, b := wimg.NRGBAAt(8, y), rimg.NRGBAAt(0, y); a != b {
+			t.Fatalf("瓦片边缘第 %d 行不连续: %v != %v", y, a, b)
+		}
+	}
+}
+
+// unbufferedLayer 忽略外扩，始终按原瓦片大小返回高程
+type unbufferedLayer struct {
+	*demLayer
+}
+
+func (l *unbufferedLayer) GetMap(query *layer.MapQuery) (tile.Source, error) {
+	resx := (query.BBox.Max[0] - query.BBox.Min[0]) / float64(query.Size[0])
+	resy := (query.BBox.Max[1] - query.BBox.Min[1]) / float64(query.Size[1])
+	q := *query
+	q.BBox = vec2d.Rect{
+		Min: vec2d.T{query.BBox.Min[0] + resx, query.BBox.Min[1] + resy},
+		Max: vec2d.T{query.BBox.Max[0] - resx, query.BBox.Max[1] - resy},
+	}
+	q.Size = [2]uint32{query.Size[0] - 2, query.Size[1] - 2}
+	return l.demLayer.GetMap(&q)
+}
+
+func TestRasterShadeSourceUnbuffered(t *testing.T) {
+	dem := &unbufferedLayer{newDemLayer(func(x, y float64) float64 { return x*x/10 + y*3 })}
+	source := NewRasterShadeSource(dem, terrain.NewShader(terrain.SHADE_HILLSHADE), nil)
+
+	_, err := source.GetMap(&layer.MapQuery{BBox: vec2d.Rect{Min: vec2d.T{0, 0}, Max: vec2d.T{8, 8}}, Size: [2]uint32{8, 8}, Srs: geo.NewProj(3857)})
+	if err == nil {
+		t.Fatal("返回大小与外扩请求不一致时应返回错误")
+	}
+	if len(dem.queries) != 1 {
+		t.Errorf("返回大小不一致时不应重新请求, 请求次数: %d", len(dem.queries))
+	}
+}
+
+// newDemCache 以高程图层为数据源创建瓦片大小为16的高程缓存，按瓦片读取
+func newDemCache(t *testing.T, dem *demLayer) layer.Layer {
+	opts := geo.DefaultTileGridOptions()
+	opts[geo.TILEGRID_SRS] = "EPSG:3857"
+	opts[geo.TILEGRID_TILE_SIZE] = []uint32{16, 16}
+	grid := geo.NewTileGrid(opts)
+	rasterOpts := dem.Options.(*terrain.RasterOptions)
+
+	c := cache.NewLocalCache(t.TempDir(), "tms", cache.GetSourceCreater(rasterOpts))
+	manager := cache.NewTileManager(&cache.TileManagerOptions{
+		Sources:      []layer.Layer{dem},
+		Grid:         grid,
+		Cache:        c,
+		Locker:       &cache.DummyTileLocker{},
+		Identifier:   "dem",
+		Format:       "tiff",
+		Options:      rasterOpts,
+		RescaleTiles: -1,
+		MetaBuffer:   -1,
+		MetaSize:     [2]uint32{1, 1},
+	})
+	return cache.NewCacheSource(manager, nil, rasterOpts, nil, true, nil, nil, nil)
+}
+
+func TestRasterShadeSourceCacheSeamless(t *testing.T) {
+	dem := newDemLayer(func(x, y float64) float64 { return math.Sin(x/2000)*300 + math.Cos(y/1500)*200 })
+	source := NewRasterShadeSource(newDemCache(t, dem), terrain.NewShader(terrain.SHADE_HILLSHADE), nil)
+
+	// 第12级相邻的两个瓦片
+	grid := geo.NewTileGrid(geo.DefaultTileGridOptions())
+	left, right := [3]int{2047, 2047, 12}, [3]int{2048, 2047, 12}
+	render := func(coord [3]int) *image.NRGBA {
+		bbox := grid.TileBBox(coord, false)
+		src, err := source.GetMap(&layer.MapQuery{BBox: bbox, Size: [2]uint32{16, 16}, Srs: geo.NewProj(3857), TileId: coord})
+		if err != nil {
+			t.Fatalf("GetMap 失败: %v", err)
+		}
+		return src.GetTile().(*image.NRGBA)
+	}
+	limg, rimg := render(left), render(right)
+
+	// 外扩请求跨越缓存中的多个瓦片，瓦片边缘的坡度应与跨越两个瓦片的整体渲染一致
+	lbox, rbox := grid.TileBBox(left, false), grid.TileBBox(right, false)
+	whole, err := source.GetMap(&layer.MapQuery{BBox: vec2d.Rect{Min: lbox.Min, Max: rbox.Max}, Size: [2]uint32{32, 16}, Srs: geo.NewProj(3857), TileId: left})
+	if err != nil {
+		t.Fatalf("GetMap 失败: %v", err)
+	}
+	wimg := whole.GetTile().(*image.NRGBA)
+	for y := 0; y < 16; y++ {
+		if a, b := wimg.NRGBAAt(15, y), limg.NRGBAAt(15, y); !similarColor(a, b) {
+			t.Fatalf("左侧瓦片右边缘第 %d 行不连续: %v != %v", y, a, b)
+		}
+		if a, b := wimg.NRGBAAt(16, y), rimg.NRGBAAt(0, y); !similarColor(a, b) {
+			t.Fatalf("右侧瓦片左边缘第 %d 行不连续: %v != %v", y, a, b)
+		}
+	}
+}
+
+func similarColor(a, b color.NRGBA) bool {
+	d := func(x, y uint8) int {
+		if x > y {
+			return int(x - y)
+		}
+		return int(y - x)
+	}
+	return d(a.R, b.R) <= 1 && d(a.G, b.G) <= 1 && d(a.B, b.B) <= 1 && d(a.A, b.A) <= 1
+}
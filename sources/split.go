@@ -0,0 +1,134 @@
+package sources
+
+import (
+	"bytes"
+	"errors"
+	"sync"
+
+	vec2d "github.com/flywave/go3d/float64/vec2"
+
+	"github.com/flywave/go-tileproxy/imagery"
+	"github.com/flywave/go-tileproxy/layer"
+	"github.com/flywave/go-tileproxy/terrain"
+	"github.com/flywave/go-tileproxy/tile"
+)
+
+// exceedsMaxSize 判断请求尺寸是否超过上游服务允许的最大宽高，为0表示不限制
+func (s *WMSSource) exceedsMaxSize(size [2]uint32) bool {
+	return (s.MaxSize[0] > 0 && size[0] > s.MaxSize[0]) || (s.MaxSize[1] > 0 && size[1] > s.MaxSize[1])
+}
+
+// splitQuery 将请求按最大尺寸拆分为等大的子请求，按行优先自上而下排列，
+// 子请求保持原分辨率，右侧与下方可能超出原请求范围
+func splitQuery(query *layer.MapQuery, maxSize [2]uint32) ([2]int, [2]uint32, []*layer.MapQuery) {
+	grid := [2]int{1, 1}
+	tileSize := query.Size
+	for i := 0; i < 2; i++ {
+		if maxSize[i] > 0 && query.Size[i] > maxSize[i] {
+			grid[i] = int((query.Size[i] + maxSize[i] - 1) / maxSize[i])
+			tileSize[i] = (query.Size[i] + uint32(grid[i]) - 1) / uint32(grid[i])
+		}
+	}
+
+	resx := (query.BBox.Max[0] - query.BBox.Min[0]) / float64(query.Size[0])
+	resy := (query.BBox.Max[1] - query.BBox.Min[1]) / float64(query.Size[1])
+	width := resx * float64(tileSize[0])
+	height := resy * float64(tileSize[1])
+
+	queries := make([]*layer.MapQuery, 0, grid[0]*grid[1])
+	for row := 0; row < grid[1]; row++ {
+		for col := 0; col < grid[0]; col++ {
+			minx := query.BBox.Min[0] + float64(col)*width
+			maxy := query.BBox.Max[1] - float64(row)*height
+			sub := *query
+			sub.BBox = vec2d.Rect{Min: vec2d.T{minx, maxy - height}, Max: vec2d.T{minx + width, maxy}}
+			sub.Size = tileSize
+			queries = append(queries, &sub)
+		}
+	}
+	return grid, tileSize, queries
+}
+
+// retrieveSplit 并发请求拆分后的子请求，拼接后裁剪为原请求大小
+func (s *WMSSource) retrieveSplit(query *layer.MapQuery, format tile.TileFormat) (tile.Source, error) {
+	grid, tileSize, queries := splitQuery(query, s.MaxSize)
+
+	tiles := make([]tile.Source, len(queries))
+	errs := make([]error, len(queries))
+	var wg sync.WaitGroup
+	for i := range queries {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			tiles[i], errs[i] = s.retrieveOne(queries[i], format)
+		}(i)
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	first, last := queries[0].BBox, queries[len(queries)-1].BBox
+	bbox := vec2d.Rect{Min: vec2d.T{first.Min[0], last.Min[1]}, Max: vec2d.T{last.Max[0], first.Max[1]}}
+
+	var merged tile.Source
+	switch opts := s.Options.(type) {
+	case *terrain.RasterOptions:
+		m := terrain.NewRasterMerger(grid, tileSize)
+		m.BBox = bbox
+		m.BBoxSrs = query.Srs
+		merged = m.Merge(tiles, opts)
+	default:
+		merged = imagery.NewTileMerger(grid, tileSize).Merge(tiles, s.Options.(*imagery.ImageOptions))
+	}
+
+	if merged.GetSize() == query.Size {
+		return merged, nil
+	}
+	return s.cropSource(merged, [2]int{0, 0}, query.Size), nil
+}
+
+// createSource 解码上游返回的数据，返回为空或无法解码（如 ServiceException）时返回错误
+func (s *WMSSource) createSource(size [2]uint32, resp []byte) (tile.Source, error) {
+	if len(resp) == 0 {
+		return nil, errors.New("upstream returned no data")
+	}
+	switch opts := s.Options.(type) {
+	case *terrain.RasterOptions:
+		src := terrain.CreateRasterSourceFromBufer(resp, opts)
+		if src == nil {
+			return nil, errors.New("upstream response is not a valid raster")
+		}
+		if data, ok := src.GetTile().(*terrain.TileData); src.GetTile() == nil || (ok && data == nil) {
+			return nil, errors.New("upstream response is not a valid raster")
+		}
+		return src, nil
+	default:
+		src := imagery.CreateImageSource(size, s.Options.(*imagery.ImageOptions))
+		src.SetSource(bytes.NewBuffer(resp))
+		if src.GetImage() == nil {
+			return nil, errors.New("upstream response is not a valid image")
+		}
+		return src, nil
+	}
+}
+
+func (s *WMSSource) cropSource(src tile.Source, offset [2]int, size [2]uint32) tile.Source {
+	switch opts := s.Options.(type) {
+	case *terrain.RasterOptions:
+		return terrain.NewRasterSplitter(src, opts).GetSplitTile(offset, size)
+	default:
+		return imagery.NewTileSplitter(src, s.Options.(*imagery.ImageOptions)).GetTile(offset, size)
+	}
+}
+
+func (s *WMSSource) blankSource(size [2]uint32) tile.Source {
+	switch opts := s.Options.(type) {
+	case *terrain.RasterOptions:
+		return terrain.NewBlankRasterSource(size, opts, nil)
+	default:
+		return imagery.NewBlankImageSource(size, s.Options.(*imagery.ImageOptions), nil)
+	}
+}
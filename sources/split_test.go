@@ -0,0 +1,182 @@
+package sources
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	vec2d "github.com/flywave/go3d/float64/vec2"
+
+	"github.com/flywave/go-geo"
+	"github.com/flywave/go-tileproxy/client"
+	"github.com/flywave/go-tileproxy/imagery"
+	"github.com/flywave/go-tileproxy/layer"
+	"github.com/flywave/go-tileproxy/request"
+	"github.com/flywave/go-tileproxy/tile"
+)
+
+func TestSplitQuery(t *testing.T) {
+	query := &layer.MapQuery{
+		BBox: vec2d.Rect{Min: vec2d.T{0, 0}, Max: vec2d.T{250, 100}},
+		Size: [2]uint32{250, 100},
+		Srs:  geo.NewProj(4326),
+	}
+	grid, tileSize, queries := splitQuery(query, [2]uint32{100, 100})
+
+	if grid != [2]int{3, 1} {
+		t.Fatalf("拆分网格错误: %v", grid)
+	}
+	if tileSize != [2]uint32{84, 100} {
+		t.Fatalf("子请求大小错误: %v", tileSize)
+	}
+	if len(queries) != 3 {
+		t.Fatalf("子请求数量错误: %d", len(queries))
+	}
+	if queries[1].BBox.Min[0] != 84 || queries[2].BBox.Max[0] != 252 {
+		t.Errorf("子请求范围错误: %v %v", queries[1].BBox, queries[2].BBox)
+	}
+	if queries[0].BBox.Max[1] != 100 || queries[0].BBox.Min[1] != 0 {
+		t.Errorf("子请求纵向范围错误: %v", queries[0].BBox)
+	}
+}
+
+func TestWMSSourceSplitRequest(t *testing.T) {
+	rgba := image.NewRGBA(image.Rect(0, 0, 84, 100))
+	for y := 0; y < 100; y++ {
+		for x := 0; x < 84; x++ {
+			rgba.SetRGBA(x, y, color.RGBA{R: 255, A: 255})
+		}
+	}
+	imagedata := &bytes.Buffer{}
+	png.Encode(imagedata, rgba)
+
+	mock := &slowClient{body: imagedata.Bytes()}
+	param := http.Header{"layers": []string{"split-layer"}, "format": []string{"image/png"}}
+	req := request.NewWMSMapRequest(param, "/service?map=split", false, nil, false)
+	wmsClient := client.NewWMSClient(req, nil, nil, &slowContext{mockContext: &mockContext{}, c: mock})
+
+	source := NewWMSSource(wmsClient, &imagery.ImageOptions{Format: tile.TileFormat("png")}, nil, nil, nil, nil, nil, nil, nil)
+	source.Coalescer = nil
+	source.MaxSize = [2]uint32{100, 100}
+
+	query := &layer.MapQuery{
+		BBox:   vec2d.Rect{Min: vec2d.T{-100, -40}, Max: vec2d.T{150, 60}},
+		Size:   [2]uint32{250, 100},
+		Srs:    geo.NewProj(4326),
+		Format: tile.TileFormat("png"),
+	}
+	src, err := source.GetMap(query)
+	if err != nil {
+		t.Fatalf("GetMap 失败: %v", err)
+	}
+	if calls := atomic.LoadInt32(&mock.calls); calls != 3 {
+		t.Fatalf("应拆分为3个上游请求, 实际: %d", calls)
+	}
+
+	img := src.GetTile().(image.Image)
+	if img.Bounds().Dx() != 250 || img.Bounds().Dy() != 100 {
+		t.Fatalf("拼接结果大小错误: %v", img.Bounds())
+	}
+	if r, _, _, a := img.At(249, 99).RGBA(); r>>8 != 255 || a>>8 != 255 {
+		t.Errorf("拼接结果像素错误: %v", img.At(249, 99))
+	}
+}
+
+func TestWMSSourceSplitRequestFailure(t *testing.T) {
+	rgba := image.NewRGBA(image.Rect(0, 0, 84, 100))
+	imagedata := &bytes.Buffer{}
+	png.Encode(imagedata, rgba)
+
+	mock := &slowClient{body: imagedata.Bytes(), failAt: 2}
+	param := http.Header{"layers": []string{"split-layer"}, "format": []string{"image/png"}}
+	req := request.NewWMSMapRequest(param, "/service?map=split-failure", false, nil, false)
+	wmsClient := client.NewWMSClient(req, nil, nil, &slowContext{mockContext: &mockContext{}, c: mock})
+
+	source := NewWMSSource(wmsClient, &imagery.ImageOptions{Format: tile.TileFormat("png")}, nil, nil, nil, nil, nil, nil, nil)
+	source.Coalescer = nil
+	source.MaxSize = [2]uint32{100, 100}
+
+	query := &layer.MapQuery{
+		BBox:   vec2d.Rect{Min: vec2d.T{-100, -40}, Max: vec2d.T{150, 60}},
+		Size:   [2]uint32{250, 100},
+		Srs:    geo.NewProj(4326),
+		Format: tile.TileFormat("png"),
+	}
+	if _, err := source.GetMap(query); err == nil {
+		t.Fatal("子请求失败时应返回错误")
+	}
+	if calls := atomic.LoadInt32(&mock.calls); calls != 3 {
+		t.Errorf("应发送3个上游请求, 实际: %d", calls)
+	}
+}
+
+// bboxClient 记录每个上游请求的 BBOX 参数
+type bboxClient struct {
+	slowClient
+	mu    sync.Mutex
+	bboxs []string
+}
+
+func (c *bboxClient) Open(u string, data []byte, hdr http.Header) (int, []byte) {
+	if ind := strings.Index(u, "?"); ind != -1 {
+		values, _ := url.ParseQuery(u[ind+1:])
+		c.mu.Lock()
+		c.bboxs = append(c.bboxs, values.Get("BBOX"))
+		c.mu.Unlock()
+	}
+	return c.slowClient.Open(u, data, hdr)
+}
+
+func TestWMSSourceSplitRequestBBox(t *testing.T) {
+	rgba := image.NewRGBA(image.Rect(0, 0, 84, 100))
+	imagedata := &bytes.Buffer{}
+	png.Encode(imagedata, rgba)
+
+	mock := &bboxClient{slowClient: slowClient{body: imagedata.Bytes(), delay: 10 * time.Millisecond}}
+	param := http.Header{"layers": []string{"split-layer"}, "format": []string{"image/png"}}
+	req := request.NewWMSMapRequest(param, "/service?map=split-bbox", false, nil, false)
+	wmsClient := client.NewWMSClient(req, nil, nil, &bboxContext{mockContext: &mockContext{}, c: mock})
+
+	source := NewWMSSource(wmsClient, &imagery.ImageOptions{Format: tile.TileFormat("png")}, nil, nil, nil, nil, nil, nil, nil)
+	source.Coalescer = nil
+	source.MaxSize = [2]uint32{100, 100}
+
+	query := &layer.MapQuery{
+		BBox:   vec2d.Rect{Min: vec2d.T{0, 0}, Max: vec2d.T{250, 100}},
+		Size:   [2]uint32{250, 100},
+		Srs:    geo.NewProj(3857),
+		Format: tile.TileFormat("png"),
+	}
+	for i := 0; i < 5; i++ {
+		mock.bboxs = nil
+		if _, err := source.GetMap(query); err != nil {
+			t.Fatalf("GetMap 失败: %v", err)
+		}
+		sort.Strings(mock.bboxs)
+		want := []string{"0,0,84,100", "168,0,252,100", "84,0,168,100"}
+		if strings.Join(mock.bboxs, " ") != strings.Join(want, " ") {
+			t.Fatalf("子请求 BBOX 错误: %v", mock.bboxs)
+		}
+	}
+	if bbox, ok := req.GetParams().Get("BBOX"); ok {
+		t.Errorf("请求模板不应被修改: %v", bbox)
+	}
+}
+
+type bboxContext struct {
+	*mockContext
+	c *bboxClient
+}
+
+func (c *bboxContext) Client() client.HttpClient {
+	return c.c
+}
@@ -14,6 +14,7 @@ type TileSource struct {
 	Grid          *geo.TileGrid
 	Client        *client.TileClient
 	SourceCreater tile.SourceCreater
+	Validator     *ResponseValidator
 }
 
 func NewTileSource(grid *geo.TileGrid, c *client.TileClient, coverage geo.Coverage, opts tile.TileOptions, res_range *geo.ResolutionRange, creater tile.SourceCreater) *TileSource {
@@ -35,38 +36,59 @@ func NewTileSource(grid *geo.TileGrid, c *client.TileClient, coverage geo.Covera
 }
 
 func (s *TileSource) GetMap(query *layer.MapQuery) (tile.Source, error) {
+	src, _, err := s.GetMapConditional(query, nil)
+	return src, err
+}
+
+// GetMapConditional 上游返回304时返回nil数据源且notModified为true
+func (s *TileSource) GetMapConditional(query *layer.MapQuery, info *tile.CacheInfo) (tile.Source, bool, error) {
 	if s.Grid.TileSize[0] != query.Size[0] || s.Grid.TileSize[1] != query.Size[1] {
-		return nil, errors.New("tile size of cache and tile source do not match")
+		return nil, false, errors.New("tile size of cache and tile source do not match")
 	}
 
 	if !s.Grid.Srs.Eq(query.Srs) {
-		return nil, errors.New("SRS of cache and tile source do not match")
+		return nil, false, errors.New("SRS of cache and tile source do not match")
 	}
 
 	if s.ResRange != nil && !s.ResRange.Contains(query.BBox, query.Size, query.Srs) {
-		return s.SourceCreater.CreateEmpty(query.Size, s.Options), nil
+		return s.SourceCreater.CreateEmpty(query.Size, s.Options), false, nil
 	}
 
 	if s.Coverage != nil && !s.Coverage.Intersects(query.BBox, query.Srs) {
-		return s.SourceCreater.CreateEmpty(query.Size, s.Options), nil
+		return s.SourceCreater.CreateEmpty(query.Size, s.Options), false, nil
 	}
 
 	_, grid, tiles, err := s.Grid.GetAffectedTiles(query.BBox, query.Size, nil)
 
 	if err != nil {
-		return nil, err
+		return nil, false, err
 	}
 
 	if grid != [2]int{1, 1} {
-		return nil, errors.New("BBOX does not align to tile")
+		return nil, false, errors.New("BBOX does not align to tile")
 	}
 
 	x, y, z, _ := tiles.Next()
 
-	resp := s.Client.GetTile([3]int{x, y, z}, &query.Format)
-	if resp == nil {
-		return nil, errors.New("500 error")
+	resp, err := s.Client.FetchTileConditional([3]int{x, y, z}, &query.Format, info)
+	if err != nil {
+		return nil, false, err
+	}
+	if resp.NotModified {
+		return nil, true, nil
+	}
+	if resp.Data == nil {
+		return nil, false, errors.New("500 error")
+	}
+	if s.Validator != nil {
+		if err := s.Validator.Validate(resp.Data, resp.ContentType); err != nil {
+			return nil, false, err
+		}
+	}
+	src := s.SourceCreater.Create(resp.Data, [3]int{x, y, z})
+	if cacheable := src.GetCacheable(); cacheable != nil {
+		cacheable.ETag = resp.ETag
+		cacheable.LastModified = resp.LastModified
 	}
-	src := s.SourceCreater.Create(resp, [3]int{x, y, z})
-	return src, nil
+	return src, false, nil
 }
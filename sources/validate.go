@@ -0,0 +1,162 @@
+package sources
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"image"
+	"mime"
+	"strings"
+
+	"github.com/flywave/go-tileproxy/imagery"
+	"github.com/flywave/go-tileproxy/metrics"
+)
+
+const (
+	ValidationRuleContentType    = "content_type"
+	ValidationRuleMagic          = "magic"
+	ValidationRuleDecode         = "decode"
+	ValidationRuleHash           = "hash"
+	ValidationRulePerceptualHash = "perceptual_hash"
+)
+
+// ValidationError 上游返回200但内容无效，视为暂时性错误，不写入缓存
+type ValidationError struct {
+	Source string
+	Rule   string
+	Reason string
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("invalid upstream response from %s (%s): %s", e.Source, e.Rule, e.Reason)
+}
+
+func (e *ValidationError) Temporary() bool {
+	return true
+}
+
+// ResponseValidator 按规则校验上游瓦片，未配置的规则不生效
+type ResponseValidator struct {
+	Name         string
+	ContentTypes []string
+	Formats      []string
+	Decode       bool
+	BadHashes    map[string]struct{}
+	BadPHashes   []uint64
+	PHashMaxDist int
+}
+
+func NewResponseValidator(name string) *ResponseValidator {
+	return &ResponseValidator{Name: name, BadHashes: make(map[string]struct{})}
+}
+
+func (v *ResponseValidator) AddBadHash(hash string) {
+	v.BadHashes[strings.ToLower(hash)] = struct{}{}
+}
+
+// AddBadTile 登记一个已知的错误瓦片，同时记录其SHA256与感知哈希，纯色瓦片只按SHA256匹配
+func (v *ResponseValidator) AddBadTile(data []byte) {
+	v.AddBadHash(TileHash(data))
+	if img, _, err := image.Decode(bytes.NewReader(data)); err == nil {
+		if h := imagery.AverageHash(img); !imagery.DegenerateHash(h) {
+			v.BadPHashes = append(v.BadPHashes, h)
+		}
+	}
+}
+
+func TileHash(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func (v *ResponseValidator) Validate(data []byte, contentType string) error {
+	if err := v.validate(data, contentType); err != nil {
+		metrics.RecordValidationFailure(v.Name, err.Rule)
+		return err
+	}
+	return nil
+}
+
+func (v *ResponseValidator) validate(data []byte, contentType string) *ValidationError {
+	if len(v.ContentTypes) > 0 && contentType != "" {
+		mt, _, err := mime.ParseMediaType(contentType)
+		if err != nil || !matchContentType(mt, v.ContentTypes) {
+			return v.fail(ValidationRuleContentType, "unexpected content type "+contentType)
+		}
+	}
+
+	format := imagery.PeekImageFormat(string(data))
+	if len(v.Formats) > 0 && !matchFormat(format, v.Formats) {
+		if format == "" {
+			format = "unknown"
+		}
+		return v.fail(ValidationRuleMagic, "unexpected format "+format)
+	}
+
+	if len(v.BadHashes) > 0 {
+		if _, ok := v.BadHashes[TileHash(data)]; ok {
+			return v.fail(ValidationRuleHash, "known bad tile")
+		}
+	}
+
+	if !v.Decode && len(v.BadPHashes) == 0 {
+		return nil
+	}
+	if format == "" {
+		if v.Decode {
+			return v.fail(ValidationRuleDecode, "not an image")
+		}
+		return nil
+	}
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		if v.Decode {
+			return v.fail(ValidationRuleDecode, err.Error())
+		}
+		return nil
+	}
+	if len(v.BadPHashes) > 0 {
+		h := imagery.AverageHash(img)
+		if imagery.DegenerateHash(h) {
+			return nil
+		}
+		for _, bad := range v.BadPHashes {
+			if imagery.HashDistance(h, bad) <= v.PHashMaxDist {
+				return v.fail(ValidationRulePerceptualHash, fmt.Sprintf("matches known bad tile %016x", bad))
+			}
+		}
+	}
+	return nil
+}
+
+func (v *ResponseValidator) fail(rule, reason string) *ValidationError {
+	return &ValidationError{Source: v.Name, Rule: rule, Reason: reason}
+}
+
+func matchContentType(mt string, allowed []string) bool {
+	for _, a := range allowed {
+		a = strings.ToLower(a)
+		if strings.HasSuffix(a, "/*") {
+			if strings.HasPrefix(mt, strings.TrimSuffix(a, "*")) {
+				return true
+			}
+		} else if mt == a {
+			return true
+		}
+	}
+	return false
+}
+
+func matchFormat(format string, allowed []string) bool {
+	for _, a := range allowed {
+		a = strings.TrimPrefix(strings.ToLower(a), "image/")
+		if a == "jpg" {
+			a = "jpeg"
+		}
+		if format == a {
+			return true
+		}
+	}
+	return false
+}
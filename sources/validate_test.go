@@ -0,0 +1,98 @@
+package sources
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"image/png"
+	"testing"
+
+	"github.com/flywave/go-tileproxy/metrics"
+)
+
+// encodeTestPNG 生成左半部分为指定颜色、右半部分透明的图像，vertical为true时改为上半部分
+func encodeTestPNG(c color.Color, vertical bool) []byte {
+	img := image.NewNRGBA(image.Rect(0, 0, 16, 16))
+	for y := 0; y < 16; y++ {
+		for x := 0; x < 16; x++ {
+			if (!vertical && x < 8) || (vertical && y < 8) {
+				img.Set(x, y, c)
+			}
+		}
+	}
+	buf := &bytes.Buffer{}
+	png.Encode(buf, img)
+	return buf.Bytes()
+}
+
+func TestResponseValidator(t *testing.T) {
+	metrics.ResetMetrics()
+	good := encodeTestPNG(color.NRGBA{0, 0, 255, 255}, true)
+	bad := encodeTestPNG(color.NRGBA{255, 0, 0, 255}, false)
+
+	v := NewResponseValidator("osm")
+	v.ContentTypes = []string{"image/*"}
+	v.Formats = []string{"png", "jpeg"}
+	v.Decode = true
+	v.AddBadTile(bad)
+
+	if err := v.Validate(good, "image/png"); err != nil {
+		t.Fatalf("有效瓦片不应被拒绝: %v", err)
+	}
+
+	cases := []struct {
+		data        []byte
+		contentType string
+		rule        string
+	}{
+		{[]byte("<html>error</html>"), "text/html; charset=utf-8", ValidationRuleContentType},
+		{[]byte("<html>error</html>"), "", ValidationRuleMagic},
+		{good[:20], "image/png", ValidationRuleDecode},
+		{bad, "image/png", ValidationRuleHash},
+	}
+	for _, c := range cases {
+		err := v.Validate(c.data, c.contentType)
+		var verr *ValidationError
+		if !errors.As(err, &verr) || verr.Rule != c.rule {
+			t.Errorf("期望规则 %s, 实际 %v", c.rule, err)
+			continue
+		}
+		if !verr.Temporary() {
+			t.Error("校验错误应视为暂时性错误")
+		}
+	}
+
+	// 重新编码后的错误瓦片SHA256不同，但感知哈希相同
+	reencoded := encodeTestPNG(color.NRGBA{250, 0, 0, 255}, false)
+	if err := v.Validate(reencoded, "image/png"); err == nil {
+		t.Error("感知哈希相近的瓦片应被拒绝")
+	}
+
+	// 纯色的错误瓦片只按SHA256匹配，不应拒绝其他纯色瓦片
+	nodata := image.NewNRGBA(image.Rect(0, 0, 16, 16))
+	for i := range nodata.Pix {
+		nodata.Pix[i] = 255
+	}
+	buf := &bytes.Buffer{}
+	png.Encode(buf, nodata)
+	v.AddBadTile(buf.Bytes())
+	if err := v.Validate(buf.Bytes(), "image/png"); err == nil {
+		t.Error("纯色的错误瓦片应按SHA256拒绝")
+	}
+	water := image.NewNRGBA(image.Rect(0, 0, 16, 16))
+	for i := 0; i < len(water.Pix); i += 4 {
+		copy(water.Pix[i:], []byte{170, 211, 223, 255})
+	}
+	buf.Reset()
+	png.Encode(buf, water)
+	if err := v.Validate(buf.Bytes(), "image/png"); err != nil {
+		t.Errorf("其他纯色瓦片不应被拒绝: %v", err)
+	}
+
+	stats := metrics.GetStats()["validation"].(map[string]interface{})
+	counts := stats["osm"].(map[string]int64)
+	if counts[ValidationRuleHash] != 2 || counts[ValidationRulePerceptualHash] != 1 {
+		t.Errorf("规则命中统计错误: %v", counts)
+	}
+}
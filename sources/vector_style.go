@@ -0,0 +1,56 @@
+package sources
+
+import (
+	"errors"
+
+	"github.com/flywave/go-geo"
+	"github.com/flywave/go-tileproxy/imagery"
+	"github.com/flywave/go-tileproxy/layer"
+	"github.com/flywave/go-tileproxy/tile"
+	"github.com/flywave/go-tileproxy/vector"
+)
+
+// VectorStyleSource 从矢量数据源读取要素并按样式渲染为影像，可作为影像缓存与 WMS 的数据源
+type VectorStyleSource struct {
+	layer.MapLayer
+	Source layer.Layer
+	Style  *vector.VectorStyle
+}
+
+func NewVectorStyleSource(src layer.Layer, style *vector.VectorStyle, opts *imagery.ImageOptions) *VectorStyleSource {
+	if opts == nil {
+		opts = &imagery.ImageOptions{Format: "image/png", Transparent: geo.NewBool(true)}
+	}
+	ret := &VectorStyleSource{
+		MapLayer: layer.MapLayer{
+			SupportMetaTiles: true,
+			Extent:           geo.MapExtentFromDefault(),
+			Options:          opts,
+		},
+		Source: src,
+		Style:  style,
+	}
+	if ext := src.GetExtent(); ext != nil {
+		ret.Extent = ext
+	}
+	ret.ResRange = src.GetResolutionRange()
+	return ret
+}
+
+func (s *VectorStyleSource) GetMap(query *layer.MapQuery) (tile.Source, error) {
+	if err := s.CheckResRange(query); err != nil {
+		return nil, err
+	}
+	q := *query
+	resp, err := s.Source.GetMap(&q)
+	if err != nil {
+		return nil, err
+	}
+	if resp.GetType() != tile.TILE_VECTOR {
+		return nil, errors.New("source does not provide vector data")
+	}
+	// 空瓦片没有要素，仍按背景色渲染
+	vt, _ := resp.GetTile().(vector.Vector)
+	zoom := vector.StyleZoom(query.BBox, query.Srs, query.Size)
+	return s.Style.Render(vt, query.BBox, query.Srs, query.Size, zoom, s.Options.(*imagery.ImageOptions)), nil
+}
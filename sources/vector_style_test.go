@@ -0,0 +1,62 @@
+package sources
+
+import (
+	"image"
+	"image/color"
+	"testing"
+
+	"github.com/flywave/go-geom"
+	vec2d "github.com/flywave/go3d/float64/vec2"
+
+	"github.com/flywave/go-geo"
+	"github.com/flywave/go-tileproxy/layer"
+	"github.com/flywave/go-tileproxy/tile"
+	"github.com/flywave/go-tileproxy/vector"
+)
+
+// vectorLayer 返回固定要素的测试矢量图层
+type vectorLayer struct {
+	layer.MapLayer
+	vt vector.Vector
+}
+
+func (l *vectorLayer) GetMap(query *layer.MapQuery) (tile.Source, error) {
+	return vector.CreateVectorSourceFromVector(l.vt, query.TileId, l.Options.(*vector.VectorOptions), nil), nil
+}
+
+func TestVectorStyleSource(t *testing.T) {
+	building := geom.NewPolygonFeature([][][]float64{{{0, 0}, {10, 0}, {10, 10}, {0, 10}, {0, 0}}})
+	src := &vectorLayer{
+		MapLayer: layer.MapLayer{Options: &vector.VectorOptions{Format: vector.MVT_MIME, Proto: int(vector.PBF_PTOTO_MAPBOX)}},
+		vt:       vector.Vector{"building": {building}},
+	}
+	fill := color.NRGBA{R: 200, G: 100, B: 50, A: 255}
+	style := vector.NewVectorStyle([]*vector.StyleLayer{
+		{Type: vector.STYLE_FILL, SourceLayer: "building", Paint: vector.StylePaint{FillColor: &vector.StyleColor{Value: fill}}},
+	})
+	source := NewVectorStyleSource(src, style, nil)
+
+	query := &layer.MapQuery{
+		BBox: vec2d.Rect{Min: vec2d.T{0, 0}, Max: vec2d.T{20, 10}},
+		Size: [2]uint32{20, 10},
+		Srs:  geo.NewProj(4326),
+	}
+	res, err := source.GetMap(query)
+	if err != nil {
+		t.Fatalf("GetMap 失败: %v", err)
+	}
+	if res.GetType() != tile.TILE_IMAGERY {
+		t.Fatalf("渲染结果应为影像瓦片, 实际为 %v", res.GetType())
+	}
+	img := res.GetTile().(image.Image)
+	if c := color.NRGBAModel.Convert(img.At(5, 5)).(color.NRGBA); c != fill {
+		t.Errorf("面内像素颜色为 %v, 期望 %v", c, fill)
+	}
+	if c := color.NRGBAModel.Convert(img.At(15, 5)).(color.NRGBA); c.A != 0 {
+		t.Errorf("面外像素应透明, 实际为 %v", c)
+	}
+
+	if _, err := NewVectorStyleSource(newDemLayer(func(x, y float64) float64 { return 0 }), style, nil).GetMap(query); err == nil {
+		t.Error("非矢量数据源应返回错误")
+	}
+}
@@ -1,7 +1,8 @@
 package sources
 
 import (
-	"bytes"
+	"errors"
+	"fmt"
 	"image"
 	"image/color"
 
@@ -11,6 +12,7 @@ import (
 	"github.com/flywave/go-tileproxy/layer"
 	"github.com/flywave/go-tileproxy/request"
 	"github.com/flywave/go-tileproxy/resource"
+	"github.com/flywave/go-tileproxy/terrain"
 	"github.com/flywave/go-tileproxy/tile"
 	"github.com/flywave/go-tileproxy/utils"
 )
@@ -24,6 +26,9 @@ type WMSSource struct {
 	TransparentColor          color.Color
 	TransparentColorTolerance *float64
 	Opacity                   *float64
+	Coalescer                 *RequestCoalescer
+	MaxSize                   [2]uint32
+	target                    string
 }
 
 func NewWMSSource(client client.MapClient, image_opts *imagery.ImageOptions, coverage geo.Coverage, res_range *geo.ResolutionRange,
@@ -42,6 +47,8 @@ func NewWMSSource(client client.MapClient, image_opts *imagery.ImageOptions, cov
 		SupportedSRS:              supported_srs,
 		SupportedFormats:          supported_formats,
 		ExtReqParams:              fwd_req_params,
+		Coalescer:                 DefaultRequestCoalescer,
+		target:                    requestTarget(client),
 	}
 	if transparent_color != nil {
 		image_opts.Transparent = geo.NewBool(true)
@@ -63,9 +70,7 @@ func (s *WMSSource) IsOpaque(query *layer.MapQuery) bool {
 		return false
 	}
 
-	imageOptions := s.Options.(*imagery.ImageOptions)
-
-	if imageOptions.Transparent != nil && *imageOptions.Transparent {
+	if imageOptions, ok := s.Options.(*imagery.ImageOptions); ok && imageOptions.Transparent != nil && *imageOptions.Transparent {
 		return false
 	}
 
@@ -84,25 +89,25 @@ func (s *WMSSource) IsOpaque(query *layer.MapQuery) bool {
 }
 
 func (s *WMSSource) GetMap(query *layer.MapQuery) (tile.Source, error) {
-	imageOptions := s.Options.(*imagery.ImageOptions)
-
 	if s.ResRange != nil && !s.ResRange.Contains(query.BBox, query.Size, query.Srs) {
-		return imagery.NewBlankImageSource(query.Size, imageOptions, nil), nil
+		return s.blankSource(query.Size), nil
 	}
 
 	if s.Coverage != nil && !s.Coverage.Intersects(query.BBox, query.Srs) {
-		return imagery.NewBlankImageSource(query.Size, imageOptions, nil), nil
+		return s.blankSource(query.Size), nil
+	}
+	resp, err := s.getMap(query)
+	if err != nil {
+		return nil, err
+	}
+	if opts, ok := resp.GetTileOptions().(*imagery.ImageOptions); ok {
+		opts.Opacity = s.Opacity
 	}
-	resp := s.getMap(query)
-	opts := resp.GetTileOptions().(*imagery.ImageOptions)
-	opts.Opacity = s.Opacity
 	return resp, nil
 }
 
-func (s *WMSSource) getMap(query *layer.MapQuery) tile.Source {
-	imageOptions := s.Options.(*imagery.ImageOptions)
-
-	format := imageOptions.Format
+func (s *WMSSource) getMap(query *layer.MapQuery) (tile.Source, error) {
+	format := s.Options.GetFormat()
 	if format == "" {
 		format = tile.TileFormat(query.Format)
 	}
@@ -127,29 +132,73 @@ func (s *WMSSource) getMap(query *layer.MapQuery) tile.Source {
 	if s.Extent != nil && !s.Extent.Contains(&geo.MapExtent{BBox: query.BBox, Srs: query.Srs}) {
 		return s.getSubQuery(query, format)
 	}
-	resp := s.Client.Retrieve(query, &format)
-	src := imagery.CreateImageSource(query.Size, imageOptions)
-	src.SetSource(bytes.NewBuffer(resp))
-	return src
+	return s.retrieve(query, format)
 }
 
-func (s *WMSSource) getSubQuery(query *layer.MapQuery, format tile.TileFormat) tile.Source {
-	imageOptions := s.Options.(*imagery.ImageOptions)
+// retrieve 请求上游服务，超过上游最大尺寸的请求拆分为多个子请求
+func (s *WMSSource) retrieve(query *layer.MapQuery, format tile.TileFormat) (tile.Source, error) {
+	if s.exceedsMaxSize(query.Size) {
+		return s.retrieveSplit(query, format)
+	}
+	return s.retrieveOne(query, format)
+}
 
+// retrieveOne 请求上游服务，并发的相同或被包含的请求合并为一次上游调用，
+// 上游返回错误或无法解码的数据时返回错误
+func (s *WMSSource) retrieveOne(query *layer.MapQuery, format tile.TileFormat) (tile.Source, error) {
+	if s.Coalescer == nil {
+		resp, err := s.Client.Fetch(query, &format)
+		if err != nil {
+			return nil, err
+		}
+		return s.createSource(query.Size, resp)
+	}
+
+	target := s.target
+	if target == "" {
+		target = requestTarget(s.Client)
+	}
+	key := coalesceKey(target, query, format, query.DimensionsForParams(s.ExtReqParams))
+	resp, offset, size, err := s.Coalescer.Do(key, query.BBox, query.Size, func() ([]byte, error) {
+		return s.Client.Fetch(query, &format)
+	})
+	if err != nil {
+		return nil, err
+	}
+	src, err := s.createSource(size, resp)
+	if err != nil || size == query.Size {
+		return src, err
+	}
+	return s.cropSource(src, offset, query.Size), nil
+}
+
+// requestTarget 返回客户端的请求目标，在创建数据源时计算一次
+func requestTarget(c client.MapClient) string {
+	if t, ok := c.(interface{ RequestTarget() string }); ok {
+		return t.RequestTarget()
+	}
+	return fmt.Sprintf("%p", c)
+}
+
+func (s *WMSSource) getSubQuery(query *layer.MapQuery, format tile.TileFormat) (tile.Source, error) {
 	size, offset, bbox := imagery.BBoxPositionInImage(query.BBox, query.Size, s.Extent.BBoxFor(query.Srs))
 	if size[0] == 0 || size[1] == 0 {
-		return imagery.NewBlankImageSource(size, imageOptions, nil)
+		return s.blankSource(query.Size), nil
 	}
 	src_query := &layer.MapQuery{BBox: bbox, Size: size, Srs: query.Srs, Format: format, Dimensions: query.Dimensions}
-	resp := s.Client.Retrieve(src_query, &format)
-	src := imagery.CreateImageSource(query.Size, imageOptions)
-	src.SetSource(bytes.NewBuffer(resp))
-	return imagery.SubImageSource(src, query.Size, offset[:], imageOptions, nil)
+	src, err := s.retrieve(src_query, format)
+	if err != nil {
+		return nil, err
+	}
+	switch opts := s.Options.(type) {
+	case *terrain.RasterOptions:
+		return terrain.NewRasterSplitter(src, opts).GetSplitTile([2]int{-int(offset[0]), -int(offset[1])}, query.Size), nil
+	default:
+		return imagery.SubImageSource(src.(*imagery.ImageSource), query.Size, offset[:], s.Options.(*imagery.ImageOptions), nil), nil
+	}
 }
 
-func (s *WMSSource) getTransformed(query *layer.MapQuery, format tile.TileFormat) tile.Source {
-	imageOptions := s.Options.(*imagery.ImageOptions)
-
+func (s *WMSSource) getTransformed(query *layer.MapQuery, format tile.TileFormat) (tile.Source, error) {
 	dst_srs := query.Srs
 	src_srs, _ := s.SupportedSRS.BestSrs(dst_srs)
 	dst_bbox := query.BBox
@@ -168,20 +217,31 @@ func (s *WMSSource) getTransformed(query *layer.MapQuery, format tile.TileFormat
 
 	src_query := &layer.MapQuery{BBox: src_bbox, Size: src_size, Srs: src_srs, Format: format, Dimensions: query.Dimensions}
 	var img tile.Source
+	var err error
 	if s.Coverage != nil && !s.Coverage.Contains(src_bbox, src_srs) {
-		img = s.getSubQuery(src_query, format)
+		img, err = s.getSubQuery(src_query, format)
 	} else {
-		resp := s.Client.Retrieve(src_query, &format)
-		img = imagery.CreateImageSource(src_size, imageOptions)
-		img.SetSource(bytes.NewBuffer(resp))
+		img, err = s.retrieve(src_query, format)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if opts, ok := s.Options.(*terrain.RasterOptions); ok {
+		raster := terrain.Resample([]tile.Source{img}, [2]int{1, 1}, src_size, src_bbox, src_srs, dst_bbox, dst_srs, query.Size, opts, opts)
+		if raster == nil {
+			return nil, errors.New("failed to transform raster")
+		}
+		return raster, nil
 	}
 
+	imageOptions := s.Options.(*imagery.ImageOptions)
 	img = imagery.NewImageTransformer(src_srs, dst_srs, nil).Transform(img, src_bbox,
 		query.Size, dst_bbox, imageOptions)
 
 	opts := img.GetTileOptions().(*imagery.ImageOptions)
 	opts.Format = format
-	return img
+	return img, nil
 }
 
 func (s *WMSSource) isCompatible(other *WMSSource, query *layer.MapQuery) bool {
@@ -222,7 +282,10 @@ func (s *WMSSource) isCompatible(other *WMSSource, query *layer.MapQuery) bool {
 }
 
 func (s *WMSSource) CombinedLayer(other layer.Layer, query *layer.MapQuery) layer.Layer {
-	imageOptions := s.Options.(*imagery.ImageOptions)
+	imageOptions, ok := s.Options.(*imagery.ImageOptions)
+	if !ok {
+		return nil
+	}
 
 	o := other.(*WMSSource)
 	if !s.isCompatible(o, query) {
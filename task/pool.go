@@ -152,9 +152,10 @@ type WorkerPool interface {
 
 type TileWorkerPool struct {
 	WorkerPool
-	Queue  *workerQueue
-	Logger ProgressLogger
-	Task   Task
+	Queue       *workerQueue
+	Logger      ProgressLogger
+	Task        Task
+	RateLimiter RateLimiter
 }
 
 func NewTileWorkerPool(cancel context.CancelFunc, threads int, task Task, logger ProgressLogger) *TileWorkerPool {
@@ -166,6 +167,11 @@ func (p *TileWorkerPool) Process(tiles Work, progress *TaskProgress) bool {
 	if !p.Queue.IsRuning() {
 		return false
 	}
+	if p.RateLimiter != nil {
+		if err := p.RateLimiter.Wait(context.Background()); err != nil {
+			return false
+		}
+	}
 	p.Queue.AddRequest(tiles)
 
 	<-tiles.Done()
@@ -0,0 +1,81 @@
+package task
+
+import (
+	"context"
+	"sync"
+)
+
+// pyramidTask 的遍历先递归处理下一级再处理当前级，因此每个分支都是自下而上生成的
+func pyramidTask(cancel context.CancelFunc, task *TilePyramidTask, config *TaskRunnerConfig, progress_logger ProgressLogger, pyramidProgress *TaskProgress) {
+	if task.GetCoverage() == nil {
+		return
+	}
+
+	task.GetManager().SetMinimizeMetaRequests(false)
+
+	tile_worker_pool := NewTileWorkerPool(cancel, config.Concurrency, task, progress_logger)
+	tile_worker_pool.RateLimiter = config.RateLimiter
+
+	var wg sync.WaitGroup
+
+	wg.Add(1)
+
+	go func() {
+		tile_worker_pool.Queue.Run()
+		wg.Done()
+	}()
+
+	tile_walker := NewTileWalker(task, tile_worker_pool, false, progress_logger, pyramidProgress, false, !task.Overwrite)
+	tile_walker.Walk()
+
+	if tile_worker_pool.Queue.IsRuning() {
+		tile_worker_pool.Queue.Stop()
+	}
+
+	wg.Wait()
+}
+
+func Pyramid(cancel context.CancelFunc, tasks []*TilePyramidTask, concurrency int, progress_logger ProgressLogger, cache_locker CacheLocker) {
+	config := DefaultTaskRunnerConfig()
+	config.Concurrency = concurrency
+	PyramidWithConfig(cancel, tasks, config, progress_logger, cache_locker)
+}
+
+func PyramidWithConfig(cancel context.CancelFunc, tasks []*TilePyramidTask, config *TaskRunnerConfig, progress_logger ProgressLogger, cache_locker CacheLocker) {
+	if config == nil {
+		config = DefaultTaskRunnerConfig()
+	}
+
+	if cache_locker == nil {
+		cache_locker = &DummyCacheLocker{}
+	}
+
+	var progress_store ProgressStore
+	if progress_logger != nil {
+		progress_store = progress_logger.GetStore()
+	}
+	active_tasks := tasks[:]
+	active_tasks = reverse(active_tasks).([]*TilePyramidTask)
+	for len(active_tasks) > 0 {
+		task := active_tasks[len(active_tasks)-1]
+		cacheName := "default"
+		if cn, ok := task.GetMetadata()["cache_name"]; ok {
+			cacheName = cn.(string)
+		}
+		if err := cache_locker.Lock(cacheName, func() {
+			var start_progress [][2]int
+			if progress_logger != nil && progress_store != nil {
+				progress_logger.SetCurrentTaskId(task.GetID())
+				if data := progress_store.Get(task.GetID()); data != nil {
+					start_progress = data.([][2]int)
+				}
+			}
+			pyramid_progress := &TaskProgress{oldLevelProgresses: start_progress}
+			pyramidTask(cancel, task, config, progress_logger, pyramid_progress)
+		}); err != nil {
+			active_tasks = append([]*TilePyramidTask{task}, active_tasks[:len(active_tasks)-1]...)
+		} else {
+			active_tasks = active_tasks[:len(active_tasks)-1]
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package task
+
+import (
+	"context"
+	"image"
+	"image/color"
+	"os"
+	"testing"
+
+	"github.com/flywave/go-geo"
+	"github.com/flywave/go-tileproxy/cache"
+	"github.com/flywave/go-tileproxy/imagery"
+)
+
+func TestPyramidFromChildren(t *testing.T) {
+	dir, err := os.MkdirTemp("", "pyramid")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	opts := geo.DefaultTileGridOptions()
+	opts[geo.TILEGRID_SRS] = "EPSG:3857"
+	opts[geo.TILEGRID_TILE_SIZE] = []uint32{16, 16}
+	grid := geo.NewTileGrid(opts)
+
+	imageopts := &imagery.ImageOptions{Format: "png", Transparent: geo.NewBool(true), BgColor: color.NRGBA{}}
+	c := cache.NewLocalCache(dir, "tms", &imagery.ImageSourceCreater{Opt: imageopts})
+	manager := cache.NewTileManager(&cache.TileManagerOptions{
+		Grid:       grid,
+		Cache:      c,
+		Locker:     &cache.DummyTileLocker{},
+		Identifier: "test",
+		Format:     "png",
+		Options:    imageopts,
+		MetaSize:   [2]uint32{1, 1},
+	})
+
+	// 只缓存 (0, 0, 1) 的三个子瓦片
+	children, _, err := cache.ChildTileCoords(grid, [3]int{0, 0, 1})
+	if err != nil || len(children) != 4 {
+		t.Fatalf("children %v %v", children, err)
+	}
+	red := image.NewNRGBA(image.Rect(0, 0, 16, 16))
+	for i := 0; i < len(red.Pix); i += 4 {
+		red.Pix[i], red.Pix[i+3] = 255, 255
+	}
+	for _, coord := range children[:3] {
+		tl := cache.NewTile(coord)
+		tl.Source = imagery.CreateImageSourceFromImage(red, imageopts)
+		if err := c.StoreTile(tl); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	md := map[string]interface{}{"cache_name": "test"}
+	coverage := geo.NewBBoxCoverage(*grid.BBox, grid.Srs, false)
+	task := NewTilePyramidTask("1", md, manager, []int{0, 1}, "", coverage)
+	_, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	Pyramid(cancel, []*TilePyramidTask{task}, 1, nil, nil)
+
+	for _, coord := range [][3]int{{0, 0, 1}, {0, 0, 0}} {
+		if !manager.IsCached(coord, nil) {
+			t.Errorf("tile %v should be generated", coord)
+		}
+	}
+	for _, coord := range [][3]int{{1, 0, 1}, {0, 1, 1}, {1, 1, 1}} {
+		if manager.IsCached(coord, nil) {
+			t.Errorf("tile %v has no children and should be skipped", coord)
+		}
+	}
+
+	parent := cache.NewTile([3]int{0, 0, 1})
+	if err := c.LoadTile(parent, false); err != nil {
+		t.Fatal(err)
+	}
+	img := parent.Source.GetTile().(image.Image)
+	// 缺失的子瓦片位于右下角，按背景色填充
+	if c := color.NRGBAModel.Convert(img.At(2, 2)).(color.NRGBA); c != (color.NRGBA{R: 255, A: 255}) {
+		t.Errorf("unexpected color %v", c)
+	}
+	if _, _, _, a := img.At(13, 13).RGBA(); a != 0 {
+		t.Errorf("missing child should stay transparent")
+	}
+}
@@ -32,7 +32,7 @@ func DefaultTaskRunnerConfig() *TaskRunnerConfig {
 	}
 }
 
-func seedTask(cancel context.CancelFunc, task *TileSeedTask, concurrency int, progress_logger ProgressLogger, seedProgress *TaskProgress) {
+func seedTask(cancel context.CancelFunc, task *TileSeedTask, config *TaskRunnerConfig, progress_logger ProgressLogger, seedProgress *TaskProgress) {
 	if task.GetCoverage() == nil {
 		return
 	}
@@ -47,7 +47,8 @@ func seedTask(cancel context.CancelFunc, task *TileSeedTask, concurrency int, pr
 		work_on_metatiles = false
 	}
 
-	tile_worker_pool := NewTileWorkerPool(cancel, concurrency, task, progress_logger)
+	tile_worker_pool := NewTileWorkerPool(cancel, config.Concurrency, task, progress_logger)
+	tile_worker_pool.RateLimiter = config.RateLimiter
 
 	var wg sync.WaitGroup
 
@@ -115,7 +116,7 @@ func SeedWithConfig(cancel context.CancelFunc, tasks []*TileSeedTask, config *Ta
 				start_progress = nil
 			}
 			seed_progress := &TaskProgress{oldLevelProgresses: start_progress}
-			seedTask(cancel, task, config.Concurrency, progress_logger, seed_progress)
+			seedTask(cancel, task, config, progress_logger, seed_progress)
 		}); err != nil {
 			active_tasks = append([]*TileSeedTask{task}, active_tasks[:len(active_tasks)-1]...)
 		} else {
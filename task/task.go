@@ -9,6 +9,7 @@ import (
 	"github.com/flywave/go-tileproxy/cache"
 	"github.com/flywave/go-tileproxy/exports"
 	"github.com/flywave/go-tileproxy/imports"
+	"github.com/flywave/go-tileproxy/terrain"
 )
 
 type Task interface {
@@ -163,3 +164,35 @@ func NewTileImportTask(jobid string, md map[string]interface{}, manager cache.Ma
 func (t *TileImportTask) GetID() string {
 	return t.JobId
 }
+
+// TilePyramidTask 由已缓存的下一级瓦片逐级向上生成瓦片，Method 为高程的聚合方式
+type TilePyramidTask struct {
+	BaseTask
+	Method    terrain.DownsampleMethod
+	Overwrite bool
+}
+
+func (t *TilePyramidTask) NewWork(handle_tiles [][3]int) Work {
+	return &PyramidWorker{task: t, manager: t.Manager, tiles: handle_tiles, done: make(chan struct{}), method: t.Method}
+}
+
+func NewTilePyramidTask(jobid string, md map[string]interface{}, manager cache.Manager, levels []int, method terrain.DownsampleMethod, coverage geo.Coverage) *TilePyramidTask {
+	if method == "" {
+		method = terrain.DOWNSAMPLE_AVERAGE
+	}
+	return &TilePyramidTask{
+		BaseTask: BaseTask{
+			JobId:    jobid,
+			Metadata: md,
+			Manager:  manager,
+			Coverage: coverage,
+			Grid:     manager.GetGrid(),
+			Levels:   levels,
+		},
+		Method: method,
+	}
+}
+
+func (t *TilePyramidTask) GetID() string {
+	return t.JobId
+}
@@ -4,6 +4,8 @@ import (
 	"github.com/flywave/go-tileproxy/cache"
 	"github.com/flywave/go-tileproxy/exports"
 	"github.com/flywave/go-tileproxy/imports"
+	"github.com/flywave/go-tileproxy/terrain"
+	"github.com/flywave/go-tileproxy/tile"
 )
 
 type Work interface {
@@ -136,3 +138,59 @@ func (w *ImportWorker) Run() {
 
 	close(w.done)
 }
+
+type PyramidWorker struct {
+	Work
+	task    Task
+	manager cache.Manager
+	tiles   [][3]int
+	err     error
+	done    chan struct{}
+	method  terrain.DownsampleMethod
+}
+
+func (w *PyramidWorker) Done() <-chan struct{} {
+	return w.done
+}
+
+// Run 只读取缓存中的子瓦片，没有任何子瓦片的瓦片直接跳过
+func (w *PyramidWorker) Run() {
+	defer close(w.done)
+
+	grid := w.manager.GetGrid()
+	c := w.manager.GetCache()
+	for _, coord := range w.tiles {
+		coords, tile_grid, err := cache.ChildTileCoords(grid, coord)
+		if err != nil {
+			w.err = err
+			return
+		}
+
+		children := make([]tile.Source, len(coords))
+		found := false
+		for i := range coords {
+			child := cache.NewTile(coords[i])
+			if err := c.LoadTile(child, false); err != nil || child.Source == nil {
+				continue
+			}
+			children[i] = child.Source
+			found = true
+		}
+		if !found {
+			continue
+		}
+
+		source, err := cache.DownsampleTiles(children, tile_grid, grid, coord, w.manager.GetTileOptions(), w.method)
+		if err != nil {
+			w.err = err
+			return
+		}
+
+		t := cache.NewTile(coord)
+		t.Source = source
+		if err := w.manager.StoreTile(t); err != nil {
+			w.err = err
+			return
+		}
+	}
+}
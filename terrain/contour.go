@@ -0,0 +1,263 @@
+package terrain
+
+import (
+	"math"
+)
+
+// ContourLine 等高线，坐标与高程数据范围使用同一坐标系，闭合线首尾点相同
+type ContourLine struct {
+	Level  float64
+	Index  int
+	Points [][2]float64
+}
+
+// 单元格四条边：上、右、下、左
+const (
+	edgeTop = iota
+	edgeRight
+	edgeBottom
+	edgeLeft
+)
+
+// marching squares 查找表，角点顺序为左上8、右上4、右下2、左下1，鞍点情况单独处理
+var contourSegments = [16][][2]int{
+	1:  {{edgeLeft, edgeBottom}},
+	2:  {{edgeBottom, edgeRight}},
+	3:  {{edgeLeft, edgeRight}},
+	4:  {{edgeTop, edgeRight}},
+	6:  {{edgeTop, edgeBottom}},
+	7:  {{edgeTop, edgeLeft}},
+	8:  {{edgeTop, edgeLeft}},
+	9:  {{edgeTop, edgeBottom}},
+	11: {{edgeTop, edgeRight}},
+	12: {{edgeLeft, edgeRight}},
+	13: {{edgeBottom, edgeRight}},
+	14: {{edgeLeft, edgeBottom}},
+}
+
+// ContourLevels 返回 [minv, maxv] 内 interval 整数倍的高程及其倍数
+func ContourLevels(minv, maxv, interval float64) ([]float64, []int) {
+	if interval <= 0 || math.IsNaN(minv) || math.IsNaN(maxv) || minv > maxv {
+		return nil, nil
+	}
+	var levels []float64
+	var index []int
+	for k := int(math.Ceil(minv / interval)); float64(k)*interval <= maxv; k++ {
+		levels = append(levels, float64(k)*interval)
+		index = append(index, k)
+	}
+	return levels, index
+}
+
+// ElevationRange 返回有效高程的最小与最大值，包含边框
+func ElevationRange(data *TileData) (float64, float64) {
+	win := newElevationWindow(data)
+	minv, maxv := math.Inf(1), math.Inf(-1)
+	for _, v := range win.data {
+		if win.isNodata(v) {
+			continue
+		}
+		minv = math.Min(minv, v)
+		maxv = math.Max(maxv, v)
+	}
+	if minv > maxv {
+		return math.NaN(), math.NaN()
+	}
+	return minv, maxv
+}
+
+// Contours 使用 marching squares 按像素中心追踪等高线，有边框时包含边框像素，含无数据角点的单元格跳过
+func Contours(data *TileData, interval float64) []ContourLine {
+	minv, maxv := ElevationRange(data)
+	levels, index := ContourLevels(minv, maxv, interval)
+	if len(levels) == 0 {
+		return nil
+	}
+
+	win := newElevationWindow(data)
+	resx := (data.Box.Max[0] - data.Box.Min[0]) / float64(data.Size[0])
+	resy := (data.Box.Max[1] - data.Box.Min[1]) / float64(data.Size[1])
+	toCoord := func(px, py float64) [2]float64 {
+		px -= float64(win.offset)
+		py -= float64(win.offset)
+		return [2]float64{data.Box.Min[0] + (px+0.5)*resx, data.Box.Max[1] - (py+0.5)*resy}
+	}
+
+	var ret []ContourLine
+	for li, level := range levels {
+		for _, line := range traceContours(win, level) {
+			pts := make([][2]float64, len(line))
+			for i, p := range line {
+				pts[i] = toCoord(p[0], p[1])
+			}
+			ret = append(ret, ContourLine{Level: level, Index: index[li], Points: pts})
+		}
+	}
+	return ret
+}
+
+func (e *elevationWindow) raw(x, y int) float64 {
+	return e.data[y*e.w+x]
+}
+
+// traceContours 追踪单个高程的等高线，返回像素坐标
+func traceContours(win *elevationWindow, level float64) [][][2]float64 {
+	w, h := win.w, win.h
+	// 边编号：水平边 (x,y)-(x+1,y) 为 2*(y*w+x)，垂直边 (x,y)-(x,y+1) 为 2*(y*w+x)+1
+	points := make(map[int][2]float64)
+	edgePoint := func(x, y, edge int) int {
+		var id int
+		var a, b float64
+		var p [2]float64
+		switch edge {
+		case edgeTop:
+			id, a, b = 2*(y*w+x), win.raw(x, y), win.raw(x+1, y)
+		case edgeBottom:
+			id, a, b = 2*((y+1)*w+x), win.raw(x, y+1), win.raw(x+1, y+1)
+		case edgeLeft:
+			id, a, b = 2*(y*w+x)+1, win.raw(x, y), win.raw(x, y+1)
+		case edgeRight:
+			id, a, b = 2*(y*w+x+1)+1, win.raw(x+1, y), win.raw(x+1, y+1)
+		}
+		if _, ok := points[id]; ok {
+			return id
+		}
+		t := (level - a) / (b - a)
+		switch edge {
+		case edgeTop:
+			p = [2]float64{float64(x) + t, float64(y)}
+		case edgeBottom:
+			p = [2]float64{float64(x) + t, float64(y + 1)}
+		case edgeLeft:
+			p = [2]float64{float64(x), float64(y) + t}
+		case edgeRight:
+			p = [2]float64{float64(x + 1), float64(y) + t}
+		}
+		points[id] = p
+		return id
+	}
+
+	var segments [][2]int
+	for y := 0; y < h-1; y++ {
+		for x := 0; x < w-1; x++ {
+			tl, tr, br, bl := win.raw(x, y), win.raw(x+1, y), win.raw(x+1, y+1), win.raw(x, y+1)
+			if win.isNodata(tl) || win.isNodata(tr) || win.isNodata(br) || win.isNodata(bl) {
+				continue
+			}
+			c := 0
+			if tl >= level {
+				c |= 8
+			}
+			if tr >= level {
+				c |= 4
+			}
+			if br >= level {
+				c |= 2
+			}
+			if bl >= level {
+				c |= 1
+			}
+
+			edges := contourSegments[c]
+			if c == 5 || c == 10 {
+				// 鞍点按单元格中心值判断连通方向
+				centerAbove := (tl+tr+br+bl)/4 >= level
+				if (c == 5) == centerAbove {
+					edges = [][2]int{{edgeTop, edgeLeft}, {edgeBottom, edgeRight}}
+				} else {
+					edges = [][2]int{{edgeTop, edgeRight}, {edgeLeft, edgeBottom}}
+				}
+			}
+			for _, e := range edges {
+				segments = append(segments, [2]int{edgePoint(x, y, e[0]), edgePoint(x, y, e[1])})
+			}
+		}
+	}
+
+	return joinSegments(segments, points)
+}
+
+// joinSegments 将共享端点的线段连接为折线，先处理开放线再处理闭合线
+func joinSegments(segments [][2]int, points map[int][2]float64) [][][2]float64 {
+	adjacent := make(map[int][]int, len(segments)*2)
+	for i, s := range segments {
+		adjacent[s[0]] = append(adjacent[s[0]], i)
+		adjacent[s[1]] = append(adjacent[s[1]], i)
+	}
+	used := make([]bool, len(segments))
+
+	walk := func(start int) [][2]float64 {
+		var ids []int
+		cur := start
+		ids = append(ids, cur)
+		for {
+			next := -1
+			for _, si := range adjacent[cur] {
+				if !used[si] {
+					next = si
+					break
+				}
+			}
+			if next < 0 {
+				break
+			}
+			used[next] = true
+			s := segments[next]
+			if s[0] == cur {
+				cur = s[1]
+			} else {
+				cur = s[0]
+			}
+			ids = append(ids, cur)
+		}
+		line := make([][2]float64, len(ids))
+		for i, id := range ids {
+			line[i] = points[id]
+		}
+		return line
+	}
+
+	var lines [][][2]float64
+	for i, s := range segments {
+		if used[i] {
+			continue
+		}
+		for _, end := range s {
+			if len(adjacent[end]) == 1 {
+				lines = append(lines, walk(end))
+				break
+			}
+		}
+	}
+	for i, s := range segments {
+		if !used[i] {
+			lines = append(lines, walk(s[0]))
+		}
+	}
+	return lines
+}
+
+// SmoothContour 使用 Chaikin 算法平滑折线，闭合线保持闭合，开放线保留端点
+func SmoothContour(points [][2]float64, iterations int) [][2]float64 {
+	for it := 0; it < iterations && len(points) > 2; it++ {
+		closed := points[0] == points[len(points)-1]
+		n := len(points)
+		next := make([][2]float64, 0, 2*n)
+		if !closed {
+			next = append(next, points[0])
+		}
+		for i := 0; i < n-1; i++ {
+			p, q := points[i], points[i+1]
+			next = append(next,
+				[2]float64{0.75*p[0] + 0.25*q[0], 0.75*p[1] + 0.25*q[1]},
+				[2]float64{0.25*p[0] + 0.75*q[0], 0.25*p[1] + 0.75*q[1]})
+		}
+		if closed {
+			next = append(next, next[0])
+		} else {
+			next = append(next, points[n-1])
+		}
+		points = next
+	}
+	return points
+}
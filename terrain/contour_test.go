@@ -0,0 +1,72 @@
+package terrain
+
+import (
+	"math"
+	"testing"
+)
+
+func TestContourLevels(t *testing.T) {
+	levels, index := ContourLevels(-15, 42, 10)
+	if len(levels) != 6 || levels[0] != -10 || levels[5] != 40 || index[0] != -1 {
+		t.Fatalf("levels %v index %v", levels, index)
+	}
+	if l, _ := ContourLevels(0, 10, 0); l != nil {
+		t.Fatalf("zero interval should not produce levels")
+	}
+}
+
+func TestContoursRamp(t *testing.T) {
+	// 高程向东递增，等高线为南北向直线
+	td := newPlaneTileData(8, BORDER_NONE, func(x, y float64) float64 { return x * 10 })
+	lines := Contours(td, 20)
+	if len(lines) != 3 {
+		t.Fatalf("expected 3 contours, got %d", len(lines))
+	}
+	for _, l := range lines {
+		if l.Points[0] == l.Points[len(l.Points)-1] {
+			t.Errorf("ramp contour should be open")
+		}
+		for _, p := range l.Points {
+			if math.Abs(p[0]*10-l.Level) > 1e-9 {
+				t.Fatalf("contour %v point %v off level", l.Level, p)
+			}
+		}
+		if len(l.Points) != 8 {
+			t.Errorf("contour %v should span all rows, got %d points", l.Level, len(l.Points))
+		}
+	}
+}
+
+func TestContoursClosed(t *testing.T) {
+	td := newPlaneTileData(16, BORDER_NONE, func(x, y float64) float64 {
+		return 100 - math.Hypot(x-8, y-8)*10
+	})
+	td.Set(0, 0, td.NoData)
+
+	lines := Contours(td, 30)
+	closed := 0
+	var ring [][2]float64
+	for _, l := range lines {
+		if l.Points[0] == l.Points[len(l.Points)-1] {
+			closed++
+			ring = l.Points
+			for _, p := range l.Points {
+				if r := math.Hypot(p[0]-8, p[1]-8); math.Abs(100-r*10-l.Level) > 3 {
+					t.Fatalf("contour %v point %v radius %v", l.Level, p, r)
+				}
+			}
+		}
+	}
+	if closed != 3 {
+		t.Fatalf("expected 3 closed rings (90, 60, 30), got %d of %d", closed, len(lines))
+	}
+
+	smooth := SmoothContour(ring, 2)
+	if smooth[0] != smooth[len(smooth)-1] || len(smooth) <= len(ring) {
+		t.Errorf("smoothed ring should stay closed and gain points")
+	}
+	open := SmoothContour([][2]float64{{0, 0}, {1, 1}, {2, 0}}, 1)
+	if open[0] != [2]float64{0, 0} || open[len(open)-1] != [2]float64{2, 0} {
+		t.Errorf("smoothed line should keep its endpoints: %v", open)
+	}
+}
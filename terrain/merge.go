@@ -134,7 +134,7 @@ func (t *RasterSplitter) GetSplitTile(crop_coord [2]int, tile_size [2]uint32) ti
 	for i := sx; i < ex; i++ {
 		for j := sy; j < ey; j++ {
 			h := dt.Get(i, j)
-			td.Set(i, j, h)
+			td.Set(i-minx, j-miny, h)
 		}
 	}
 	return CreateRasterSourceFromTileData(td, t.Options, nil)
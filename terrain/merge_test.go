@@ -108,3 +108,22 @@ func TestRasterMerger(t *testing.T) {
 
 	defer os.Remove("./smtd.webp")
 }
+
+func TestRasterSplitterOffset(t *testing.T) {
+	opts := &RasterOptions{Format: tile.TileFormat("tif"), Mode: BORDER_NONE}
+	td := NewTileData([2]uint32{4, 4}, BORDER_NONE)
+	for y := 0; y < 4; y++ {
+		for x := 0; x < 4; x++ {
+			td.Set(x, y, float64(y*4+x))
+		}
+	}
+	src := CreateRasterSourceFromTileData(td, opts, nil)
+
+	splitter := NewRasterSplitter(src, opts)
+	sub := splitter.GetSplitTile([2]int{2, 2}, [2]uint32{2, 2})
+	data := sub.GetTile().(*TileData)
+
+	if data.Get(0, 0) != 10 || data.Get(1, 1) != 15 {
+		t.Errorf("unexpected split data: %v", data.Datas)
+	}
+}
@@ -0,0 +1,176 @@
+package terrain
+
+import (
+	"math"
+
+	vec2d "github.com/flywave/go3d/float64/vec2"
+
+	"github.com/flywave/go-geo"
+)
+
+type DownsampleMethod string
+
+const (
+	DOWNSAMPLE_AVERAGE DownsampleMethod = "average"
+	DOWNSAMPLE_MIN     DownsampleMethod = "min"
+	DOWNSAMPLE_MAX     DownsampleMethod = "max"
+)
+
+func IsValidDownsampleMethod(m DownsampleMethod) bool {
+	switch m {
+	case DOWNSAMPLE_AVERAGE, DOWNSAMPLE_MIN, DOWNSAMPLE_MAX:
+		return true
+	}
+	return false
+}
+
+// Downsample 将下一级的子瓦片高程按像素块聚合为 dst_bbox 范围的一个瓦片。
+// tiles 按行从上到下排列，缺失的子瓦片与无效值不参与聚合，块内全部无效时输出无效值。
+// 边框取最近的边缘像素
+func Downsample(tiles []*TileData, tile_grid [2]int, src_bbox, dst_bbox vec2d.Rect, srs geo.Proj, out_size [2]uint32, method DownsampleMethod) *TileData {
+	var first *TileData
+	for _, t := range tiles {
+		if t != nil {
+			first = t
+			break
+		}
+	}
+	if first == nil {
+		return nil
+	}
+
+	ret := NewTileData(out_size, first.Border)
+	ret.NoData = first.NoData
+	ret.Box = dst_bbox
+	ret.Boxsrs = srs
+
+	tw, th := int(first.Size[0]), int(first.Size[1])
+	mw, mh := tile_grid[0]*tw, tile_grid[1]*th
+	resx := (src_bbox.Max[0] - src_bbox.Min[0]) / float64(mw)
+	resy := (src_bbox.Max[1] - src_bbox.Min[1]) / float64(mh)
+	dx := (dst_bbox.Max[0] - dst_bbox.Min[0]) / float64(out_size[0])
+	dy := (dst_bbox.Max[1] - dst_bbox.Min[1]) / float64(out_size[1])
+
+	sample := func(mx, my int) (float64, bool) {
+		t := tiles[(my/th)*tile_grid[0]+mx/tw]
+		if t == nil || t.Size != first.Size {
+			return 0, false
+		}
+		v := t.Get(mx%tw, my%th)
+		if math.IsNaN(v) || v == t.NoData {
+			return 0, false
+		}
+		return v, true
+	}
+
+	// 目标像素按范围映射到拼接后高程的像素块
+	for py := 0; py < int(out_size[1]); py++ {
+		y0 := (src_bbox.Max[1] - (dst_bbox.Max[1] - float64(py)*dy)) / resy
+		y1 := (src_bbox.Max[1] - (dst_bbox.Max[1] - float64(py+1)*dy)) / resy
+		my0 := max(0, int(math.Floor(y0+1e-9)))
+		my1 := min(mh, max(my0+1, int(math.Ceil(y1-1e-9))))
+		for px := 0; px < int(out_size[0]); px++ {
+			x0 := (dst_bbox.Min[0] + float64(px)*dx - src_bbox.Min[0]) / resx
+			x1 := (dst_bbox.Min[0] + float64(px+1)*dx - src_bbox.Min[0]) / resx
+			mx0 := max(0, int(math.Floor(x0+1e-9)))
+			mx1 := min(mw, max(mx0+1, int(math.Ceil(x1-1e-9))))
+
+			var acc float64
+			count := 0
+			for my := my0; my < my1; my++ {
+				for mx := mx0; mx < mx1; mx++ {
+					v, ok := sample(mx, my)
+					if !ok {
+						continue
+					}
+					switch {
+					case count == 0:
+						acc = v
+					case method == DOWNSAMPLE_MIN:
+						acc = math.Min(acc, v)
+					case method == DOWNSAMPLE_MAX:
+						acc = math.Max(acc, v)
+					default:
+						acc += v
+					}
+					count++
+				}
+			}
+			if count == 0 {
+				ret.Set(px, py, ret.NoData)
+				continue
+			}
+			if method != DOWNSAMPLE_MIN && method != DOWNSAMPLE_MAX {
+				acc /= float64(count)
+			}
+			ret.Set(px, py, acc)
+		}
+	}
+
+	ret.fillEdgeBorder()
+	return ret
+}
+
+// Upscale 截取上级瓦片中 dst_bbox 的部分并按双线性插值放大为 out_size，相邻像素含无效值时取最近像素
+func Upscale(data *TileData, src_bbox, dst_bbox vec2d.Rect, srs geo.Proj, out_size [2]uint32) *TileData {
+	ret := NewTileData(out_size, data.Border)
+	ret.NoData = data.NoData
+	ret.Box = dst_bbox
+	ret.Boxsrs = srs
+
+	w, h := int(data.Size[0]), int(data.Size[1])
+	resx := (src_bbox.Max[0] - src_bbox.Min[0]) / float64(w)
+	resy := (src_bbox.Max[1] - src_bbox.Min[1]) / float64(h)
+	dx := (dst_bbox.Max[0] - dst_bbox.Min[0]) / float64(out_size[0])
+	dy := (dst_bbox.Max[1] - dst_bbox.Min[1]) / float64(out_size[1])
+	isNodata := func(v float64) bool {
+		return math.IsNaN(v) || v == data.NoData
+	}
+
+	for py := 0; py < int(out_size[1]); py++ {
+		fy := (src_bbox.Max[1]-(dst_bbox.Max[1]-(float64(py)+0.5)*dy))/resy - 0.5
+		fy = math.Max(0, math.Min(fy, float64(h-1)))
+		y0 := int(fy)
+		y1 := min(y0+1, h-1)
+		ty := fy - float64(y0)
+		for px := 0; px < int(out_size[0]); px++ {
+			fx := (dst_bbox.Min[0]+(float64(px)+0.5)*dx-src_bbox.Min[0])/resx - 0.5
+			fx = math.Max(0, math.Min(fx, float64(w-1)))
+			x0 := int(fx)
+			x1 := min(x0+1, w-1)
+			tx := fx - float64(x0)
+
+			v00, v10, v01, v11 := data.Get(x0, y0), data.Get(x1, y0), data.Get(x0, y1), data.Get(x1, y1)
+			if isNodata(v00) || isNodata(v10) || isNodata(v01) || isNodata(v11) {
+				ret.Set(px, py, data.Get(int(math.Round(fx)), int(math.Round(fy))))
+				continue
+			}
+			top := v00 + (v10-v00)*tx
+			bottom := v01 + (v11-v01)*tx
+			ret.Set(px, py, top+(bottom-top)*ty)
+		}
+	}
+
+	ret.fillEdgeBorder()
+	return ret
+}
+
+// fillEdgeBorder 用最近的边缘像素填充边框
+func (d *TileData) fillEdgeBorder() {
+	if !d.HasBorder() {
+		return
+	}
+	w, h := int(d.Size[0]), int(d.Size[1])
+	for y := 0; y < h; y++ {
+		d.FillBorder(BORDER_LEFT, y, d.Get(0, y))
+		d.FillBorder(BORDER_RIGHT, y, d.Get(w-1, y))
+	}
+	d.FillBorder(BORDER_TOP, 0, d.Get(0, 0))
+	d.FillBorder(BORDER_BOTTOM, 0, d.Get(0, h-1))
+	for x := 0; x < w; x++ {
+		d.FillBorder(BORDER_TOP, x+1, d.Get(x, 0))
+		d.FillBorder(BORDER_BOTTOM, x+1, d.Get(x, h-1))
+	}
+	d.FillBorder(BORDER_TOP, w+1, d.Get(w-1, 0))
+	d.FillBorder(BORDER_BOTTOM, w+1, d.Get(w-1, h-1))
+}
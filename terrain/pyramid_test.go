@@ -0,0 +1,50 @@
+package terrain
+
+import (
+	"math"
+	"testing"
+
+	vec2d "github.com/flywave/go3d/float64/vec2"
+
+	"github.com/flywave/go-geo"
+)
+
+func TestDownsample(t *testing.T) {
+	tiles := make([]*TileData, 4)
+	for i := 0; i < 3; i++ {
+		d := NewTileData([2]uint32{2, 2}, BORDER_UNILATERAL)
+		d.NoData = -9999
+		for j := range d.Datas {
+			d.Datas[j] = float64(i*10 + j)
+		}
+		tiles[i] = d
+	}
+	tiles[0].Datas[3] = -9999
+
+	src := vec2d.Rect{Min: vec2d.T{0, 0}, Max: vec2d.T{4, 4}}
+	srs := geo.NewProj(4326)
+
+	cases := []struct {
+		method DownsampleMethod
+		want   []float64
+	}{
+		{DOWNSAMPLE_AVERAGE, []float64{1, 11.5, 21.5, -9999}},
+		{DOWNSAMPLE_MIN, []float64{0, 10, 20, -9999}},
+		{DOWNSAMPLE_MAX, []float64{2, 13, 23, -9999}},
+	}
+	for _, c := range cases {
+		d := Downsample(tiles, [2]int{2, 2}, src, src, srs, [2]uint32{2, 2}, c.method)
+		for i, v := range c.want {
+			if math.Abs(d.Datas[i]-v) > 1e-9 {
+				t.Errorf("%s: pixel %d got %v, want %v", c.method, i, d.Datas[i], v)
+			}
+		}
+		if d.TopBorder[0] != d.Datas[0] || d.TopBorder[2] != d.Datas[1] || d.LeftBorder[1] != d.Datas[2] {
+			t.Errorf("%s: border not filled from edges", c.method)
+		}
+	}
+
+	if Downsample(make([]*TileData, 4), [2]int{2, 2}, src, src, srs, [2]uint32{2, 2}, DOWNSAMPLE_AVERAGE) != nil {
+		t.Error("expected nil without children")
+	}
+}
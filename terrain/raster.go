@@ -424,7 +424,8 @@ func CreateRasterSourceFromBufer(buf []byte, opts *RasterOptions) tile.Source {
 		return src
 	} else if opts.Format.Extension() == "tif" || opts.Format.Extension() == "tiff" {
 		src := NewGeoTIFFRasterSource(opts.Mode, opts)
-		reader := bytes.NewBuffer(buf)
+		// GeoTIFF 解码需要随机读取
+		reader := bytes.NewReader(buf)
 		src.SetSource(reader)
 		return src
 	} else if opts.Format.Extension() == "terrain" {
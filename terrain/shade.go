@@ -0,0 +1,213 @@
+package terrain
+
+import (
+	"image"
+	"image/color"
+	"math"
+
+	"github.com/flywave/go-geo"
+	"github.com/flywave/go-tileproxy/imagery"
+)
+
+type ShadeMode string
+
+const (
+	SHADE_HILLSHADE ShadeMode = "hillshade"
+	SHADE_SLOPE     ShadeMode = "slope"
+	SHADE_ASPECT    ShadeMode = "aspect"
+)
+
+// 多方向晕渲使用的光源方位角
+var multidirectionalAzimuths = []float64{225, 270, 315, 360}
+
+// Shader 由高程计算晕渲、坡度或坡向并按色带输出影像。
+// 晕渲结果为0-255，坡度为0-90度，坡向为正北顺时针的0-360度，平地坡向无值
+type Shader struct {
+	Mode             ShadeMode
+	Azimuth          float64
+	Altitude         float64
+	ZFactor          float64
+	Multidirectional bool
+	Ramp             *imagery.ColorRamp
+	Opacity          float64
+}
+
+func NewShader(mode ShadeMode) *Shader {
+	s := &Shader{Mode: mode, Azimuth: 315, Altitude: 45, ZFactor: 1, Opacity: 1}
+	switch mode {
+	case SHADE_SLOPE:
+		s.Ramp = imagery.NewColorRamp([]imagery.ColorStop{
+			{Value: 0, Color: color.NRGBA{R: 255, G: 255, B: 255, A: 255}},
+			{Value: 90, Color: color.NRGBA{A: 255}},
+		}, false)
+	case SHADE_ASPECT:
+		s.Ramp = imagery.NewColorRamp([]imagery.ColorStop{
+			{Value: 0, Color: color.NRGBA{R: 255, A: 255}},
+			{Value: 90, Color: color.NRGBA{R: 255, G: 255, A: 255}},
+			{Value: 180, Color: color.NRGBA{G: 255, B: 255, A: 255}},
+			{Value: 270, Color: color.NRGBA{B: 255, A: 255}},
+			{Value: 360, Color: color.NRGBA{R: 255, A: 255}},
+		}, false)
+	default:
+		s.Ramp = imagery.DefaultColorRamp()
+	}
+	return s
+}
+
+// elevationWindow 访问高程数据及其外围一圈像素，有边框时使用边框，否则取最近的边缘像素
+type elevationWindow struct {
+	data   []float64
+	w, h   int
+	offset int
+	nodata float64
+}
+
+func newElevationWindow(d *TileData) *elevationWindow {
+	win := &elevationWindow{data: d.Datas, w: int(d.Size[0]), h: int(d.Size[1]), nodata: d.NoData}
+	if d.HasBorder() {
+		data, size, _ := d.GetExtend()
+		if data != nil {
+			win.data, win.w, win.h, win.offset = data, int(size[0]), int(size[1]), 1
+		}
+	}
+	return win
+}
+
+func (e *elevationWindow) at(x, y int) float64 {
+	x = max(0, min(x+e.offset, e.w-1))
+	y = max(0, min(y+e.offset, e.h-1))
+	return e.data[y*e.w+x]
+}
+
+func (e *elevationWindow) isNodata(v float64) bool {
+	return math.IsNaN(v) || v == e.nodata
+}
+
+// pixelSizeMeters 像素的地面尺寸，地理坐标系按中心纬度换算为米
+func pixelSizeMeters(d *TileData) (float64, float64) {
+	resx := (d.Box.Max[0] - d.Box.Min[0]) / float64(d.Size[0])
+	resy := (d.Box.Max[1] - d.Box.Min[1]) / float64(d.Size[1])
+	if resx <= 0 || resy <= 0 {
+		return 1, 1
+	}
+	if d.Boxsrs != nil && d.Boxsrs.IsLatLong() {
+		lat := (d.Box.Min[1] + d.Box.Max[1]) / 2 * math.Pi / 180
+		resx *= 111320 * math.Cos(lat)
+		resy *= 110574
+	}
+	return resx, resy
+}
+
+// gradient 使用 Horn 算法计算东向与北向的高程梯度
+func (e *elevationWindow) gradient(x, y int, resx, resy float64) (float64, float64, bool) {
+	center := e.at(x, y)
+	if e.isNodata(center) {
+		return 0, 0, false
+	}
+	var v [3][3]float64
+	for j := -1; j <= 1; j++ {
+		for i := -1; i <= 1; i++ {
+			h := e.at(x+i, y+j)
+			if e.isNodata(h) {
+				h = center
+			}
+			v[j+1][i+1] = h
+		}
+	}
+	gx := ((v[0][2] + 2*v[1][2] + v[2][2]) - (v[0][0] + 2*v[1][0] + v[2][0])) / (8 * resx)
+	gy := ((v[0][0] + 2*v[0][1] + v[0][2]) - (v[2][0] + 2*v[2][1] + v[2][2])) / (8 * resy)
+	return gx, gy, true
+}
+
+func hillshade(gx, gy, azimuth, altitude float64) float64 {
+	az := azimuth * math.Pi / 180
+	alt := altitude * math.Pi / 180
+	nx, ny, nz := -gx, -gy, 1.0
+	norm := math.Sqrt(nx*nx + ny*ny + nz*nz)
+	v := (nx*math.Sin(az)*math.Cos(alt) + ny*math.Cos(az)*math.Cos(alt) + nz*math.Sin(alt)) / norm
+	return math.Max(0, v) * 255
+}
+
+func aspect(gx, gy float64) float64 {
+	if gx == 0 && gy == 0 {
+		return math.NaN()
+	}
+	a := math.Atan2(-gx, -gy) * 180 / math.Pi
+	if a < 0 {
+		a += 360
+	}
+	return a
+}
+
+func (s *Shader) value(gx, gy float64) float64 {
+	gx, gy = gx*s.ZFactor, gy*s.ZFactor
+	switch s.Mode {
+	case SHADE_SLOPE:
+		return math.Atan(math.Hypot(gx, gy)) * 180 / math.Pi
+	case SHADE_ASPECT:
+		return aspect(gx, gy)
+	}
+	if !s.Multidirectional {
+		return hillshade(gx, gy, s.Azimuth, s.Altitude)
+	}
+	// 按坡向为各方向光源加权，四个权重之和为2
+	a := aspect(gx, gy)
+	var sum float64
+	for _, az := range multidirectionalAzimuths {
+		w := 0.5
+		if !math.IsNaN(a) {
+			w = math.Pow(math.Sin((a-az)*math.Pi/180), 2)
+		}
+		sum += w * hillshade(gx, gy, az, s.Altitude)
+	}
+	return sum / 2
+}
+
+// Compute 计算去掉外围 buffer 个像素后的结果，行优先排列，无数据像素为 NaN
+func (s *Shader) Compute(data *TileData, buffer int) ([]float64, [2]uint32) {
+	size := [2]uint32{uint32(max(int(data.Size[0])-2*buffer, 0)), uint32(max(int(data.Size[1])-2*buffer, 0))}
+	ret := make([]float64, size[0]*size[1])
+	win := newElevationWindow(data)
+	resx, resy := pixelSizeMeters(data)
+	for y := 0; y < int(size[1]); y++ {
+		for x := 0; x < int(size[0]); x++ {
+			gx, gy, ok := win.gradient(x+buffer, y+buffer, resx, resy)
+			if !ok {
+				ret[y*int(size[0])+x] = math.NaN()
+				continue
+			}
+			ret[y*int(size[0])+x] = s.value(gx, gy)
+		}
+	}
+	return ret, size
+}
+
+// Render 计算并按色带渲染为指定大小的影像，大小不一致时按最近邻采样
+func (s *Shader) Render(data *TileData, buffer int, size [2]uint32, opts *imagery.ImageOptions) *imagery.ImageSource {
+	if opts == nil {
+		opts = &imagery.ImageOptions{Format: "image/png", Transparent: geo.NewBool(true)}
+	}
+	img := image.NewNRGBA(image.Rect(0, 0, int(size[0]), int(size[1])))
+	if data == nil {
+		return imagery.CreateImageSourceFromImage(img, opts)
+	}
+	values, vsize := s.Compute(data, buffer)
+	if vsize[0] == 0 || vsize[1] == 0 {
+		return imagery.CreateImageSourceFromImage(img, opts)
+	}
+
+	sx := float64(vsize[0]) / float64(size[0])
+	sy := float64(vsize[1]) / float64(size[1])
+	for y := 0; y < int(size[1]); y++ {
+		row := min(int(float64(y)*sy), int(vsize[1])-1)
+		for x := 0; x < int(size[0]); x++ {
+			col := min(int(float64(x)*sx), int(vsize[0])-1)
+			c := s.Ramp.Color(values[row*int(vsize[0])+col])
+			if s.Opacity < 1 {
+				c.A = uint8(math.Round(float64(c.A) * math.Max(s.Opacity, 0)))
+			}
+			img.SetNRGBA(x, y, c)
+		}
+	}
+	return imagery.CreateImageSourceFromImage(img, opts)
+}
@@ -0,0 +1,105 @@
+package terrain
+
+import (
+	"math"
+	"testing"
+
+	vec2d "github.com/flywave/go3d/float64/vec2"
+
+	"github.com/flywave/go-geo"
+)
+
+func newPlaneTileData(size int, border BorderMode, height func(x, y float64) float64) *TileData {
+	td := NewTileData([2]uint32{uint32(size), uint32(size)}, border)
+	td.Box = vec2d.Rect{Min: vec2d.T{0, 0}, Max: vec2d.T{float64(size), float64(size)}}
+	td.Boxsrs = geo.NewProj(3857)
+	td.NoData = -9999
+	for y := 0; y < size; y++ {
+		for x := 0; x < size; x++ {
+			td.Set(x, y, height(float64(x)+0.5, float64(size-y)-0.5))
+		}
+	}
+	return td
+}
+
+func TestShaderSlopeAspect(t *testing.T) {
+	// 高程向东递增，坡度45度，坡向朝西
+	td := newPlaneTileData(4, BORDER_NONE, func(x, y float64) float64 { return x })
+
+	slope, size := NewShader(SHADE_SLOPE).Compute(td, 0)
+	if size != [2]uint32{4, 4} {
+		t.Fatalf("size %v", size)
+	}
+	if math.Abs(slope[5]-45) > 1e-9 {
+		t.Errorf("slope %v", slope[5])
+	}
+	aspect, _ := NewShader(SHADE_ASPECT).Compute(td, 0)
+	if math.Abs(aspect[5]-270) > 1e-9 {
+		t.Errorf("aspect %v", aspect[5])
+	}
+
+	// 高程向北递增，坡向朝南
+	td = newPlaneTileData(4, BORDER_NONE, func(x, y float64) float64 { return 2 * y })
+	aspect, _ = NewShader(SHADE_ASPECT).Compute(td, 0)
+	if math.Abs(aspect[5]-180) > 1e-9 {
+		t.Errorf("aspect %v", aspect[5])
+	}
+
+	flat := newPlaneTileData(4, BORDER_NONE, func(x, y float64) float64 { return 10 })
+	aspect, _ = NewShader(SHADE_ASPECT).Compute(flat, 0)
+	if !math.IsNaN(aspect[0]) {
+		t.Errorf("flat aspect should be NaN, got %v", aspect[0])
+	}
+}
+
+func TestShaderHillshade(t *testing.T) {
+	flat := newPlaneTileData(4, BORDER_NONE, func(x, y float64) float64 { return 10 })
+	shade, _ := NewShader(SHADE_HILLSHADE).Compute(flat, 0)
+	if want := 255 * math.Sin(math.Pi/4); math.Abs(shade[0]-want) > 1e-9 {
+		t.Errorf("flat hillshade %v, want %v", shade[0], want)
+	}
+
+	// 朝向西北光源的坡面更亮
+	toLight := newPlaneTileData(4, BORDER_NONE, func(x, y float64) float64 { return x - y })
+	away := newPlaneTileData(4, BORDER_NONE, func(x, y float64) float64 { return y - x })
+	s := NewShader(SHADE_HILLSHADE)
+	lit, _ := s.Compute(toLight, 0)
+	dark, _ := s.Compute(away, 0)
+	if lit[5] <= shade[5] || dark[5] >= shade[5] {
+		t.Errorf("hillshade lit %v flat %v dark %v", lit[5], shade[5], dark[5])
+	}
+
+	s.Multidirectional = true
+	multi, _ := s.Compute(flat, 0)
+	if math.Abs(multi[0]-shade[0]) > 1e-9 {
+		t.Errorf("multidirectional flat %v", multi[0])
+	}
+}
+
+func TestShaderBorder(t *testing.T) {
+	height := func(x, y float64) float64 { return x*x + 3*x }
+	td := newPlaneTileData(4, BORDER_BILATERAL, height)
+	for i := 0; i < 4; i++ {
+		y := float64(4-i) - 0.5
+		td.FillBorder(BORDER_LEFT, i, height(-0.5, y))
+		td.FillBorder(BORDER_RIGHT, i, height(4.5, y))
+	}
+	for i := 0; i < 6; i++ {
+		x := float64(i) - 0.5
+		td.FillBorder(BORDER_TOP, i, height(x, 4.5))
+		td.FillBorder(BORDER_BOTTOM, i, height(x, -0.5))
+	}
+
+	slope, _ := NewShader(SHADE_SLOPE).Compute(td, 0)
+	// 左边缘像素中心 x=0.5，梯度为 2x+3=4
+	want := math.Atan(4) * 180 / math.Pi
+	if math.Abs(slope[0]-want) > 1e-9 {
+		t.Errorf("border slope %v", slope[0])
+	}
+
+	noBorder := newPlaneTileData(4, BORDER_NONE, height)
+	clamped, _ := NewShader(SHADE_SLOPE).Compute(noBorder, 0)
+	if math.Abs(clamped[0]-want) < 1e-3 {
+		t.Errorf("edge without border should differ, got %v", clamped[0])
+	}
+}
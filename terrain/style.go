@@ -0,0 +1,61 @@
+package terrain
+
+import (
+	"image"
+	"image/color"
+	"math"
+
+	"github.com/flywave/go-geo"
+	"github.com/flywave/go-tileproxy/imagery"
+)
+
+// RasterStyle 按色带将高程数据渲染为影像，无数据像素使用 NodataColor，默认透明
+type RasterStyle struct {
+	Ramp        *imagery.ColorRamp
+	Opacity     float64
+	NodataColor color.NRGBA
+}
+
+func NewRasterStyle(ramp *imagery.ColorRamp) *RasterStyle {
+	if ramp == nil {
+		ramp = imagery.NewColorRampPreset("hypsometric", false)
+	}
+	return &RasterStyle{Ramp: ramp, Opacity: 1}
+}
+
+func isNodata(data *TileData, v float64) bool {
+	return math.IsNaN(v) || v == data.NoData
+}
+
+// Render 将高程数据渲染为指定大小的影像，大小与数据不一致时按最近邻采样
+func (s *RasterStyle) Render(data *TileData, size [2]uint32, opts *imagery.ImageOptions) *imagery.ImageSource {
+	if opts == nil {
+		opts = &imagery.ImageOptions{Format: "image/png", Transparent: geo.NewBool(true)}
+	}
+	img := image.NewNRGBA(image.Rect(0, 0, int(size[0]), int(size[1])))
+	if data == nil || data.Size[0] == 0 || data.Size[1] == 0 {
+		return imagery.CreateImageSourceFromImage(img, opts)
+	}
+
+	sx := float64(data.Size[0]) / float64(size[0])
+	sy := float64(data.Size[1]) / float64(size[1])
+	for y := 0; y < int(size[1]); y++ {
+		row := min(int(float64(y)*sy), int(data.Size[1])-1)
+		for x := 0; x < int(size[0]); x++ {
+			col := min(int(float64(x)*sx), int(data.Size[0])-1)
+			v := data.Get(col, row)
+
+			var c color.NRGBA
+			if isNodata(data, v) {
+				c = s.NodataColor
+			} else {
+				c = s.Ramp.Color(v)
+				if s.Opacity < 1 {
+					c.A = uint8(math.Round(float64(c.A) * math.Max(s.Opacity, 0)))
+				}
+			}
+			img.SetNRGBA(x, y, c)
+		}
+	}
+	return imagery.CreateImageSourceFromImage(img, opts)
+}
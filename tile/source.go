@@ -15,9 +15,11 @@ const (
 )
 
 type CacheInfo struct {
-	Cacheable bool
-	Timestamp time.Time
-	Size      int64
+	Cacheable    bool
+	Timestamp    time.Time
+	Size         int64
+	ETag         string
+	LastModified string
 }
 
 type Source interface {
@@ -40,17 +40,24 @@ func (t *TileProxy) RemoveService(id string) {
 	delete(t.serviceCache, id)
 	t.serviceCacheMu.Unlock()
 
-	d.Clean()
+	if d != nil {
+		d.Clean()
+	}
 }
 
 func (t *TileProxy) Reload(proxy []*setting.ProxyService, fac setting.CacheFactory) {
 	t.m.Lock()
+	old := t.Services
 	t.Services = make(map[string]*Service)
 	for i := range proxy {
 		t.Services[proxy[i].Id] = NewService(proxy[i], t.globals, fac)
 	}
 	t.m.Unlock()
 
+	for _, d := range old {
+		d.Clean()
+	}
+
 	t.serviceCacheMu.Lock()
 	t.serviceCache = make(map[string]*Service)
 	t.serviceCacheMu.Unlock()
@@ -0,0 +1,314 @@
+package vector
+
+import (
+	"encoding/json"
+	"math"
+	"sort"
+
+	"github.com/flywave/go-geom"
+)
+
+const defaultBudgetIterations = 8
+
+// SizeBudget 单个级别的瓦片大小限制，为 0 时不限制
+type SizeBudget struct {
+	MaxBytes    int
+	MaxFeatures int
+}
+
+func (b SizeBudget) exceeded(size, features int) bool {
+	return b.bytesExceeded(size) || (b.MaxFeatures > 0 && features > b.MaxFeatures)
+}
+
+func (b SizeBudget) bytesExceeded(size int) bool {
+	return b.MaxBytes > 0 && size > b.MaxBytes
+}
+
+// VectorBudget 矢量瓦片大小限制，Levels 中的级别覆盖默认限制。
+// 超出限制时先合并属性相同的要素，再逐步加大简化容差并丢弃优先级最低、面积最小的面与点，
+// 简化不再减小瓦片后按优先级与长度丢弃线，直到满足限制。
+// PriorityProperty 为数值属性，值越大越晚丢弃，缺失时视为最低
+type VectorBudget struct {
+	SizeBudget
+	Levels           map[int]SizeBudget
+	PriorityProperty string
+	MaxIterations    int
+}
+
+// ForZoom 返回 zoom 级别生效的限制
+func (b *VectorBudget) ForZoom(zoom int) SizeBudget {
+	if l, ok := b.Levels[zoom]; ok {
+		return l
+	}
+	return b.SizeBudget
+}
+
+func countFeatures(vt Vector) int {
+	n := 0
+	for _, feats := range vt {
+		n += len(feats)
+	}
+	return n
+}
+
+func featureGeometry(feat *geom.Feature) geom.GeometryData {
+	if feat.GeometryData.Type == "" && feat.Geometry != nil {
+		return *geom.NewGeometryData(feat.Geometry)
+	}
+	return feat.GeometryData
+}
+
+// CoalesceFeatures 将同一图层中几何类型与属性都相同的要素合并为一个多部件要素，合并后的要素不保留ID
+func CoalesceFeatures(vt Vector) Vector {
+	ret := make(Vector)
+	for layer, feats := range vt {
+		groups := make(map[string]int)
+		parts := make([][]*geom.Feature, 0, len(feats))
+		for _, f := range feats {
+			if f == nil {
+				continue
+			}
+			props, err := json.Marshal(f.Properties)
+			if err != nil {
+				parts = append(parts, []*geom.Feature{f})
+				continue
+			}
+			key := featureType(f) + ":" + string(props)
+			if i, ok := groups[key]; ok {
+				parts[i] = append(parts[i], f)
+				continue
+			}
+			groups[key] = len(parts)
+			parts = append(parts, []*geom.Feature{f})
+		}
+		for _, p := range parts {
+			ret[layer] = append(ret[layer], mergeFeatures(p))
+		}
+	}
+	return ret
+}
+
+func mergeFeatures(feats []*geom.Feature) *geom.Feature {
+	if len(feats) == 1 {
+		return feats[0]
+	}
+	var merged geom.GeometryData
+	for _, f := range feats {
+		g := featureGeometry(f)
+		switch g.GetType() {
+		case "Point":
+			merged.Type = "MultiPoint"
+			merged.MultiPoint = append(merged.MultiPoint, g.Point)
+		case "MultiPoint":
+			merged.Type = "MultiPoint"
+			merged.MultiPoint = append(merged.MultiPoint, g.MultiPoint...)
+		case "LineString":
+			merged.Type = "MultiLineString"
+			merged.MultiLineString = append(merged.MultiLineString, g.LineString)
+		case "MultiLineString":
+			merged.Type = "MultiLineString"
+			merged.MultiLineString = append(merged.MultiLineString, g.MultiLineString...)
+		case "Polygon":
+			merged.Type = "MultiPolygon"
+			merged.MultiPolygon = append(merged.MultiPolygon, g.Polygon)
+		case "MultiPolygon":
+			merged.Type = "MultiPolygon"
+			merged.MultiPolygon = append(merged.MultiPolygon, g.MultiPolygon...)
+		}
+	}
+	ret := geom.NewFeatureFromGeometryData(&merged)
+	ret.Properties = feats[0].Properties
+	return ret
+}
+
+func ringArea(ring [][]float64) float64 {
+	return math.Abs(signedRingArea(ring))
+}
+
+func polygonArea(rings [][][]float64) float64 {
+	if len(rings) == 0 {
+		return 0
+	}
+	a := ringArea(rings[0])
+	for _, r := range rings[1:] {
+		a -= ringArea(r)
+	}
+	return math.Max(a, 0)
+}
+
+func lineLength(line [][]float64) float64 {
+	l := 0.0
+	for i := 1; i < len(line); i++ {
+		l += math.Hypot(line[i][0]-line[i-1][0], line[i][1]-line[i-1][1])
+	}
+	return l
+}
+
+// featureLength 返回线要素的长度，其他几何返回 -1
+func featureLength(feat *geom.Feature) float64 {
+	g := featureGeometry(feat)
+	switch g.GetType() {
+	case "LineString":
+		return lineLength(g.LineString)
+	case "MultiLineString":
+		l := 0.0
+		for _, line := range g.MultiLineString {
+			l += lineLength(line)
+		}
+		return l
+	}
+	return -1
+}
+
+// featureArea 返回面要素的面积，点返回 0，其他几何返回 -1
+func featureArea(feat *geom.Feature) float64 {
+	g := featureGeometry(feat)
+	switch g.GetType() {
+	case "Point", "MultiPoint":
+		return 0
+	case "Polygon":
+		return polygonArea(g.Polygon)
+	case "MultiPolygon":
+		a := 0.0
+		for _, p := range g.MultiPolygon {
+			a += polygonArea(p)
+		}
+		return a
+	}
+	return -1
+}
+
+// dropFeatures 按优先级与面积从低到高丢弃面与点，至少丢弃 count 个，不修改原数据。
+// lines 为 true 时线也可丢弃，同一优先级中先丢弃面与点，再按长度从短到长丢弃线
+func dropFeatures(vt Vector, count int, priority string, lines bool) Vector {
+	type candidate struct {
+		layer    string
+		index    int
+		priority float64
+		line     bool
+		size     float64
+	}
+	var candidates []candidate
+	for layer, feats := range vt {
+		for i, f := range feats {
+			size, line := featureArea(f), false
+			if size < 0 && lines {
+				size, line = featureLength(f), true
+			}
+			if size < 0 {
+				continue
+			}
+			p := math.Inf(-1)
+			if priority != "" {
+				if v, ok := toFloat(f.Properties[priority]); ok {
+					p = v
+				}
+			}
+			candidates = append(candidates, candidate{layer: layer, index: i, priority: p, line: line, size: size})
+		}
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		if candidates[i].priority != candidates[j].priority {
+			return candidates[i].priority < candidates[j].priority
+		}
+		if candidates[i].line != candidates[j].line {
+			return !candidates[i].line
+		}
+		if candidates[i].size != candidates[j].size {
+			return candidates[i].size < candidates[j].size
+		}
+		if candidates[i].layer != candidates[j].layer {
+			return candidates[i].layer < candidates[j].layer
+		}
+		return candidates[i].index < candidates[j].index
+	})
+
+	dropped := make(map[string]map[int]bool)
+	for _, c := range candidates[:min(count, len(candidates))] {
+		if dropped[c.layer] == nil {
+			dropped[c.layer] = make(map[int]bool)
+		}
+		dropped[c.layer][c.index] = true
+	}
+
+	ret := make(Vector)
+	for layer, feats := range vt {
+		for i, f := range feats {
+			if !dropped[layer][i] {
+				ret[layer] = append(ret[layer], f)
+			}
+		}
+	}
+	return ret
+}
+
+func simplifyVector(vt Vector, tolerance float64) Vector {
+	ret := make(Vector)
+	for layer, feats := range vt {
+		for _, f := range feats {
+			if s := SimplifyFeature(f, tolerance); s != nil {
+				ret[layer] = append(ret[layer], s)
+			}
+		}
+	}
+	return ret
+}
+
+// reduceVector 逐步缩减要素直到编码结果满足限制，达到最大迭代次数时返回最后一次的结果，并返回是否满足限制
+func reduceVector(opts *VectorOptions, tile [3]int, data Vector, budget SizeBudget) ([]byte, bool, error) {
+	vt := CoalesceFeatures(data)
+	buf, err := encodeVector(opts, tile, vt)
+	if err != nil {
+		return nil, false, err
+	}
+	if !budget.exceeded(len(buf), countFeatures(vt)) {
+		return buf, true, nil
+	}
+
+	extent := 4096.0
+	if opts.Extent > 0 {
+		extent = float64(opts.Extent)
+	}
+	pixels := math.Max(opts.Tolerance, 1)
+	b := tileBounds(tile)
+	tolerance := math.Max(b[2]-b[0], b[3]-b[1]) / extent * pixels
+
+	iterations := opts.Budget.MaxIterations
+	if iterations <= 0 {
+		iterations = defaultBudgetIterations
+	}
+	// 简化无法减少要素数量，字节数未超限时直接视为简化已用尽
+	exhausted := !budget.bytesExceeded(len(buf))
+	for i := 0; i < iterations; i++ {
+		if !exhausted {
+			prev := len(buf)
+			vt = simplifyVector(vt, tolerance)
+			tolerance *= 2
+			buf, err = encodeVector(opts, tile, vt)
+			if err != nil {
+				return nil, false, err
+			}
+			if !budget.exceeded(len(buf), countFeatures(vt)) {
+				return buf, true, nil
+			}
+			exhausted = len(buf) >= prev || !budget.bytesExceeded(len(buf))
+		}
+
+		// 简化后仍超限时至少丢弃四分之一的可丢弃要素，要素数量超限时一次丢弃到限制以内
+		n := countFeatures(vt)
+		drop := (n + 3) / 4
+		if budget.MaxFeatures > 0 {
+			drop = max(drop, n-budget.MaxFeatures)
+		}
+		vt = dropFeatures(vt, drop, opts.Budget.PriorityProperty, exhausted)
+
+		buf, err = encodeVector(opts, tile, vt)
+		if err != nil {
+			return nil, false, err
+		}
+		if !budget.exceeded(len(buf), countFeatures(vt)) {
+			return buf, true, nil
+		}
+	}
+	return buf, false, nil
+}
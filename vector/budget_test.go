@@ -0,0 +1,139 @@
+package vector
+
+import (
+	"testing"
+
+	"github.com/flywave/go-geom"
+
+	"github.com/flywave/go-tileproxy/metrics"
+)
+
+func budgetTestVector() Vector {
+	vt := make(Vector)
+	// 属性相同的点可以合并，面按 rank 与面积排序丢弃
+	for i := 0; i < 50; i++ {
+		p := geom.NewPointFeature([]float64{1 + float64(i)*0.1, 1})
+		p.Properties["kind"] = "poi"
+		vt["poi"] = append(vt["poi"], p)
+	}
+	for i := 0; i < 40; i++ {
+		x := float64(i)
+		size := 0.1 + float64(i)*0.05
+		poly := geom.NewPolygonFeature([][][]float64{{{x, 10}, {x + size, 10}, {x + size, 10 + size}, {x, 10 + size}, {x, 10}}})
+		poly.Properties["id"] = i
+		poly.Properties["rank"] = i % 4
+		vt["building"] = append(vt["building"], poly)
+	}
+	return vt
+}
+
+func TestCoalesceFeatures(t *testing.T) {
+	vt := CoalesceFeatures(budgetTestVector())
+	if len(vt["poi"]) != 1 || vt["poi"][0].GeometryData.Type != "MultiPoint" || len(vt["poi"][0].GeometryData.MultiPoint) != 50 {
+		t.Errorf("expected points to be merged into one MultiPoint, got %d features", len(vt["poi"]))
+	}
+	if len(vt["building"]) != 40 {
+		t.Errorf("features with different properties should not be merged, got %d", len(vt["building"]))
+	}
+}
+
+func TestDropFeatures(t *testing.T) {
+	vt := dropFeatures(budgetTestVector(), 60, "rank", false)
+	if len(vt["building"]) != 30 {
+		t.Fatalf("expected 30 buildings left, got %d", len(vt["building"]))
+	}
+	for _, f := range vt["building"] {
+		if f.Properties["rank"].(int) == 0 {
+			t.Errorf("building %v with lowest rank should be dropped first", f.Properties["id"])
+		}
+	}
+}
+
+func TestEncodeVectorBudget(t *testing.T) {
+	coord := [3]int{32, 31, 6}
+	opts := &VectorOptions{Format: PBF_MIME, Proto: int(PBF_PTOTO_MAPBOX), Extent: 4096}
+	full, err := EncodeVector(opts, coord, budgetTestVector())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	opts.Budget = &VectorBudget{
+		SizeBudget:       SizeBudget{MaxBytes: len(full) * 10},
+		Levels:           map[int]SizeBudget{6: {MaxBytes: len(full) / 2, MaxFeatures: 20}},
+		PriorityProperty: "rank",
+	}
+	buf, err := EncodeVector(opts, coord, budgetTestVector())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(buf) > len(full)/2 {
+		t.Errorf("expected at most %d bytes, got %d", len(full)/2, len(buf))
+	}
+
+	// 未超出限制的级别保持不变
+	buf, err = EncodeVector(opts, [3]int{16, 15, 5}, budgetTestVector())
+	if err != nil {
+		t.Fatal(err)
+	}
+	plain, _ := encodeVector(opts, [3]int{16, 15, 5}, budgetTestVector())
+	if len(buf) != len(plain) {
+		t.Errorf("tile within budget should not be reduced")
+	}
+}
+
+func roadTestVector() Vector {
+	vt := make(Vector)
+	for i := 0; i < 40; i++ {
+		y := 1 + float64(i)*0.2
+		length := 0.5 + float64(i)*0.1
+		road := geom.NewLineStringFeature([][]float64{{1, y}, {1 + length/2, y + 0.05}, {1 + length, y}})
+		road.Properties["id"] = i
+		road.Properties["rank"] = i % 2
+		vt["road"] = append(vt["road"], road)
+	}
+	return vt
+}
+
+func TestDropLines(t *testing.T) {
+	if vt := dropFeatures(roadTestVector(), 10, "rank", false); len(vt["road"]) != 40 {
+		t.Errorf("lines should be kept until simplification is exhausted, got %d", len(vt["road"]))
+	}
+	vt := dropFeatures(roadTestVector(), 10, "rank", true)
+	if len(vt["road"]) != 30 {
+		t.Fatalf("expected 30 roads left, got %d", len(vt["road"]))
+	}
+	for _, f := range vt["road"] {
+		id := f.Properties["id"].(int)
+		if id%2 == 0 && id < 20 {
+			t.Errorf("short road %d with lowest rank should be dropped first", id)
+		}
+	}
+}
+
+func TestEncodeVectorBudgetLines(t *testing.T) {
+	metrics.ResetMetrics()
+	coord := [3]int{32, 31, 6}
+	opts := &VectorOptions{Format: PBF_MIME, Proto: int(PBF_PTOTO_MAPBOX), Extent: 4096}
+	full, err := EncodeVector(opts, coord, roadTestVector())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	opts.Budget = &VectorBudget{SizeBudget: SizeBudget{MaxBytes: len(full) / 4}, PriorityProperty: "rank"}
+	buf, err := EncodeVector(opts, coord, roadTestVector())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(buf) > len(full)/4 {
+		t.Errorf("lines should be dropped to meet the budget: %d > %d", len(buf), len(full)/4)
+	}
+
+	opts.Budget = &VectorBudget{SizeBudget: SizeBudget{MaxBytes: 1}, MaxIterations: 1}
+	if _, err := EncodeVector(opts, coord, roadTestVector()); err != nil {
+		t.Fatal(err)
+	}
+	stats := metrics.GetStats()["vector_reduced"].(map[string]interface{})["6"].(map[string]interface{})
+	if stats["tiles"].(int64) != 2 || stats["over_budget"].(int64) != 1 {
+		t.Errorf("unexpected reduction stats %v", stats)
+	}
+}
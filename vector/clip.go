@@ -0,0 +1,242 @@
+package vector
+
+import (
+	"github.com/flywave/go-geom"
+	vec2d "github.com/flywave/go3d/float64/vec2"
+
+	"github.com/flywave/go-tileproxy/tile"
+)
+
+func pointInRect(p []float64, bbox vec2d.Rect) bool {
+	return p[0] >= bbox.Min[0] && p[0] <= bbox.Max[0] && p[1] >= bbox.Min[1] && p[1] <= bbox.Max[1]
+}
+
+// clipSegment 使用 Liang-Barsky 算法裁剪线段，线段完全在范围外时返回 false
+func clipSegment(a, b []float64, bbox vec2d.Rect) ([]float64, []float64, bool) {
+	t0, t1 := 0.0, 1.0
+	dx, dy := b[0]-a[0], b[1]-a[1]
+	p := [4]float64{-dx, dx, -dy, dy}
+	q := [4]float64{a[0] - bbox.Min[0], bbox.Max[0] - a[0], a[1] - bbox.Min[1], bbox.Max[1] - a[1]}
+	for i := 0; i < 4; i++ {
+		if p[i] == 0 {
+			if q[i] < 0 {
+				return nil, nil, false
+			}
+			continue
+		}
+		r := q[i] / p[i]
+		if p[i] < 0 {
+			if r > t1 {
+				return nil, nil, false
+			}
+			t0 = max(t0, r)
+		} else {
+			if r < t0 {
+				return nil, nil, false
+			}
+			t1 = min(t1, r)
+		}
+	}
+	ca, cb := a, b
+	if t0 > 0 {
+		ca = []float64{a[0] + t0*dx, a[1] + t0*dy}
+	}
+	if t1 < 1 {
+		cb = []float64{a[0] + t1*dx, a[1] + t1*dy}
+	}
+	return ca, cb, true
+}
+
+func samePoint(a, b []float64) bool {
+	return a[0] == b[0] && a[1] == b[1]
+}
+
+// ClipLine 将折线裁剪到范围内，折线多次进出范围时拆分为多段
+func ClipLine(line [][]float64, bbox vec2d.Rect) [][][]float64 {
+	var ret [][][]float64
+	var cur [][]float64
+	for i := 0; i+1 < len(line); i++ {
+		a, b, ok := clipSegment(line[i], line[i+1], bbox)
+		if !ok {
+			if len(cur) >= 2 {
+				ret = append(ret, cur)
+			}
+			cur = nil
+			continue
+		}
+		if len(cur) > 0 && !samePoint(cur[len(cur)-1], a) {
+			if len(cur) >= 2 {
+				ret = append(ret, cur)
+			}
+			cur = nil
+		}
+		if len(cur) == 0 {
+			cur = append(cur, a)
+		}
+		cur = append(cur, b)
+		if !samePoint(b, line[i+1]) {
+			ret = append(ret, cur)
+			cur = nil
+		}
+	}
+	if len(cur) >= 2 {
+		ret = append(ret, cur)
+	}
+	return ret
+}
+
+// clipRingEdge 使用 Sutherland-Hodgman 算法按一条边裁剪环，axis 为坐标轴，keepLess 表示保留小于 v 的一侧
+func clipRingEdge(ring [][]float64, axis int, v float64, keepLess bool) [][]float64 {
+	inside := func(p []float64) bool {
+		if keepLess {
+			return p[axis] <= v
+		}
+		return p[axis] >= v
+	}
+	intersect := func(a, b []float64) []float64 {
+		t := (v - a[axis]) / (b[axis] - a[axis])
+		return []float64{a[0] + t*(b[0]-a[0]), a[1] + t*(b[1]-a[1])}
+	}
+	ret := make([][]float64, 0, len(ring))
+	for i := range ring {
+		cur, prev := ring[i], ring[(i+len(ring)-1)%len(ring)]
+		if inside(cur) {
+			if !inside(prev) {
+				ret = append(ret, intersect(prev, cur))
+			}
+			ret = append(ret, cur)
+		} else if inside(prev) {
+			ret = append(ret, intersect(prev, cur))
+		}
+	}
+	return ret
+}
+
+// ClipRing 将闭合环裁剪到范围内，结果退化时返回 nil
+func ClipRing(ring [][]float64, bbox vec2d.Rect) [][]float64 {
+	if len(ring) > 1 && samePoint(ring[0], ring[len(ring)-1]) {
+		ring = ring[:len(ring)-1]
+	}
+	ring = clipRingEdge(ring, 0, bbox.Min[0], false)
+	ring = clipRingEdge(ring, 0, bbox.Max[0], true)
+	ring = clipRingEdge(ring, 1, bbox.Min[1], false)
+	ring = clipRingEdge(ring, 1, bbox.Max[1], true)
+	if len(ring) < 3 {
+		return nil
+	}
+	return append(ring, ring[0])
+}
+
+func clipPolygon(rings [][][]float64, bbox vec2d.Rect) [][][]float64 {
+	ret := make([][][]float64, 0, len(rings))
+	for i, r := range rings {
+		c := ClipRing(r, bbox)
+		if c == nil {
+			if i == 0 {
+				return nil
+			}
+			continue
+		}
+		ret = append(ret, c)
+	}
+	return ret
+}
+
+// ClipFeature 将要素几何裁剪到 bbox，裁剪后为空时返回 nil
+func ClipFeature(feat *geom.Feature, bbox vec2d.Rect) *geom.Feature {
+	geometry := feat.GeometryData
+	if geometry.Type == "" && feat.Geometry != nil {
+		geometry = *geom.NewGeometryData(feat.Geometry)
+	}
+
+	switch geometry.GetType() {
+	case "Point":
+		if !pointInRect(geometry.Point, bbox) {
+			return nil
+		}
+	case "MultiPoint":
+		points := make([][]float64, 0, len(geometry.MultiPoint))
+		for _, p := range geometry.MultiPoint {
+			if pointInRect(p, bbox) {
+				points = append(points, p)
+			}
+		}
+		if len(points) == 0 {
+			return nil
+		}
+		geometry.MultiPoint = points
+	case "LineString", "MultiLineString":
+		lines := geometry.MultiLineString
+		if geometry.GetType() == "LineString" {
+			lines = [][][]float64{geometry.LineString}
+		}
+		var clipped [][][]float64
+		for _, l := range lines {
+			clipped = append(clipped, ClipLine(l, bbox)...)
+		}
+		switch len(clipped) {
+		case 0:
+			return nil
+		case 1:
+			geometry.Type, geometry.LineString, geometry.MultiLineString = "LineString", clipped[0], nil
+		default:
+			geometry.Type, geometry.LineString, geometry.MultiLineString = "MultiLineString", nil, clipped
+		}
+	case "Polygon":
+		geometry.Polygon = clipPolygon(geometry.Polygon, bbox)
+		if len(geometry.Polygon) == 0 {
+			return nil
+		}
+	case "MultiPolygon":
+		polys := make([][][][]float64, 0, len(geometry.MultiPolygon))
+		for _, p := range geometry.MultiPolygon {
+			if c := clipPolygon(p, bbox); len(c) > 0 {
+				polys = append(polys, c)
+			}
+		}
+		if len(polys) == 0 {
+			return nil
+		}
+		geometry.MultiPolygon = polys
+	default:
+		return nil
+	}
+
+	newFeature := geom.NewFeatureFromGeometryData(&geometry)
+	newFeature.Properties = feat.Properties
+	newFeature.ID = feat.ID
+	return newFeature
+}
+
+// ClipVector 将全部图层的要素裁剪到 bbox，裁剪后为空的图层被删除
+func ClipVector(vt Vector, bbox vec2d.Rect) Vector {
+	ret := make(Vector)
+	for layer, feats := range vt {
+		for _, f := range feats {
+			if f == nil {
+				continue
+			}
+			if c := ClipFeature(f, bbox); c != nil {
+				ret[layer] = append(ret[layer], c)
+			}
+		}
+	}
+	return ret
+}
+
+// Overzoom 从上级瓦片中截取 coord 范围内的要素生成新瓦片，bbox 为 coord 的经纬度范围，
+// 按 opts.Buffer 在 opts.Extent 中的比例向外扩展裁剪范围，编码时要素按新瓦片缩放
+func Overzoom(src tile.Source, coord [3]int, bbox vec2d.Rect, opts *VectorOptions) tile.Source {
+	vt, ok := src.GetTile().(Vector)
+	if !ok {
+		return nil
+	}
+	extent := 4096.0
+	if opts.Extent > 0 {
+		extent = float64(opts.Extent)
+	}
+	bx := bbox.Width() * float64(opts.Buffer) / extent
+	by := bbox.Height() * float64(opts.Buffer) / extent
+	clip := vec2d.Rect{Min: vec2d.T{bbox.Min[0] - bx, bbox.Min[1] - by}, Max: vec2d.T{bbox.Max[0] + bx, bbox.Max[1] + by}}
+	return CreateVectorSourceFromVector(ClipVector(vt, clip), coord, opts, src.GetCacheable())
+}
@@ -0,0 +1,55 @@
+package vector
+
+import (
+	"testing"
+
+	"github.com/flywave/go-geom"
+	vec2d "github.com/flywave/go3d/float64/vec2"
+)
+
+func TestClipLine(t *testing.T) {
+	bbox := vec2d.Rect{Min: vec2d.T{0, 0}, Max: vec2d.T{10, 10}}
+
+	// 折线两次穿过范围，拆分为两段
+	lines := ClipLine([][]float64{{-5, 5}, {5, 5}, {5, 15}, {8, 15}, {8, 5}, {15, 5}}, bbox)
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 parts, got %v", lines)
+	}
+	if lines[0][0][0] != 0 || lines[0][len(lines[0])-1][1] != 10 {
+		t.Errorf("unexpected first part %v", lines[0])
+	}
+	if lines[1][0][1] != 10 || lines[1][len(lines[1])-1][0] != 10 {
+		t.Errorf("unexpected second part %v", lines[1])
+	}
+
+	if len(ClipLine([][]float64{{-5, -5}, {-1, 20}}, bbox)) != 0 {
+		t.Error("line outside bbox should be dropped")
+	}
+}
+
+func TestClipFeature(t *testing.T) {
+	bbox := vec2d.Rect{Min: vec2d.T{0, 0}, Max: vec2d.T{10, 10}}
+
+	poly := geom.NewPolygonFeature([][][]float64{{{-5, -5}, {5, -5}, {5, 5}, {-5, 5}, {-5, -5}}})
+	poly.Properties["name"] = "a"
+	c := ClipFeature(poly, bbox)
+	if c == nil || c.Properties["name"] != "a" {
+		t.Fatal("polygon should be clipped")
+	}
+	ring := c.GeometryData.Polygon[0]
+	if len(ring) != 5 || !samePoint(ring[0], ring[len(ring)-1]) {
+		t.Errorf("unexpected ring %v", ring)
+	}
+	for _, p := range ring {
+		if !pointInRect(p, bbox) {
+			t.Errorf("point %v outside bbox", p)
+		}
+	}
+
+	if ClipFeature(geom.NewPointFeature([]float64{11, 5}), bbox) != nil {
+		t.Error("point outside bbox should be dropped")
+	}
+	if ClipFeature(geom.NewPolygonFeature([][][]float64{{{20, 20}, {30, 20}, {30, 30}, {20, 20}}}), bbox) != nil {
+		t.Error("polygon outside bbox should be dropped")
+	}
+}
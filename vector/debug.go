@@ -0,0 +1,39 @@
+package vector
+
+import (
+	vec2d "github.com/flywave/go3d/float64/vec2"
+
+	"github.com/flywave/go-geom"
+	"github.com/flywave/go-tileproxy/tile"
+)
+
+const DebugLayerName = "debug"
+
+// AddDebugLayer 在矢量瓦片中加入debug图层，包含瓦片边框及携带调试属性的中心点，bbox为经纬度范围
+func AddDebugLayer(src tile.Source, coord [3]int, bbox vec2d.Rect, props map[string]interface{}) tile.Source {
+	opts, ok := src.GetTileOptions().(*VectorOptions)
+	if !ok {
+		return src
+	}
+	data, ok := src.GetTile().(Vector)
+	if !ok {
+		return src
+	}
+
+	vt := make(Vector, len(data)+1)
+	for k, v := range data {
+		vt[k] = v
+	}
+
+	minx, miny, maxx, maxy := bbox.Min[0], bbox.Min[1], bbox.Max[0], bbox.Max[1]
+	outline := geom.NewLineStringFeature([][]float64{{minx, miny}, {maxx, miny}, {maxx, maxy}, {minx, maxy}, {minx, miny}})
+	center := geom.NewPointFeature([]float64{(minx + maxx) / 2, (miny + maxy) / 2})
+	for k, v := range props {
+		center.Properties[k] = v
+	}
+	outline.Properties["kind"] = "outline"
+	center.Properties["kind"] = "label"
+	vt[DebugLayerName] = []*geom.Feature{outline, center}
+
+	return CreateVectorSourceFromVector(vt, coord, opts, nil)
+}
@@ -0,0 +1,41 @@
+package vector
+
+import (
+	"testing"
+
+	vec2d "github.com/flywave/go3d/float64/vec2"
+
+	"github.com/flywave/go-geom"
+)
+
+func TestAddDebugLayer(t *testing.T) {
+	opts := &VectorOptions{Format: MVT_MIME, Extent: 4096, Proto: int(PBF_PTOTO_MAPBOX)}
+	road := geom.NewLineStringFeature([][]float64{{0, 0}, {1, 1}})
+	src := CreateVectorSourceFromVector(Vector{"roads": {road}}, [3]int{0, 0, 1}, opts, nil)
+
+	bbox := vec2d.Rect{Min: vec2d.T{-180, 0}, Max: vec2d.T{0, 85}}
+	out := AddDebugLayer(src, [3]int{0, 0, 1}, bbox, map[string]interface{}{"z": 1, "cache": "hit"})
+
+	vt, ok := out.GetTile().(Vector)
+	if !ok {
+		t.Fatal("Expected vector tile data")
+	}
+	if len(vt["roads"]) != 1 {
+		t.Errorf("Expected original layer to be kept, got %d features", len(vt["roads"]))
+	}
+	feats := vt[DebugLayerName]
+	if len(feats) != 2 {
+		t.Fatalf("Expected 2 debug features, got %d", len(feats))
+	}
+	if feats[0].Properties["kind"] != "outline" {
+		t.Errorf("Expected outline feature, got %v", feats[0].Properties["kind"])
+	}
+	if feats[1].Properties["cache"] != "hit" || feats[1].Properties["kind"] != "label" {
+		t.Errorf("Unexpected label properties: %v", feats[1].Properties)
+	}
+
+	data, ok := src.GetTile().(Vector)
+	if !ok || len(data) != 1 {
+		t.Error("Expected source tile to stay unchanged")
+	}
+}
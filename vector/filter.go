@@ -0,0 +1,209 @@
+package vector
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/flywave/go-geom"
+)
+
+// FeatureFilter 要素过滤条件，返回 true 时保留要素
+type FeatureFilter func(feat *geom.Feature, zoom int) bool
+
+// ParseFeatureFilter 解析 Mapbox 旧版过滤表达式，例如 ["all", ["==", "$type", "Polygon"], [">=", "height", 10]]。
+// 支持 ==、!=、<、<=、>、>=、in、!in、has、!has、all、any、none，
+// 特殊键 $type 为几何类型（多部件几何视为对应的单部件类型），$id 为要素ID，$zoom 为瓦片级别
+func ParseFeatureFilter(expr []interface{}) (FeatureFilter, error) {
+	if len(expr) == 0 {
+		return nil, fmt.Errorf("empty filter expression")
+	}
+	op, ok := expr[0].(string)
+	if !ok {
+		return nil, fmt.Errorf("filter operator must be a string: %v", expr[0])
+	}
+	args := expr[1:]
+
+	switch op {
+	case "all", "any", "none":
+		subs := make([]FeatureFilter, 0, len(args))
+		for _, a := range args {
+			sub, ok := a.([]interface{})
+			if !ok {
+				return nil, fmt.Errorf("%s expects filter expressions, got %v", op, a)
+			}
+			f, err := ParseFeatureFilter(sub)
+			if err != nil {
+				return nil, err
+			}
+			subs = append(subs, f)
+		}
+		return combineFilters(op, subs), nil
+	case "has", "!has":
+		if len(args) != 1 {
+			return nil, fmt.Errorf("%s expects one key", op)
+		}
+		key, ok := args[0].(string)
+		if !ok {
+			return nil, fmt.Errorf("%s key must be a string", op)
+		}
+		want := op == "has"
+		return func(feat *geom.Feature, zoom int) bool {
+			_, found := featureValue(feat, key, zoom)
+			return found == want
+		}, nil
+	case "in", "!in":
+		if len(args) < 1 {
+			return nil, fmt.Errorf("%s expects a key", op)
+		}
+		key, ok := args[0].(string)
+		if !ok {
+			return nil, fmt.Errorf("%s key must be a string", op)
+		}
+		values := args[1:]
+		want := op == "in"
+		return func(feat *geom.Feature, zoom int) bool {
+			v, found := featureValue(feat, key, zoom)
+			in := false
+			if found {
+				for _, c := range values {
+					if compareValues(v, c) == 0 {
+						in = true
+						break
+					}
+				}
+			}
+			return in == want
+		}, nil
+	case "==", "!=", "<", "<=", ">", ">=":
+		if len(args) != 2 {
+			return nil, fmt.Errorf("%s expects a key and a value", op)
+		}
+		key, ok := args[0].(string)
+		if !ok {
+			return nil, fmt.Errorf("%s key must be a string", op)
+		}
+		value := args[1]
+		return func(feat *geom.Feature, zoom int) bool {
+			v, found := featureValue(feat, key, zoom)
+			if !found {
+				return op == "!="
+			}
+			c := compareValues(v, value)
+			switch op {
+			case "==":
+				return c == 0
+			case "!=":
+				return c != 0
+			case "<":
+				return c == -1
+			case "<=":
+				return c == -1 || c == 0
+			case ">":
+				return c == 1
+			}
+			return c == 1 || c == 0
+		}, nil
+	}
+	return nil, fmt.Errorf("unknown filter operator: %s", op)
+}
+
+func combineFilters(op string, subs []FeatureFilter) FeatureFilter {
+	return func(feat *geom.Feature, zoom int) bool {
+		for _, f := range subs {
+			matched := f(feat, zoom)
+			switch {
+			case op == "all" && !matched:
+				return false
+			case op == "any" && matched:
+				return true
+			case op == "none" && matched:
+				return false
+			}
+		}
+		return op != "any"
+	}
+}
+
+func featureType(feat *geom.Feature) string {
+	t := string(feat.GeometryData.Type)
+	if t == "" && feat.Geometry != nil {
+		t = string(geom.NewGeometryData(feat.Geometry).Type)
+	}
+	return strings.TrimPrefix(t, "Multi")
+}
+
+func featureValue(feat *geom.Feature, key string, zoom int) (interface{}, bool) {
+	switch key {
+	case "$type":
+		return featureType(feat), true
+	case "$id":
+		return feat.ID, feat.ID != nil
+	case "$zoom":
+		return zoom, true
+	}
+	if feat.Properties == nil {
+		return nil, false
+	}
+	v, ok := feat.Properties[key]
+	return v, ok
+}
+
+func toFloat(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case int:
+		return float64(n), true
+	case int8:
+		return float64(n), true
+	case int16:
+		return float64(n), true
+	case int32:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case uint:
+		return float64(n), true
+	case uint8:
+		return float64(n), true
+	case uint16:
+		return float64(n), true
+	case uint32:
+		return float64(n), true
+	case uint64:
+		return float64(n), true
+	case float32:
+		return float64(n), true
+	case float64:
+		return n, true
+	}
+	return 0, false
+}
+
+// compareValues 比较两个值，数字按数值比较，字符串按字典序比较，类型不同或不可比较时返回 2
+func compareValues(a, b interface{}) int {
+	if fa, ok := toFloat(a); ok {
+		fb, ok := toFloat(b)
+		if !ok {
+			return 2
+		}
+		switch {
+		case fa < fb:
+			return -1
+		case fa > fb:
+			return 1
+		}
+		return 0
+	}
+	switch va := a.(type) {
+	case string:
+		vb, ok := b.(string)
+		if !ok {
+			return 2
+		}
+		return strings.Compare(va, vb)
+	case bool:
+		if vb, ok := b.(bool); ok && va == vb {
+			return 0
+		}
+	}
+	return 2
+}
@@ -1,6 +1,8 @@
 package vector
 
 import (
+	"math"
+
 	"github.com/flywave/go-geom"
 
 	vec2d "github.com/flywave/go3d/float64/vec2"
@@ -86,7 +88,9 @@ func (l *LayerBuilder) AddFeatures(feats []*geom.Feature) {
 func (l *LayerBuilder) addFeature(feature *geom.Feature) {
 	builder := NewFeatureBuilder(l.coverage)
 	builder.Apply(feature)
-	l.result = append(l.result, builder.Finalize())
+	if f := builder.Finalize(); f != nil {
+		l.result = append(l.result, f)
+	}
 }
 
 func (l *LayerBuilder) Finalize() []*geom.Feature {
@@ -211,6 +215,25 @@ func (t *TiledVector) Transform(grid *geo.TileGrid, req_bbox vec2d.Rect, req_srs
 
 	transformer := NewVectorTransformer(geo_src_srs, geo_req_srs)
 
+	// 按输出瓦片的一个单位加密长边，并裁剪到带缓冲区的请求范围
+	clip := req_bbox
+	if !req_srs.Eq(geo_req_srs) {
+		clip = req_srs.TransformRectTo(geo_req_srs, req_bbox, 16)
+	}
+	extent, buffer := 4096.0, 0.0
+	if vec_opts != nil {
+		if vec_opts.Extent > 0 {
+			extent = float64(vec_opts.Extent)
+		}
+		buffer = float64(vec_opts.Buffer)
+	}
+	xbuf, ybuf := clip.Width()*buffer/extent, clip.Height()*buffer/extent
+	transformer.MaxError = math.Max(clip.Width(), clip.Height()) / extent
+	transformer.ClipBBox = &vec2d.Rect{
+		Min: vec2d.T{clip.Min[0] - xbuf, clip.Min[1] - ybuf},
+		Max: vec2d.T{clip.Max[0] + xbuf, clip.Max[1] + ybuf},
+	}
+
 	vecs := transformer.ApplyVector(src_img)
 
 	_, _, tiles, _ := grid.GetAffectedTiles(req_bbox, t.TileSize, req_srs)
@@ -0,0 +1,120 @@
+package vector
+
+import (
+	"github.com/flywave/go-geom"
+)
+
+// LayerRule 单个图层的处理规则
+type LayerRule struct {
+	Rename            string
+	MinZoom           *int
+	MaxZoom           *int
+	Properties        []string
+	ExcludeProperties []string
+	RenameProperties  map[string]string
+	Filter            FeatureFilter
+}
+
+func (r *LayerRule) inZoom(zoom int) bool {
+	if r.MinZoom != nil && zoom < *r.MinZoom {
+		return false
+	}
+	if r.MaxZoom != nil && zoom > *r.MaxZoom {
+		return false
+	}
+	return true
+}
+
+func (r *LayerRule) properties(props map[string]interface{}) map[string]interface{} {
+	ret := make(map[string]interface{}, len(props))
+	if len(r.Properties) > 0 {
+		for _, k := range r.Properties {
+			if v, ok := props[k]; ok {
+				ret[k] = v
+			}
+		}
+	} else {
+		for k, v := range props {
+			ret[k] = v
+		}
+	}
+	for _, k := range r.ExcludeProperties {
+		delete(ret, k)
+	}
+	for from, to := range r.RenameProperties {
+		if v, ok := ret[from]; ok {
+			delete(ret, from)
+			ret[to] = v
+		}
+	}
+	return ret
+}
+
+func (r *LayerRule) apply(feats []*geom.Feature, zoom int) []*geom.Feature {
+	ret := make([]*geom.Feature, 0, len(feats))
+	for _, f := range feats {
+		if f == nil {
+			continue
+		}
+		if r.Filter != nil && !r.Filter(f, zoom) {
+			continue
+		}
+		if len(r.Properties)+len(r.ExcludeProperties)+len(r.RenameProperties) > 0 {
+			nf := *f
+			nf.Properties = r.properties(f.Properties)
+			f = &nf
+		}
+		ret = append(ret, f)
+	}
+	return ret
+}
+
+// VectorProcessor 按图层名保留或删除图层，并按图层规则重命名图层、筛选属性与要素。
+// Layers 为空时保留全部图层，重命名为同名的图层合并为一个图层
+type VectorProcessor struct {
+	Layers     []string
+	DropLayers []string
+	Rules      map[string]*LayerRule
+}
+
+func NewVectorProcessor(layers, drop_layers []string, rules map[string]*LayerRule) *VectorProcessor {
+	return &VectorProcessor{Layers: layers, DropLayers: drop_layers, Rules: rules}
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *VectorProcessor) keepLayer(name string) bool {
+	if len(p.Layers) > 0 && !containsString(p.Layers, name) {
+		return false
+	}
+	return !containsString(p.DropLayers, name)
+}
+
+// Apply 处理 zoom 级别的矢量瓦片，返回新的图层集合，不修改原数据
+func (p *VectorProcessor) Apply(vt Vector, zoom int) Vector {
+	ret := make(Vector)
+	for name, feats := range vt {
+		if !p.keepLayer(name) {
+			continue
+		}
+		out := name
+		if rule, ok := p.Rules[name]; ok && rule != nil {
+			if !rule.inZoom(zoom) {
+				continue
+			}
+			feats = rule.apply(feats, zoom)
+			if rule.Rename != "" {
+				out = rule.Rename
+			}
+		}
+		ret[out] = append(ret[out], feats...)
+	}
+	return ret
+}
@@ -0,0 +1,99 @@
+package vector
+
+import (
+	"testing"
+
+	"github.com/flywave/go-geom"
+)
+
+func testFeature(props map[string]interface{}) *geom.Feature {
+	f := geom.NewLineStringFeature([][]float64{{0, 0}, {1, 1}})
+	f.Properties = props
+	return f
+}
+
+func TestParseFeatureFilter(t *testing.T) {
+	f := testFeature(map[string]interface{}{"class": "primary", "lanes": int64(4), "oneway": true})
+
+	cases := []struct {
+		expr []interface{}
+		want bool
+	}{
+		{[]interface{}{"==", "class", "primary"}, true},
+		{[]interface{}{"!=", "class", "primary"}, false},
+		{[]interface{}{">=", "lanes", 4.0}, true},
+		{[]interface{}{"<", "lanes", 2}, false},
+		{[]interface{}{"in", "class", "motorway", "primary"}, true},
+		{[]interface{}{"!in", "class", "motorway", "primary"}, false},
+		{[]interface{}{"has", "oneway"}, true},
+		{[]interface{}{"!has", "name"}, true},
+		{[]interface{}{"==", "$type", "LineString"}, true},
+		{[]interface{}{"all", []interface{}{"==", "$type", "LineString"}, []interface{}{">", "$zoom", 10}}, true},
+		{[]interface{}{"any", []interface{}{"==", "class", "motorway"}, []interface{}{"==", "oneway", false}}, false},
+		{[]interface{}{"none", []interface{}{"==", "class", "motorway"}}, true},
+		{[]interface{}{"==", "lanes", "4"}, false},
+	}
+	for i, c := range cases {
+		filter, err := ParseFeatureFilter(c.expr)
+		if err != nil {
+			t.Fatalf("case %d: %v", i, err)
+		}
+		if got := filter(f, 12); got != c.want {
+			t.Errorf("case %d %v: got %v, want %v", i, c.expr, got, c.want)
+		}
+	}
+
+	for _, expr := range [][]interface{}{{}, {"like", "class", "a"}, {"==", "class"}, {"all", "x"}} {
+		if _, err := ParseFeatureFilter(expr); err == nil {
+			t.Errorf("expected error for %v", expr)
+		}
+	}
+}
+
+func TestVectorProcessor(t *testing.T) {
+	vt := Vector{
+		"roads": {
+			testFeature(map[string]interface{}{"class": "primary", "name": "a", "ref": "A1"}),
+			testFeature(map[string]interface{}{"class": "path", "name": "b", "ref": "B1"}),
+		},
+		"water":     {testFeature(map[string]interface{}{"name": "lake"})},
+		"buildings": {testFeature(map[string]interface{}{"height": 10})},
+	}
+
+	filter, _ := ParseFeatureFilter([]interface{}{"!=", "class", "path"})
+	minzoom := 14
+	p := NewVectorProcessor(nil, []string{"water"}, map[string]*LayerRule{
+		"roads": {
+			Rename:           "transportation",
+			Properties:       []string{"class", "name"},
+			RenameProperties: map[string]string{"name": "label"},
+			Filter:           filter,
+		},
+		"buildings": {MinZoom: &minzoom},
+	})
+
+	out := p.Apply(vt, 12)
+	if len(out) != 1 {
+		t.Fatalf("unexpected layers %v", out)
+	}
+	roads := out["transportation"]
+	if len(roads) != 1 {
+		t.Fatalf("expected 1 road, got %d", len(roads))
+	}
+	props := roads[0].Properties
+	if props["class"] != "primary" || props["label"] != "a" || len(props) != 2 {
+		t.Errorf("unexpected properties %v", props)
+	}
+	if _, ok := vt["roads"][0].Properties["ref"]; !ok {
+		t.Error("source feature modified")
+	}
+
+	if out := p.Apply(vt, 14); len(out["buildings"]) != 1 {
+		t.Error("buildings should be kept at zoom 14")
+	}
+
+	keep := NewVectorProcessor([]string{"water"}, nil, nil).Apply(vt, 0)
+	if len(keep) != 1 || len(keep["water"]) != 1 {
+		t.Errorf("unexpected layers %v", keep)
+	}
+}
@@ -0,0 +1,100 @@
+package vector
+
+import (
+	"github.com/flywave/go-geom"
+)
+
+func pointInRing(p []float64, ring [][]float64) bool {
+	in := false
+	for i, j := 0, len(ring)-1; i < len(ring); j, i = i, i+1 {
+		a, b := ring[i], ring[j]
+		if (a[1] > p[1]) != (b[1] > p[1]) && p[0] < (b[0]-a[0])*(p[1]-a[1])/(b[1]-a[1])+a[0] {
+			in = !in
+		}
+	}
+	return in
+}
+
+func nearLine(p []float64, line [][]float64, sqTolerance float64) bool {
+	if len(line) == 1 {
+		return sqSegDist(p, line[0], line[0]) <= sqTolerance
+	}
+	for i := 0; i+1 < len(line); i++ {
+		if sqSegDist(p, line[i], line[i+1]) <= sqTolerance {
+			return true
+		}
+	}
+	return false
+}
+
+func hitPolygon(p []float64, rings [][][]float64, sqTolerance float64) bool {
+	if len(rings) == 0 {
+		return false
+	}
+	for _, r := range rings {
+		if nearLine(p, r, sqTolerance) {
+			return true
+		}
+	}
+	if !pointInRing(p, rings[0]) {
+		return false
+	}
+	for _, hole := range rings[1:] {
+		if pointInRing(p, hole) {
+			return false
+		}
+	}
+	return true
+}
+
+// HitFeature 判断要素是否命中点 p，点与线按 tolerance 距离判断，面判断点是否在面内或距边界不超过 tolerance
+func HitFeature(feat *geom.Feature, p []float64, tolerance float64) bool {
+	g := featureGeometry(feat)
+	sq := tolerance * tolerance
+	switch g.GetType() {
+	case "Point":
+		return nearLine(p, [][]float64{g.Point}, sq)
+	case "MultiPoint":
+		for _, pt := range g.MultiPoint {
+			if nearLine(p, [][]float64{pt}, sq) {
+				return true
+			}
+		}
+	case "LineString":
+		return nearLine(p, g.LineString, sq)
+	case "MultiLineString":
+		for _, l := range g.MultiLineString {
+			if nearLine(p, l, sq) {
+				return true
+			}
+		}
+	case "Polygon":
+		return hitPolygon(p, g.Polygon, sq)
+	case "MultiPolygon":
+		for _, poly := range g.MultiPolygon {
+			if hitPolygon(p, poly, sq) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// QueryVector 返回各图层中命中点 p 的要素，limit 大于 0 时最多返回 limit 个要素
+func QueryVector(vt Vector, p []float64, tolerance float64, limit int) Vector {
+	ret := make(Vector)
+	count := 0
+	for _, layer := range vt.LayerNames() {
+		for _, f := range vt[layer] {
+			if f == nil || !HitFeature(f, p, tolerance) {
+				continue
+			}
+			ret[layer] = append(ret[layer], f)
+			count++
+			if limit > 0 && count >= limit {
+				return ret
+			}
+		}
+	}
+	return ret
+}
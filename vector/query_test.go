@@ -0,0 +1,39 @@
+package vector
+
+import (
+	"testing"
+
+	"github.com/flywave/go-geom"
+)
+
+func TestQueryVector(t *testing.T) {
+	poly := geom.NewPolygonFeature([][][]float64{
+		{{0, 0}, {10, 0}, {10, 10}, {0, 10}, {0, 0}},
+		{{4, 4}, {6, 4}, {6, 6}, {4, 6}, {4, 4}},
+	})
+	line := geom.NewLineStringFeature([][]float64{{0, 20}, {10, 20}})
+	point := geom.NewPointFeature([]float64{2, 2})
+	vt := Vector{"area": {poly}, "road": {line}, "poi": {point}}
+
+	// 面内命中面，点容差内同时命中点
+	hits := QueryVector(vt, []float64{2.5, 2}, 1, 0)
+	if len(hits["area"]) != 1 || len(hits["poi"]) != 1 || len(hits["road"]) != 0 {
+		t.Errorf("unexpected hits %v", hits)
+	}
+
+	// 洞内不命中面
+	if hits := QueryVector(vt, []float64{5, 5}, 0.1, 0); len(hits) != 0 {
+		t.Errorf("point inside hole should not hit polygon, got %v", hits)
+	}
+
+	if hits := QueryVector(vt, []float64{5, 20.5}, 1, 0); len(hits["road"]) != 1 {
+		t.Errorf("expected line within tolerance to be hit, got %v", hits)
+	}
+	if hits := QueryVector(vt, []float64{5, 22}, 1, 0); len(hits) != 0 {
+		t.Errorf("expected no hit, got %v", hits)
+	}
+
+	if hits := QueryVector(vt, []float64{2.5, 2}, 1, 1); countFeatures(hits) != 1 {
+		t.Errorf("expected feature count limit 1, got %d", countFeatures(hits))
+	}
+}
@@ -1,72 +1,267 @@
 package vector
 
 import (
+	"math"
+
 	"github.com/flywave/go-geo"
 	"github.com/flywave/go-geom"
 
 	vec2d "github.com/flywave/go3d/float64/vec2"
 )
 
+const maxDensifyDepth = 8
+
+// VectorTransformer 转换要素坐标系。MaxError 大于 0 时按目标坐标系下的最大偏差加密长边，
+// 源坐标系为经纬度时在 180 度经线处拆分线与面，面的外环转为逆时针、内环转为顺时针，
+// ClipBBox 不为空时按目标坐标系下的范围裁剪转换结果
 type VectorTransformer struct {
-	SrcSRS geo.Proj
-	DstSRS geo.Proj
+	SrcSRS   geo.Proj
+	DstSRS   geo.Proj
+	MaxError float64
+	ClipBBox *vec2d.Rect
 }
 
 func NewVectorTransformer(src, dst geo.Proj) *VectorTransformer {
 	return &VectorTransformer{SrcSRS: src, DstSRS: dst}
 }
 
-func (t *VectorTransformer) project(line [][]float64) [][]float64 {
-	retline := make([][]float64, len(line))
+func (t *VectorTransformer) transform(line [][]float64) [][]float64 {
+	pts := make([]vec2d.T, len(line))
 	for i, p := range line {
-		pv := t.SrcSRS.TransformTo(t.DstSRS, []vec2d.T{{p[0], p[1]}})
-		retline[i] = pv[0][:]
+		pts[i] = vec2d.T{p[0], p[1]}
+	}
+	pts = t.SrcSRS.TransformTo(t.DstSRS, pts)
+	retline := make([][]float64, len(pts))
+	for i := range pts {
+		retline[i] = []float64{pts[i][0], pts[i][1]}
 	}
 	return retline
 }
 
+// densify 递归插入中点，直到转换后的中点与转换后端点连线中点的距离不超过 MaxError
+func (t *VectorTransformer) densify(a, b, pa, pb []float64, depth int, out [][]float64) [][]float64 {
+	if depth >= maxDensifyDepth {
+		return out
+	}
+	m := []float64{(a[0] + b[0]) / 2, (a[1] + b[1]) / 2}
+	pm := t.transform([][]float64{m})[0]
+	if math.Hypot(pm[0]-(pa[0]+pb[0])/2, pm[1]-(pa[1]+pb[1])/2) <= t.MaxError {
+		return out
+	}
+	out = t.densify(a, m, pa, pm, depth+1, out)
+	out = append(out, pm)
+	return t.densify(m, b, pm, pb, depth+1, out)
+}
+
+func (t *VectorTransformer) project(line [][]float64) [][]float64 {
+	proj := t.transform(line)
+	if t.MaxError <= 0 || len(line) < 2 {
+		return proj
+	}
+	retline := [][]float64{proj[0]}
+	for i := 1; i < len(line); i++ {
+		retline = t.densify(line[i-1], line[i], proj[i-1], proj[i], 0, retline)
+		retline = append(retline, proj[i])
+	}
+	return retline
+}
+
+// splitAntimeridian 在相邻点经度差超过 180 度处将折线断开，断点插值到 ±180 度经线上
+func splitAntimeridian(line [][]float64) [][][]float64 {
+	if len(line) < 2 {
+		return [][][]float64{line}
+	}
+	var ret [][][]float64
+	cur := [][]float64{line[0]}
+	for i := 1; i < len(line); i++ {
+		a, b := line[i-1], line[i]
+		dx := b[0] - a[0]
+		if math.Abs(dx) <= 180 {
+			cur = append(cur, b)
+			continue
+		}
+		edge, bx := 180.0, b[0]+360
+		if dx > 0 {
+			edge, bx = -180, b[0]-360
+		}
+		lat := a[1] + (edge-a[0])/(bx-a[0])*(b[1]-a[1])
+		ret = append(ret, append(cur, []float64{edge, lat}))
+		cur = [][]float64{{-edge, lat}, b}
+	}
+	return append(ret, cur)
+}
+
+// unwrapRing 使环上相邻点的经度差不超过 180 度
+func unwrapRing(ring [][]float64) [][]float64 {
+	ret := make([][]float64, len(ring))
+	offset := 0.0
+	for i, p := range ring {
+		if i > 0 {
+			dx := p[0] - ring[i-1][0]
+			if dx > 180 {
+				offset -= 360
+			} else if dx < -180 {
+				offset += 360
+			}
+		}
+		ret[i] = []float64{p[0] + offset, p[1]}
+	}
+	return ret
+}
+
+func shiftRings(rings [][][]float64, dx float64) [][][]float64 {
+	ret := make([][][]float64, len(rings))
+	for i, r := range rings {
+		ret[i] = make([][]float64, len(r))
+		for j, p := range r {
+			ret[i][j] = []float64{p[0] + dx, p[1]}
+		}
+	}
+	return ret
+}
+
+// splitPolygonAntimeridian 将跨越 180 度经线的面展开后按 [-180, 180] 拆分为多个面，包含极点的面保持不变
+func splitPolygonAntimeridian(rings [][][]float64) [][][][]float64 {
+	if len(rings) == 0 || len(rings[0]) == 0 {
+		return nil
+	}
+	unwrapped := make([][][]float64, 0, len(rings))
+	minx, maxx := math.Inf(1), math.Inf(-1)
+	for i, r := range rings {
+		if len(r) == 0 {
+			continue
+		}
+		u := unwrapRing(r)
+		if math.Abs(u[len(u)-1][0]-u[0][0]) >= 180 {
+			return [][][][]float64{rings}
+		}
+		if i == 0 {
+			for _, p := range u {
+				minx, maxx = math.Min(minx, p[0]), math.Max(maxx, p[0])
+			}
+		} else {
+			// 内环平移到与外环相同的经度区间
+			u = shiftRings([][][]float64{u}, 360*math.Round((unwrapped[0][0][0]-u[0][0])/360))[0]
+		}
+		unwrapped = append(unwrapped, u)
+	}
+	if minx >= -180 && maxx <= 180 {
+		return [][][][]float64{rings}
+	}
+
+	world := vec2d.Rect{Min: vec2d.T{-180, -90}, Max: vec2d.T{180, 90}}
+	var ret [][][][]float64
+	for _, dx := range []float64{-360, 0, 360} {
+		if c := clipPolygon(shiftRings(unwrapped, dx), world); len(c) > 0 {
+			ret = append(ret, c)
+		}
+	}
+	return ret
+}
+
+func signedRingArea(ring [][]float64) float64 {
+	a := 0.0
+	for i := 0; i+1 < len(ring); i++ {
+		a += ring[i][0]*ring[i+1][1] - ring[i+1][0]*ring[i][1]
+	}
+	return a / 2
+}
+
+// orientRings 使外环为逆时针、内环为顺时针
+func orientRings(rings [][][]float64) [][][]float64 {
+	for i, r := range rings {
+		if (signedRingArea(r) > 0) == (i == 0) {
+			continue
+		}
+		rev := make([][]float64, len(r))
+		for j, p := range r {
+			rev[len(r)-1-j] = p
+		}
+		rings[i] = rev
+	}
+	return rings
+}
+
 func (t *VectorTransformer) apply(feat *geom.Feature) *geom.Feature {
 	geometry := feat.GeometryData
 
 	if geometry.Type == "" && feat.Geometry != nil {
 		geometry = *geom.NewGeometryData(feat.Geometry)
 	}
+	split := t.SrcSRS.IsLatLong()
 
 	switch geometry.GetType() {
 	case "Point":
-		geometry.Point = t.project([][]float64{geometry.Point})[0]
+		geometry.Point = t.transform([][]float64{geometry.Point})[0]
 	case "MultiPoint":
-		geometry.MultiPoint = t.project(geometry.MultiPoint)
-	case "LineString":
-		geometry.LineString = t.project(geometry.LineString)
-	case "MultiLineString":
-		for i, ls := range geometry.MultiLineString {
-			geometry.MultiLineString[i] = t.project(ls)
-		}
-	case "Polygon":
-		for i, lr := range geometry.Polygon {
-			geometry.Polygon[i] = t.project(lr)
-		}
-	case "MultiPolygon":
-		for i, polys := range geometry.MultiPolygon {
-			for j, ls := range polys {
-				geometry.MultiPolygon[i][j] = t.project(ls)
+		geometry.MultiPoint = t.transform(geometry.MultiPoint)
+	case "LineString", "MultiLineString":
+		lines := geometry.MultiLineString
+		if geometry.GetType() == "LineString" {
+			lines = [][][]float64{geometry.LineString}
+		}
+		var projected [][][]float64
+		for _, ls := range lines {
+			parts := [][][]float64{ls}
+			if split {
+				parts = splitAntimeridian(ls)
+			}
+			for _, p := range parts {
+				projected = append(projected, t.project(p))
 			}
 		}
+		if geometry.GetType() == "LineString" && len(projected) == 1 {
+			geometry.LineString = projected[0]
+		} else {
+			geometry.Type, geometry.LineString, geometry.MultiLineString = "MultiLineString", nil, projected
+		}
+	case "Polygon", "MultiPolygon":
+		polys := geometry.MultiPolygon
+		if geometry.GetType() == "Polygon" {
+			polys = [][][][]float64{geometry.Polygon}
+		}
+		var projected [][][][]float64
+		for _, poly := range polys {
+			parts := [][][][]float64{poly}
+			if split {
+				parts = splitPolygonAntimeridian(poly)
+			}
+			for _, part := range parts {
+				rings := make([][][]float64, len(part))
+				for i, r := range part {
+					rings[i] = t.project(r)
+				}
+				projected = append(projected, orientRings(rings))
+			}
+		}
+		if len(projected) == 0 {
+			return nil
+		}
+		if geometry.GetType() == "Polygon" && len(projected) == 1 {
+			geometry.Polygon = projected[0]
+		} else {
+			geometry.Type, geometry.Polygon, geometry.MultiPolygon = "MultiPolygon", nil, projected
+		}
 	}
 
 	newFeature := geom.NewFeatureFromGeometryData(&geometry)
 	newFeature.Properties = feat.Properties
 	newFeature.ID = feat.ID
 
+	if t.ClipBBox != nil {
+		return ClipFeature(newFeature, *t.ClipBBox)
+	}
 	return newFeature
 }
 
 func (t *VectorTransformer) Apply(feats []*geom.Feature) []*geom.Feature {
 	rets := make([]*geom.Feature, 0, len(feats))
 	for _, f := range feats {
-		if f != nil {
-			rets = append(rets, t.apply(f))
+		if f == nil {
+			continue
+		}
+		if nf := t.apply(f); nf != nil {
+			rets = append(rets, nf)
 		}
 	}
 	return rets
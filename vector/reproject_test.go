@@ -4,7 +4,10 @@ import (
 	"testing"
 
 	"github.com/flywave/go-geo"
+	"github.com/flywave/go-geom"
 	"github.com/flywave/go-mapbox/mvt"
+
+	vec2d "github.com/flywave/go3d/float64/vec2"
 )
 
 func TestVectorTransformer(t *testing.T) {
@@ -31,3 +34,74 @@ func TestVectorTransformer(t *testing.T) {
 	}
 
 }
+
+func TestVectorTransformerDensify(t *testing.T) {
+	tran := NewVectorTransformer(geo.NewProj(4326), geo.NewProj(3857))
+	line := [][]float64{{0, 0}, {0, 80}}
+
+	if got := tran.project(line); len(got) != 2 {
+		t.Errorf("expected 2 points without densification, got %d", len(got))
+	}
+
+	tran.MaxError = 1000
+	got := tran.project(line)
+	if len(got) <= 2 {
+		t.Fatalf("expected densified line, got %d points", len(got))
+	}
+	last := tran.transform(line[1:])[0]
+	if got[len(got)-1][0] != last[0] || got[len(got)-1][1] != last[1] {
+		t.Errorf("expected line to end at %v, got %v", last, got[len(got)-1])
+	}
+}
+
+func TestSplitAntimeridian(t *testing.T) {
+	parts := splitAntimeridian([][]float64{{170, 0}, {-170, 10}})
+	if len(parts) != 2 {
+		t.Fatalf("expected 2 parts, got %d", len(parts))
+	}
+	if e := parts[0][len(parts[0])-1]; e[0] != 180 || e[1] != 5 {
+		t.Errorf("unexpected split point %v", e)
+	}
+	if s := parts[1][0]; s[0] != -180 || s[1] != 5 {
+		t.Errorf("unexpected split point %v", s)
+	}
+
+	polys := splitPolygonAntimeridian([][][]float64{{{170, 0}, {-170, 0}, {-170, 10}, {170, 10}, {170, 0}}})
+	if len(polys) != 2 {
+		t.Fatalf("expected 2 polygons, got %d", len(polys))
+	}
+	for _, p := range polys {
+		for _, pt := range p[0] {
+			if pt[0] < -180 || pt[0] > 180 {
+				t.Errorf("point %v outside [-180, 180]", pt)
+			}
+		}
+	}
+}
+
+func TestVectorTransformerPolygon(t *testing.T) {
+	srs4326 := geo.NewProj(4326)
+	tran := NewVectorTransformer(srs4326, srs4326)
+	tran.ClipBBox = &vec2d.Rect{Min: vec2d.T{0, 0}, Max: vec2d.T{5, 5}}
+
+	// 顺时针外环
+	g := geom.GeometryData{Type: "Polygon", Polygon: [][][]float64{{{0, 0}, {0, 10}, {10, 10}, {10, 0}, {0, 0}}}}
+	feat := tran.apply(geom.NewFeatureFromGeometryData(&g))
+	if feat == nil {
+		t.Fatal("expected clipped polygon")
+	}
+	ring := feat.GeometryData.Polygon[0]
+	if signedRingArea(ring) <= 0 {
+		t.Errorf("expected counter-clockwise exterior ring")
+	}
+	for _, pt := range ring {
+		if pt[0] > 5 || pt[1] > 5 {
+			t.Errorf("point %v outside clip bbox", pt)
+		}
+	}
+
+	g = geom.GeometryData{Type: "Point", Point: []float64{8, 8}}
+	if tran.apply(geom.NewFeatureFromGeometryData(&g)) != nil {
+		t.Errorf("expected point outside clip bbox to be dropped")
+	}
+}
@@ -0,0 +1,159 @@
+package vector
+
+import (
+	"math"
+
+	"github.com/flywave/go-geom"
+	vec2d "github.com/flywave/go3d/float64/vec2"
+
+	"github.com/flywave/go-tileproxy/tile"
+)
+
+func sqSegDist(p, a, b []float64) float64 {
+	x, y := a[0], a[1]
+	dx, dy := b[0]-x, b[1]-y
+	if dx != 0 || dy != 0 {
+		t := ((p[0]-x)*dx + (p[1]-y)*dy) / (dx*dx + dy*dy)
+		if t > 1 {
+			x, y = b[0], b[1]
+		} else if t > 0 {
+			x += dx * t
+			y += dy * t
+		}
+	}
+	dx, dy = p[0]-x, p[1]-y
+	return dx*dx + dy*dy
+}
+
+func simplifyDP(line [][]float64, first, last int, sqTolerance float64, keep []bool) {
+	maxSqDist, index := sqTolerance, -1
+	for i := first + 1; i < last; i++ {
+		if d := sqSegDist(line[i], line[first], line[last]); d > maxSqDist {
+			index, maxSqDist = i, d
+		}
+	}
+	if index < 0 {
+		return
+	}
+	keep[index] = true
+	simplifyDP(line, first, index, sqTolerance, keep)
+	simplifyDP(line, index, last, sqTolerance, keep)
+}
+
+// SimplifyLine 使用 Douglas-Peucker 算法简化折线，保留首尾点
+func SimplifyLine(line [][]float64, tolerance float64) [][]float64 {
+	if len(line) <= 2 || tolerance <= 0 {
+		return line
+	}
+	keep := make([]bool, len(line))
+	keep[0], keep[len(line)-1] = true, true
+	simplifyDP(line, 0, len(line)-1, tolerance*tolerance, keep)
+	ret := make([][]float64, 0, len(line))
+	for i := range line {
+		if keep[i] {
+			ret = append(ret, line[i])
+		}
+	}
+	return ret
+}
+
+func simplifyRings(rings [][][]float64, tolerance float64) [][][]float64 {
+	ret := make([][][]float64, 0, len(rings))
+	for i, r := range rings {
+		s := SimplifyLine(r, tolerance)
+		if len(s) < 4 {
+			// 外环退化时整个多边形无效，内环退化时直接丢弃
+			if i == 0 {
+				return nil
+			}
+			continue
+		}
+		ret = append(ret, s)
+	}
+	return ret
+}
+
+// SimplifyFeature 按容差简化要素几何，简化后退化的几何返回 nil
+func SimplifyFeature(feat *geom.Feature, tolerance float64) *geom.Feature {
+	geometry := feat.GeometryData
+	if geometry.Type == "" && feat.Geometry != nil {
+		geometry = *geom.NewGeometryData(feat.Geometry)
+	}
+
+	switch geometry.GetType() {
+	case "LineString":
+		geometry.LineString = SimplifyLine(geometry.LineString, tolerance)
+		if len(geometry.LineString) < 2 {
+			return nil
+		}
+	case "MultiLineString":
+		lines := make([][][]float64, 0, len(geometry.MultiLineString))
+		for _, ls := range geometry.MultiLineString {
+			if s := SimplifyLine(ls, tolerance); len(s) >= 2 {
+				lines = append(lines, s)
+			}
+		}
+		if len(lines) == 0 {
+			return nil
+		}
+		geometry.MultiLineString = lines
+	case "Polygon":
+		geometry.Polygon = simplifyRings(geometry.Polygon, tolerance)
+		if len(geometry.Polygon) == 0 {
+			return nil
+		}
+	case "MultiPolygon":
+		polys := make([][][][]float64, 0, len(geometry.MultiPolygon))
+		for _, p := range geometry.MultiPolygon {
+			if s := simplifyRings(p, tolerance); len(s) > 0 {
+				polys = append(polys, s)
+			}
+		}
+		if len(polys) == 0 {
+			return nil
+		}
+		geometry.MultiPolygon = polys
+	}
+
+	newFeature := geom.NewFeatureFromGeometryData(&geometry)
+	newFeature.Properties = feat.Properties
+	newFeature.ID = feat.ID
+	return newFeature
+}
+
+// DownsampleVector 合并下一级子瓦片的要素并按上级瓦片一个像素的大小简化，bbox 为上级瓦片的经纬度范围
+func DownsampleVector(children []tile.Source, bbox vec2d.Rect, opts *VectorOptions) Vector {
+	extent := 4096.0
+	pixels := 1.0
+	if opts != nil {
+		if opts.Extent > 0 {
+			extent = float64(opts.Extent)
+		}
+		if opts.Tolerance > 0 {
+			pixels = opts.Tolerance
+		}
+	}
+	tolerance := math.Max(bbox.Width(), bbox.Height()) / extent * pixels
+
+	ret := make(Vector)
+	for _, child := range children {
+		if child == nil {
+			continue
+		}
+		vt, ok := child.GetTile().(Vector)
+		if !ok {
+			continue
+		}
+		for layer, feats := range vt {
+			for _, f := range feats {
+				if f == nil {
+					continue
+				}
+				if s := SimplifyFeature(f, tolerance); s != nil {
+					ret[layer] = append(ret[layer], s)
+				}
+			}
+		}
+	}
+	return ret
+}
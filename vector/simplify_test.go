@@ -0,0 +1,26 @@
+package vector
+
+import (
+	"testing"
+
+	"github.com/flywave/go-geom"
+)
+
+func TestSimplifyFeature(t *testing.T) {
+	line := geom.NewLineStringFeature([][]float64{{0, 0}, {1, 0.01}, {2, 0}, {3, 1}})
+	line.Properties["name"] = "a"
+	s := SimplifyFeature(line, 0.1)
+	if len(s.GeometryData.LineString) != 3 || s.Properties["name"] != "a" {
+		t.Errorf("unexpected line %v", s.GeometryData.LineString)
+	}
+
+	poly := geom.NewPolygonFeature([][][]float64{{{0, 0}, {0.01, 0}, {0.01, 0.01}, {0, 0.01}, {0, 0}}})
+	if SimplifyFeature(poly, 1) != nil {
+		t.Error("collapsed polygon should be dropped")
+	}
+
+	pt := geom.NewPointFeature([]float64{1, 2})
+	if s := SimplifyFeature(pt, 1); s == nil || s.GeometryData.Point[0] != 1 {
+		t.Error("point should be kept")
+	}
+}
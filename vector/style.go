@@ -0,0 +1,486 @@
+package vector
+
+import (
+	"fmt"
+	"image/color"
+	"math"
+	"regexp"
+
+	"github.com/flywave/gg"
+	"github.com/flywave/go-geo"
+	"github.com/flywave/go-geom"
+	vec2d "github.com/flywave/go3d/float64/vec2"
+	"golang.org/x/image/font"
+
+	"github.com/flywave/go-tileproxy/imagery"
+)
+
+type StyleLayerType string
+
+const (
+	STYLE_FILL   StyleLayerType = "fill"
+	STYLE_LINE   StyleLayerType = "line"
+	STYLE_CIRCLE StyleLayerType = "circle"
+	STYLE_SYMBOL StyleLayerType = "symbol"
+)
+
+func IsValidStyleLayerType(t StyleLayerType) bool {
+	switch t {
+	case STYLE_FILL, STYLE_LINE, STYLE_CIRCLE, STYLE_SYMBOL:
+		return true
+	}
+	return false
+}
+
+// stopFactor 返回 zoom 在 z0 与 z1 之间的插值系数，base 大于 0 且不为 1 时按指数插值
+func stopFactor(zoom, z0, z1, base float64) float64 {
+	if z1 <= z0 {
+		return 0
+	}
+	if base <= 0 || base == 1 {
+		return (zoom - z0) / (z1 - z0)
+	}
+	return (math.Pow(base, zoom-z0) - 1) / (math.Pow(base, z1-z0) - 1)
+}
+
+// StyleNumber 随级别变化的数值，Stops 为级别与数值对，为空时使用 Value
+type StyleNumber struct {
+	Value float64
+	Base  float64
+	Stops [][2]float64
+}
+
+func (n *StyleNumber) At(zoom float64) float64 {
+	if len(n.Stops) == 0 {
+		return n.Value
+	}
+	if zoom <= n.Stops[0][0] {
+		return n.Stops[0][1]
+	}
+	for i := 0; i+1 < len(n.Stops); i++ {
+		a, b := n.Stops[i], n.Stops[i+1]
+		if zoom < b[0] {
+			return a[1] + (b[1]-a[1])*stopFactor(zoom, a[0], b[0], n.Base)
+		}
+	}
+	return n.Stops[len(n.Stops)-1][1]
+}
+
+type StyleColorStop struct {
+	Zoom  float64
+	Color color.NRGBA
+}
+
+// StyleColor 随级别变化的颜色，级别之间按通道插值
+type StyleColor struct {
+	Value color.NRGBA
+	Base  float64
+	Stops []StyleColorStop
+}
+
+func (c *StyleColor) At(zoom float64) color.NRGBA {
+	if len(c.Stops) == 0 {
+		return c.Value
+	}
+	if zoom <= c.Stops[0].Zoom {
+		return c.Stops[0].Color
+	}
+	for i := 0; i+1 < len(c.Stops); i++ {
+		a, b := c.Stops[i], c.Stops[i+1]
+		if zoom < b.Zoom {
+			t := stopFactor(zoom, a.Zoom, b.Zoom, c.Base)
+			lerp := func(x, y uint8) uint8 {
+				return uint8(math.Round(float64(x) + (float64(y)-float64(x))*t))
+			}
+			return color.NRGBA{R: lerp(a.Color.R, b.Color.R), G: lerp(a.Color.G, b.Color.G), B: lerp(a.Color.B, b.Color.B), A: lerp(a.Color.A, b.Color.A)}
+		}
+	}
+	return c.Stops[len(c.Stops)-1].Color
+}
+
+// StylePaint 图层的绘制属性，未设置的属性使用 Mapbox 样式规范的默认值
+type StylePaint struct {
+	FillColor         *StyleColor
+	FillOpacity       *StyleNumber
+	FillOutlineColor  *StyleColor
+	LineColor         *StyleColor
+	LineWidth         *StyleNumber
+	LineOpacity       *StyleNumber
+	CircleColor       *StyleColor
+	CircleRadius      *StyleNumber
+	CircleOpacity     *StyleNumber
+	CircleStrokeColor *StyleColor
+	CircleStrokeWidth *StyleNumber
+	TextField         string
+	TextFont          string
+	TextSize          *StyleNumber
+	TextColor         *StyleColor
+	TextHaloColor     *StyleColor
+	TextHaloWidth     *StyleNumber
+}
+
+// StyleLayer 样式图层，SourceLayer 为矢量瓦片中的图层名
+type StyleLayer struct {
+	ID          string
+	Type        StyleLayerType
+	SourceLayer string
+	MinZoom     *float64
+	MaxZoom     *float64
+	Filter      FeatureFilter
+	Paint       StylePaint
+}
+
+func (l *StyleLayer) inZoom(zoom float64) bool {
+	if l.MinZoom != nil && zoom < *l.MinZoom {
+		return false
+	}
+	if l.MaxZoom != nil && zoom >= *l.MaxZoom {
+		return false
+	}
+	return true
+}
+
+// VectorStyle 简化的矢量瓦片样式，图层按顺序绘制，文字标注最后绘制并避让已放置的标注
+type VectorStyle struct {
+	Background *StyleColor
+	Layers     []*StyleLayer
+}
+
+func NewVectorStyle(layers []*StyleLayer) *VectorStyle {
+	return &VectorStyle{Layers: layers}
+}
+
+// StyleZoom 按 256 像素的 Web 墨卡托瓦片由查询分辨率计算样式级别，经纬度坐标按赤道处每度的长度换算
+func StyleZoom(bbox vec2d.Rect, srs geo.Proj, size [2]uint32) float64 {
+	res := (bbox.Max[0] - bbox.Min[0]) / float64(size[0])
+	if srs.IsLatLong() {
+		res *= 2 * math.Pi * 6378137 / 360
+	}
+	return math.Log2(2 * math.Pi * 6378137 / 256 / res)
+}
+
+func numberAt(n *StyleNumber, zoom, def float64) float64 {
+	if n == nil {
+		return def
+	}
+	return n.At(zoom)
+}
+
+func colorAt(c *StyleColor, zoom float64, opacity float64) color.NRGBA {
+	var ret color.NRGBA
+	if c != nil {
+		ret = c.At(zoom)
+	} else {
+		ret = color.NRGBA{A: 255}
+	}
+	ret.A = uint8(math.Round(float64(ret.A) * math.Max(0, math.Min(1, opacity))))
+	return ret
+}
+
+var textFieldPattern = regexp.MustCompile(`\{([^}]+)\}`)
+
+// textField 用要素属性替换模板中的 {属性名}
+func textField(tmpl string, feat *geom.Feature) string {
+	return textFieldPattern.ReplaceAllStringFunc(tmpl, func(m string) string {
+		if v, ok := feat.Properties[m[1:len(m)-1]]; ok && v != nil {
+			return fmt.Sprint(v)
+		}
+		return ""
+	})
+}
+
+type labelBox struct {
+	minx, miny, maxx, maxy float64
+}
+
+func (b labelBox) intersects(o labelBox) bool {
+	return b.minx < o.maxx && o.minx < b.maxx && b.miny < o.maxy && o.miny < b.maxy
+}
+
+type styleRenderer struct {
+	dc     *gg.Context
+	bbox   vec2d.Rect
+	srs    geo.Proj
+	wgs84  geo.Proj
+	size   [2]uint32
+	zoom   float64
+	labels []labelBox
+	faces  map[string]font.Face
+}
+
+// toPixel 将经纬度坐标转换为影像像素坐标
+func (r *styleRenderer) toPixel(points [][]float64) [][2]float64 {
+	pts := make([]vec2d.T, len(points))
+	latlong := r.srs.IsLatLong()
+	for i, p := range points {
+		pts[i] = vec2d.T{p[0], p[1]}
+		if !latlong {
+			pts[i][1] = math.Max(-85.0511287798, math.Min(85.0511287798, p[1]))
+		}
+	}
+	if !r.srs.Eq(r.wgs84) {
+		pts = r.wgs84.TransformTo(r.srs, pts)
+	}
+	resx := (r.bbox.Max[0] - r.bbox.Min[0]) / float64(r.size[0])
+	resy := (r.bbox.Max[1] - r.bbox.Min[1]) / float64(r.size[1])
+	ret := make([][2]float64, len(pts))
+	for i, p := range pts {
+		ret[i] = [2]float64{(p[0] - r.bbox.Min[0]) / resx, (r.bbox.Max[1] - p[1]) / resy}
+	}
+	return ret
+}
+
+func (r *styleRenderer) addPath(points [][]float64, closed bool) {
+	pts := r.toPixel(points)
+	if len(pts) == 0 {
+		return
+	}
+	r.dc.NewSubPath()
+	r.dc.MoveTo(pts[0][0], pts[0][1])
+	for _, p := range pts[1:] {
+		r.dc.LineTo(p[0], p[1])
+	}
+	if closed {
+		r.dc.ClosePath()
+	}
+}
+
+func polygonsOf(g geom.GeometryData) [][][][]float64 {
+	switch g.GetType() {
+	case "Polygon":
+		return [][][][]float64{g.Polygon}
+	case "MultiPolygon":
+		return g.MultiPolygon
+	}
+	return nil
+}
+
+func linesOf(g geom.GeometryData) [][][]float64 {
+	switch g.GetType() {
+	case "LineString":
+		return [][][]float64{g.LineString}
+	case "MultiLineString":
+		return g.MultiLineString
+	}
+	var rings [][][]float64
+	for _, p := range polygonsOf(g) {
+		rings = append(rings, p...)
+	}
+	return rings
+}
+
+func pointsOf(g geom.GeometryData) [][]float64 {
+	switch g.GetType() {
+	case "Point":
+		return [][]float64{g.Point}
+	case "MultiPoint":
+		return g.MultiPoint
+	}
+	return nil
+}
+
+func (r *styleRenderer) fill(g geom.GeometryData, p *StylePaint) {
+	polys := polygonsOf(g)
+	if len(polys) == 0 {
+		return
+	}
+	for _, poly := range polys {
+		for _, ring := range poly {
+			r.addPath(ring, true)
+		}
+	}
+	opacity := numberAt(p.FillOpacity, r.zoom, 1)
+	r.dc.SetFillRuleEvenOdd()
+	r.dc.SetColor(colorAt(p.FillColor, r.zoom, opacity))
+	if p.FillOutlineColor == nil {
+		r.dc.Fill()
+		return
+	}
+	r.dc.FillPreserve()
+	r.dc.SetLineWidth(1)
+	r.dc.SetColor(colorAt(p.FillOutlineColor, r.zoom, opacity))
+	r.dc.Stroke()
+}
+
+func (r *styleRenderer) line(g geom.GeometryData, p *StylePaint) {
+	lines := linesOf(g)
+	if len(lines) == 0 {
+		return
+	}
+	for _, l := range lines {
+		r.addPath(l, false)
+	}
+	r.dc.SetLineCapRound()
+	r.dc.SetLineJoinRound()
+	r.dc.SetLineWidth(numberAt(p.LineWidth, r.zoom, 1))
+	r.dc.SetColor(colorAt(p.LineColor, r.zoom, numberAt(p.LineOpacity, r.zoom, 1)))
+	r.dc.Stroke()
+}
+
+func (r *styleRenderer) circle(g geom.GeometryData, p *StylePaint) {
+	radius := numberAt(p.CircleRadius, r.zoom, 5)
+	opacity := numberAt(p.CircleOpacity, r.zoom, 1)
+	stroke := numberAt(p.CircleStrokeWidth, r.zoom, 0)
+	for _, pt := range r.toPixel(pointsOf(g)) {
+		r.dc.DrawCircle(pt[0], pt[1], radius)
+		r.dc.SetColor(colorAt(p.CircleColor, r.zoom, opacity))
+		if stroke <= 0 {
+			r.dc.Fill()
+			continue
+		}
+		r.dc.FillPreserve()
+		r.dc.SetLineWidth(stroke)
+		r.dc.SetColor(colorAt(p.CircleStrokeColor, r.zoom, opacity))
+		r.dc.Stroke()
+	}
+}
+
+// anchors 返回标注位置：点取每个点，线取最长线的中点，面取外环范围的中心
+func anchors(g geom.GeometryData) [][]float64 {
+	if pts := pointsOf(g); len(pts) > 0 {
+		return pts
+	}
+	if polys := polygonsOf(g); len(polys) > 0 {
+		var ret [][]float64
+		for _, p := range polys {
+			if len(p) == 0 || len(p[0]) == 0 {
+				continue
+			}
+			minx, miny, maxx, maxy := p[0][0][0], p[0][0][1], p[0][0][0], p[0][0][1]
+			for _, c := range p[0] {
+				minx, miny = math.Min(minx, c[0]), math.Min(miny, c[1])
+				maxx, maxy = math.Max(maxx, c[0]), math.Max(maxy, c[1])
+			}
+			ret = append(ret, []float64{(minx + maxx) / 2, (miny + maxy) / 2})
+		}
+		return ret
+	}
+	var longest [][]float64
+	best := -1.0
+	for _, l := range linesOf(g) {
+		length := 0.0
+		for i := 0; i+1 < len(l); i++ {
+			length += math.Hypot(l[i+1][0]-l[i][0], l[i+1][1]-l[i][1])
+		}
+		if length > best {
+			longest, best = l, length
+		}
+	}
+	half := best / 2
+	for i := 0; i+1 < len(longest); i++ {
+		d := math.Hypot(longest[i+1][0]-longest[i][0], longest[i+1][1]-longest[i][1])
+		if half <= d && d > 0 {
+			t := half / d
+			return [][]float64{{longest[i][0] + (longest[i+1][0]-longest[i][0])*t, longest[i][1] + (longest[i+1][1]-longest[i][1])*t}}
+		}
+		half -= d
+	}
+	return nil
+}
+
+func (r *styleRenderer) face(name string, size float64) font.Face {
+	key := fmt.Sprintf("%s:%g", name, size)
+	if f, ok := r.faces[key]; ok {
+		return f
+	}
+	f := imagery.LoadFontFace(name, size)
+	r.faces[key] = f
+	return f
+}
+
+func (r *styleRenderer) symbol(feat *geom.Feature, g geom.GeometryData, p *StylePaint) {
+	text := textField(p.TextField, feat)
+	if text == "" {
+		return
+	}
+	size := numberAt(p.TextSize, r.zoom, 16)
+	halo := numberAt(p.TextHaloWidth, r.zoom, 0)
+	r.dc.SetFontFace(r.face(p.TextFont, size))
+	w, h := r.dc.MeasureString(text)
+
+	for _, pt := range r.toPixel(anchors(g)) {
+		box := labelBox{pt[0] - w/2 - halo, pt[1] - h/2 - halo, pt[0] + w/2 + halo, pt[1] + h/2 + halo}
+		if box.maxx < 0 || box.maxy < 0 || box.minx > float64(r.size[0]) || box.miny > float64(r.size[1]) {
+			continue
+		}
+		overlap := false
+		for _, b := range r.labels {
+			if b.intersects(box) {
+				overlap = true
+				break
+			}
+		}
+		if overlap {
+			continue
+		}
+		r.labels = append(r.labels, box)
+
+		if halo > 0 {
+			r.dc.SetColor(colorAt(p.TextHaloColor, r.zoom, 1))
+			for dy := -1; dy <= 1; dy++ {
+				for dx := -1; dx <= 1; dx++ {
+					if dx != 0 || dy != 0 {
+						r.dc.DrawStringAnchored(text, pt[0]+float64(dx)*halo, pt[1]+float64(dy)*halo, 0.5, 0.5)
+					}
+				}
+			}
+		}
+		r.dc.SetColor(colorAt(p.TextColor, r.zoom, 1))
+		r.dc.DrawStringAnchored(text, pt[0], pt[1], 0.5, 0.5)
+	}
+}
+
+// Render 将经纬度坐标的要素按 srs 下的 bbox 绘制为 size 大小的影像，zoom 为样式级别
+func (s *VectorStyle) Render(vt Vector, bbox vec2d.Rect, srs geo.Proj, size [2]uint32, zoom float64, opts *imagery.ImageOptions) *imagery.ImageSource {
+	if opts == nil {
+		opts = &imagery.ImageOptions{Format: "image/png", Transparent: geo.NewBool(true)}
+	}
+	r := &styleRenderer{
+		dc:    gg.NewContext(int(size[0]), int(size[1])),
+		bbox:  bbox,
+		srs:   srs,
+		wgs84: geo.NewProj(4326),
+		size:  size,
+		zoom:  zoom,
+		faces: make(map[string]font.Face),
+	}
+	if s.Background != nil {
+		r.dc.SetColor(s.Background.At(zoom))
+		r.dc.Clear()
+	}
+
+	var symbols []*StyleLayer
+	for _, l := range s.Layers {
+		if l == nil || !l.inZoom(zoom) {
+			continue
+		}
+		if l.Type == STYLE_SYMBOL {
+			symbols = append(symbols, l)
+			continue
+		}
+		s.renderLayer(r, vt, l)
+	}
+	for _, l := range symbols {
+		s.renderLayer(r, vt, l)
+	}
+	return imagery.CreateImageSourceFromImage(r.dc.Image(), opts)
+}
+
+func (s *VectorStyle) renderLayer(r *styleRenderer, vt Vector, l *StyleLayer) {
+	for _, f := range vt[l.SourceLayer] {
+		if f == nil || (l.Filter != nil && !l.Filter(f, int(math.Floor(r.zoom)))) {
+			continue
+		}
+		g := featureGeometry(f)
+		switch l.Type {
+		case STYLE_FILL:
+			r.fill(g, &l.Paint)
+		case STYLE_LINE:
+			r.line(g, &l.Paint)
+		case STYLE_CIRCLE:
+			r.circle(g, &l.Paint)
+		case STYLE_SYMBOL:
+			r.symbol(f, g, &l.Paint)
+		}
+	}
+}
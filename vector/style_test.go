@@ -0,0 +1,95 @@
+package vector
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"testing"
+
+	"github.com/flywave/go-geo"
+	"github.com/flywave/go-geom"
+	vec2d "github.com/flywave/go3d/float64/vec2"
+)
+
+func TestStyleStops(t *testing.T) {
+	n := &StyleNumber{Stops: [][2]float64{{10, 1}, {14, 5}}}
+	for _, c := range []struct{ zoom, want float64 }{{5, 1}, {10, 1}, {12, 3}, {20, 5}} {
+		if got := n.At(c.zoom); math.Abs(got-c.want) > 1e-9 {
+			t.Errorf("At(%v) = %v, want %v", c.zoom, got, c.want)
+		}
+	}
+	// 指数插值在级别区间前半段增长较慢
+	exp := &StyleNumber{Base: 2, Stops: [][2]float64{{10, 0}, {12, 3}}}
+	if got := exp.At(11); math.Abs(got-1) > 1e-9 {
+		t.Errorf("exponential At(11) = %v, want 1", got)
+	}
+
+	c := &StyleColor{Stops: []StyleColorStop{{Zoom: 0, Color: color.NRGBA{A: 255}}, {Zoom: 10, Color: color.NRGBA{R: 200, A: 255}}}}
+	if got := c.At(5); got.R != 100 || got.A != 255 {
+		t.Errorf("color At(5) = %v", got)
+	}
+}
+
+func TestVectorStyleRender(t *testing.T) {
+	red := color.NRGBA{R: 255, A: 255}
+	blue := color.NRGBA{B: 255, A: 255}
+	green := color.NRGBA{G: 255, A: 255}
+	filter, err := ParseFeatureFilter([]interface{}{"==", "kind", "lake"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	minzoom := 20.0
+	style := NewVectorStyle([]*StyleLayer{
+		{Type: STYLE_FILL, SourceLayer: "water", Filter: filter, Paint: StylePaint{FillColor: &StyleColor{Value: blue}}},
+		{Type: STYLE_LINE, SourceLayer: "road", Paint: StylePaint{LineColor: &StyleColor{Value: red}, LineWidth: &StyleNumber{Value: 4}}},
+		{Type: STYLE_CIRCLE, SourceLayer: "poi", Paint: StylePaint{CircleColor: &StyleColor{Value: green}, CircleRadius: &StyleNumber{Value: 3}}},
+		{Type: STYLE_CIRCLE, SourceLayer: "poi", MinZoom: &minzoom, Paint: StylePaint{CircleRadius: &StyleNumber{Value: 50}}},
+		{Type: STYLE_SYMBOL, SourceLayer: "poi", Paint: StylePaint{TextField: "{name}", TextSize: &StyleNumber{Value: 12}}},
+	})
+
+	lake := geom.NewPolygonFeature([][][]float64{{{0, 0}, {5, 0}, {5, 5}, {0, 5}, {0, 0}}})
+	lake.Properties["kind"] = "lake"
+	sea := geom.NewPolygonFeature([][][]float64{{{5, 5}, {10, 5}, {10, 10}, {5, 10}, {5, 5}}})
+	sea.Properties["kind"] = "sea"
+	road := geom.NewLineStringFeature([][]float64{{0, 7.5}, {5, 7.5}})
+	poi := geom.NewPointFeature([]float64{7.5, 2.5})
+	poi.Properties["name"] = "A"
+	vt := Vector{"water": {lake, sea}, "road": {road}, "poi": {poi}}
+
+	bbox := vec2d.Rect{Min: vec2d.T{0, 0}, Max: vec2d.T{10, 10}}
+	srs := geo.NewProj(4326)
+	size := [2]uint32{100, 100}
+	src := style.Render(vt, bbox, srs, size, StyleZoom(bbox, srs, size), nil)
+	img := src.GetTile().(image.Image)
+
+	at := func(x, y int) color.NRGBA {
+		return color.NRGBAModel.Convert(img.At(x, y)).(color.NRGBA)
+	}
+	if c := at(25, 75); c != blue {
+		t.Errorf("lake should be filled blue, got %v", c)
+	}
+	if c := at(75, 25); c.A != 0 {
+		t.Errorf("sea is filtered out and should be transparent, got %v", c)
+	}
+	if c := at(25, 25); c != red {
+		t.Errorf("road should be red, got %v", c)
+	}
+	if c := at(25, 20); c.A != 0 {
+		t.Errorf("outside road width should be transparent, got %v", c)
+	}
+	// 标注绘制在点符号之上
+	labeled := false
+	for y := 70; y < 80; y++ {
+		for x := 70; x < 80; x++ {
+			if c := at(x, y); c.A > 0 && c.G == 0 {
+				labeled = true
+			}
+		}
+	}
+	if !labeled {
+		t.Error("expected label pixels around the point")
+	}
+	if c := at(75, 60); c.A != 0 {
+		t.Errorf("circle layer above its zoom range should not be drawn, got %v", c)
+	}
+}
@@ -0,0 +1,362 @@
+package vector
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/flywave/go-geom"
+	"github.com/flywave/go-geos"
+	"github.com/flywave/go-mapbox/mvt"
+	"github.com/flywave/go-mapbox/tileid"
+)
+
+const (
+	// mvtExtent SavePBF 写出瓦片时使用的坐标范围
+	mvtExtent    = 4096
+	mercatorPole = 20037508.34
+)
+
+// ValidationReport 记录矢量瓦片校验时修复的内容
+type ValidationReport struct {
+	DuplicatePoints      int
+	DegenerateGeometries int
+	ReorientedRings      int
+	RepairedPolygons     int
+	InvalidPolygons      int
+	DroppedFeatures      int
+}
+
+// Fixed 返回是否修复过任何内容
+func (r *ValidationReport) Fixed() bool {
+	return *r != ValidationReport{}
+}
+
+// Unrepairable 返回是否有修复后仍无效而被丢弃的面
+func (r *ValidationReport) Unrepairable() bool {
+	return r.InvalidPolygons > 0
+}
+
+// Counts 按修复类型返回数量，用于记录统计
+func (r *ValidationReport) Counts() map[string]int {
+	return map[string]int{
+		"duplicate_points":      r.DuplicatePoints,
+		"degenerate_geometries": r.DegenerateGeometries,
+		"reoriented_rings":      r.ReorientedRings,
+		"repaired_polygons":     r.RepairedPolygons,
+		"invalid_polygons":      r.InvalidPolygons,
+		"dropped_features":      r.DroppedFeatures,
+	}
+}
+
+func (r *ValidationReport) String() string {
+	return fmt.Sprintf("duplicate points: %d, degenerate geometries: %d, reoriented rings: %d, repaired polygons: %d, invalid polygons: %d, dropped features: %d",
+		r.DuplicatePoints, r.DegenerateGeometries, r.ReorientedRings, r.RepairedPolygons, r.InvalidPolygons, r.DroppedFeatures)
+}
+
+// ValidationError 严格模式下瓦片存在无法修复的几何，或设置 FailOnRepair 且瓦片需要修复时返回
+type ValidationError struct {
+	Tile   [3]int
+	Report ValidationReport
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("vector tile %d/%d/%d is invalid: %s", e.Tile[2], e.Tile[0], e.Tile[1], e.Report.String())
+}
+
+// tileSnapper 在经纬度与瓦片网格坐标之间转换，网格坐标与 mvt 编码时的取整结果一致
+type tileSnapper struct {
+	cur *mvt.Cursor
+}
+
+func newTileSnapper(tile [3]int) *tileSnapper {
+	id := tileid.TileID{X: int64(tile[0]), Y: int64(tile[1]), Z: uint64(tile[2])}
+	return &tileSnapper{cur: mvt.NewCursorExtent(id, mvtExtent)}
+}
+
+func (s *tileSnapper) snap(p []float64) []float64 {
+	v := s.cur.SinglePoint(p)
+	return []float64{float64(v[0]), float64(v[1])}
+}
+
+func (s *tileSnapper) unsnap(p []float64) []float64 {
+	x := s.cur.Bounds.W + p[0]/mvtExtent*s.cur.DeltaX
+	y := s.cur.Bounds.N - p[1]/mvtExtent*s.cur.DeltaY
+	lon := x / mercatorPole * 180
+	lat := math.Atan(math.Exp(y/mercatorPole*math.Pi))*360/math.Pi - 90
+	return []float64{lon, lat}
+}
+
+func (s *tileSnapper) unsnapLine(line [][]float64) [][]float64 {
+	ret := make([][]float64, len(line))
+	for i, p := range line {
+		ret[i] = s.unsnap(p)
+	}
+	return ret
+}
+
+// snapLine 取整到瓦片网格并去除相邻的重复点，返回去除的点数
+func (s *tileSnapper) snapLine(line [][]float64) ([][]float64, int) {
+	ret := make([][]float64, 0, len(line))
+	for _, p := range line {
+		sp := s.snap(p)
+		if n := len(ret); n > 0 && ret[n-1][0] == sp[0] && ret[n-1][1] == sp[1] {
+			continue
+		}
+		ret = append(ret, sp)
+	}
+	return ret, len(line) - len(ret)
+}
+
+func closeRing(ring [][]float64) [][]float64 {
+	if n := len(ring); n > 0 && (ring[0][0] != ring[n-1][0] || ring[0][1] != ring[n-1][1]) {
+		ring = append(ring, ring[0])
+	}
+	return ring
+}
+
+// collinearRing 判断环上的点是否都在同一直线上，自相交的环面积可能为零，不能按面积判断
+func collinearRing(ring [][]float64) bool {
+	for i := 1; i < len(ring); i++ {
+		dx, dy := ring[i][0]-ring[0][0], ring[i][1]-ring[0][1]
+		if dx == 0 && dy == 0 {
+			continue
+		}
+		for _, p := range ring[i+1:] {
+			if dx*(p[1]-ring[0][1])-dy*(p[0]-ring[0][0]) != 0 {
+				return false
+			}
+		}
+		return true
+	}
+	return true
+}
+
+func reverseRing(ring [][]float64) [][]float64 {
+	ret := make([][]float64, len(ring))
+	for i, p := range ring {
+		ret[len(ring)-1-i] = p
+	}
+	return ret
+}
+
+// orientTileRings 按 MVT v2 规范在 y 轴向下的瓦片坐标中使外环为顺时针、内环为逆时针，返回调整的环数
+func orientTileRings(rings [][][]float64) int {
+	n := 0
+	for i, r := range rings {
+		if (signedRingArea(r) > 0) != (i == 0) {
+			rings[i] = reverseRing(r)
+			n++
+		}
+	}
+	return n
+}
+
+func toGeosCoords(ring [][]float64) []geos.Coord {
+	ret := make([]geos.Coord, len(ring))
+	for i, p := range ring {
+		ret[i] = geos.Coord{X: p[0], Y: p[1]}
+	}
+	return ret
+}
+
+func fromGeosRing(g *geos.Geometry) [][]float64 {
+	coords := g.GetCoords()
+	ret := make([][]float64, 0, len(coords))
+	for _, c := range coords {
+		p := []float64{math.Round(c.X), math.Round(c.Y)}
+		if n := len(ret); n > 0 && ret[n-1][0] == p[0] && ret[n-1][1] == p[1] {
+			continue
+		}
+		ret = append(ret, p)
+	}
+	return ret
+}
+
+func geosPolygons(g *geos.Geometry) [][][][]float64 {
+	if g == nil || g.IsEmpty() {
+		return nil
+	}
+	switch g.GetType() {
+	case geos.POLYGON:
+		rings := [][][]float64{fromGeosRing(g.GetExteriorRing())}
+		for i := 0; i < g.GetNumInteriorRings(); i++ {
+			rings = append(rings, fromGeosRing(g.GetInteriorRingN(i)))
+		}
+		return [][][][]float64{rings}
+	case geos.MULTIPOLYGON, geos.GEOMETRYCOLLECTION:
+		var ret [][][][]float64
+		for i := 0; i < g.GetNumGeometries(); i++ {
+			ret = append(ret, geosPolygons(g.GetGeometryN(i))...)
+		}
+		return ret
+	}
+	return nil
+}
+
+func geosPolygon(rings [][][]float64) *geos.Geometry {
+	holes := make([][]geos.Coord, 0, len(rings)-1)
+	for _, h := range rings[1:] {
+		holes = append(holes, toGeosCoords(h))
+	}
+	return geos.CreatePolygon(toGeosCoords(rings[0]), holes...)
+}
+
+// repairPolygon 通过 GEOS 修复自相交的面，修复结果取整到瓦片网格后仍无效的面被丢弃并计入 InvalidPolygons
+func repairPolygon(g *geos.Geometry, report *ValidationReport) [][][][]float64 {
+	report.RepairedPolygons++
+	var ret [][][][]float64
+	for _, p := range geosPolygons(g.Buffer(0)) {
+		var fixed [][][]float64
+		for i, r := range p {
+			if len(r) < 4 || signedRingArea(r) == 0 {
+				if i == 0 {
+					break
+				}
+				continue
+			}
+			fixed = append(fixed, r)
+		}
+		if len(fixed) == 0 {
+			continue
+		}
+		orientTileRings(fixed)
+		if pg := geosPolygon(fixed); pg == nil || !pg.IsValid() {
+			report.InvalidPolygons++
+			continue
+		}
+		ret = append(ret, fixed)
+	}
+	return ret
+}
+
+func (s *tileSnapper) validatePolygon(rings [][][]float64, report *ValidationReport) [][][][]float64 {
+	var snapped [][][]float64
+	for i, r := range rings {
+		sr, dup := s.snapLine(closeRing(r))
+		report.DuplicatePoints += dup
+		if len(sr) < 4 || collinearRing(sr) {
+			report.DegenerateGeometries++
+			if i == 0 {
+				return nil
+			}
+			continue
+		}
+		snapped = append(snapped, sr)
+	}
+	if len(snapped) == 0 {
+		return nil
+	}
+	report.ReorientedRings += orientTileRings(snapped)
+
+	g := geosPolygon(snapped)
+	if g == nil || g.IsValid() {
+		return [][][][]float64{snapped}
+	}
+	return repairPolygon(g, report)
+}
+
+func (s *tileSnapper) validateFeature(feat *geom.Feature, report *ValidationReport) *geom.Feature {
+	g := featureGeometry(feat)
+	var ret geom.GeometryData
+
+	switch g.GetType() {
+	case "Point", "MultiPoint":
+		pts := g.MultiPoint
+		if g.GetType() == "Point" {
+			pts = [][]float64{g.Point}
+		}
+		seen := make(map[[2]float64]bool, len(pts))
+		var out [][]float64
+		for _, p := range pts {
+			sp := s.snap(p)
+			if seen[[2]float64{sp[0], sp[1]}] {
+				report.DuplicatePoints++
+				continue
+			}
+			seen[[2]float64{sp[0], sp[1]}] = true
+			out = append(out, s.unsnap(sp))
+		}
+		if len(out) == 0 {
+			return nil
+		}
+		if g.GetType() == "Point" {
+			ret = geom.GeometryData{Type: "Point", Point: out[0]}
+		} else {
+			ret = geom.GeometryData{Type: "MultiPoint", MultiPoint: out}
+		}
+	case "LineString", "MultiLineString":
+		lines := g.MultiLineString
+		if g.GetType() == "LineString" {
+			lines = [][][]float64{g.LineString}
+		}
+		var out [][][]float64
+		for _, l := range lines {
+			sl, dup := s.snapLine(l)
+			report.DuplicatePoints += dup
+			if len(sl) < 2 {
+				report.DegenerateGeometries++
+				continue
+			}
+			out = append(out, s.unsnapLine(sl))
+		}
+		if len(out) == 0 {
+			return nil
+		}
+		if g.GetType() == "LineString" && len(out) == 1 {
+			ret = geom.GeometryData{Type: "LineString", LineString: out[0]}
+		} else {
+			ret = geom.GeometryData{Type: "MultiLineString", MultiLineString: out}
+		}
+	case "Polygon", "MultiPolygon":
+		polys := g.MultiPolygon
+		if g.GetType() == "Polygon" {
+			polys = [][][][]float64{g.Polygon}
+		}
+		var out [][][][]float64
+		for _, p := range polys {
+			for _, vp := range s.validatePolygon(p, report) {
+				rings := make([][][]float64, len(vp))
+				for i, r := range vp {
+					rings[i] = s.unsnapLine(r)
+				}
+				out = append(out, rings)
+			}
+		}
+		if len(out) == 0 {
+			return nil
+		}
+		if g.GetType() == "Polygon" && len(out) == 1 {
+			ret = geom.GeometryData{Type: "Polygon", Polygon: out[0]}
+		} else {
+			ret = geom.GeometryData{Type: "MultiPolygon", MultiPolygon: out}
+		}
+	default:
+		return feat
+	}
+
+	nf := geom.NewFeatureFromGeometryData(&ret)
+	nf.Properties = feat.Properties
+	nf.ID = feat.ID
+	return nf
+}
+
+// ValidateVector 按 MVT v2 规范校验并修复瓦片中的要素：取整到瓦片网格，去除重复点与退化的线和环，
+// 调整环的方向，通过 GEOS 修复自相交的面，并丢弃修复后为空的要素。不修改原数据
+func ValidateVector(vt Vector, tile [3]int) (Vector, *ValidationReport) {
+	s := newTileSnapper(tile)
+	report := &ValidationReport{}
+	ret := make(Vector, len(vt))
+	for _, layer := range vt.LayerNames() {
+		for _, f := range vt[layer] {
+			if f == nil {
+				continue
+			}
+			if nf := s.validateFeature(f, report); nf != nil {
+				ret[layer] = append(ret[layer], nf)
+			} else {
+				report.DroppedFeatures++
+			}
+		}
+	}
+	return ret, report
+}
@@ -0,0 +1,153 @@
+package vector
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/flywave/go-geom"
+
+	"github.com/flywave/go-tileproxy/metrics"
+)
+
+func newTestFeature(g geom.GeometryData) *geom.Feature {
+	f := geom.NewFeatureFromGeometryData(&g)
+	f.Properties = map[string]interface{}{"name": "test"}
+	return f
+}
+
+func TestValidateVector(t *testing.T) {
+	tile := [3]int{0, 0, 0}
+	vt := Vector{
+		"lines": {
+			newTestFeature(geom.GeometryData{Type: "LineString", LineString: [][]float64{{0, 0}, {0.001, 0.001}, {10, 10}}}),
+			newTestFeature(geom.GeometryData{Type: "LineString", LineString: [][]float64{{0, 0}, {0.001, 0.001}}}),
+		},
+		"polygons": {
+			// 自相交的蝴蝶结面
+			newTestFeature(geom.GeometryData{Type: "Polygon", Polygon: [][][]float64{{{0, 0}, {10, 10}, {10, 0}, {0, 10}, {0, 0}}}}),
+			// 面积为零的环
+			newTestFeature(geom.GeometryData{Type: "Polygon", Polygon: [][][]float64{{{0, 0}, {10, 0}, {20, 0}, {0, 0}}}}),
+		},
+	}
+
+	ret, report := ValidateVector(vt, tile)
+	if !report.Fixed() {
+		t.Fatal("expected fixes to be reported")
+	}
+	if len(ret["lines"]) != 1 || len(ret["polygons"]) != 1 {
+		t.Fatalf("expected 1 line and 1 polygon, got %d and %d", len(ret["lines"]), len(ret["polygons"]))
+	}
+	if report.DroppedFeatures != 2 {
+		t.Errorf("expected 2 dropped features, got %d", report.DroppedFeatures)
+	}
+	if report.DuplicatePoints == 0 {
+		t.Errorf("expected duplicate points to be removed")
+	}
+	if report.RepairedPolygons != 1 {
+		t.Errorf("expected 1 repaired polygon, got %d", report.RepairedPolygons)
+	}
+	if ret["lines"][0].Properties["name"] != "test" {
+		t.Errorf("expected properties to be kept")
+	}
+
+	s := newTileSnapper(tile)
+	g := featureGeometry(ret["polygons"][0])
+	polys := g.MultiPolygon
+	if g.GetType() == "Polygon" {
+		polys = [][][][]float64{g.Polygon}
+	}
+	for _, p := range polys {
+		ring, _ := s.snapLine(p[0])
+		if signedRingArea(ring) <= 0 {
+			t.Errorf("expected clockwise exterior ring in tile coordinates")
+		}
+	}
+}
+
+func TestValidateVectorOrientation(t *testing.T) {
+	vt := Vector{
+		"polygons": {
+			newTestFeature(geom.GeometryData{Type: "Polygon", Polygon: [][][]float64{
+				{{0, 0}, {20, 0}, {20, 20}, {0, 20}, {0, 0}},
+				{{5, 5}, {5, 15}, {15, 15}, {15, 5}, {5, 5}},
+			}}),
+		},
+	}
+	ret, report := ValidateVector(vt, [3]int{0, 0, 0})
+	if report.ReorientedRings != 2 {
+		t.Errorf("expected 2 reoriented rings, got %d", report.ReorientedRings)
+	}
+	if report.RepairedPolygons != 0 || report.DroppedFeatures != 0 {
+		t.Errorf("unexpected report: %s", report.String())
+	}
+
+	_, report = ValidateVector(ret, [3]int{0, 0, 0})
+	if report.Fixed() {
+		t.Errorf("expected validated tile to stay unchanged, got %s", report.String())
+	}
+}
+
+func TestRepairPolygonRounding(t *testing.T) {
+	// 修复结果的交点不在瓦片网格上，取整后仍自相交
+	ring := [][]float64{{4, 3}, {1, 4}, {0, 6}, {0, 5}, {3, 2}, {3, 6}, {1, 3}, {4, 3}}
+	report := &ValidationReport{}
+	polys := repairPolygon(geosPolygon([][][]float64{ring}), report)
+	if report.RepairedPolygons != 1 || report.InvalidPolygons == 0 {
+		t.Fatalf("expected invalid polygons after rounding to be counted: %s", report.String())
+	}
+	for _, p := range polys {
+		if g := geosPolygon(p); g == nil || !g.IsValid() {
+			t.Errorf("invalid polygon kept after repair: %v", p)
+		}
+	}
+}
+
+func TestEncodeVectorStrictValidate(t *testing.T) {
+	vt := Vector{
+		"polygons": {
+			newTestFeature(geom.GeometryData{Type: "Polygon", Polygon: [][][]float64{{{0, 0}, {10, 10}, {10, 0}, {0, 10}, {0, 0}}}}),
+		},
+	}
+	opts := &VectorOptions{Format: PBF_MIME, Validate: true}
+	if _, err := EncodeVector(opts, [3]int{0, 0, 0}, vt); err != nil {
+		t.Fatal(err)
+	}
+
+	// 可修复的问题在严格模式下只记录统计
+	metrics.ResetMetrics()
+	opts.StrictValidate = true
+	if _, err := EncodeVector(opts, [3]int{0, 0, 0}, vt); err != nil {
+		t.Fatalf("expected repairable tile to encode in strict mode, got %v", err)
+	}
+	fixed := metrics.GetStats()["vector_fixed"].(map[string]interface{})
+	if counts, ok := fixed["0"].(map[string]int64); !ok || counts["repaired_polygons"] != 1 || counts["tiles"] != 1 {
+		t.Errorf("expected repair to be recorded in metrics, got %v", fixed)
+	}
+
+	opts.StrictValidate = false
+	opts.FailOnRepair = true
+	_, err := EncodeVector(opts, [3]int{0, 0, 0}, vt)
+	var verr *ValidationError
+	if !errors.As(err, &verr) {
+		t.Fatalf("expected validation error, got %v", err)
+	}
+	if verr.Report.RepairedPolygons != 1 {
+		t.Errorf("expected 1 repaired polygon, got %d", verr.Report.RepairedPolygons)
+	}
+}
+
+func TestEncodeVectorValidateOnceWithBudget(t *testing.T) {
+	vt := budgetTestVector()
+	vt["polygons"] = []*geom.Feature{
+		newTestFeature(geom.GeometryData{Type: "Polygon", Polygon: [][][]float64{{{0, 0}, {10, 10}, {10, 0}, {0, 10}, {0, 0}}}}),
+	}
+	metrics.ResetMetrics()
+	opts := &VectorOptions{Format: PBF_MIME, Validate: true, Budget: &VectorBudget{SizeBudget: SizeBudget{MaxBytes: 1}, MaxIterations: 3}}
+	if _, err := EncodeVector(opts, [3]int{32, 31, 6}, vt); err != nil {
+		t.Fatal(err)
+	}
+	fixed := metrics.GetStats()["vector_fixed"].(map[string]interface{})
+	if counts, ok := fixed["6"].(map[string]int64); !ok || counts["tiles"] != 1 || counts["repaired_polygons"] != 1 {
+		t.Errorf("expected validation to be recorded once per tile, got %v", fixed)
+	}
+}
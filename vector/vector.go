@@ -5,24 +5,42 @@ import (
 	"errors"
 	"io"
 	"os"
+	"sort"
 
 	"github.com/flywave/go-geo"
 	"github.com/flywave/go-geom"
 	"github.com/flywave/go-mapbox/mvt"
+
+	"github.com/flywave/go-tileproxy/metrics"
 	"github.com/flywave/go-tileproxy/tile"
 )
 
 type Vector map[string][]*geom.Feature
 
+// LayerNames 返回按名称排序的图层名
+func (vt Vector) LayerNames() []string {
+	names := make([]string, 0, len(vt))
+	for name := range vt {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
 type VectorOptions struct {
 	tile.TileOptions
-	Format      tile.TileFormat
-	Tolerance   float64
-	Extent      uint16
-	Buffer      uint16
-	LineMetrics bool
-	MaxZoom     uint8
-	Proto       int
+	Format         tile.TileFormat
+	Tolerance      float64
+	Extent         uint16
+	Buffer         uint16
+	LineMetrics    bool
+	MaxZoom        uint8
+	Proto          int
+	Budget         *VectorBudget
+	InfoTolerance  float64
+	Validate       bool
+	StrictValidate bool
+	FailOnRepair   bool
 }
 
 func (s *VectorOptions) GetFormat() tile.TileFormat {
@@ -205,7 +223,47 @@ func (c *VectorSourceCreater) GetExtension() string {
 	return c.Opt.Format.Extension()
 }
 
+// EncodeVector 编码矢量瓦片，开启校验时先修复几何并记录统计，设置了 Budget 且超出当前级别的限制时缩减要素后重新编码
 func EncodeVector(opts *VectorOptions, tile [3]int, data Vector) ([]byte, error) {
+	data, err := validateVector(opts, tile, data)
+	if err != nil {
+		return nil, err
+	}
+	buf, err := encodeVector(opts, tile, data)
+	if err != nil || opts.Budget == nil {
+		return buf, err
+	}
+	budget := opts.Budget.ForZoom(tile[2])
+	if !budget.exceeded(len(buf), countFeatures(data)) {
+		return buf, nil
+	}
+	reduced, met, err := reduceVector(opts, tile, data, budget)
+	if err != nil {
+		return nil, err
+	}
+	metrics.RecordVectorReduction(tile[2], len(buf), len(reduced), met)
+	return reduced, nil
+}
+
+// validateVector 编码 MVT 前校验并修复几何，每个瓦片只执行一次
+func validateVector(opts *VectorOptions, tile [3]int, data Vector) (Vector, error) {
+	if opts.Format.Extension() != "mvt" && opts.Format.Extension() != "pbf" {
+		return data, nil
+	}
+	if !opts.Validate && !opts.StrictValidate && !opts.FailOnRepair {
+		return data, nil
+	}
+	data, report := ValidateVector(data, tile)
+	if report.Fixed() {
+		metrics.RecordVectorValidation(tile[2], report.Counts())
+	}
+	if (opts.StrictValidate && report.Unrepairable()) || (opts.FailOnRepair && report.Fixed()) {
+		return nil, &ValidationError{Tile: tile, Report: *report}
+	}
+	return data, nil
+}
+
+func encodeVector(opts *VectorOptions, tile [3]int, data Vector) ([]byte, error) {
 	if opts.Format.Extension() == "mvt" || opts.Format.Extension() == "pbf" {
 		io := &PBFIO{tile: tile, proto: mvt.ProtoType(opts.Proto)}
 		return io.Encode(data)